@@ -1,51 +1,115 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"log"
 	"os"
-	"strconv"
 
 	"github.com/bestmjj/prometheus-telegram-bot/internal/bot"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/config"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/heartbeat"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/monitor"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/notify"
 	"github.com/bestmjj/prometheus-telegram-bot/internal/prometheus"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/render"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/store"
 )
 
-var (
-	prometheusURL string
-	botToken      string
-	pageSize      int
-)
-
-func init() {
-	prometheusURL = os.Getenv("PROMETHEUS_URL")
-	if prometheusURL == "" {
-		log.Fatal("PROMETHEUS_URL environment variable not set")
-	}
-	botToken = os.Getenv("BOT_TOKEN")
-	if botToken == "" {
-		log.Fatal("BOT_TOKEN environment variable not set")
-	}
-	pageSizeStr := os.Getenv("PAGE_SIZE")
-	if pageSizeStr == "" {
-		pageSize = 5 // Default value if not set
-	} else {
-		var err error
-		pageSize, err = strconv.Atoi(pageSizeStr)
-		if err != nil {
-			log.Fatalf("PAGE_SIZE is invalid %v", err)
+func main() {
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if cfg.PrintConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			log.Fatal(err)
 		}
+		return
 	}
-}
 
-func main() {
-	prometheusClient, err := prometheus.NewClient(prometheusURL)
+	prometheusClient, err := prometheus.NewClient(cfg.PrometheusURL, cfg.FallbackURL)
 	if err != nil {
 		log.Fatalf("创建 Prometheus 客户端失败: %v", err)
 	}
+	prometheusClient.SetConcurrencyLimit(cfg.MaxConcurrency, cfg.MaxConcurrencyPerChat)
+	prometheusClient.SetMaxSeries(cfg.MaxQuerySeries)
+	prometheusClient.SetStaleThreshold(cfg.StaleThreshold)
+	prometheusClient.SetSlowQueryThreshold(cfg.SlowQueryThreshold)
+	prometheusClient.SetResourceWindows(cfg.ResourceWindows)
+	prometheusClient.SetNetworkRateWindows(cfg.NetworkRateWindows)
+	prometheusClient.SetFilesystemFilter(cfg.FilesystemFilter)
+	prometheusClient.SetDirectorySizeMetric(cfg.DirectorySizeMetric, cfg.DirectorySizeLabel)
+	prometheusClient.SetDedupeLabel(cfg.DedupeLabel)
 
-	botInstance, err := bot.NewBot(botToken, prometheusClient, pageSize)
+	st, err := store.Open(cfg.StorePath)
+	if err != nil {
+		log.Fatalf("打开存储失败: %v", err)
+	}
+
+	theme, err := render.NewTheme(cfg.Theme, cfg.ThemeOverrides)
+	if err != nil {
+		log.Fatalf("加载图标主题失败: %v", err)
+	}
+
+	directions, err := render.ParseDirections(cfg.TrafficDirections)
+	if err != nil {
+		log.Fatalf("加载流量方向名称失败: %v", err)
+	}
+
+	renderer, err := render.New(cfg.TemplatesDir, theme, render.NewLocale(cfg.Locale).WithDirections(directions))
+	if err != nil {
+		log.Fatalf("加载消息模板失败: %v", err)
+	}
+
+	botInstance, err := bot.NewBot(cfg, prometheusClient, st, renderer)
 	if err != nil {
 		log.Fatalf("创建 Telegram Bot 失败: %v", err)
 	}
 
+	mon := monitor.New(prometheusClient, st, cfg.PollInterval)
+	router, err := notify.LoadRouter(cfg.NotifyConfig, botInstance)
+	if err != nil {
+		log.Fatalf("加载通知路由配置失败: %v", err)
+	}
+	if !cfg.Enabled(config.FeatureWebhooks) {
+		router.DisableWebhooks()
+	}
+	mon.SetNotifier(router)
+	botInstance.SetNotifyRouter(router)
+	if cfg.StaleNotify {
+		mon.WatchStaleness(cfg.StaleThreshold)
+	}
+	mon.SetThresholds(cfg.Thresholds)
+	if cfg.UPSMinRuntime > 0 {
+		mon.WatchUPS(cfg.UPSMinRuntime)
+	}
+
+	// 机器人自身的心跳，供外部告警在机器人停止工作时发现
+	hb := heartbeat.New()
+	mon.SetPollObserver(hb)
+	if cfg.PushgatewayURL != "" {
+		go hb.RunPush(context.Background(), cfg.PushgatewayURL, cfg.PushInterval)
+	}
+	if cfg.MetricsAddr != "" {
+		go func() {
+			if err := hb.Serve(cfg.MetricsAddr); err != nil {
+				log.Printf("指标服务退出: %v", err)
+			}
+		}()
+		go botInstance.RunDerivedMetrics(context.Background(), hb.Registry())
+	}
+	go mon.Run(context.Background())
+	go botInstance.RunMenuExpiry(context.Background())
+	go botInstance.RunMonthlyReport(context.Background())
+	go botInstance.RunScheduledQueries(context.Background())
+	go botInstance.RunBackups(context.Background())
+	go botInstance.RunBriefings(context.Background())
+	go botInstance.RunBudgetChecks(context.Background())
+
 	botInstance.Start()
 }
@@ -0,0 +1,114 @@
+// Package backup 对机器人的存储数据进行加密和解密，用于定期备份和恢复
+package backup
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const (
+	// magic 标识备份文件的格式和版本
+	magic = "PTGBAK1\n"
+	// saltSize 和 nonceSize 是每个备份随机生成的盐和 AES-GCM nonce 的长度
+	saltSize  = 16
+	nonceSize = 12
+	// keySize 是 AES-256 的密钥长度
+	keySize = 32
+	// iterations 是 PBKDF2 的迭代次数，越大越难以暴力破解口令
+	iterations = 600000
+)
+
+// ErrInvalidPassphrase 表示口令错误或文件已损坏，两者无法区分
+var ErrInvalidPassphrase = errors.New("口令错误或备份文件已损坏")
+
+// Encrypt 使用口令加密数据。文件格式为 magic | salt | nonce | AES-256-GCM 密文，
+// 密钥由口令和随机盐经 PBKDF2-HMAC-SHA256 派生
+func Encrypt(plaintext []byte, passphrase string) ([]byte, error) {
+	salt := make([]byte, saltSize)
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("Failed to generate salt: %v", err)
+	}
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("Failed to generate nonce: %v", err)
+	}
+	aead, err := newAEAD(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, len(magic)+saltSize+nonceSize+len(plaintext)+aead.Overhead())
+	out = append(out, magic...)
+	out = append(out, salt...)
+	out = append(out, nonce...)
+	// magic 作为附加数据参与认证，防止篡改文件头
+	return aead.Seal(out, nonce, plaintext, []byte(magic)), nil
+}
+
+// Decrypt 解密 Encrypt 生成的数据
+func Decrypt(content []byte, passphrase string) ([]byte, error) {
+	if !bytes.HasPrefix(content, []byte(magic)) {
+		return nil, errors.New("不是机器人的备份文件")
+	}
+	content = content[len(magic):]
+	if len(content) < saltSize+nonceSize {
+		return nil, errors.New("备份文件不完整")
+	}
+	salt, nonce, ciphertext := content[:saltSize], content[saltSize:saltSize+nonceSize], content[saltSize+nonceSize:]
+	aead, err := newAEAD(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(magic))
+	if err != nil {
+		return nil, ErrInvalidPassphrase
+	}
+	return plaintext, nil
+}
+
+func newAEAD(passphrase string, salt []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(pbkdf2([]byte(passphrase), salt, iterations, keySize))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create cipher: %v", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create cipher: %v", err)
+	}
+	return aead, nil
+}
+
+// pbkdf2 按 RFC 8018 使用 HMAC-SHA256 从口令派生 keyLen 字节的密钥
+func pbkdf2(password, salt []byte, iter, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	hashLen := prf.Size()
+	blocks := (keyLen + hashLen - 1) / hashLen
+	key := make([]byte, 0, blocks*hashLen)
+	var counter [4]byte
+	u := make([]byte, hashLen)
+	t := make([]byte, hashLen)
+	for block := 1; block <= blocks; block++ {
+		binary.BigEndian.PutUint32(counter[:], uint32(block))
+		prf.Reset()
+		prf.Write(salt)
+		prf.Write(counter[:])
+		u = prf.Sum(u[:0])
+		copy(t, u)
+		for i := 1; i < iter; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+	return key[:keyLen]
+}
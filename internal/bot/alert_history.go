@@ -0,0 +1,159 @@
+package bot
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/store"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/utils"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	alertHistoryMenuID   = "alert_history"
+	alertHistoryPageSize = 10
+	alertHistoryUsage    = "用法: /alerts history [instance=<实例名>] [severity=info|warning|critical] [range=<范围>]\n" +
+		"范围: today | yesterday | 7d | 30d | month | lastmonth | 2026-10-01 | 2026-10-01~2026-10-15"
+)
+
+// alertHistorySearch 是某个聊天最近一次 /alerts history 的筛选条件，翻页时重新按条件查找
+type alertHistorySearch struct {
+	Filter store.NotificationFilter
+	// Range 是时间范围的名称，没有筛选时间时为空
+	Range string
+}
+
+type alertHistorySearches struct {
+	mu       sync.Mutex
+	searches map[int64]alertHistorySearch
+}
+
+func (c *alertHistorySearches) get(chatID int64) (alertHistorySearch, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.searches[chatID]
+	return s, ok
+}
+
+func (c *alertHistorySearches) set(chatID int64, s alertHistorySearch) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.searches == nil {
+		c.searches = make(map[int64]alertHistorySearch)
+	}
+	c.searches[chatID] = s
+}
+
+// handleAlertsCommand 处理 /alerts history，按实例、严重程度和时间范围检索已发送的告警通知
+func (b *BotInstance) handleAlertsCommand(chatID int64, args string) {
+	sub, rest, _ := strings.Cut(args, " ")
+	if sub != "" && sub != "history" {
+		b.sendText(chatID, escapeHTML(alertHistoryUsage))
+		return
+	}
+	search, err := parseAlertHistoryArgs(rest, time.Now())
+	if err != nil {
+		b.sendText(chatID, escapeHTML(fmt.Sprintf("%v\n%s", err, alertHistoryUsage)))
+		return
+	}
+	b.alertHistory.set(chatID, search)
+	if _, err := b.send(priorityInteractive, b.alertHistoryPage(chatID, 0, 1)); err != nil {
+		b.sendError(chatID, "发送告警历史", err)
+	}
+}
+
+// parseAlertHistoryArgs 解析 key=value 形式的筛选条件
+func parseAlertHistoryArgs(args string, now time.Time) (alertHistorySearch, error) {
+	var search alertHistorySearch
+	for _, field := range strings.Fields(args) {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok || value == "" {
+			return alertHistorySearch{}, fmt.Errorf("无效的筛选条件: %s", field)
+		}
+		switch key {
+		case "instance":
+			search.Filter.Instance = value
+		case "severity":
+			severity := store.Severity(strings.ToLower(value))
+			if !slices.Contains(store.Severities, severity) {
+				return alertHistorySearch{}, fmt.Errorf("无效的严重程度: %s", value)
+			}
+			search.Filter.Severity = severity
+		case "range":
+			r, ok := resolveTimeRange(value, now)
+			if !ok {
+				var err error
+				if r, err = parseCustomRange(value, now); err != nil {
+					return alertHistorySearch{}, err
+				}
+			}
+			search.Filter.Since, search.Filter.Until, search.Range = r.Start, r.End, r.Label
+		default:
+			return alertHistorySearch{}, fmt.Errorf("未知的筛选条件: %s", key)
+		}
+	}
+	return search, nil
+}
+
+// alertHistoryPage 显示告警历史的第 page 页
+func (b *BotInstance) alertHistoryPage(chatID int64, messageID int, page int) tgbotapi.Chattable {
+	search, ok := b.alertHistory.get(chatID)
+	if !ok {
+		return b.textPage(chatID, messageID, "告警历史已过期，请重新执行 /alerts history", nil)
+	}
+	notifications := b.Store.Notifications(search.Filter)
+	totalPages := (len(notifications) + alertHistoryPageSize - 1) / alertHistoryPageSize
+	if page < 1 || page > totalPages {
+		page = 1
+	}
+	startIndex := (page - 1) * alertHistoryPageSize
+	endIndex := min(startIndex+alertHistoryPageSize, len(notifications))
+
+	text := "<b>告警历史</b>"
+	if totalPages > 1 {
+		text += fmt.Sprintf(" (%d/%d)", page, totalPages)
+	}
+	text += "\n"
+	var conditions []string
+	if search.Filter.Instance != "" {
+		conditions = append(conditions, "实例 "+escapeHTML(search.Filter.Instance))
+	}
+	if search.Filter.Severity != "" {
+		conditions = append(conditions, "严重程度 "+search.Filter.Severity.Label())
+	}
+	if search.Range != "" {
+		conditions = append(conditions, escapeHTML(search.Range))
+	}
+	if len(conditions) > 0 {
+		text += "筛选: " + strings.Join(conditions, "，") + "\n"
+	}
+	text += fmt.Sprintf("共 %d 条\n\n", len(notifications))
+	if len(notifications) == 0 {
+		text += "没有符合条件的告警通知"
+	}
+	locale := b.chatLocale(chatID)
+	for _, n := range notifications[startIndex:endIndex] {
+		text += fmt.Sprintf("%s %s [%s] %s %s", b.Renderer.Glyph(eventGlyph(n.Kind)), locale.ShortDateTime(n.SentAt),
+			n.Severity.Label(), escapeHTML(utils.TruncateString(n.Instance, 30)), escapeHTML(n.Message))
+		if n.Digest {
+			text += "（汇总）"
+		}
+		text += "\n"
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var pageButtons []tgbotapi.InlineKeyboardButton
+	if page > 1 {
+		pageButtons = append(pageButtons, tgbotapi.NewInlineKeyboardButtonData("上一页", fmt.Sprintf("prev_%s_%d", alertHistoryMenuID, page-1)))
+	}
+	if endIndex < len(notifications) {
+		pageButtons = append(pageButtons, tgbotapi.NewInlineKeyboardButtonData("下一页", fmt.Sprintf("next_%s_%d", alertHistoryMenuID, page+1)))
+	}
+	if len(pageButtons) > 0 {
+		rows = append(rows, pageButtons)
+	}
+	return b.textPage(chatID, messageID, text, rows)
+}
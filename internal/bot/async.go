@@ -0,0 +1,57 @@
+package bot
+
+import (
+	"log"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const loadingText = "正在查询…"
+
+// isSlowMenu 判断菜单是否需要查询大量 Prometheus 数据
+func (b *BotInstance) isSlowMenu(menuID string) bool {
+	route, _, ok := b.menus.match(menuID)
+	return ok && route.slow
+}
+
+// showMenuPage 编辑消息显示指定菜单。对于耗时的菜单，先将消息改为加载提示，
+// 然后在后台生成页面，完成后再编辑为最终内容；超时则显示超时提示和重试按钮
+func (b *BotInstance) showMenuPage(chatID int64, messageID int, menuID string, page int) {
+	if !b.isSlowMenu(menuID) || messageID == 0 {
+		b.requestMenu(chatID, menuID, page, b.buildMenuPage(chatID, messageID, menuID, page))
+		return
+	}
+	if b.showCachedPage(chatID, messageID, menuID, page) {
+		return
+	}
+
+	// 加载提示只是过渡状态，编辑失败时不补发新消息，由后续的页面编辑处理
+	b.post(priorityInteractive, tgbotapi.NewEditMessageText(chatID, messageID, loadingText))
+
+	go func() {
+		done := make(chan tgbotapi.Chattable, 1)
+		go func() {
+			done <- b.buildMenuPage(chatID, messageID, menuID, page)
+		}()
+
+		select {
+		case msg := <-done:
+			// 用户在查询期间已离开该菜单时不再覆盖消息
+			if b.currentMenu(chatID) != menuID {
+				return
+			}
+			b.requestMenu(chatID, menuID, page, msg)
+		case <-time.After(b.config.MenuTimeout):
+			log.Printf("Menu page %s timed out after %s", menuID, b.config.MenuTimeout)
+			if b.currentMenu(chatID) != menuID {
+				return
+			}
+			rows := [][]tgbotapi.InlineKeyboardButton{tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("重试", retryCallback(menuID, page)),
+				tgbotapi.NewInlineKeyboardButtonData("返回主菜单", mainMenuID),
+			)}
+			b.editOrSend(b.textPage(chatID, messageID, "查询超时，Prometheus 响应较慢，请稍后重试。", rows))
+		}
+	}()
+}
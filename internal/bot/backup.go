@@ -0,0 +1,168 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/backup"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/store"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// maxBackupSize 是 /restore 接受的最大文件大小，与 Telegram 允许机器人下载的文件大小上限相同
+const maxBackupSize = 20 << 20
+
+// handleBackupCommand 处理 /backup，立即向当前聊天发送一份加密备份
+func (b *BotInstance) handleBackupCommand(chatID int64) {
+	if !b.isAdmin(chatID) {
+		b.sendText(chatID, "只有管理员可以备份机器人数据。")
+		return
+	}
+	if b.config.BackupPassphrase == "" {
+		b.sendText(chatID, "没有设置 BACKUP_PASSPHRASE，无法加密备份。")
+		return
+	}
+	if err := b.sendBackup(chatID, time.Now()); err != nil {
+		b.sendError(chatID, "发送备份", err)
+	}
+}
+
+// sendBackup 加密全部存储数据（订阅、阈值、定时任务、事件等）并作为文件发送
+func (b *BotInstance) sendBackup(chatID int64, now time.Time) error {
+	content, err := b.Store.Backup()
+	if err != nil {
+		return err
+	}
+	encrypted, err := backup.Encrypt(content, b.config.BackupPassphrase)
+	if err != nil {
+		return err
+	}
+	doc := tgbotapi.NewDocument(chatID, tgbotapi.FileBytes{Name: fmt.Sprintf("bot-backup-%s.bin", now.Format("20060102-1504")), Bytes: encrypted})
+	doc.Caption = "机器人数据备份（已加密）\n重新安装后发送 /restore，再发送此文件即可恢复。"
+	if _, err := b.send(priorityReport, doc); err != nil {
+		return fmt.Errorf("Failed to send backup document: %v", err)
+	}
+	return nil
+}
+
+// RunBackups 每隔 BackupInterval 向管理员发送一份加密备份，直到 ctx 被取消。
+// 发送时间记录在存储中，重启后按上次发送的时间继续计算
+func (b *BotInstance) RunBackups(ctx context.Context) {
+	if b.config.BackupInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(min(b.config.BackupInterval, time.Hour))
+	defer ticker.Stop()
+	b.sendScheduledBackup(time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			b.sendScheduledBackup(now)
+		}
+	}
+}
+
+func (b *BotInstance) sendScheduledBackup(now time.Time) {
+	if now.Sub(b.Store.LastBackup()) < b.config.BackupInterval {
+		return
+	}
+	for _, chatID := range b.config.AdminChatIDs {
+		if err := b.sendBackup(chatID, now); err != nil {
+			log.Printf("Failed to send backup to %d: %v", chatID, err)
+		}
+	}
+	if err := b.Store.MarkBackup(now); err != nil {
+		log.Printf("Failed to save backup state: %v", err)
+	}
+}
+
+// handleRestoreCommand 处理 /restore：等待管理员发送备份文件，解密并确认后替换当前的全部数据
+func (b *BotInstance) handleRestoreCommand(chatID int64) {
+	if !b.isAdmin(chatID) {
+		b.sendText(chatID, "只有管理员可以恢复机器人数据。")
+		return
+	}
+	if b.config.BackupPassphrase == "" {
+		b.sendText(chatID, "没有设置 BACKUP_PASSPHRASE，无法解密备份。请使用备份时的口令重新启动机器人。")
+		return
+	}
+	b.askDocument(chatID, "请发送 /backup 或自动备份生成的备份文件。", func(doc *tgbotapi.Document) bool {
+		if doc.FileSize > maxBackupSize {
+			b.sendText(chatID, "文件过大，不是机器人的备份文件。")
+			return false
+		}
+		content, err := b.downloadFile(doc.FileID)
+		if err != nil {
+			b.sendError(chatID, "下载备份文件", err)
+			return true
+		}
+		plaintext, err := backup.Decrypt(content, b.config.BackupPassphrase)
+		if err != nil {
+			b.sendText(chatID, fmt.Sprintf("无法解密: %s，请发送其他文件。", escapeHTML(err.Error())))
+			return false
+		}
+		summary, err := store.ParseBackup(plaintext)
+		if err != nil {
+			b.sendError(chatID, "读取备份", err)
+			return true
+		}
+		prompt := fmt.Sprintf("<b>%s</b> 包含:\n事件 %d 条，定时任务 %d 个，规则订阅 %d 个，阈值修改 %d 个，聊天 %d 个，分享授权 %d 个\n\n"+
+			"恢复后将替换机器人当前的全部数据，确认恢复？",
+			escapeHTML(doc.FileName), summary.Events, summary.ScheduledQueries, summary.RuleSubscriptions,
+			summary.ThresholdOverrides, summary.Chats, summary.Grants)
+		b.confirmAction(chatID, prompt, func() {
+			if err := b.Store.Restore(plaintext); err != nil {
+				b.sendError(chatID, "恢复备份", err)
+				return
+			}
+			b.sendText(chatID, "已恢复备份。")
+		})
+		return true
+	})
+}
+
+// downloadFile 下载用户发送给机器人的文件
+func (b *BotInstance) downloadFile(fileID string) ([]byte, error) {
+	resp, err := b.request(priorityInteractive, tgbotapi.FileConfig{FileID: fileID})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get file: %v", err)
+	}
+	var file tgbotapi.File
+	if err := json.Unmarshal(resp.Result, &file); err != nil {
+		return nil, fmt.Errorf("Failed to parse file: %v", err)
+	}
+	req, err := http.NewRequest(http.MethodGet, file.Link(b.BotAPI.Token), nil)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to download file: %v", err)
+	}
+	httpResp, err := b.BotAPI.Client.Do(req)
+	if err != nil {
+		// 下载地址中包含机器人的 token，不要出现在错误信息中
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, fmt.Errorf("Failed to download file: %v", err)
+	}
+	defer httpResp.Body.Close()
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Failed to download file: %s", httpResp.Status)
+	}
+	content, err := io.ReadAll(io.LimitReader(httpResp.Body, maxBackupSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to download file: %v", err)
+	}
+	if len(content) > maxBackupSize {
+		return nil, fmt.Errorf("Failed to download file: larger than %d bytes", maxBackupSize)
+	}
+	return content, nil
+}
@@ -0,0 +1,117 @@
+package bot
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	benchUsage = "用法: /bench [次数]，默认 5 次，最多 50 次"
+	// defaultBenchIterations 和 maxBenchIterations 是 /bench 每个视图的默认和最大运行次数
+	defaultBenchIterations = 5
+	maxBenchIterations     = 50
+)
+
+// benchViews 是基准测试覆盖的标准视图，实例相关的视图以 "%s" 代表测试实例
+var benchViews = []string{
+	instanceOverviewMenuID,
+	instanceDetailTableMenuID,
+	allInstancesMenuID,
+	fleetSystemMenuID,
+	upsMenuID,
+	instanceInfoPrefix + "%s",
+	uptimePrefix + "%s",
+	systemdPrefix + "%s",
+	directoriesPrefix + "%s",
+}
+
+// benchResult 是一个视图多次生成的耗时
+type benchResult struct {
+	view      string
+	durations []time.Duration
+}
+
+// percentile 按最近排名法返回已排序耗时的 p 分位数
+func (r benchResult) percentile(p float64) time.Duration {
+	i := int(float64(len(r.durations))*p+0.5) - 1
+	return r.durations[max(0, min(i, len(r.durations)-1))]
+}
+
+// handleBenchCommand 处理 /bench [次数]，依次生成各标准视图并统计端到端耗时，用于评估缓存和并发改动的效果。
+// 该命令不出现在帮助中，只有管理员可以使用；页面直接生成，不经过页面缓存
+func (b *BotInstance) handleBenchCommand(chatID int64, args string) {
+	if !b.isAdmin(chatID) {
+		b.sendText(chatID, "只有管理员可以使用此命令。")
+		return
+	}
+	iterations := defaultBenchIterations
+	if args != "" {
+		n, err := strconv.Atoi(args)
+		if err != nil || n <= 0 || n > maxBenchIterations {
+			b.sendText(chatID, benchUsage)
+			return
+		}
+		iterations = n
+	}
+
+	instances, err := b.fetchInstancesForMenu(chatID, onlineInstancesMenuID)
+	if err != nil {
+		b.sendError(chatID, "获取实例列表", err)
+		return
+	}
+	var instance string
+	if len(instances) > 0 {
+		instance = string(instances[0]["instance"])
+	}
+
+	b.sendText(chatID, fmt.Sprintf("正在对 %d 个视图各运行 %d 次…", len(benchViews), iterations))
+	go func() {
+		started := time.Now()
+		var results []benchResult
+		for _, view := range benchViews {
+			menuID := view
+			if strings.Contains(view, "%s") {
+				if instance == "" {
+					continue
+				}
+				menuID = fmt.Sprintf(view, instance)
+			}
+			r := benchResult{view: strings.TrimSuffix(view, ":%s")}
+			for range iterations {
+				viewStarted := time.Now()
+				b.editMenuPage(chatID, 0, menuID, 1)
+				r.durations = append(r.durations, time.Since(viewStarted))
+			}
+			sort.Slice(r.durations, func(i, j int) bool { return r.durations[i] < r.durations[j] })
+			results = append(results, r)
+		}
+		b.sendText(chatID, benchReport(results, iterations, instance, time.Since(started)))
+	}()
+}
+
+// benchReport 将结果格式化为等宽表格
+func benchReport(results []benchResult, iterations int, instance string, elapsed time.Duration) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "<b>视图基准测试</b>（每个视图 %d 次，共耗时 %s）\n", iterations, elapsed.Round(time.Millisecond))
+	if instance != "" {
+		fmt.Fprintf(&sb, "测试实例: %s\n", html.EscapeString(instance))
+	} else {
+		sb.WriteString("没有在线实例，跳过实例视图\n")
+	}
+	sb.WriteString("<pre>")
+	fmt.Fprintf(&sb, "%-22s %8s %8s %8s\n", "view", "p50", "p95", "max")
+	for _, r := range results {
+		fmt.Fprintf(&sb, "%-22s %8s %8s %8s\n", r.view,
+			benchDuration(r.percentile(0.5)), benchDuration(r.percentile(0.95)), benchDuration(r.durations[len(r.durations)-1]))
+	}
+	sb.WriteString("</pre>")
+	return sb.String()
+}
+
+func benchDuration(d time.Duration) string {
+	return fmt.Sprintf("%dms", d.Milliseconds())
+}
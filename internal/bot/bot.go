@@ -3,21 +3,53 @@ package bot
 import (
 	"fmt"
 	"log"
-	"net/url"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/bestmjj/prometheus-telegram-bot/internal/config"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/geo"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/notify"
 	"github.com/bestmjj/prometheus-telegram-bot/internal/prometheus"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/render"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/store"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/prometheus/common/model"
 )
 
 type BotInstance struct {
-	BotAPI           *tgbotapi.BotAPI
+	BotAPI *tgbotapi.BotAPI
+	// sender 是所有发往 Telegram 的消息共用的发送队列，不要直接调用 BotAPI.Send
+	sender           *sendQueue
 	PrometheusClient *prometheus.Client
+	Store            *store.Store
+	Renderer         *render.Renderer
 	PageSize         int
+	config           *config.Config
 	currentMessageID int
-	menuStack        []string
+	// menuStacks 是各聊天的菜单栈，由 menuMu 保护
+	menuStacks   map[int64][]menuEntry
+	menuMu       sync.Mutex
+	queryResults queryCache
+	alertHistory alertHistorySearches
+	selections   instanceSelections
+	// notifyRouter 是外部通知渠道的路由，用于预览通知规则，未设置时只预览 Telegram 通知
+	notifyRouter  *notify.Router
+	locales       chatLocales
+	alertBatch    alertBatch
+	digests       digestCache
+	pinned        pinnedAlerts
+	shortcuts     []shortcut
+	menus         *menuRouter
+	aliases       instanceAliases
+	pageCache     pageCache
+	snapshots     detailSnapshots
+	confirms      confirmations
+	conversations conversations
+	publicStatus  publicStatusLimiter
+	// geo 查询实例 IP 所在的国家和 ASN，未配置 GeoIP 数据库时为 nil
+	geo *geo.Resolver
 }
 
 const (
@@ -29,6 +61,8 @@ const (
 	onlineInstancesMenuID     = "online_instances"
 	offlineInstancesMenuID    = "offline_instances"
 	instanceDetailTableMenuID = "instance_detail_table" // 新增：实例详情表菜单ID
+	// instanceInfoPrefix 是实例详情页的菜单ID前缀，格式为 instance_info:<instance>
+	instanceInfoPrefix = "instance_info:"
 )
 
 type MenuItem struct {
@@ -36,8 +70,17 @@ type MenuItem struct {
 	CallbackData string
 }
 
-func NewBot(token string, prometheusClient *prometheus.Client, pageSize int) (*BotInstance, error) {
-	bot, err := tgbotapi.NewBotAPI(token)
+func NewBot(cfg *config.Config, prometheusClient *prometheus.Client, st *store.Store, renderer *render.Renderer) (*BotInstance, error) {
+	shortcuts, err := loadShortcuts(cfg.ShortcutsFile)
+	if err != nil {
+		return nil, err
+	}
+	geoResolver, err := geo.Open(cfg.GeoIPCountryDB, cfg.GeoIPASNDB)
+	if err != nil {
+		return nil, err
+	}
+
+	bot, err := tgbotapi.NewBotAPI(cfg.BotToken)
 	if err != nil {
 		return nil, fmt.Errorf("创建 Telegram Bot 失败: %w", err)
 	}
@@ -46,31 +89,63 @@ func NewBot(token string, prometheusClient *prometheus.Client, pageSize int) (*B
 
 	return &BotInstance{
 		BotAPI:           bot,
+		sender:           newSendQueue(bot),
 		PrometheusClient: prometheusClient,
-		PageSize:         pageSize,
-		menuStack:        []string{mainMenuID},
+		Store:            st,
+		Renderer:         renderer,
+		PageSize:         cfg.PageSize,
+		config:           cfg,
+		menuStacks:       make(map[int64][]menuEntry),
+		shortcuts:        shortcuts,
+		menus:            newMenuRouter(),
+		geo:              geoResolver,
 	}, nil
 }
 
+// SetNotifyRouter 设置外部通知渠道的路由，预览通知规则时据此列出会收到通知的渠道
+func (b *BotInstance) SetNotifyRouter(r *notify.Router) {
+	b.notifyRouter = r
+}
+
 func (b *BotInstance) Start() {
-	u := tgbotapi.NewUpdate(0)
-	u.Timeout = 60
-	updates := b.BotAPI.GetUpdatesChan(u)
+	updates := b.pollUpdates()
 
 	for update := range updates {
-		if update.CallbackQuery != nil {
+		if update.MessageReaction != nil {
+			b.handleReaction(update.MessageReaction)
+		} else if update.CallbackQuery != nil {
+			if update.CallbackQuery.Message == nil {
+				continue
+			}
+			b.rememberLocale(update.CallbackQuery.Message.Chat.ID, update.CallbackQuery.From)
+			if !b.hasFullAccess(update.CallbackQuery.Message.Chat.ID) {
+				b.handleGuestCallback(update.CallbackQuery)
+				continue
+			}
 			b.handleCallback(update.CallbackQuery)
+		} else if update.ChannelPost != nil {
+			if b.isPublicStatusChat(update.ChannelPost.Chat.ID) {
+				b.handlePublicMessage(update.ChannelPost)
+			}
 		} else if update.Message != nil {
-			if strings.HasPrefix(update.Message.Text, "/start=") {
-				parts := strings.Split(update.Message.Text, "=")
-				if len(parts) > 1 {
-					callbackData := parts[1]
-					b.pushMenu(callbackData)
-					b.currentMessageID = b.sendMenuPage(update.Message.Chat.ID, 1)
-				} else {
-					b.currentMessageID = b.sendMenuPage(update.Message.Chat.ID, 1)
-
-				}
+			b.rememberLocale(update.Message.Chat.ID, update.Message.From)
+			if b.isPublicStatusChat(update.Message.Chat.ID) {
+				b.handlePublicMessage(update.Message)
+				continue
+			}
+			if token, ok := shareStartToken(update.Message); ok {
+				b.redeemShare(update.Message.Chat.ID, token)
+				continue
+			}
+			if !b.hasFullAccess(update.Message.Chat.ID) {
+				b.handleGuestMessage(update.Message.Chat.ID)
+				continue
+			}
+			b.rememberChat(update.Message.Chat.ID)
+			if update.Message.IsCommand() && b.handleCommand(update.Message) {
+				continue
+			}
+			if !update.Message.IsCommand() && b.handleConversation(update.Message.Chat.ID, update.Message) {
 				continue
 			}
 			b.currentMessageID = b.sendMenuPage(update.Message.Chat.ID, 1)
@@ -80,52 +155,38 @@ func (b *BotInstance) Start() {
 }
 
 func (b *BotInstance) sendMenuPage(chatID int64, page int) int {
-	menuID := b.currentMenu()
-	msg := b.editMenuPage(chatID, 0, menuID, page)
-	if messageID, ok := msg.(tgbotapi.MessageConfig); ok {
-		sentMsg, err := b.BotAPI.Send(messageID)
-		if err != nil {
-			log.Printf("发送菜单失败: %v", err)
-			return 0
-		}
-		return sentMsg.MessageID
-	} else {
-		editMsg := msg.(tgbotapi.EditMessageTextConfig)
-		_, err := b.BotAPI.Request(editMsg)
-		if err != nil {
-			log.Printf("发送菜单失败: %v", err)
-			return 0
-		}
-		return editMsg.MessageID
+	menuID := b.currentMenu(chatID)
+	messageID, err := b.editOrSend(b.editMenuPage(chatID, 0, menuID, page))
+	if err != nil {
+		log.Printf("发送菜单失败: %v", err)
+		return 0
 	}
+	b.touchMenu(chatID, messageID)
+	return messageID
+}
+
+// openMenu 在新消息中打开菜单，用于通知消息上的按钮，通知本身保持不变
+func (b *BotInstance) openMenu(chatID int64, menuID string) {
+	route, param, ok := b.menus.match(menuID)
+	if !ok {
+		b.sendText(chatID, "未知菜单")
+		return
+	}
+	b.recordUsage(chatID, store.UsageMenu, route.name)
+	if strings.HasPrefix(menuID, instanceInfoPrefix) {
+		b.recordUsage(chatID, store.UsageInstance, param)
+	}
+	page := b.navigateTo(chatID, menuID)
+	b.currentMessageID = b.sendMenuPage(chatID, page)
 }
 
 func (b *BotInstance) editMenuPage(chatID int64, messageID int, menuID string, page int) tgbotapi.Chattable {
-	switch menuID {
-	case mainMenuID:
-		return b.mainMenuPage(chatID, messageID)
-	case instanceMenuID:
-		return b.instanceMenuPage(chatID, messageID)
-	case instanceOverviewMenuID:
-		return b.instanceOverviewMenuPage(chatID, messageID)
-	case allInstancesMenuID:
-		return b.allInstancesMenuPage(chatID, messageID, page)
-	case onlineInstancesMenuID:
-		return b.onlineInstancesMenuPage(chatID, messageID, page)
-	case offlineInstancesMenuID:
-		return b.offlineInstancesMenuPage(chatID, messageID, page)
-	case otherMenuID:
-		return b.otherMenuPage(chatID, messageID)
-	case instanceDetailTableMenuID: // 新增：处理实例详情表菜单
-		// Pass page explicitly
-		return b.instanceDetailTableMenuPage(chatID, messageID, page)
-	default:
-		if strings.HasPrefix(menuID, "instance_info:") {
-			instanceName := strings.TrimPrefix(menuID, "instance_info:")
-			return b.instanceInfoPage(chatID, messageID, instanceName)
-		}
+	route, param, ok := b.menus.match(menuID)
+	if !ok {
 		return tgbotapi.NewMessage(chatID, "未知菜单")
 	}
+	msg := route.handler(b, menuRequest{ChatID: chatID, MessageID: messageID, MenuID: menuID, Param: param, Page: page})
+	return b.redactMessage(chatID, withHeader(msg, b.breadcrumbs(chatID)))
 }
 
 func (b *BotInstance) handleCallback(callback *tgbotapi.CallbackQuery) {
@@ -134,102 +195,141 @@ func (b *BotInstance) handleCallback(callback *tgbotapi.CallbackQuery) {
 	messageID := callback.Message.MessageID
 	//log.Printf("Callback data %v", data)
 
+	if b.expireIfStale(chatID, messageID, time.Now()) {
+		b.post(priorityInteractive, tgbotapi.NewCallback(callback.ID, "会话已过期"))
+		return
+	}
+
+	if menuID, page, ok := parsePageCallback(data); ok {
+		b.setMenuPage(chatID, menuID, page)
+		b.showMenuPage(chatID, messageID, menuID, page)
+		b.post(priorityInteractive, tgbotapi.NewCallback(callback.ID, ""))
+		return
+	}
 	if strings.HasPrefix(data, "prev_") || strings.HasPrefix(data, "next_") {
-		parts := strings.Split(data, "_")
-		if len(parts) < 3 {
-			log.Printf("Invalid page callback data: %v", data)
-			return
-		}
-		page, err := strconv.Atoi(parts[len(parts)-1])
-		if err != nil {
-			log.Printf("Invalid page number %v from %v", parts[len(parts)-1], data)
-			return
-		}
-		menuID := strings.Join(parts[1:len(parts)-1], "_")
-		editMsg := b.editMenuPage(chatID, messageID, menuID, page)
-		b.BotAPI.Request(editMsg)
-		b.BotAPI.Request(tgbotapi.NewCallback(callback.ID, ""))
+		log.Printf("Invalid page callback data: %v", data)
 		return
 	}
 
-	// 检查是否是实例详情的回调数据
-	if strings.HasPrefix(data, "instance_detail:") {
-		instanceName := strings.TrimPrefix(data, "instance_detail:")
+	// 旧版本通知消息上的实例详情按钮，格式为 instance_detail:<实例>
+	if instanceName, ok := strings.CutPrefix(data, "instance_detail:"); ok {
+		data = openMenuPrefix + instanceInfoPrefix + instanceName
+	}
 
-		// 查找实例
-		var selectedInstance model.Metric
-		allInstances := b.fetchInstancesForMenu(allInstancesMenuID)
-		for _, instance := range allInstances {
-			if string(instance["instance"]) == instanceName {
-				selectedInstance = instance
-				break
-			}
-		}
+	if menuID, ok := strings.CutPrefix(data, openMenuPrefix); ok {
+		b.post(priorityInteractive, tgbotapi.NewCallback(callback.ID, ""))
+		b.openMenu(chatID, menuID)
+		return
+	}
 
-		if len(selectedInstance) == 0 {
-			b.editMessage(chatID, messageID, "找不到指定的实例，请重试。")
-			return
-		}
+	if strings.HasPrefix(data, alertPrefix) {
+		text := b.handleAlertCallback(callback, strings.TrimPrefix(data, alertPrefix))
+		b.post(priorityInteractive, tgbotapi.NewCallback(callback.ID, text))
+		return
+	}
 
-		info, err := b.PrometheusClient.GetInstanceInfo(selectedInstance)
-		if err != nil {
-			b.editMessage(chatID, messageID, fmt.Sprintf("获取实例信息失败: %v", err))
-			return
-		}
+	if strings.HasPrefix(data, confirmPrefix) {
+		text := b.handleConfirmCallback(chatID, messageID, strings.TrimPrefix(data, confirmPrefix))
+		b.post(priorityInteractive, tgbotapi.NewCallback(callback.ID, text))
+		return
+	}
 
-		msg := tgbotapi.NewMessage(chatID, info)
-		msg.ParseMode = "HTML"
-		b.BotAPI.Send(msg)
-		b.BotAPI.Request(tgbotapi.NewCallback(callback.ID, ""))
+	if strings.HasPrefix(data, shortcutPrefix) {
+		b.post(priorityInteractive, tgbotapi.NewCallback(callback.ID, ""))
+		b.handleShortcutCallback(chatID, messageID, data)
 		return
 	}
 
-	switch data {
-	case mainMenuID, instanceMenuID, otherMenuID, instanceOverviewMenuID, instanceDetailTableMenuID: // 添加新菜单ID到主菜单切换处理
-		// 简单的导航逻辑优化
-		if data == mainMenuID {
-			// 如果是返回主菜单，重置栈
-			b.menuStack = []string{mainMenuID}
-		} else if len(b.menuStack) > 1 && b.menuStack[len(b.menuStack)-2] == data {
-			// 如果是返回上一级（目标ID等于栈中倒数第二个ID），则出栈
-			b.popMenu()
-		} else if data != b.currentMenu() {
-			// 如果不是刷新当前页，则入栈
-			b.pushMenu(data)
-		}
+	if strings.HasPrefix(data, digestPrefix) {
+		b.handleDigestCallback(chatID, messageID, strings.TrimPrefix(data, digestPrefix))
+		b.post(priorityInteractive, tgbotapi.NewCallback(callback.ID, ""))
+		return
+	}
 
-		editMsg := b.editMenuPage(chatID, messageID, data, 1)
-		if _, err := b.BotAPI.Request(editMsg); err != nil {
-			log.Printf("Failed to edit menu page: %v", err)
-		}
-		b.BotAPI.Request(tgbotapi.NewCallback(callback.ID, ""))
-	case allInstancesMenuID, onlineInstancesMenuID, offlineInstancesMenuID:
-		b.pushMenu(data)
-		editMsg := b.editMenuPage(chatID, messageID, data, 1)
-		b.BotAPI.Request(editMsg)
-		b.BotAPI.Request(tgbotapi.NewCallback(callback.ID, ""))
-	default:
-		// 当点击具体实例时，不再发送新消息，而是进入实例详情菜单
-		// 构造一个特殊的菜单ID来表示实例详情
-		instanceInfoMenuID := "instance_info:" + data
-
-		// 检查是否已经在详情页（避免重复点击）
-		if b.currentMenu() == instanceInfoMenuID {
-			b.BotAPI.Request(tgbotapi.NewCallback(callback.ID, ""))
-			return
+	if strings.HasPrefix(data, scheduleActionPrefix) {
+		b.post(priorityInteractive, tgbotapi.NewCallback(callback.ID, ""))
+		b.handleScheduleCallback(chatID, strings.TrimPrefix(data, scheduleActionPrefix))
+		return
+	}
+
+	if strings.HasPrefix(data, briefingPrefix) {
+		b.post(priorityInteractive, tgbotapi.NewCallback(callback.ID, ""))
+		b.handleBriefingCallback(chatID, messageID, strings.TrimPrefix(data, briefingPrefix))
+		return
+	}
+
+	if strings.HasPrefix(data, subscribePrefix) {
+		text := b.handleSubscribeCallback(chatID, messageID, strings.TrimPrefix(data, subscribePrefix))
+		b.post(priorityInteractive, tgbotapi.NewCallback(callback.ID, text))
+		return
+	}
+
+	// 关闭图表前发出的图表按钮仍然可以点击
+	if (strings.HasPrefix(data, chartPrefsPrefix) || strings.HasPrefix(data, chartPrefix)) && !b.config.Enabled(config.FeatureCharts) {
+		b.post(priorityInteractive, tgbotapi.NewCallback(callback.ID, featureDisabledText))
+		return
+	}
+
+	if strings.HasPrefix(data, chartPrefsPrefix) {
+		b.post(priorityInteractive, tgbotapi.NewCallback(callback.ID, ""))
+		b.handleChartPrefsCallback(chatID, messageID, strings.TrimPrefix(data, chartPrefsPrefix))
+		return
+	}
+
+	if strings.HasPrefix(data, trafficRangePrefix) {
+		b.post(priorityInteractive, tgbotapi.NewCallback(callback.ID, "正在统计流量..."))
+		go b.handleTrafficRangeCallback(chatID, strings.TrimPrefix(data, trafficRangePrefix))
+		return
+	}
+
+	if strings.HasPrefix(data, chartPrefix) {
+		b.post(priorityInteractive, tgbotapi.NewCallback(callback.ID, "正在生成图表..."))
+		go b.sendChart(chatID, strings.TrimPrefix(data, chartPrefix))
+		return
+	}
+
+	if route, param, ok := b.menus.match(data); ok {
+		b.recordUsage(chatID, store.UsageMenu, route.name)
+		if strings.HasPrefix(data, instanceInfoPrefix) {
+			b.recordUsage(chatID, store.UsageInstance, param)
 		}
+		page := b.navigateTo(chatID, data)
+		b.showMenuPage(chatID, messageID, data, page)
+		b.post(priorityInteractive, tgbotapi.NewCallback(callback.ID, ""))
+		return
+	}
 
-		b.pushMenu(instanceInfoMenuID)
-		editMsg := b.editMenuPage(chatID, messageID, instanceInfoMenuID, 1)
-		b.BotAPI.Request(editMsg)
-		b.BotAPI.Request(tgbotapi.NewCallback(callback.ID, ""))
+	// 当点击具体实例时，不再发送新消息，而是进入实例详情菜单
+	// 构造一个特殊的菜单ID来表示实例详情
+	instanceInfoMenuID := instanceInfoPrefix + data
+
+	// 检查是否已经在详情页（避免重复点击）
+	if b.currentMenu(chatID) == instanceInfoMenuID {
+		b.post(priorityInteractive, tgbotapi.NewCallback(callback.ID, ""))
+		return
 	}
+
+	b.recordUsage(chatID, store.UsageInstance, data)
+	b.pushMenu(chatID, instanceInfoMenuID)
+	b.showMenuPage(chatID, messageID, instanceInfoMenuID, 1)
+	b.post(priorityInteractive, tgbotapi.NewCallback(callback.ID, ""))
 }
 
 func (b *BotInstance) editMessage(chatID int64, messageID int, text string) {
 	editMsg := tgbotapi.NewEditMessageText(chatID, messageID, text)
 	editMsg.ParseMode = "HTML"
-	b.BotAPI.Request(editMsg)
+	if _, err := b.editOrSend(editMsg); err != nil {
+		log.Printf("Failed to edit message: %v", err)
+	}
+}
+
+// maxCallbackData 是 Telegram 按钮回调数据的最大字节数，消息中任何一个按钮超出时整条消息都会被拒绝
+const maxCallbackData = 64
+
+// fitsCallback 判断回调数据是否在 Telegram 的长度限制内。回调数据中包含实例名的按钮，
+// 实例名太长（例如很长的 host:port）时不显示
+func fitsCallback(data string) bool {
+	return len(data) <= maxCallbackData
 }
 
 func (b *BotInstance) generateMenuRows(menuItems []MenuItem) [][]tgbotapi.InlineKeyboardButton {
@@ -241,30 +341,83 @@ func (b *BotInstance) generateMenuRows(menuItems []MenuItem) [][]tgbotapi.Inline
 	return rows
 }
 
-func (b *BotInstance) currentMenu() string {
-	if len(b.menuStack) > 0 {
-		return b.menuStack[len(b.menuStack)-1]
+// menuEntry 是菜单栈中的一项，记录离开该菜单时所在的页码，返回时恢复到同一页
+type menuEntry struct {
+	ID   string
+	Page int
+}
+
+// menuStackLocked 返回聊天的菜单栈，还没有导航过的聊天位于主菜单。调用方需持有 menuMu
+func (b *BotInstance) menuStackLocked(chatID int64) []menuEntry {
+	if stack := b.menuStacks[chatID]; len(stack) > 0 {
+		return stack
 	}
-	return mainMenuID
+	return []menuEntry{{ID: mainMenuID, Page: 1}}
 }
 
-func (b *BotInstance) pushMenu(menuID string) {
-	b.menuStack = append(b.menuStack, menuID)
+func (b *BotInstance) currentMenu(chatID int64) string {
+	b.menuMu.Lock()
+	defer b.menuMu.Unlock()
+	stack := b.menuStackLocked(chatID)
+	return stack[len(stack)-1].ID
 }
-func (b *BotInstance) popMenu() string {
-	if len(b.menuStack) > 1 {
-		b.menuStack = b.menuStack[:len(b.menuStack)-1]
-	}
-	return b.currentMenu()
+
+func (b *BotInstance) pushMenu(chatID int64, menuID string) {
+	b.menuMu.Lock()
+	defer b.menuMu.Unlock()
+	b.menuStacks[chatID] = append(b.menuStackLocked(chatID), menuEntry{ID: menuID, Page: 1})
 }
-func (b *BotInstance) getPreviousMenuID() string {
-	if len(b.menuStack) > 1 {
-		return b.menuStack[len(b.menuStack)-2]
+
+func (b *BotInstance) getPreviousMenuID(chatID int64) string {
+	b.menuMu.Lock()
+	defer b.menuMu.Unlock()
+	if stack := b.menuStackLocked(chatID); len(stack) > 1 {
+		return stack[len(stack)-2].ID
 	}
 	return mainMenuID
 }
 
-func (b *BotInstance) fetchInstancesForMenu(menuID string) []model.Metric {
+// setMenuPage 在当前菜单翻页时记录页码，从下一级菜单返回时恢复
+func (b *BotInstance) setMenuPage(chatID int64, menuID string, page int) {
+	b.menuMu.Lock()
+	defer b.menuMu.Unlock()
+	if stack := b.menuStackLocked(chatID); stack[len(stack)-1].ID == menuID {
+		stack[len(stack)-1].Page = page
+		b.menuStacks[chatID] = stack
+	}
+}
+
+// navigateTo 根据目标菜单调整聊天的菜单栈，返回应显示的页码：返回上一级或刷新时为之前所在的页，进入新菜单时为 1
+func (b *BotInstance) navigateTo(chatID int64, menuID string) int {
+	b.menuMu.Lock()
+	defer b.menuMu.Unlock()
+	stack := b.menuStackLocked(chatID)
+	n := len(stack)
+	switch {
+	case menuID == mainMenuID:
+		// 如果是返回主菜单，重置栈
+		b.menuStacks[chatID] = []menuEntry{{ID: mainMenuID, Page: 1}}
+		return 1
+	case n > 1 && stack[n-2].ID == menuID:
+		// 如果是返回上一级（目标ID等于栈中倒数第二个ID），则出栈并恢复之前的页码
+		b.menuStacks[chatID] = stack[:n-1]
+		return stack[n-2].Page
+	case stack[n-1].ID == menuID:
+		// 刷新当前页
+		return stack[n-1].Page
+	default:
+		// 进入新菜单，入栈
+		b.menuStacks[chatID] = append(stack, menuEntry{ID: menuID, Page: 1})
+		return 1
+	}
+}
+
+// prom 返回以聊天作为查询来源的 Prometheus 客户端，用于按聊天限制查询并发
+func (b *BotInstance) prom(chatID int64) *prometheus.Client {
+	return b.PrometheusClient.ForKey(strconv.FormatInt(chatID, 10))
+}
+
+func (b *BotInstance) fetchInstancesForMenu(chatID int64, menuID string) ([]model.Metric, error) {
 	var query string
 	switch menuID {
 	case allInstancesMenuID:
@@ -276,14 +429,24 @@ func (b *BotInstance) fetchInstancesForMenu(menuID string) []model.Metric {
 	default:
 		query = `up{job="node-exporter"}`
 	}
-	instances, err := b.PrometheusClient.FetchInstances(query)
+	instances, err := b.prom(chatID).FetchInstances(query)
 	if err != nil {
-		log.Printf("Failed to fetch instance with query %v: %v", query, err)
+		return nil, fmt.Errorf("Failed to fetch instance with query %v: %v", query, err)
 	}
-	return instances
+	b.aliases.update(instances, b.config.PrivacyAliasLabel)
+	return instances, nil
 }
 
-func (b *BotInstance) generateCallbackURL(callbackData string) string {
-	encodedData := url.QueryEscape(callbackData)
-	return fmt.Sprintf("tg://bot?start=%s", encodedData)
+// findInstance 按 instance 标签查找实例，找不到时返回 nil
+func (b *BotInstance) findInstance(chatID int64, instanceName string) (model.Metric, error) {
+	instances, err := b.fetchInstancesForMenu(chatID, allInstancesMenuID)
+	if err != nil {
+		return nil, err
+	}
+	for _, instance := range instances {
+		if string(instance["instance"]) == instanceName {
+			return instance, nil
+		}
+	}
+	return nil, nil
 }
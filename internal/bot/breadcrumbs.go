@@ -0,0 +1,59 @@
+package bot
+
+import (
+	"slices"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// breadcrumbSeparator 是导航路径中各级菜单之间的分隔符
+const breadcrumbSeparator = " › "
+
+// menuTitle 返回菜单在导航路径中显示的名称
+func (r *menuRouter) menuTitle(menuID string) string {
+	route, param, ok := r.match(menuID)
+	switch {
+	case !ok:
+		return menuID
+	case route.title != "":
+		return route.title
+	case param != "":
+		return param
+	default:
+		return route.name
+	}
+}
+
+// breadcrumbs 根据聊天的菜单栈返回当前所在位置，例如 主菜单 › 实例 › 在线实例 › web01，
+// 最后一级之前的部分就是"返回"会回到的菜单。只在主菜单时为空
+func (b *BotInstance) breadcrumbs(chatID int64) string {
+	b.menuMu.Lock()
+	stack := slices.Clone(b.menuStackLocked(chatID))
+	b.menuMu.Unlock()
+	if len(stack) <= 1 {
+		return ""
+	}
+	titles := make([]string, len(stack))
+	for i, entry := range stack {
+		titles[i] = escapeHTML(b.menus.menuTitle(entry.ID))
+	}
+	return "<i>" + strings.Join(titles, breadcrumbSeparator) + "</i>\n\n"
+}
+
+// withHeader 在页面正文前插入 header，不是文本消息时原样返回
+func withHeader(msg tgbotapi.Chattable, header string) tgbotapi.Chattable {
+	if header == "" {
+		return msg
+	}
+	switch m := msg.(type) {
+	case tgbotapi.MessageConfig:
+		m.Text = header + m.Text
+		return m
+	case tgbotapi.EditMessageTextConfig:
+		m.Text = header + m.Text
+		return m
+	default:
+		return msg
+	}
+}
@@ -0,0 +1,276 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/prometheus"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/render"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/store"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/utils"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// briefingPrefix 是早报设置按钮的回调前缀：briefing:toggle:<部分> 或 briefing:now
+	briefingPrefix      = "briefing:"
+	defaultBriefingTime = "08:00"
+	briefingTimeLayout  = "15:04"
+	// briefingExpiryDays 是早报中列出的到期实例的天数范围
+	briefingExpiryDays = 7
+	// briefingQuotaPercent 是 95 计费值占承诺速率的比例超过多少时列入早报
+	briefingQuotaPercent = 80
+	// maxBriefingEvents 限制早报中每部分列出的事件数量
+	maxBriefingEvents = 10
+	briefingUsage     = "用法: /briefing 查看早报设置\n" +
+		"/briefing on [HH:MM] 每天定时发送早报，默认 08:00\n" +
+		"/briefing off 关闭早报\n" +
+		"/briefing now 立即发送一次"
+)
+
+// 早报的各个部分，可以在设置中单独隐藏
+const (
+	briefingAlerts    = "alerts"
+	briefingAnomalies = "anomalies"
+	briefingQuota     = "quota"
+	briefingExpiring  = "expiring"
+)
+
+var briefingSections = []struct {
+	Key   string
+	Label string
+}{
+	{briefingAlerts, "当前告警"},
+	{briefingAnomalies, "昨日异常"},
+	{briefingQuota, "配额"},
+	{briefingExpiring, "本周到期"},
+}
+
+// handleBriefingCommand 处理 /briefing，设置每天定时发送的早报
+func (b *BotInstance) handleBriefingCommand(chatID int64, args string) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		b.post(priorityInteractive, b.briefingSettingsPage(chatID, 0))
+		return
+	}
+	switch {
+	case fields[0] == "on" && len(fields) <= 2:
+		at := defaultBriefingTime
+		if len(fields) == 2 {
+			t, err := time.Parse(briefingTimeLayout, fields[1])
+			if err != nil {
+				b.sendText(chatID, "无效的时间，例如 08:00\n"+briefingUsage)
+				return
+			}
+			at = t.Format(briefingTimeLayout)
+		}
+		if err := b.Store.UpdateChatSettings(chatID, func(s *store.ChatSettings) { s.Briefing.Time = at }); err != nil {
+			b.sendError(chatID, "保存早报设置", err)
+			return
+		}
+		b.post(priorityInteractive, b.briefingSettingsPage(chatID, 0))
+	case fields[0] == "off" && len(fields) == 1:
+		if err := b.Store.UpdateChatSettings(chatID, func(s *store.ChatSettings) { s.Briefing.Time = "" }); err != nil {
+			b.sendError(chatID, "保存早报设置", err)
+			return
+		}
+		b.sendText(chatID, "已关闭每日早报。")
+	case fields[0] == "now" && len(fields) == 1:
+		b.sendBriefing(chatID, time.Now())
+	default:
+		b.sendText(chatID, briefingUsage)
+	}
+}
+
+// briefingSettingsPage 显示早报的发送时间和各部分的开关
+func (b *BotInstance) briefingSettingsPage(chatID int64, messageID int) tgbotapi.Chattable {
+	settings := b.Store.ChatSettings(chatID).Briefing
+	text := "<b>每日早报</b>\n\n"
+	if settings.Time == "" {
+		text += "未开启\n"
+	} else {
+		text += fmt.Sprintf("每天 %s 发送\n", settings.Time)
+	}
+	text += "点击按钮切换各部分是否显示。\n\n" + briefingUsage
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+	for _, section := range briefingSections {
+		mark := b.Renderer.Glyph(render.GlyphSelected)
+		if slices.Contains(settings.Hidden, section.Key) {
+			mark = b.Renderer.Glyph(render.GlyphUnselected)
+		}
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(mark+" "+section.Label, briefingPrefix+"toggle:"+section.Key))
+		if len(row) == 2 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("立即发送", briefingPrefix+"now")))
+	return b.textPage(chatID, messageID, text, rows)
+}
+
+// handleBriefingCallback 处理早报设置页面上的按钮
+func (b *BotInstance) handleBriefingCallback(chatID int64, messageID int, args string) {
+	if args == "now" {
+		go b.sendBriefing(chatID, time.Now())
+		return
+	}
+	key, ok := strings.CutPrefix(args, "toggle:")
+	if !ok || !slices.ContainsFunc(briefingSections, func(s struct{ Key, Label string }) bool { return s.Key == key }) {
+		return
+	}
+	err := b.Store.UpdateChatSettings(chatID, func(s *store.ChatSettings) {
+		if i := slices.Index(s.Briefing.Hidden, key); i >= 0 {
+			s.Briefing.Hidden = slices.Delete(slices.Clone(s.Briefing.Hidden), i, i+1)
+		} else {
+			s.Briefing.Hidden = append(slices.Clone(s.Briefing.Hidden), key)
+		}
+	})
+	if err != nil {
+		b.sendError(chatID, "保存早报设置", err)
+		return
+	}
+	b.editOrSend(b.briefingSettingsPage(chatID, messageID))
+}
+
+// RunBriefings 每分钟检查一次，向到了早报时间的聊天发送早报，直到 ctx 被取消
+func (b *BotInstance) RunBriefings(ctx context.Context) {
+	ticker := time.NewTicker(15 * time.Second)
+	defer ticker.Stop()
+	last := time.Now().Truncate(time.Minute)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			minute := now.Truncate(time.Minute)
+			if !minute.After(last) {
+				continue
+			}
+			last = minute
+			at := minute.Format(briefingTimeLayout)
+			for chatID, settings := range b.Store.AllChatSettings() {
+				if settings.Briefing.Time == at && b.hasFullAccess(chatID) {
+					go b.sendBriefing(chatID, now)
+				}
+			}
+		}
+	}
+}
+
+// sendBriefing 生成并发送早报
+func (b *BotInstance) sendBriefing(chatID int64, now time.Time) {
+	text, err := b.render(chatID, render.Briefing, b.briefingData(chatID, now))
+	if err != nil {
+		b.sendError(chatID, "生成早报", err)
+		return
+	}
+	if _, err := b.send(priorityReport, b.textPage(chatID, 0, text, nil)); err != nil {
+		log.Printf("Failed to send briefing to %d: %v", chatID, err)
+	}
+}
+
+// briefingData 汇总早报的各个部分，某一部分查询失败时只在该部分记录错误，其他部分照常显示
+func (b *BotInstance) briefingData(chatID int64, now time.Time) render.BriefingData {
+	hidden := b.Store.ChatSettings(chatID).Briefing.Hidden
+	data := render.BriefingData{
+		Time:          now,
+		ShowAlerts:    !slices.Contains(hidden, briefingAlerts),
+		ShowAnomalies: !slices.Contains(hidden, briefingAnomalies),
+		ShowQuota:     !slices.Contains(hidden, briefingQuota),
+		ShowExpiring:  !slices.Contains(hidden, briefingExpiring),
+	}
+
+	if data.ShowAlerts || data.ShowAnomalies {
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		yesterday := today.AddDate(0, 0, -1)
+		// RecentEvents 按时间倒序返回，早报中按发生顺序显示
+		events := b.Store.RecentEvents("", 0)
+		slices.Reverse(events)
+		for _, e := range events {
+			if e.Kind.Severity() == store.SeverityInfo {
+				continue
+			}
+			if data.ShowAlerts && !e.Resolved() {
+				data.AlertCount++
+				if len(data.Alerts) < maxBriefingEvents {
+					data.Alerts = append(data.Alerts, b.briefingEvent(e, now))
+				}
+			}
+			if data.ShowAnomalies && !e.StartedAt.Before(yesterday) && e.StartedAt.Before(today) {
+				data.AnomalyCount++
+				if len(data.Anomalies) < maxBriefingEvents {
+					data.Anomalies = append(data.Anomalies, b.briefingEvent(e, now))
+				}
+			}
+		}
+	}
+
+	if !data.ShowQuota && !data.ShowExpiring {
+		return data
+	}
+	instances, err := b.fetchInstancesForMenu(chatID, allInstancesMenuID)
+	if err != nil {
+		data.Errors = append(data.Errors, fmt.Sprintf("获取实例列表失败，错误编号 %s", reportError("生成早报", err)))
+		return data
+	}
+	var quotaFailed int
+	var quotaErr error
+	for _, instance := range instances {
+		name := string(instance["instance"])
+		if data.ShowExpiring {
+			if expiry, err := prometheus.ActualExpiryDate(instance, now); err == nil {
+				if days := utils.DaysBetween(now, expiry); days >= 0 && days <= briefingExpiryDays {
+					data.Expiring = append(data.Expiring, render.BriefingExpiry{Instance: name, Expiry: expiry, DaysLeft: days, Price: string(instance["price"])})
+				}
+			}
+		}
+		if data.ShowQuota && prometheus.TrafficBilling(instance["billing"]) == prometheus.BillingP95 {
+			policy, err := prometheus.ResetPolicyFor(instance, now)
+			if err != nil {
+				quotaFailed, quotaErr = quotaFailed+1, err
+				continue
+			}
+			p, err := b.prom(chatID).QueryPercentile95(instance, policy.LastReset(now), now)
+			if err != nil {
+				quotaFailed, quotaErr = quotaFailed+1, err
+				continue
+			}
+			if p == nil || p.Commit <= 0 {
+				continue
+			}
+			if percent := p.Billed() / p.Commit * 100; percent >= briefingQuotaPercent {
+				data.Quota = append(data.Quota, render.BriefingQuota{Instance: name, Billed: p.Billed(), Commit: p.Commit, Percent: percent})
+			}
+		}
+	}
+	sort.Slice(data.Expiring, func(i, j int) bool { return data.Expiring[i].Expiry.Before(data.Expiring[j].Expiry) })
+	sort.Slice(data.Quota, func(i, j int) bool { return data.Quota[i].Percent > data.Quota[j].Percent })
+	if quotaFailed > 0 {
+		data.Errors = append(data.Errors, fmt.Sprintf("%d 个实例的配额查询失败，错误编号 %s", quotaFailed, reportError("查询早报配额", quotaErr)))
+	}
+	return data
+}
+
+// briefingEvent 将事件转换为早报中的一行
+func (b *BotInstance) briefingEvent(e store.Event, now time.Time) render.AlertData {
+	data := render.AlertData{
+		Icon:     b.Renderer.Glyph(eventGlyph(e.Kind)),
+		Instance: e.Instance,
+		Message:  e.Message,
+		Time:     e.StartedAt.Local(),
+	}
+	if e.Resolved() {
+		data.Duration = utils.ShortDuration(e.Duration(now))
+	}
+	return data
+}
@@ -0,0 +1,102 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"slices"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// maxBroadcastFailures 是广播结果中最多列出的失败聊天数量
+const maxBroadcastFailures = 10
+
+// isAdmin 判断聊天是否可以使用管理命令
+func (b *BotInstance) isAdmin(chatID int64) bool {
+	return slices.Contains(b.config.AdminChatIDs, chatID)
+}
+
+// rememberChat 记录与机器人交互过的聊天
+func (b *BotInstance) rememberChat(chatID int64) {
+	if err := b.Store.RememberChat(chatID); err != nil {
+		log.Printf("Failed to save chat %d: %v", chatID, err)
+	}
+}
+
+// broadcastTargets 返回广播的目标聊天：所有交互过的聊天和告警聊天
+func (b *BotInstance) broadcastTargets() []int64 {
+	targets := b.Store.Chats()
+	for _, id := range b.config.AlertChatIDs {
+		if !slices.Contains(targets, id) {
+			targets = append(targets, id)
+		}
+	}
+	return targets
+}
+
+// handleBroadcastCommand 处理 /broadcast <消息>，向所有已知聊天发送公告，完成后向管理员汇报结果
+func (b *BotInstance) handleBroadcastCommand(chatID int64, args string) {
+	if !b.isAdmin(chatID) {
+		b.sendText(chatID, "只有管理员可以使用此命令。")
+		return
+	}
+	if args == "" {
+		b.sendText(chatID, "用法: /broadcast &lt;消息内容&gt;")
+		return
+	}
+
+	text := "<b>【公告】</b>\n" + html.EscapeString(args)
+	b.confirmAction(chatID, fmt.Sprintf("确认向 %d 个聊天发送以下公告？\n\n%s", len(b.broadcastTargets()), text), func() {
+		b.broadcast(chatID, text)
+	})
+}
+
+// broadcast 在后台向所有目标聊天发送公告，完成后向 chatID 汇报结果
+func (b *BotInstance) broadcast(chatID int64, text string) {
+	// 目标在确认时重新获取，等待确认期间可能有新的聊天
+	targets := b.broadcastTargets()
+	b.sendText(chatID, fmt.Sprintf("正在向 %d 个聊天发送公告…", len(targets)))
+	go func() {
+		var delivered int
+		var failures []string
+		for _, target := range targets {
+			if err := b.sendBroadcast(target, text); err != nil {
+				failures = append(failures, fmt.Sprintf("%d: %s", target, html.EscapeString(err.Error())))
+			} else {
+				delivered++
+			}
+		}
+		b.sendText(chatID, broadcastSummary(delivered, failures))
+	}()
+}
+
+// sendBroadcast 发送一条广播消息，限速和限流重试由发送队列处理；
+// 机器人被屏蔽或移出群组时不再向该聊天广播
+func (b *BotInstance) sendBroadcast(chatID int64, text string) error {
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ParseMode = "HTML"
+	_, err := b.send(priorityReport, msg)
+	var apiErr *tgbotapi.Error
+	if errors.As(err, &apiErr) && apiErr.Code == 403 {
+		if err := b.Store.ForgetChat(chatID); err != nil {
+			log.Printf("Failed to forget chat %d: %v", chatID, err)
+		}
+	}
+	return err
+}
+
+func broadcastSummary(delivered int, failures []string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "广播完成: 成功 %d，失败 %d", delivered, len(failures))
+	for i, f := range failures {
+		if i == maxBroadcastFailures {
+			fmt.Fprintf(&sb, "\n… 另有 %d 个失败", len(failures)-maxBroadcastFailures)
+			break
+		}
+		sb.WriteString("\n" + f)
+	}
+	return sb.String()
+}
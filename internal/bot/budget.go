@@ -0,0 +1,182 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/prometheus"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/render"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/store"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/utils"
+)
+
+const (
+	budgetUsage = "用法: /budget 查看本月预计支出明细\n" +
+		"/budget set &lt;金额&gt; 设置每月预算，例如 /budget set 100 USD\n" +
+		"/budget off 取消预算"
+	// maxBudgetItems 限制支出明细中列出的条数
+	maxBudgetItems = 30
+)
+
+// handleBudgetCommand 处理 /budget：查看本月预计支出，管理员可以设置每月预算，超出时提醒
+func (b *BotInstance) handleBudgetCommand(chatID int64, args string) {
+	action, rest, _ := strings.Cut(args, " ")
+	switch action {
+	case "":
+		now := time.Now()
+		spend, err := b.prom(chatID).ProjectedSpend(now)
+		if err != nil {
+			b.sendError(chatID, "推算本月支出", err)
+			return
+		}
+		budget, hasBudget := b.Store.Budget()
+		b.sendText(chatID, b.budgetText(chatID, spend, budget, hasBudget))
+		return
+	case "set", "off":
+		if !b.isAdmin(chatID) {
+			b.sendText(chatID, "只有管理员可以修改预算。")
+			return
+		}
+	default:
+		b.sendText(chatID, budgetUsage)
+		return
+	}
+
+	if action == "off" {
+		if err := b.Store.ClearBudget(); err != nil {
+			b.sendError(chatID, "取消预算", err)
+			return
+		}
+		b.sendText(chatID, "已取消每月预算。")
+		return
+	}
+	amount, currency, ok := utils.ParsePrice(rest)
+	if !ok || amount <= 0 {
+		b.sendText(chatID, "无效的金额\n"+budgetUsage)
+		return
+	}
+	if err := b.Store.SetBudget(store.Budget{Amount: amount, Currency: currency}); err != nil {
+		b.sendError(chatID, "保存预算", err)
+		return
+	}
+	b.sendText(chatID, fmt.Sprintf("已设置每月预算 %s，预计支出超出时会提醒管理员。", b.formatMoney(chatID, amount, currency)))
+}
+
+// formatMoney 格式化金额和货币
+func (b *BotInstance) formatMoney(chatID int64, amount float64, currency string) string {
+	text := b.chatLocale(chatID).Number(amount, 2)
+	if currency != "" {
+		text += " " + escapeHTML(currency)
+	}
+	return text
+}
+
+// budgetText 显示本月预计支出与预算的对比，以及每笔续费和超额费用的明细
+func (b *BotInstance) budgetText(chatID int64, spend *prometheus.MonthlySpend, budget store.Budget, hasBudget bool) string {
+	locale := b.chatLocale(chatID)
+	totals := spend.Totals()
+	text := fmt.Sprintf("<b>本月预计支出</b> %s\n\n", spend.Start.Format(monthFormat))
+	if hasBudget {
+		total := totals[budget.Currency]
+		text += fmt.Sprintf("预算: %s\n预计: %s", b.formatMoney(chatID, budget.Amount, budget.Currency), b.formatMoney(chatID, total, budget.Currency))
+		if total > budget.Amount {
+			text += fmt.Sprintf(" %s 超出 %s", b.Renderer.Glyph(render.GlyphWarning), b.formatMoney(chatID, total-budget.Amount, budget.Currency))
+		} else {
+			text += fmt.Sprintf("（%s）", locale.Percent(total/budget.Amount*100))
+		}
+		text += "\n"
+	} else {
+		text += "未设置预算\n"
+	}
+
+	currencies := make([]string, 0, len(totals))
+	for currency := range totals {
+		if !hasBudget || currency != budget.Currency {
+			currencies = append(currencies, currency)
+		}
+	}
+	sort.Strings(currencies)
+	if len(currencies) > 0 {
+		var parts []string
+		for _, currency := range currencies {
+			parts = append(parts, b.formatMoney(chatID, totals[currency], currency))
+		}
+		label := "合计"
+		if hasBudget {
+			label = "其他货币（不计入预算）"
+		}
+		text += fmt.Sprintf("%s: %s\n", label, strings.Join(parts, ", "))
+	}
+
+	text += "\n<b>明细</b>\n"
+	if len(spend.Items) == 0 {
+		text += "本月没有续费或超额费用\n"
+	}
+	for i, item := range spend.Items {
+		if i == maxBudgetItems {
+			text += fmt.Sprintf("… 还有 %d 笔\n", len(spend.Items)-i)
+			break
+		}
+		kind := "续费"
+		switch item.Kind {
+		case prometheus.SpendOverage:
+			kind = "95 超额（预计）"
+		case prometheus.SpendTrafficOverage:
+			kind = "流量超额（预计）"
+		}
+		text += fmt.Sprintf("%s %s %s %s: %s\n", b.Renderer.Glyph(render.GlyphBullet), item.Date.Format("01-02"),
+			escapeHTML(utils.TruncateString(item.Instance, 30)), kind, b.formatMoney(chatID, item.Amount, item.Currency))
+	}
+	if spend.Failed > 0 {
+		text += fmt.Sprintf("\n%s %d 个实例无法推算费用（价格、周期或配额标签无效，或流量、95 值查询失败）\n", b.Renderer.Glyph(render.GlyphWarning), spend.Failed)
+	}
+	if !hasBudget {
+		text += "\n" + budgetUsage
+	}
+	return text
+}
+
+// RunBudgetChecks 每小时推算一次本月支出，超出预算时提醒管理员，每月只提醒一次，直到 ctx 被取消
+func (b *BotInstance) RunBudgetChecks(ctx context.Context) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	b.checkBudget(time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			b.checkBudget(now)
+		}
+	}
+}
+
+func (b *BotInstance) checkBudget(now time.Time) {
+	budget, ok := b.Store.Budget()
+	month := now.Format(monthFormat)
+	if !ok || b.Store.BudgetWarned(month) {
+		return
+	}
+	spend, err := b.PrometheusClient.ProjectedSpend(now)
+	if err != nil {
+		log.Printf("Failed to project monthly spend: %v", err)
+		return
+	}
+	if spend.Totals()[budget.Currency] <= budget.Amount {
+		return
+	}
+	for _, chatID := range b.adminTargets() {
+		text := fmt.Sprintf("%s <b>本月预计支出超出预算</b>\n\n", b.Renderer.Glyph(render.GlyphWarning)) +
+			b.budgetText(chatID, spend, budget, true)
+		if _, err := b.send(priorityAlert, b.textPage(chatID, 0, text, nil)); err != nil {
+			log.Printf("Failed to send budget warning to %d: %v", chatID, err)
+		}
+	}
+	if err := b.Store.MarkBudgetWarned(month); err != nil {
+		log.Printf("Failed to save budget warning state: %v", err)
+	}
+}
@@ -0,0 +1,228 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/prometheus"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/render"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/utils"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	selectorUsage = "选择器: all | re:<正则> | <标签>=<值> | <实例名>"
+	// trafficRangePrefix 是流量汇总时间范围按钮的回调前缀，格式为 traffic:<范围>:<选择器>
+	trafficRangePrefix = "traffic:"
+)
+
+type instanceTraffic struct {
+	name    string
+	daily   prometheus.Traffic
+	monthly prometheus.Traffic
+}
+
+// handleTrafficCommand 汇总选择器匹配的实例的日流量和月流量，并列出每个实例的明细
+func (b *BotInstance) handleTrafficCommand(chatID int64, args string) {
+	sel, err := parseInstanceSelector(args)
+	if err != nil {
+		b.sendText(chatID, fmt.Sprintf("%v\n%s", err, selectorUsage))
+		return
+	}
+	instances, err := b.selectInstances(chatID, sel)
+	if err != nil {
+		b.sendError(chatID, "获取实例列表", err)
+		return
+	}
+	if len(instances) == 0 {
+		b.sendText(chatID, fmt.Sprintf("没有匹配 %s 的实例\n%s", escapeHTML(sel.String()), selectorUsage))
+		return
+	}
+
+	now := time.Now()
+	var items []instanceTraffic
+	var totalDaily, totalMonthly prometheus.Traffic
+	// 单个实例查询失败不影响汇总，所有失败共用一个错误编号
+	var failed []string
+	errorID := newErrorID()
+	for _, instance := range instances {
+		item := instanceTraffic{name: string(instance["instance"])}
+		item.daily.Billing = prometheus.TrafficBillingFor(instance)
+		item.monthly.Billing = item.daily.Billing
+		item.daily.Transmit, item.daily.Receive, err = b.prom(chatID).GetDailyTraffic(instance, now)
+		if err != nil {
+			log.Printf("[error %s] Failed to get daily traffic for %s: %v", errorID, item.name, err)
+			failed = append(failed, item.name)
+		}
+		item.monthly.Transmit, item.monthly.Receive, err = b.prom(chatID).GetNaturalMonthTraffic(instance, now)
+		if err != nil {
+			log.Printf("[error %s] Failed to get monthly traffic for %s: %v", errorID, item.name, err)
+			failed = append(failed, item.name)
+		}
+		totalDaily.Transmit += item.daily.Transmit
+		totalDaily.Receive += item.daily.Receive
+		totalMonthly.Transmit += item.monthly.Transmit
+		totalMonthly.Receive += item.monthly.Receive
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].monthly.Usage() > items[j].monthly.Usage() })
+
+	bullet := b.Renderer.Glyph(render.GlyphBullet)
+	locale := b.chatLocale(chatID)
+	text := fmt.Sprintf("<b>流量汇总</b> %s（%d 个实例）\n\n", escapeHTML(sel.String()), len(items))
+	directions := locale.Directions()
+	text += fmt.Sprintf("<b>日流量:</b> %s / 总共 %s\n",
+		directions.Join(totalDaily.Transmit, totalDaily.Receive, locale.Bytes), locale.Bytes(totalDaily.Total()))
+	text += fmt.Sprintf("<b>月流量:</b> %s / 总共 %s\n\n",
+		directions.Join(totalMonthly.Transmit, totalMonthly.Receive, locale.Bytes), locale.Bytes(totalMonthly.Total()))
+	// 明细按各实例的计费方式统计用量，汇总仍是原始的上传和下载之和
+	text += "<b>明细（按月计费用量排序）:</b>\n"
+	for _, item := range items {
+		text += fmt.Sprintf("%s %s: 日 %s / 月 %s", bullet, escapeHTML(utils.TruncateString(item.name, 30)),
+			locale.Bytes(item.daily.Usage()), locale.Bytes(item.monthly.Usage()))
+		if item.monthly.CustomBilling() {
+			text += fmt.Sprintf("（%s）", locale.Directions().BillingLabel(item.monthly.Billing))
+		}
+		text += "\n"
+	}
+	if len(failed) > 0 {
+		text += fmt.Sprintf("\n%s %d 项查询失败，结果可能偏小。错误编号: <code>%s</code>\n", b.Renderer.Glyph(render.GlyphWarning), len(failed), errorID)
+	}
+	if _, err := b.send(priorityInteractive, b.textPage(chatID, 0, text, trafficRangeKeyboard("", sel))); err != nil {
+		log.Printf("Failed to send message: %v", err)
+	}
+}
+
+// trafficRangeKeyboard 生成流量汇总的时间范围选择器，选择器太长放不进回调数据时不显示
+func trafficRangeKeyboard(current string, sel *instanceSelector) [][]tgbotapi.InlineKeyboardButton {
+	callback := func(key string) string { return trafficRangePrefix + key + ":" + sel.String() }
+	// 自定义范围的键最长，回调数据不能超过 64 字节
+	if !fitsCallback(callback("20060102-20060102")) {
+		return nil
+	}
+	return timeRangeKeyboard(current, callback)
+}
+
+// handleTrafficRangeCallback 处理流量汇总的时间范围按钮，data 为 <范围>:<选择器>
+func (b *BotInstance) handleTrafficRangeCallback(chatID int64, data string) {
+	key, raw, _ := strings.Cut(data, ":")
+	if key == customRangeKey {
+		b.askTimeRange(chatID, func(key string) { b.sendTrafficRange(chatID, key, raw) })
+		return
+	}
+	b.sendTrafficRange(chatID, key, raw)
+}
+
+// sendTrafficRange 汇总选择器匹配的实例在指定时间范围内的流量
+func (b *BotInstance) sendTrafficRange(chatID int64, key, raw string) {
+	sel, err := parseInstanceSelector(raw)
+	if err != nil {
+		b.sendText(chatID, fmt.Sprintf("%v\n%s", err, selectorUsage))
+		return
+	}
+	r, ok := resolveTimeRange(key, time.Now())
+	if !ok {
+		b.sendText(chatID, "无效的时间范围")
+		return
+	}
+	instances, err := b.selectInstances(chatID, sel)
+	if err != nil {
+		b.sendError(chatID, "获取实例列表", err)
+		return
+	}
+	if len(instances) == 0 {
+		b.sendText(chatID, fmt.Sprintf("没有匹配 %s 的实例\n%s", escapeHTML(sel.String()), selectorUsage))
+		return
+	}
+
+	var items []instanceTraffic
+	var total prometheus.Traffic
+	var failed []string
+	errorID := newErrorID()
+	for _, instance := range instances {
+		item := instanceTraffic{name: string(instance["instance"])}
+		item.monthly.Billing = prometheus.TrafficBillingFor(instance)
+		item.monthly.Transmit, item.monthly.Receive, err = b.prom(chatID).GetTrafficBetween(instance, r.Start, r.End)
+		if err != nil {
+			log.Printf("[error %s] Failed to get traffic for %s: %v", errorID, item.name, err)
+			failed = append(failed, item.name)
+		}
+		total.Transmit += item.monthly.Transmit
+		total.Receive += item.monthly.Receive
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].monthly.Usage() > items[j].monthly.Usage() })
+
+	bullet := b.Renderer.Glyph(render.GlyphBullet)
+	locale := b.chatLocale(chatID)
+	text := fmt.Sprintf("<b>流量汇总</b> %s（%s，%d 个实例）\n\n", escapeHTML(sel.String()), escapeHTML(r.Label), len(items))
+	text += fmt.Sprintf("<b>流量:</b> %s / 总共 %s\n\n",
+		locale.Directions().Join(total.Transmit, total.Receive, locale.Bytes), locale.Bytes(total.Total()))
+	text += "<b>明细（按计费用量排序）:</b>\n"
+	for _, item := range items {
+		text += fmt.Sprintf("%s %s: %s", bullet, escapeHTML(utils.TruncateString(item.name, 30)), locale.Bytes(item.monthly.Usage()))
+		if item.monthly.CustomBilling() {
+			text += fmt.Sprintf("（%s）", locale.Directions().BillingLabel(item.monthly.Billing))
+		}
+		text += "\n"
+	}
+	if len(failed) > 0 {
+		text += fmt.Sprintf("\n%s %d 项查询失败，结果可能偏小。错误编号: <code>%s</code>\n", b.Renderer.Glyph(render.GlyphWarning), len(failed), errorID)
+	}
+	if _, err := b.send(priorityInteractive, b.textPage(chatID, 0, text, trafficRangeKeyboard(key, sel))); err != nil {
+		log.Printf("Failed to send message: %v", err)
+	}
+}
+
+// handleStatusCommand 列出选择器匹配的实例的在线状态
+func (b *BotInstance) handleStatusCommand(chatID int64, args string) {
+	sel, err := parseInstanceSelector(args)
+	if err != nil {
+		b.sendText(chatID, fmt.Sprintf("%v\n%s", err, selectorUsage))
+		return
+	}
+	instances, err := b.selectInstances(chatID, sel)
+	if err != nil {
+		b.sendError(chatID, "获取实例列表", err)
+		return
+	}
+	if len(instances) == 0 {
+		b.sendText(chatID, fmt.Sprintf("没有匹配 %s 的实例\n%s", escapeHTML(sel.String()), selectorUsage))
+		return
+	}
+
+	online, err := b.onlineInstanceSet(chatID)
+	if err != nil {
+		b.sendError(chatID, "获取在线实例", err)
+		return
+	}
+	var onlineNames, offlineNames []string
+	for _, instance := range instances {
+		name := string(instance["instance"])
+		if online[name] {
+			onlineNames = append(onlineNames, name)
+		} else {
+			offlineNames = append(offlineNames, name)
+		}
+	}
+	sort.Strings(onlineNames)
+	sort.Strings(offlineNames)
+
+	text := fmt.Sprintf("<b>实例状态</b> %s\n\n", escapeHTML(sel.String()))
+	text += fmt.Sprintf("<b>在线:</b> %d  <b>离线:</b> %d\n\n", len(onlineNames), len(offlineNames))
+	for _, name := range offlineNames {
+		text += fmt.Sprintf("%s %s\n", b.Renderer.Glyph(render.GlyphDown), escapeHTML(utils.TruncateString(name, 40)))
+	}
+	for _, name := range onlineNames {
+		text += fmt.Sprintf("%s %s\n", b.Renderer.Glyph(render.GlyphUp), escapeHTML(utils.TruncateString(name, 40)))
+	}
+	b.sendText(chatID, text)
+}
+
+// sendText 发送一条 HTML 格式的文本消息，不等待发送结果
+func (b *BotInstance) sendText(chatID int64, text string) {
+	b.post(priorityInteractive, b.textPage(chatID, 0, text, nil))
+}
@@ -0,0 +1,119 @@
+package bot
+
+import (
+	"strings"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/chart"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/render"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/store"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// chartPrefsPrefix 是图表样式设置按钮的回调前缀，格式为 chartpref:<设置>:<值>
+const chartPrefsPrefix = "chartpref:"
+
+// chartPrefOption 是图表样式设置页面上的一个选项
+type chartPrefOption struct {
+	Label string
+	Value string
+}
+
+// chartPrefOptions 按显示顺序列出每项设置的名称和可选值
+var chartPrefOptions = []struct {
+	Key     string
+	Label   string
+	Options []chartPrefOption
+}{
+	{"theme", "主题", []chartPrefOption{{"浅色", "light"}, {"深色", "dark"}}},
+	{"size", "尺寸", []chartPrefOption{{"小", chart.SizeSmall}, {"中", chart.SizeMedium}, {"大", chart.SizeLarge}}},
+	{"type", "类型", []chartPrefOption{{"折线", "line"}, {"柱状", "bar"}}},
+	{"legend", "图例", []chartPrefOption{{"显示", "show"}, {"隐藏", "hide"}}},
+}
+
+// chartPreferences 返回聊天的图表样式，应用于所有生成图表的功能
+func (b *BotInstance) chartPreferences(chatID int64) chart.Preferences {
+	s := b.Store.ChatSettings(chatID).Chart
+	return chart.Preferences{Dark: s.Dark, Size: s.Size, Bars: s.Bars, HideLegend: s.HideLegend}
+}
+
+// chartPrefValue 返回设置项的当前值
+func chartPrefValue(s store.ChartSettings, key string) string {
+	switch key {
+	case "theme":
+		if s.Dark {
+			return "dark"
+		}
+		return "light"
+	case "size":
+		if s.Size == "" {
+			return chart.SizeMedium
+		}
+		return s.Size
+	case "type":
+		if s.Bars {
+			return "bar"
+		}
+		return "line"
+	case "legend":
+		if s.HideLegend {
+			return "hide"
+		}
+		return "show"
+	}
+	return ""
+}
+
+// handleChartPrefsCommand 处理 /charts，显示图表样式设置
+func (b *BotInstance) handleChartPrefsCommand(chatID int64) {
+	b.post(priorityInteractive, b.chartPrefsPage(chatID, 0))
+}
+
+// chartPrefsPage 显示图表样式设置，每项设置一行按钮，当前值带有勾选标记
+func (b *BotInstance) chartPrefsPage(chatID int64, messageID int) tgbotapi.Chattable {
+	settings := b.Store.ChatSettings(chatID).Chart
+	text := "<b>图表样式</b>\n\n设置适用于详情页图表、定时任务图表和月度报告。点击按钮修改。"
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, pref := range chartPrefOptions {
+		current := chartPrefValue(settings, pref.Key)
+		row := []tgbotapi.InlineKeyboardButton{tgbotapi.NewInlineKeyboardButtonData(pref.Label+":", chartPrefsPrefix+pref.Key)}
+		for _, option := range pref.Options {
+			label := option.Label
+			if option.Value == current {
+				label = b.Renderer.Glyph(render.GlyphSelected) + " " + label
+			}
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(label, chartPrefsPrefix+pref.Key+":"+option.Value))
+		}
+		rows = append(rows, row)
+	}
+	return b.textPage(chatID, messageID, text, rows)
+}
+
+// handleChartPrefsCallback 处理图表样式设置页面上的按钮，args 为去掉前缀的回调数据
+func (b *BotInstance) handleChartPrefsCallback(chatID int64, messageID int, args string) {
+	key, value, ok := strings.Cut(args, ":")
+	if !ok {
+		// 行首的设置名称按钮只作为标签
+		return
+	}
+	err := b.Store.UpdateChatSettings(chatID, func(s *store.ChatSettings) {
+		switch key {
+		case "theme":
+			s.Chart.Dark = value == "dark"
+		case "size":
+			if value == chart.SizeSmall || value == chart.SizeLarge {
+				s.Chart.Size = value
+			} else {
+				s.Chart.Size = ""
+			}
+		case "type":
+			s.Chart.Bars = value == "bar"
+		case "legend":
+			s.Chart.HideLegend = value == "hide"
+		}
+	})
+	if err != nil {
+		b.sendError(chatID, "保存图表样式", err)
+		return
+	}
+	b.editOrSend(b.chartPrefsPage(chatID, messageID))
+}
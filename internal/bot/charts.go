@@ -0,0 +1,199 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/chart"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/prometheus"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/utils"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/prometheus/common/model"
+)
+
+const (
+	// chartPrefix 是图表按钮的回调前缀，格式为 chart:<kind>:<window>:<instance>
+	chartPrefix = "chart:"
+	chartDiskIO = "diskio"
+	chartCPU    = prometheus.HistoryCPU
+	chartMemory = prometheus.HistoryMemory
+	// defaultChartWindow 是从详情页打开图表时的时间窗口
+	defaultChartWindow = "1h"
+)
+
+// chartWindows 是图表可选的时间窗口，按钮按此顺序显示
+var chartWindows = []struct {
+	Label    string
+	Duration time.Duration
+}{
+	{"1h", time.Hour},
+	{"6h", 6 * time.Hour},
+	{"24h", 24 * time.Hour},
+	{"7d", 7 * 24 * time.Hour},
+}
+
+func chartCallback(kind, window, instanceName string) string {
+	return chartPrefix + kind + ":" + window + ":" + instanceName
+}
+
+func chartWindow(label string) (time.Duration, bool) {
+	for _, w := range chartWindows {
+		if w.Label == label {
+			return w.Duration, true
+		}
+	}
+	return 0, false
+}
+
+// chartWindowKeyboard 生成切换时间窗口的按钮，第一行是最近一段时间的窗口，其后是时间范围选择器，当前窗口不显示
+func chartWindowKeyboard(kind, current, instanceName string) tgbotapi.InlineKeyboardMarkup {
+	var buttons []tgbotapi.InlineKeyboardButton
+	for _, w := range chartWindows {
+		if w.Label == current || !fitsCallback(chartCallback(kind, w.Label, instanceName)) {
+			continue
+		}
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData(w.Label, chartCallback(kind, w.Label, instanceName)))
+	}
+	rows := timeRangeKeyboard(current, func(key string) string { return chartCallback(kind, key, instanceName) })
+	if len(buttons) > 0 {
+		rows = append([][]tgbotapi.InlineKeyboardButton{buttons}, rows...)
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// sendChart 渲染图表并以图片形式发送，args 为去掉前缀的回调数据
+func (b *BotInstance) sendChart(chatID int64, args string) {
+	parts := strings.SplitN(args, ":", 3)
+	if len(parts) != 3 {
+		b.sendText(chatID, "无效的图表请求")
+		return
+	}
+	kind, windowLabel, instanceName := parts[0], parts[1], parts[2]
+	if windowLabel == customRangeKey {
+		b.askTimeRange(chatID, func(key string) { b.sendChart(chatID, kind+":"+key+":"+instanceName) })
+		return
+	}
+	b.countFeature(chatID, featureChart)
+	now := time.Now()
+	// end 是图表的结束时间，昨天、上月等范围不到当前时间为止
+	end := now
+	rangeLabel := "最近 " + windowLabel
+	window, ok := chartWindow(windowLabel)
+	if !ok {
+		r, ok := resolveTimeRange(windowLabel, now)
+		if !ok {
+			b.sendText(chatID, "无效的时间范围")
+			return
+		}
+		window, end, rangeLabel = r.Duration(), r.End, r.Label
+	}
+	if window < time.Minute {
+		b.sendText(chatID, "时间范围太短，请选择其他范围")
+		return
+	}
+	instance, err := b.findInstance(chatID, instanceName)
+	if err != nil {
+		b.sendError(chatID, "获取实例列表", err)
+		return
+	}
+	if instance == nil {
+		b.sendText(chatID, "找不到指定的实例，请重试。")
+		return
+	}
+
+	var series []chart.Series
+	var opts chart.Options
+	var caption string
+	locale := b.chatLocale(chatID)
+	switch kind {
+	case chartDiskIO:
+		read, write, err := b.prom(chatID).DiskIOHistory(instance, window, end)
+		if err != nil {
+			b.sendError(chatID, "查询磁盘IO历史", err)
+			return
+		}
+		series = append(chart.FromMatrix(read, func(model.Metric) string { return "read" }),
+			chart.FromMatrix(write, func(model.Metric) string { return "write" })...)
+		opts = chart.Options{Title: "Disk IO - " + instanceName, FormatValue: locale.Rate}
+		caption = fmt.Sprintf("%s %s 磁盘读写速率", instanceName, rangeLabel)
+	case chartCPU, chartMemory:
+		history, err := b.prom(chatID).ResourceHistory(kind, instance, window, end)
+		if err != nil {
+			b.sendError(chatID, "查询资源历史", err)
+			return
+		}
+		series = chart.FromMatrix(history, func(model.Metric) string { return kind })
+		title, label := "CPU", "CPU 使用率"
+		if kind == chartMemory {
+			title, label = "Memory", "内存使用率"
+		}
+		opts = chart.Options{Title: title + " - " + instanceName, FormatValue: locale.Percent}
+		caption = fmt.Sprintf("%s %s %s%s", instanceName, rangeLabel, label, seriesSummary(series, locale.Percent))
+	default:
+		b.sendText(chatID, "未知的图表类型")
+		return
+	}
+
+	opts.Preferences = b.chartPreferences(chatID)
+	// 标注重启和离线时段，便于解释曲线上的尖峰和断档；查询失败时照常发送不带标注的图表
+	if annotations, err := b.prom(chatID).QueryAnnotations(instance, end.Add(-window), end); err != nil {
+		log.Printf("Failed to query chart annotations for %s: %v", instanceName, err)
+	} else {
+		opts.Reboots = annotations.Reboots
+		for _, o := range annotations.Outages {
+			opts.Downtime = append(opts.Downtime, chart.Window{Start: o.Start, End: o.End})
+		}
+		caption += annotationSummary(annotations)
+	}
+
+	png, err := chart.RenderPNG(series, opts)
+	if err != nil {
+		b.sendError(chatID, "生成图表", err)
+		return
+	}
+	photo := tgbotapi.NewPhoto(chatID, tgbotapi.FileBytes{
+		Name:  fmt.Sprintf("%s-%s.png", kind, now.Format("20060102-150405")),
+		Bytes: png,
+	})
+	photo.Caption = b.redact(chatID, caption)
+	photo.ReplyMarkup = chartWindowKeyboard(kind, windowLabel, instanceName)
+	if _, err := b.send(priorityInteractive, photo); err != nil {
+		b.sendError(chatID, "发送图表", err)
+	}
+}
+
+// annotationSummary 返回图表说明中的重启次数和离线时长，都没有时为空
+func annotationSummary(a *prometheus.Annotations) string {
+	var parts []string
+	if len(a.Reboots) > 0 {
+		parts = append(parts, fmt.Sprintf("重启 %d 次（虚线）", len(a.Reboots)))
+	}
+	if len(a.Outages) > 0 {
+		var total time.Duration
+		for _, o := range a.Outages {
+			total += o.End.Sub(o.Start)
+		}
+		parts = append(parts, fmt.Sprintf("离线 %d 段，共 %s（阴影）", len(a.Outages), utils.ShortDuration(total)))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "\n" + strings.Join(parts, "，")
+}
+
+// seriesSummary 返回第一条曲线的最小、平均和最大值，用于图表说明
+func seriesSummary(series []chart.Series, format func(float64) string) string {
+	if len(series) == 0 || len(series[0].Points) == 0 {
+		return ""
+	}
+	points := series[0].Points
+	minValue, maxValue, sum := points[0].Value, points[0].Value, 0.0
+	for _, p := range points {
+		minValue = min(minValue, p.Value)
+		maxValue = max(maxValue, p.Value)
+		sum += p.Value
+	}
+	return fmt.Sprintf("\n最低 %s / 平均 %s / 最高 %s", format(minValue), format(sum/float64(len(points))), format(maxValue))
+}
@@ -0,0 +1,111 @@
+package bot
+
+import (
+	"strings"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/config"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/plugin"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/store"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// featureDisabledText 是功能被 FEATURE_FLAGS 关闭时的提示
+const featureDisabledText = "该功能已在当前部署中关闭。"
+
+// commandFeatures 是受功能开关控制的命令，功能关闭时命令只返回提示
+var commandFeatures = map[string]string{
+	"query":  config.FeatureQueryCommand,
+	"charts": config.FeatureCharts,
+}
+
+// handleCommand 处理斜杠命令，返回 false 表示不是已知命令，由调用方回退到发送菜单
+func (b *BotInstance) handleCommand(message *tgbotapi.Message) (handled bool) {
+	chatID := message.Chat.ID
+	args := strings.TrimSpace(message.CommandArguments())
+	defer func() {
+		if handled {
+			b.recordUsage(chatID, store.UsageCommand, message.Command())
+		}
+	}()
+
+	if feature, ok := commandFeatures[message.Command()]; ok && !b.config.Enabled(feature) {
+		b.sendText(chatID, featureDisabledText)
+		return true
+	}
+
+	switch message.Command() {
+	case "start":
+		// 不带参数的 /start 与其他消息一样显示菜单
+		if args == "" {
+			return false
+		}
+		b.handleStartPayload(chatID, args)
+	case "link":
+		b.handleLinkCommand(chatID, args)
+	case "export":
+		b.handleExportCommand(chatID, args)
+	case "report":
+		b.handleReportCommand(chatID, args)
+	case "query":
+		b.handleQueryCommand(chatID, args)
+	case "meta":
+		b.handleMetadataCommand(chatID, args)
+	case "traffic":
+		b.handleTrafficCommand(chatID, args)
+	case "group":
+		b.handleGroupCommand(chatID, args)
+	case "status":
+		b.handleStatusCommand(chatID, args)
+	case "share":
+		b.handleShareCommand(chatID, args)
+	case "broadcast":
+		b.handleBroadcastCommand(chatID, args)
+	case "privacy":
+		b.handlePrivacyCommand(chatID, args)
+	case "units":
+		b.handleUnitsCommand(chatID, args)
+	case "charts":
+		b.handleChartPrefsCommand(chatID)
+	case "alerts":
+		b.handleAlertsCommand(chatID, args)
+	case "subscribe":
+		b.handleSubscribeCommand(chatID, args)
+	case "thresholds":
+		b.handleThresholdsCommand(chatID, args)
+	case "rules":
+		b.handleRulesCommand(chatID, args)
+	case "menu":
+		b.resetMenus(chatID)
+	case "cancel":
+		b.handleCancelCommand(chatID)
+	case "telemetry":
+		b.handleTelemetryCommand(chatID, args)
+	case "budget":
+		b.handleBudgetCommand(chatID, args)
+	case "project":
+		b.handleProjectCommand(chatID, args)
+	case "briefing":
+		b.handleBriefingCommand(chatID, args)
+	case "backup":
+		b.handleBackupCommand(chatID)
+	case "restore":
+		b.handleRestoreCommand(chatID)
+	case "bench":
+		b.handleBenchCommand(chatID, args)
+	case "explain":
+		b.handleExplainCommand(chatID, args)
+	case "diff":
+		b.handleDiffCommand(chatID, args)
+	default:
+		if v, ok := plugin.LookupCommand(message.Command()); ok {
+			b.handlePluginCommand(chatID, v, args)
+			return true
+		}
+		s, ok := b.findShortcutCommand(message.Command())
+		if !ok {
+			return false
+		}
+		b.runShortcut(chatID, 0, s, args)
+	}
+	return true
+}
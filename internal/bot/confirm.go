@@ -0,0 +1,115 @@
+package bot
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// confirmPrefix 是确认对话框按钮的回调前缀：confirm:yes:<nonce> 或 confirm:no:<nonce>
+	confirmPrefix = "confirm:"
+	// confirmTimeout 是确认对话框的有效期，超时后需要重新执行命令
+	confirmTimeout = 5 * time.Minute
+)
+
+// pendingConfirm 是等待确认的操作
+type pendingConfirm struct {
+	chatID    int64
+	action    func()
+	expiresAt time.Time
+}
+
+// confirmations 保存等待确认的操作，只保存在内存中，重启后需要重新执行命令
+type confirmations struct {
+	mu      sync.Mutex
+	pending map[string]pendingConfirm
+}
+
+func (c *confirmations) add(nonce string, p pendingConfirm) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.pending == nil {
+		c.pending = make(map[string]pendingConfirm)
+	}
+	// 顺便清理已过期的操作
+	for n, old := range c.pending {
+		if time.Now().After(old.expiresAt) {
+			delete(c.pending, n)
+		}
+	}
+	c.pending[nonce] = p
+}
+
+// take 取出并删除等待确认的操作，每个 nonce 只能使用一次
+func (c *confirmations) take(nonce string, chatID int64, now time.Time) (pendingConfirm, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p, ok := c.pending[nonce]
+	if !ok || p.chatID != chatID {
+		return pendingConfirm{}, false
+	}
+	delete(c.pending, nonce)
+	return p, now.Before(p.expiresAt)
+}
+
+// cancelChat 丢弃聊天中所有等待确认的操作，返回其中尚未过期的数量
+func (c *confirmations) cancelChat(chatID int64) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var n int
+	for nonce, p := range c.pending {
+		if p.chatID != chatID {
+			continue
+		}
+		delete(c.pending, nonce)
+		if time.Now().Before(p.expiresAt) {
+			n++
+		}
+	}
+	return n
+}
+
+// confirmAction 发送带有"确认 / 取消"按钮的对话框，用户确认后才执行 action，避免在手机上误触破坏性操作
+func (b *BotInstance) confirmAction(chatID int64, prompt string, action func()) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		b.sendError(chatID, "生成确认对话框", err)
+		return
+	}
+	nonce := hex.EncodeToString(buf)
+	b.confirms.add(nonce, pendingConfirm{chatID: chatID, action: action, expiresAt: time.Now().Add(confirmTimeout)})
+
+	rows := [][]tgbotapi.InlineKeyboardButton{tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("确认", confirmPrefix+"yes:"+nonce),
+		tgbotapi.NewInlineKeyboardButtonData("取消", confirmPrefix+"no:"+nonce),
+	)}
+	text := fmt.Sprintf("%s\n\n<i>请在 %s 内确认</i>", prompt, confirmTimeout)
+	if _, err := b.send(priorityInteractive, b.textPage(chatID, 0, text, rows)); err != nil {
+		log.Printf("Failed to send confirmation: %v", err)
+	}
+}
+
+// handleConfirmCallback 处理确认对话框的按钮，返回回调提示文字
+func (b *BotInstance) handleConfirmCallback(chatID int64, messageID int, data string) string {
+	answer, nonce, _ := strings.Cut(data, ":")
+	p, ok := b.confirms.take(nonce, chatID, time.Now())
+	// 无论结果如何都移除按钮，防止重复点击
+	b.clearKeyboard(chatID, messageID)
+	switch {
+	case answer != "yes":
+		b.sendText(chatID, "已取消。")
+		return "已取消"
+	case !ok:
+		b.sendText(chatID, "确认已过期，请重新执行命令。")
+		return "确认已过期"
+	}
+	p.action()
+	return "已确认"
+}
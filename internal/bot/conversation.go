@@ -0,0 +1,84 @@
+package bot
+
+import (
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// conversationTimeout 是等待用户输入的最长时间，超时后的消息按普通消息处理
+const conversationTimeout = 10 * time.Minute
+
+// conversationStep 是等待用户输入的下一步。handler 返回 false 表示输入无效，继续等待下一次输入
+type conversationStep struct {
+	handler func(text string) bool
+	// document 不为空时等待用户发送文件，收到文字消息时提示重新发送
+	document  func(doc *tgbotapi.Document) bool
+	expiresAt time.Time
+}
+
+// conversations 保存每个聊天正在进行的多步对话，只保存在内存中
+type conversations struct {
+	mu    sync.Mutex
+	steps map[int64]conversationStep
+}
+
+func (c *conversations) set(chatID int64, step conversationStep) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.steps == nil {
+		c.steps = make(map[int64]conversationStep)
+	}
+	c.steps[chatID] = step
+}
+
+// take 取出聊天正在等待的步骤，已超时的步骤视为不存在
+func (c *conversations) take(chatID int64, now time.Time) (conversationStep, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	step, ok := c.steps[chatID]
+	delete(c.steps, chatID)
+	return step, ok && now.Before(step.expiresAt)
+}
+
+// cancel 放弃聊天正在进行的对话，返回是否有未超时的对话
+func (c *conversations) cancel(chatID int64) bool {
+	_, ok := c.take(chatID, time.Now())
+	return ok
+}
+
+// ask 发送提示并等待用户的下一条文字消息，收到后交给 handler 处理
+func (b *BotInstance) ask(chatID int64, prompt string, handler func(text string) bool) {
+	b.conversations.set(chatID, conversationStep{handler: handler, expiresAt: time.Now().Add(conversationTimeout)})
+	b.sendText(chatID, prompt+"\n发送 /cancel 取消。")
+}
+
+// askDocument 发送提示并等待用户发送的下一个文件，收到后交给 handler 处理
+func (b *BotInstance) askDocument(chatID int64, prompt string, handler func(doc *tgbotapi.Document) bool) {
+	b.conversations.set(chatID, conversationStep{document: handler, expiresAt: time.Now().Add(conversationTimeout)})
+	b.sendText(chatID, prompt+"\n发送 /cancel 取消。")
+}
+
+// handleConversation 将消息交给聊天正在等待的对话步骤，没有进行中的对话时返回 false
+func (b *BotInstance) handleConversation(chatID int64, message *tgbotapi.Message) bool {
+	step, ok := b.conversations.take(chatID, time.Now())
+	if !ok {
+		return false
+	}
+	var done bool
+	switch {
+	case step.document == nil:
+		done = step.handler(message.Text)
+	case message.Document == nil:
+		b.sendText(chatID, "请以文件形式发送，或发送 /cancel 取消。")
+	default:
+		done = step.document(message.Document)
+	}
+	if !done {
+		// 输入无效时继续等待，handler 已经提示了原因
+		step.expiresAt = time.Now().Add(conversationTimeout)
+		b.conversations.set(chatID, step)
+	}
+	return true
+}
@@ -0,0 +1,128 @@
+package bot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/plugin"
+)
+
+const (
+	// instanceStartPrefix 是打开实例详情的 /start 参数前缀，格式为 instance_<短ID>
+	instanceStartPrefix = "instance_"
+	// viewStartPrefix 是打开菜单的 /start 参数前缀，格式为 view_<菜单ID>_<页码>，页码可以省略
+	viewStartPrefix = "view_"
+	linkUsage       = "用法: /link &lt;实例或菜单ID&gt; [页码]\n" +
+		"生成直接打开实例详情或菜单的链接，可以放在文档或二维码中，例如 /link node1:9100 或 /link all_instances 2"
+)
+
+// isView 判断菜单ID是否为可以通过链接直接打开的菜单，即不带参数的内置菜单或插件视图
+func (r *menuRouter) isView(menuID string) bool {
+	if _, ok := r.exact[menuID]; ok {
+		return true
+	}
+	_, ok := plugin.Lookup(menuID)
+	return ok
+}
+
+// parseViewPayload 解析 view_ 之后的 <菜单ID>_<页码>。菜单ID本身可以包含下划线，
+// 最后一段不是数字时视为省略了页码
+func parseViewPayload(s string) (menuID string, page int) {
+	if i := strings.LastIndex(s, "_"); i > 0 {
+		if n, err := strconv.Atoi(s[i+1:]); err == nil && n > 0 {
+			return s[:i], n
+		}
+	}
+	return s, 1
+}
+
+// handleStartPayload 处理带参数的 /start，打开链接指向的实例详情或菜单。
+// 参数中的短ID和菜单ID都需要存在，无效的链接只提示，不会打开任何页面
+func (b *BotInstance) handleStartPayload(chatID int64, payload string) {
+	if id, ok := strings.CutPrefix(payload, instanceStartPrefix); ok {
+		name, ok := b.Store.LookupShortID(id)
+		if !ok {
+			b.sendText(chatID, "链接无效或已失效。")
+			return
+		}
+		instance, err := b.findInstance(chatID, name)
+		if err != nil {
+			b.sendError(chatID, "获取实例列表", err)
+			return
+		}
+		if instance == nil {
+			b.sendText(chatID, fmt.Sprintf("链接指向的实例 %s 已不存在。", escapeHTML(name)))
+			return
+		}
+		b.openMenu(chatID, instanceInfoPrefix+name)
+		return
+	}
+	if rest, ok := strings.CutPrefix(payload, viewStartPrefix); ok {
+		menuID, page := parseViewPayload(rest)
+		if !b.menus.isView(menuID) {
+			b.sendText(chatID, "链接无效或已失效。")
+			return
+		}
+		// 先进入菜单再设置页码，openMenu 会显示菜单栈中记录的页码
+		b.navigateTo(chatID, menuID)
+		b.setMenuPage(chatID, menuID, page)
+		b.openMenu(chatID, menuID)
+		return
+	}
+	b.sendText(chatID, "链接无效或已失效。")
+}
+
+// handleLinkCommand 处理 /link，生成打开实例详情或菜单的 /start 链接
+func (b *BotInstance) handleLinkCommand(chatID int64, args string) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 || len(fields) > 2 {
+		b.sendText(chatID, linkUsage)
+		return
+	}
+	if b.menus.isView(fields[0]) {
+		payload := viewStartPrefix + fields[0]
+		if len(fields) == 2 {
+			page, err := strconv.Atoi(fields[1])
+			if err != nil || page <= 0 {
+				b.sendText(chatID, "无效的页码\n"+linkUsage)
+				return
+			}
+			payload += "_" + fields[1]
+		}
+		b.sendLink(chatID, fields[0], payload)
+		return
+	}
+	if len(fields) == 2 {
+		b.sendText(chatID, "实例链接不能指定页码\n"+linkUsage)
+		return
+	}
+	instance, err := b.findInstance(chatID, fields[0])
+	if err != nil {
+		b.sendError(chatID, "获取实例列表", err)
+		return
+	}
+	if instance == nil {
+		b.sendText(chatID, fmt.Sprintf("找不到实例或菜单 %s\n%s", escapeHTML(fields[0]), linkUsage))
+		return
+	}
+	id, err := b.Store.InstanceShortID(fields[0])
+	if err != nil {
+		b.sendError(chatID, "保存链接", err)
+		return
+	}
+	b.sendLink(chatID, fields[0], instanceStartPrefix+id)
+}
+
+// sendLink 发送 /start 链接。Telegram 限制 /start 参数最长 64 个字符，只能包含字母、数字、下划线和连字符
+func (b *BotInstance) sendLink(chatID int64, name, payload string) {
+	invalid := func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-')
+	}
+	if len(payload) > 64 || strings.IndexFunc(payload, invalid) >= 0 {
+		b.sendText(chatID, fmt.Sprintf("无法为 %s 生成链接：菜单ID过长或包含不支持的字符。", escapeHTML(name)))
+		return
+	}
+	link := fmt.Sprintf("https://t.me/%s?start=%s", b.BotAPI.Self.UserName, payload)
+	b.sendText(chatID, fmt.Sprintf("%s 的链接（需要有访问权限）:\n%s", escapeHTML(name), link))
+}
@@ -0,0 +1,164 @@
+package bot
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/prometheus"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/store"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/utils"
+	promclient "github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// derivedMetricsInterval 是重新计算导出指标的间隔，抓取时只读取上一次的快照，不会查询 Prometheus
+	derivedMetricsInterval = 5 * time.Minute
+	// 健康分从 100 开始，每个未恢复的严重和警告事件分别扣除的分数
+	healthCriticalPenalty = 50
+	healthWarningPenalty  = 20
+)
+
+// derivedMetrics 是机器人根据标签、流量和事件计算出的每个实例的指标，
+// 通过 /metrics 暴露后可以在 Grafana 中绘图，或直接用 Prometheus 告警
+type derivedMetrics struct {
+	expiryDays       *promclient.GaugeVec
+	quotaUsedRatio   *promclient.GaugeVec
+	healthScore      *promclient.GaugeVec
+	costPerGB        *promclient.GaugeVec
+	lastUpdate       promclient.Gauge
+	lastUpdateFailed promclient.Gauge
+}
+
+func newDerivedMetrics(registry *promclient.Registry) *derivedMetrics {
+	m := &derivedMetrics{
+		expiryDays: promclient.NewGaugeVec(promclient.GaugeOpts{
+			Name: "telegram_bot_instance_expiry_days",
+			Help: "Days until the next renewal of the instance, negative when expired.",
+		}, []string{"instance"}),
+		quotaUsedRatio: promclient.NewGaugeVec(promclient.GaugeOpts{
+			Name: "telegram_bot_instance_traffic_quota_used_ratio",
+			Help: "Billed traffic in the current reset period divided by the traffic_quota label.",
+		}, []string{"instance"}),
+		healthScore: promclient.NewGaugeVec(promclient.GaugeOpts{
+			Name: "telegram_bot_instance_health_score",
+			Help: "Health score from 0 to 100, reduced by unresolved warning and critical events.",
+		}, []string{"instance"}),
+		costPerGB: promclient.NewGaugeVec(promclient.GaugeOpts{
+			Name: "telegram_bot_instance_cost_per_gb",
+			Help: "Month-to-date share of the instance price divided by billed traffic in GB this month.",
+		}, []string{"instance", "currency"}),
+		lastUpdate: promclient.NewGauge(promclient.GaugeOpts{
+			Name: "telegram_bot_derived_metrics_last_update_timestamp_seconds",
+			Help: "Unix time when the per-instance metrics were last computed.",
+		}),
+		lastUpdateFailed: promclient.NewGauge(promclient.GaugeOpts{
+			Name: "telegram_bot_derived_metrics_last_update_failed",
+			Help: "Whether computing the per-instance metrics failed last time.",
+		}),
+	}
+	registry.MustRegister(m.expiryDays, m.quotaUsedRatio, m.healthScore, m.costPerGB, m.lastUpdate, m.lastUpdateFailed)
+	return m
+}
+
+// RunDerivedMetrics 定期计算每个实例的到期天数、流量配额使用比例、健康分和每 GB 成本，
+// 注册到 registry 中供 /metrics 抓取，直到 ctx 被取消
+func (b *BotInstance) RunDerivedMetrics(ctx context.Context, registry *promclient.Registry) {
+	m := newDerivedMetrics(registry)
+	ticker := time.NewTicker(derivedMetricsInterval)
+	defer ticker.Stop()
+	b.updateDerivedMetrics(m, time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			b.updateDerivedMetrics(m, now)
+		}
+	}
+}
+
+func (b *BotInstance) updateDerivedMetrics(m *derivedMetrics, now time.Time) {
+	instances, err := b.PrometheusClient.FetchInstances(`up{job="node-exporter"}`)
+	if err != nil {
+		log.Printf("Failed to update derived metrics: %v", err)
+		m.lastUpdateFailed.Set(1)
+		return
+	}
+	failed := false
+
+	// 全部计算完成后再写入指标，查询期间抓取到的仍是上一次的快照；已删除的实例在写入时清除
+	expiry := make(map[string]float64)
+	quota := make(map[string]float64)
+	for _, labels := range instances {
+		name := string(labels["instance"])
+		if date, err := prometheus.ActualExpiryDate(labels, now); err == nil {
+			expiry[name] = float64(utils.DaysBetween(now, date))
+		}
+		metered, err := b.PrometheusClient.QueryMeteredUsage(labels, now)
+		if err != nil {
+			log.Printf("Failed to query metered usage of %s: %v", name, err)
+			failed = true
+		} else if metered != nil {
+			quota[name] = metered.UsedPercent() / 100
+		}
+	}
+
+	// 本月至今按天数折算的费用除以本月至今的计费流量
+	type costKey struct{ instance, currency string }
+	costs := make(map[costKey]float64)
+	report, err := b.PrometheusClient.MonthlyReport(now, now)
+	if err != nil {
+		log.Printf("Failed to query monthly traffic for derived metrics: %v", err)
+		failed = true
+	} else {
+		monthEnd := report.Start.AddDate(0, 1, 0)
+		elapsed := report.End.Sub(report.Start).Seconds() / monthEnd.Sub(report.Start).Seconds()
+		for _, instance := range report.Instances {
+			if gb := instance.BilledTraffic() / 1e9; instance.Priced && gb > 0 {
+				costs[costKey{instance.Instance, instance.Currency}] = instance.MonthlyCost * elapsed / gb
+			}
+		}
+	}
+
+	health := make(map[string]float64)
+	for _, labels := range instances {
+		health[string(labels["instance"])] = 100
+	}
+	for _, e := range b.Store.RecentEvents("", 0) {
+		if _, ok := health[e.Instance]; !ok || e.Resolved() {
+			continue
+		}
+		switch e.Kind.Severity() {
+		case store.SeverityCritical:
+			health[e.Instance] -= healthCriticalPenalty
+		case store.SeverityWarning:
+			health[e.Instance] -= healthWarningPenalty
+		}
+	}
+
+	m.expiryDays.Reset()
+	for name, days := range expiry {
+		m.expiryDays.WithLabelValues(name).Set(days)
+	}
+	m.quotaUsedRatio.Reset()
+	for name, ratio := range quota {
+		m.quotaUsedRatio.WithLabelValues(name).Set(ratio)
+	}
+	m.healthScore.Reset()
+	for name, score := range health {
+		m.healthScore.WithLabelValues(name).Set(max(score, 0))
+	}
+	if report != nil {
+		m.costPerGB.Reset()
+		for key, cost := range costs {
+			m.costPerGB.WithLabelValues(key.instance, key.currency).Set(cost)
+		}
+	}
+	m.lastUpdate.Set(float64(now.Unix()))
+	if failed {
+		m.lastUpdateFailed.Set(1)
+	} else {
+		m.lastUpdateFailed.Set(0)
+	}
+}
@@ -0,0 +1,170 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/config"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/render"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/store"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// digestPrefix 是汇总消息展开、收起按钮的回调前缀，格式为 digest:<id>:<0|1>
+	digestPrefix = "digest:"
+	// maxDigests 是内存中保留的汇总数量，更早的汇总无法再展开
+	maxDigests = 50
+)
+
+// alertBatch 收集汇总窗口内的事件，窗口从第一个事件开始计时
+type alertBatch struct {
+	mu      sync.Mutex
+	pending []store.Event
+	timer   *time.Timer
+}
+
+func (a *alertBatch) add(e store.Event, window time.Duration, flush func()) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.pending = append(a.pending, e)
+	if a.timer == nil {
+		a.timer = time.AfterFunc(window, flush)
+	}
+}
+
+func (a *alertBatch) take() []store.Event {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	events := a.pending
+	a.pending = nil
+	a.timer = nil
+	return events
+}
+
+// digestCache 保存最近的汇总内容，用于展开详情
+type digestCache struct {
+	mu      sync.Mutex
+	nextID  int
+	digests map[int]render.DigestData
+}
+
+func (c *digestCache) add(d render.DigestData) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.digests == nil {
+		c.digests = make(map[int]render.DigestData)
+	}
+	c.nextID++
+	c.digests[c.nextID] = d
+	delete(c.digests, c.nextID-maxDigests)
+	return c.nextID
+}
+
+func (c *digestCache) get(id int) (render.DigestData, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	d, ok := c.digests[id]
+	return d, ok
+}
+
+// sendDigest 将多个事件合并为一条汇总消息发送到 chatIDs
+func (b *BotInstance) sendDigest(events []store.Event, chatIDs []int64) {
+	data := render.DigestData{Count: len(events), Time: time.Now()}
+	// 按事件类型首次出现的顺序分组
+	groupIndex := make(map[store.EventKind]int)
+	for _, e := range events {
+		kind := notifiedKind(e)
+		i, ok := groupIndex[kind]
+		if !ok {
+			i = len(data.Groups)
+			groupIndex[kind] = i
+			data.Groups = append(data.Groups, render.DigestGroup{Icon: b.Renderer.Glyph(eventGlyph(kind)), Label: kind.Label()})
+		}
+		data.Groups[i].Count++
+		data.Groups[i].Instances = append(data.Groups[i].Instances, e.Instance)
+		data.Alerts = append(data.Alerts, b.alertData(e))
+	}
+
+	id := b.digests.add(data)
+	var sent []int64
+	var messageIDs []int
+	defer func() {
+		for _, e := range events {
+			b.recordNotification(e, sent, messageIDs, true)
+		}
+	}()
+	// 汇总消息按其中最严重的事件决定通知方式，置顶后在其中任一未恢复的事件恢复时取消置顶
+	behavior := b.alertBehavior(highestSeverity(events))
+	// 同一窗口内已经恢复的事件（包括被上线等事件恢复的离线事件）不再置顶，它们的取消置顶已在 Notify 中处理过
+	closed := make(map[int64]bool)
+	for _, e := range events {
+		if e.Resolved() {
+			closed[e.ID] = true
+			if e.ResolvesID != 0 {
+				closed[e.ResolvesID] = true
+			}
+		}
+	}
+	var open []int64
+	for _, e := range events {
+		if !e.Resolved() && !closed[e.ID] {
+			open = append(open, e.ID)
+		}
+	}
+	for _, chatID := range chatIDs {
+		text, err := b.render(chatID, render.Digest, data)
+		if err != nil {
+			log.Printf("Failed to render alert digest: %v", err)
+			return
+		}
+		msg := withAlertBehavior(b.textPage(chatID, 0, text, digestKeyboard(id, false)), behavior)
+		message, err := b.send(priorityAlert, msg)
+		if err != nil {
+			log.Printf("Failed to send alert digest: %v", err)
+			continue
+		}
+		if behavior == config.AlertPin && len(open) > 0 {
+			b.pinAlert(chatID, message.MessageID, open...)
+		}
+		sent = append(sent, chatID)
+		messageIDs = append(messageIDs, message.MessageID)
+	}
+}
+
+// handleDigestCallback 展开或收起汇总消息的详情
+func (b *BotInstance) handleDigestCallback(chatID int64, messageID int, args string) {
+	idStr, expandStr, _ := strings.Cut(args, ":")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("Invalid digest callback data: %v", args)
+		return
+	}
+	data, ok := b.digests.get(id)
+	if !ok {
+		b.editMessage(chatID, messageID, "汇总已过期，请在事件列表中查看详情。")
+		return
+	}
+	data.Expanded = expandStr == "1"
+	text, err := b.render(chatID, render.Digest, data)
+	if err != nil {
+		b.sendError(chatID, "渲染告警汇总", err)
+		return
+	}
+	b.requestMenu(chatID, eventsMenuID, 1, b.textPage(chatID, messageID, text, digestKeyboard(id, data.Expanded)))
+}
+
+func digestKeyboard(id int, expanded bool) [][]tgbotapi.InlineKeyboardButton {
+	button := tgbotapi.NewInlineKeyboardButtonData("展开详情", fmt.Sprintf("%s%d:1", digestPrefix, id))
+	if expanded {
+		button = tgbotapi.NewInlineKeyboardButtonData("收起", fmt.Sprintf("%s%d:0", digestPrefix, id))
+	}
+	return [][]tgbotapi.InlineKeyboardButton{tgbotapi.NewInlineKeyboardRow(
+		button,
+		tgbotapi.NewInlineKeyboardButtonData("事件列表", eventsMenuID),
+	)}
+}
@@ -0,0 +1,55 @@
+package bot
+
+import (
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/render"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// directoriesPrefix 是目录占用页面的菜单ID前缀，格式为 dirs:<instance>
+	directoriesPrefix = "dirs:"
+	// maxDirectories 是目录占用页面最多列出的目录数量
+	maxDirectories = 15
+)
+
+// directoriesPage 显示实例通过 textfile 收集器上报的各目录大小
+func (b *BotInstance) directoriesPage(chatID int64, messageID int, instanceName string) tgbotapi.Chattable {
+	menuID := directoriesPrefix + instanceName
+	instance, err := b.findInstance(chatID, instanceName)
+	if err != nil {
+		return b.errorPage(chatID, messageID, "获取实例列表", err, menuID, 1)
+	}
+	rows := [][]tgbotapi.InlineKeyboardButton{tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("刷新", menuID),
+		tgbotapi.NewInlineKeyboardButtonData("返回", b.getPreviousMenuID(chatID)),
+		tgbotapi.NewInlineKeyboardButtonData("返回主菜单", mainMenuID),
+	)}
+	if instance == nil {
+		return b.textPage(chatID, messageID, "找不到指定的实例，请重试。", rows)
+	}
+
+	now := time.Now()
+	sizes, err := b.prom(chatID).QueryDirectorySizes(instance, now)
+	if err != nil {
+		return b.errorPage(chatID, messageID, "查询目录大小", err, menuID, 1)
+	}
+	data := render.DirectoryData{Instance: instanceName, GeneratedAt: now}
+	for _, s := range sizes {
+		data.Total += s.Bytes
+	}
+	if len(sizes) > maxDirectories {
+		sizes = sizes[:maxDirectories]
+	}
+	if len(sizes) > 0 {
+		data.Largest = sizes[0].Bytes
+	}
+	data.Directories = sizes
+
+	text, err := b.render(chatID, render.Directories, data)
+	if err != nil {
+		return b.errorPage(chatID, messageID, "渲染目录占用", err, menuID, 1)
+	}
+	return b.textPage(chatID, messageID, text, rows)
+}
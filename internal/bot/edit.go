@@ -0,0 +1,67 @@
+package bot
+
+import (
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// editGoneErrors 是消息已无法编辑时 Telegram 返回的错误，例如消息被删除或超过 48 小时
+var editGoneErrors = []string{
+	"message to edit not found",
+	"message can't be edited",
+	"MESSAGE_ID_INVALID",
+}
+
+// isEditGone 判断编辑失败是否因为原消息已不存在或不能再编辑
+func isEditGone(err error) bool {
+	for _, s := range editGoneErrors {
+		if strings.Contains(err.Error(), s) {
+			return true
+		}
+	}
+	return false
+}
+
+// editOrSend 发送或编辑消息，返回最终显示内容的消息ID。
+// 内容没有变化时视为成功；原消息已无法编辑时改为发送一条新消息，
+// 并移除旧消息上的按钮，避免用户继续点击失效的菜单
+func (b *BotInstance) editOrSend(msg tgbotapi.Chattable) (int, error) {
+	switch m := msg.(type) {
+	case tgbotapi.MessageConfig:
+		sent, err := b.send(priorityInteractive, m)
+		if err != nil {
+			return 0, err
+		}
+		return sent.MessageID, nil
+	case tgbotapi.EditMessageTextConfig:
+		_, err := b.request(priorityInteractive, m)
+		if err == nil || isNotModified(err) {
+			return m.MessageID, nil
+		}
+		if !isEditGone(err) {
+			return 0, err
+		}
+		newMsg := tgbotapi.NewMessage(m.ChatID, m.Text)
+		newMsg.ParseMode = m.ParseMode
+		newMsg.DisableWebPagePreview = m.DisableWebPagePreview
+		if m.ReplyMarkup != nil {
+			newMsg.ReplyMarkup = *m.ReplyMarkup
+		}
+		sent, err := b.send(priorityInteractive, newMsg)
+		if err != nil {
+			return 0, err
+		}
+		b.clearKeyboard(m.ChatID, m.MessageID)
+		return sent.MessageID, nil
+	default:
+		_, err := b.request(priorityInteractive, msg)
+		return 0, err
+	}
+}
+
+// clearKeyboard 移除消息上的按钮，消息已被删除时忽略错误
+func (b *BotInstance) clearKeyboard(chatID int64, messageID int) {
+	empty := tgbotapi.InlineKeyboardMarkup{InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{}}
+	b.post(priorityInteractive, tgbotapi.NewEditMessageReplyMarkup(chatID, messageID, empty))
+}
@@ -0,0 +1,124 @@
+package bot
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/prometheus"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/render"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// newErrorID 生成一个简短的错误编号，展示给用户并写入日志，方便管理员对照排查
+func newErrorID() string {
+	buf := make([]byte, 4)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("%08x", uint32(time.Now().UnixNano()))
+	}
+	return hex.EncodeToString(buf)
+}
+
+// reportError 记录错误日志并返回错误编号
+func reportError(action string, err error) string {
+	id := newErrorID()
+	log.Printf("[error %s] %s: %v", id, action, err)
+	return id
+}
+
+// errorText 生成展示给用户的错误提示，包含错误编号和按错误类型给出的建议，但不包含内部错误细节
+func (b *BotInstance) errorText(action string, err error, id string) string {
+	return fmt.Sprintf("%s %s失败，%s\n错误编号: <code>%s</code>", b.Renderer.Glyph(render.GlyphWarning), action, errorHint(err), id)
+}
+
+// errorHint 将常见的 Prometheus 查询错误转换为用户能理解的原因和建议
+func errorHint(err error) string {
+	switch prometheus.ClassifyError(err) {
+	case prometheus.ErrorBadQuery:
+		return "查询语句无效，请检查 PromQL 语法和参数。"
+	case prometheus.ErrorTimeout:
+		return "查询超时，可尝试缩短时间范围或减少查询的实例。"
+	case prometheus.ErrorTooLarge:
+		return "查询的数据点太多，可尝试缩短时间范围。"
+	case prometheus.ErrorUnavailable:
+		return "Prometheus 暂时不可用，请稍后重试；持续出现时请联系管理员检查 Prometheus 服务。"
+	default:
+		return "请稍后重试。"
+	}
+}
+
+// queryFailure 是用户输入的 PromQL 执行失败时的提示：语法错误直接展示 Prometheus 给出的原因，方便修改；
+// 其他错误只给出建议和错误编号
+func (b *BotInstance) queryFailure(err error) string {
+	if prometheus.ClassifyError(err) == prometheus.ErrorBadQuery {
+		return fmt.Sprintf("查询语句无效: %s", escapeHTML(prometheus.QueryErrorDetail(err)))
+	}
+	return b.errorText("查询", err, reportError("执行查询", err))
+}
+
+// retryCallback 返回重新加载指定菜单页的回调数据，复用翻页回调，不改变菜单栈
+func retryCallback(menuID string, page int) string {
+	if page < 1 {
+		page = 1
+	}
+	return fmt.Sprintf("next_%s_%d", menuID, page)
+}
+
+// errorPage 记录错误并生成带重试按钮的错误页面
+func (b *BotInstance) errorPage(chatID int64, messageID int, action string, err error, menuID string, page int) tgbotapi.Chattable {
+	id := reportError(action, err)
+	row := tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("返回主菜单", mainMenuID))
+	// 实例名很长时重试的回调数据可能超出长度限制，这时不显示重试按钮
+	if callbackData := retryCallback(menuID, page); fitsCallback(callbackData) {
+		row = append([]tgbotapi.InlineKeyboardButton{tgbotapi.NewInlineKeyboardButtonData("重试", callbackData)}, row...)
+	}
+	rows := [][]tgbotapi.InlineKeyboardButton{row}
+	// Prometheus 无法连接时提供不经过 Prometheus 的直接抓取
+	if prometheus.ClassifyError(err) == prometheus.ErrorUnavailable && len(b.config.ScrapeFallbackTargets) > 0 {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("直接抓取状态", directStatusMenuID)))
+	}
+	return b.textPage(chatID, messageID, b.errorText(action, err, id), rows)
+}
+
+// sendError 记录错误并向聊天发送错误提示，用于命令等无法通过按钮重试的场景
+func (b *BotInstance) sendError(chatID int64, action string, err error) {
+	id := reportError(action, err)
+	b.sendText(chatID, b.errorText(action, err, id))
+}
+
+// requestMenu 发送或编辑菜单消息。编辑失败时改为发送一条新的错误提示，避免用户点击后没有任何反应
+func (b *BotInstance) requestMenu(chatID int64, menuID string, page int, msg tgbotapi.Chattable) {
+	messageID, err := b.editOrSend(msg)
+	if err == nil {
+		b.touchMenu(chatID, messageID)
+		return
+	}
+	id := reportError(fmt.Sprintf("edit menu page %s", menuID), err)
+	row := tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("返回主菜单", mainMenuID))
+	// 实例名很长时重试的回调数据可能超出长度限制，这时不显示重试按钮
+	if callbackData := retryCallback(menuID, page); fitsCallback(callbackData) {
+		row = append([]tgbotapi.InlineKeyboardButton{tgbotapi.NewInlineKeyboardButtonData("重试", callbackData)}, row...)
+	}
+	rows := [][]tgbotapi.InlineKeyboardButton{row}
+	if _, err := b.send(priorityInteractive, b.textPage(chatID, 0, b.errorText("更新页面", err, id), rows)); err != nil {
+		log.Printf("[error %s] Failed to send error message: %v", id, err)
+	}
+}
+
+// isNotModified 判断编辑失败是否只是因为内容没有变化（例如重复点击刷新）
+func isNotModified(err error) bool {
+	return strings.Contains(err.Error(), "message is not modified")
+}
+
+// cardinalityRefusal 在查询因序列数超过上限被拒绝时返回给用户的说明
+func cardinalityRefusal(err error) (string, bool) {
+	var ce *prometheus.CardinalityError
+	if !errors.As(err, &ce) {
+		return "", false
+	}
+	return fmt.Sprintf("查询涉及的序列超过 %d 个（MAX_QUERY_SERIES），为保护 Prometheus 和机器人已拒绝执行。\n请缩小查询范围，例如添加标签过滤或换用取值较少的分组标签。", ce.Limit), true
+}
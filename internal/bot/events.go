@@ -0,0 +1,132 @@
+package bot
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/render"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/store"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/utils"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	eventsMenuID = "events"
+	// eventsInstancePrefix 用于按实例筛选事件的菜单ID前缀
+	eventsInstancePrefix = "events:"
+	eventsPageSize       = 10
+	// eventsMaxCount 事件列表最多显示的条数
+	eventsMaxCount = 200
+)
+
+func (b *BotInstance) eventsMenuPage(chatID int64, messageID int, instanceName string, page int) tgbotapi.Chattable {
+	menuID := eventsMenuID
+	if instanceName != "" {
+		menuID = eventsInstancePrefix + instanceName
+	}
+
+	events := b.Store.RecentEvents(instanceName, eventsMaxCount)
+	totalPages := (len(events) + eventsPageSize - 1) / eventsPageSize
+	if page < 1 || page > totalPages {
+		page = 1
+	}
+	startIndex := (page - 1) * eventsPageSize
+	endIndex := startIndex + eventsPageSize
+	if endIndex > len(events) {
+		endIndex = len(events)
+	}
+
+	var menuTitle string
+	if instanceName != "" {
+		menuTitle = fmt.Sprintf("<b>事件 - %s</b>", escapeHTML(instanceName))
+	} else {
+		menuTitle = "<b>最近事件</b>"
+	}
+	if totalPages > 1 {
+		menuTitle += fmt.Sprintf(" (%d/%d)", page, totalPages)
+	}
+	menuTitle += "\n\n"
+
+	if len(events) == 0 {
+		menuTitle += "暂无事件记录"
+	}
+	now := time.Now()
+	locale := b.chatLocale(chatID)
+	for _, e := range events[startIndex:endIndex] {
+		menuTitle += b.formatEventLine(locale, e, instanceName == "", now)
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var pageButtons []tgbotapi.InlineKeyboardButton
+	if page > 1 {
+		if callbackData := fmt.Sprintf("prev_%s_%d", menuID, page-1); fitsCallback(callbackData) {
+			pageButtons = append(pageButtons, tgbotapi.NewInlineKeyboardButtonData("上一页", callbackData))
+		}
+	}
+	if endIndex < len(events) {
+		if callbackData := fmt.Sprintf("next_%s_%d", menuID, page+1); fitsCallback(callbackData) {
+			pageButtons = append(pageButtons, tgbotapi.NewInlineKeyboardButtonData("下一页", callbackData))
+		}
+	}
+	if len(pageButtons) > 0 {
+		rows = append(rows, pageButtons)
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("返回", b.getPreviousMenuID(chatID)),
+		tgbotapi.NewInlineKeyboardButtonData("返回主菜单", mainMenuID),
+	))
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+
+	if messageID == 0 {
+		msg := tgbotapi.NewMessage(chatID, menuTitle)
+		msg.ReplyMarkup = keyboard
+		msg.ParseMode = "HTML"
+		return msg
+	} else {
+		editMsg := tgbotapi.NewEditMessageText(chatID, messageID, menuTitle)
+		editMsg.ReplyMarkup = &keyboard
+		editMsg.ParseMode = "HTML"
+		return editMsg
+	}
+}
+
+func (b *BotInstance) formatEventLine(locale render.Locale, e store.Event, withInstance bool, now time.Time) string {
+	line := fmt.Sprintf("%s %s (%s)", b.Renderer.Glyph(eventGlyph(e.Kind)), locale.ShortDateTime(e.StartedAt), locale.Relative(e.StartedAt, now))
+	if withInstance {
+		line += " " + escapeHTML(utils.TruncateString(e.Instance, 30))
+	}
+	line += " " + escapeHTML(e.Message)
+
+	switch {
+	case e.Kind == store.EventInstanceUp:
+		// 恢复事件本身是瞬时的，持续时间已包含在消息中
+	case e.Resolved():
+		line += fmt.Sprintf("（持续 %s）", utils.ShortDuration(e.Duration(now)))
+	default:
+		line += fmt.Sprintf("（进行中，已持续 %s）", utils.ShortDuration(e.Duration(now)))
+	}
+	if e.AckedBy != "" {
+		line += fmt.Sprintf(" [已确认: %s]", escapeHTML(e.AckedBy))
+	}
+	return line + "\n"
+}
+
+// eventGlyph 返回事件类型对应的主题图标名称
+func eventGlyph(kind store.EventKind) string {
+	switch kind {
+	case store.EventInstanceDown:
+		return render.GlyphDown
+	case store.EventInstanceUp:
+		return render.GlyphUp
+	case store.EventThresholdBreach, store.EventStaleMetrics, store.EventPrometheusAlert, store.EventOOMKill:
+		return render.GlyphWarning
+	case store.EventUPSOnBattery, store.EventUPSLowRuntime:
+		return render.GlyphCritical
+	case store.EventQuotaCrossing:
+		return render.GlyphQuota
+	case store.EventNewInstance:
+		return render.GlyphInfo
+	default:
+		return render.GlyphBullet
+	}
+}
@@ -0,0 +1,98 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/prometheus"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/store"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	explainUsage = "用法: /explain on|off\n" +
+		"开启后在本聊天的菜单页面末尾附加生成页面时执行的 PromQL 和耗时（折叠显示），用于排查数值不对的原因"
+	// maxMessageLength 是 Telegram 文本消息的最大长度
+	maxMessageLength = 4096
+)
+
+// handleExplainCommand 处理 /explain，开启或关闭本聊天的解释模式，只有管理员可以使用
+func (b *BotInstance) handleExplainCommand(chatID int64, args string) {
+	if !b.isAdmin(chatID) {
+		b.sendText(chatID, "只有管理员可以使用解释模式。")
+		return
+	}
+	switch strings.ToLower(strings.TrimSpace(args)) {
+	case "":
+		status := "未开启"
+		if b.Store.ChatSettings(chatID).Explain {
+			status = "已开启"
+		}
+		b.sendText(chatID, fmt.Sprintf("<b>解释模式</b>: %s\n\n%s", status, explainUsage))
+	case "on", "off":
+		enabled := strings.EqualFold(strings.TrimSpace(args), "on")
+		if err := b.Store.UpdateChatSettings(chatID, func(s *store.ChatSettings) { s.Explain = enabled }); err != nil {
+			b.sendError(chatID, "保存解释模式设置", err)
+			return
+		}
+		if enabled {
+			b.sendText(chatID, "已开启解释模式，菜单页面末尾会显示生成页面时执行的查询。")
+		} else {
+			b.sendText(chatID, "已关闭解释模式。")
+		}
+	default:
+		b.sendText(chatID, explainUsage)
+	}
+}
+
+// withExplain 在开启了解释模式的管理员聊天中，于页面末尾附加 since 之后本聊天执行的查询，
+// 不是文本消息时原样返回
+func (b *BotInstance) withExplain(chatID int64, msg tgbotapi.Chattable, since time.Time) tgbotapi.Chattable {
+	if !b.isAdmin(chatID) || !b.Store.ChatSettings(chatID).Explain {
+		return msg
+	}
+	queries := b.prom(chatID).QueriesSince(since)
+	switch m := msg.(type) {
+	case tgbotapi.MessageConfig:
+		m.Text += explainFooter(queries, maxMessageLength-len(m.Text))
+		return m
+	case tgbotapi.EditMessageTextConfig:
+		m.Text += explainFooter(queries, maxMessageLength-len(m.Text))
+		return m
+	default:
+		return msg
+	}
+}
+
+// explainFooter 将查询列表格式化为可展开的引用块，总长度不超过 limit。
+// 放不下所有查询时省略其余的 PromQL，只注明数量
+func explainFooter(queries []prometheus.TracedQuery, limit int) string {
+	var total time.Duration
+	for _, q := range queries {
+		total += q.Duration
+	}
+	header := fmt.Sprintf("\n\n<blockquote expandable><b>查询 %d 个，共 %.2fs</b>", len(queries), total.Seconds())
+	if len(queries) == 0 {
+		header = "\n\n<blockquote expandable><b>没有执行查询</b>"
+	}
+	// 为省略提示预留的长度
+	const closing, reserved = "</blockquote>", 64
+	if len(header)+len(closing)+reserved > limit {
+		return ""
+	}
+	text := header
+	for i, q := range queries {
+		kind := "即时"
+		if q.Range {
+			kind = "范围"
+		}
+		line := fmt.Sprintf("\n%d. %s %.2fs\n<code>%s</code>", i+1, kind, q.Duration.Seconds(), escapeHTML(q.Query))
+		if len(text)+len(line)+len(closing)+reserved > limit {
+			text += fmt.Sprintf("\n…… 另有 %d 个查询未显示", len(queries)-i)
+			break
+		}
+		text += line
+	}
+	return text + closing
+}
@@ -0,0 +1,309 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/prometheus"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/render"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/store"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/utils"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/prometheus/common/model"
+)
+
+const exportUsage = "用法: /export events|instances|report|rules"
+
+// exportDocument 是所有导出文件的外层结构，方便其他工具识别导出类型和时间
+type exportDocument struct {
+	Kind        string      `json:"kind"`
+	GeneratedAt time.Time   `json:"generated_at"`
+	Items       interface{} `json:"items"`
+}
+
+type exportInstance struct {
+	Instance string            `json:"instance"`
+	Online   bool              `json:"online"`
+	Labels   map[string]string `json:"labels"`
+}
+
+type exportTraffic struct {
+	TransmitBytes float64 `json:"transmit_bytes"`
+	ReceiveBytes  float64 `json:"receive_bytes"`
+	// BilledBytes 是按实例计费方式统计的用量
+	BilledBytes float64 `json:"billed_bytes"`
+
+	billing prometheus.TrafficBilling
+}
+
+func newExportTraffic(transmit, receive float64, billing prometheus.TrafficBilling) *exportTraffic {
+	return &exportTraffic{
+		TransmitBytes: transmit,
+		ReceiveBytes:  receive,
+		BilledBytes:   billing.Usage(transmit, receive),
+		billing:       billing,
+	}
+}
+
+// Traffic 转换为 prometheus.Traffic，供报告模板的 traffic_inline 使用
+func (t *exportTraffic) Traffic() prometheus.Traffic {
+	return prometheus.Traffic{Transmit: t.TransmitBytes, Receive: t.ReceiveBytes, Billing: t.billing}
+}
+
+type exportReport struct {
+	Instance       string         `json:"instance"`
+	Online         bool           `json:"online"`
+	Info           string         `json:"info,omitempty"`
+	Price          string         `json:"price,omitempty"`
+	Cycle          string         `json:"cycle,omitempty"`
+	Expiry         string         `json:"expiry,omitempty"`
+	DaysLeft       *int           `json:"days_left,omitempty"`
+	ResetPolicy    string         `json:"reset_policy,omitempty"`
+	NextReset      string         `json:"next_reset,omitempty"`
+	Billing        string         `json:"billing"`
+	DailyTraffic   *exportTraffic `json:"daily_traffic,omitempty"`
+	MonthlyTraffic *exportTraffic `json:"monthly_traffic,omitempty"`
+	Yesterday      *exportTraffic `json:"yesterday_traffic,omitempty"`
+	CPUUsage       float64        `json:"cpu_usage_percent"`
+	MemoryUsage    float64        `json:"memory_usage_percent"`
+	DiskUsage      float64        `json:"disk_usage_percent"`
+	Errors         []string       `json:"errors,omitempty"`
+}
+
+func (b *BotInstance) handleExportCommand(chatID int64, args string) {
+	now := time.Now()
+	var items interface{}
+	switch args {
+	case "events":
+		events := b.Store.RecentEvents("", 0)
+		if events == nil {
+			events = []store.Event{}
+		}
+		items = b.redactEvents(chatID, events)
+	case "instances":
+		instances, err := b.exportInstances(chatID)
+		if err != nil {
+			b.sendError(chatID, "导出", err)
+			return
+		}
+		for i := range instances {
+			instances[i].Instance = b.redact(chatID, instances[i].Instance)
+			instances[i].Labels = b.redactLabels(chatID, instances[i].Labels)
+		}
+		items = instances
+	case "rules":
+		b.sendRulesExport(chatID, now)
+		return
+	case "report":
+		reports, err := b.exportReports(chatID, now)
+		if err != nil {
+			b.sendError(chatID, "导出", err)
+			return
+		}
+		for i := range reports {
+			reports[i].Instance = b.redact(chatID, reports[i].Instance)
+			reports[i].Info = b.redact(chatID, reports[i].Info)
+		}
+		items = reports
+	default:
+		b.post(priorityInteractive, tgbotapi.NewMessage(chatID, exportUsage))
+		return
+	}
+
+	content, err := json.MarshalIndent(exportDocument{Kind: args, GeneratedAt: now, Items: items}, "", "  ")
+	if err != nil {
+		b.sendError(chatID, "导出", fmt.Errorf("Failed to encode export %s: %v", args, err))
+		return
+	}
+
+	doc := tgbotapi.NewDocument(chatID, tgbotapi.FileBytes{
+		Name:  fmt.Sprintf("%s-%s.json", args, now.Format("20060102-150405")),
+		Bytes: content,
+	})
+	if _, err := b.send(priorityInteractive, doc); err != nil {
+		b.sendError(chatID, "发送导出文件", err)
+	}
+}
+
+// handleReportCommand 使用 report 模板发送所有实例的文字报告，/report pdf 改为发送月度 PDF 报告
+func (b *BotInstance) handleReportCommand(chatID int64, args string) {
+	if fields := strings.Fields(args); len(fields) > 0 && fields[0] == "pdf" {
+		b.handleReportPDFCommand(chatID, strings.TrimSpace(strings.TrimPrefix(args, "pdf")))
+		return
+	}
+	now := time.Now()
+	reports, err := b.exportReports(chatID, now)
+	if err != nil {
+		b.sendError(chatID, "生成报告", err)
+		return
+	}
+	text, err := b.render(chatID, render.Report, render.ReportData{GeneratedAt: now, Items: reports})
+	if err != nil {
+		b.sendError(chatID, "生成报告", err)
+		return
+	}
+	if len(text) > 4000 {
+		text = utils.TruncateString(text, 4000)
+		text += "\n\n(Response truncated)"
+	}
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ParseMode = "HTML"
+	if _, err := b.send(priorityInteractive, msg); err != nil {
+		log.Printf("Failed to send report: %v", err)
+	}
+}
+
+// sendRulesExport 将机器人当前的检测配置导出为 Prometheus 告警规则文件，方便以后改由 Prometheus 和 Alertmanager 告警
+func (b *BotInstance) sendRulesExport(chatID int64, now time.Time) {
+	opts := prometheus.RuleOptions{
+		PollInterval:  b.config.PollInterval,
+		Thresholds:    b.Store.Thresholds(b.config.Thresholds),
+		UPSMinRuntime: b.config.UPSMinRuntime,
+	}
+	if b.config.StaleNotify {
+		opts.StaleThreshold = b.config.StaleThreshold
+	}
+	rules, err := b.prom(chatID).AlertRules(opts)
+	if err != nil {
+		b.sendError(chatID, "导出", err)
+		return
+	}
+
+	doc := tgbotapi.NewDocument(chatID, tgbotapi.FileBytes{
+		Name:  fmt.Sprintf("rules-%s.yml", now.Format("20060102-150405")),
+		Bytes: prometheus.RulesYAML("prometheus-telegram-bot", rules),
+	})
+	doc.Caption = fmt.Sprintf("共 %d 条告警规则，检查后放入 Prometheus 的 rule_files 即可", len(rules))
+	if _, err := b.send(priorityInteractive, doc); err != nil {
+		b.sendError(chatID, "发送导出文件", err)
+	}
+}
+
+func (b *BotInstance) exportInstances(chatID int64) ([]exportInstance, error) {
+	online, err := b.onlineInstanceSet(chatID)
+	if err != nil {
+		return nil, err
+	}
+	instances, err := b.fetchInstancesForMenu(chatID, allInstancesMenuID)
+	if err != nil {
+		return nil, err
+	}
+	items := []exportInstance{}
+	for _, instance := range instances {
+		name := string(instance["instance"])
+		labels := make(map[string]string, len(instance))
+		for k, v := range instance {
+			labels[string(k)] = string(v)
+		}
+		items = append(items, exportInstance{Instance: name, Online: online[name], Labels: labels})
+	}
+	return items, nil
+}
+
+func (b *BotInstance) exportReports(chatID int64, now time.Time) ([]exportReport, error) {
+	online, err := b.onlineInstanceSet(chatID)
+	if err != nil {
+		return nil, err
+	}
+	instances, err := b.fetchInstancesForMenu(chatID, allInstancesMenuID)
+	if err != nil {
+		return nil, err
+	}
+	items := []exportReport{}
+	for _, instance := range instances {
+		items = append(items, b.buildExportReport(chatID, instance, online[string(instance["instance"])], now))
+	}
+	return items, nil
+}
+
+func (b *BotInstance) buildExportReport(chatID int64, instance model.Metric, online bool, now time.Time) exportReport {
+	report := exportReport{
+		Instance: string(instance["instance"]),
+		Online:   online,
+		Info:     string(instance["info"]),
+		Price:    string(instance["price"]),
+		Cycle:    string(instance["cycle"]),
+	}
+	billing := prometheus.TrafficBillingFor(instance)
+	report.Billing = string(billing)
+
+	if expiry, err := prometheus.ActualExpiryDate(instance, now); err != nil {
+		report.Errors = append(report.Errors, err.Error())
+	} else {
+		report.Expiry = expiry.Format("2006-01-02")
+		daysLeft := utils.DaysBetween(now, expiry)
+		report.DaysLeft = &daysLeft
+	}
+
+	if policy, err := prometheus.ResetPolicyFor(instance, now); err != nil {
+		report.Errors = append(report.Errors, err.Error())
+	} else {
+		report.ResetPolicy = string(policy.Period)
+		report.NextReset = policy.NextReset(now).Format("2006-01-02")
+	}
+
+	if transmit, receive, err := b.prom(chatID).GetDailyTraffic(instance, now); err != nil {
+		report.Errors = append(report.Errors, err.Error())
+	} else {
+		report.DailyTraffic = newExportTraffic(transmit, receive, billing)
+	}
+	if transmit, receive, err := b.prom(chatID).GetNaturalMonthTraffic(instance, now); err != nil {
+		report.Errors = append(report.Errors, err.Error())
+	} else {
+		report.MonthlyTraffic = newExportTraffic(transmit, receive, billing)
+	}
+	if transmit, receive, err := b.prom(chatID).GetYesterdayTraffic(instance, now); err != nil {
+		report.Errors = append(report.Errors, err.Error())
+	} else {
+		report.Yesterday = newExportTraffic(transmit, receive, billing)
+	}
+
+	cpuUsage, memoryUsage, diskUsage, _, _, _, _, err := b.prom(chatID).FetchResourceMetrics(instance, b.prom(chatID).ResourceRange(prometheus.ResourceViewExport), now)
+	if err != nil {
+		report.Errors = append(report.Errors, err.Error())
+	}
+	report.CPUUsage = cpuUsage
+	report.MemoryUsage = memoryUsage
+	report.DiskUsage = diskUsage
+
+	return report
+}
+
+// redactEvents 按聊天的隐私模式处理导出事件中的实例地址，事件本身保持不变
+func (b *BotInstance) redactEvents(chatID int64, events []store.Event) []store.Event {
+	redacted := make([]store.Event, len(events))
+	for i, e := range events {
+		e.Instance = b.redact(chatID, e.Instance)
+		e.Message = b.redact(chatID, e.Message)
+		if e.Labels != nil {
+			e.Labels = b.redactLabels(chatID, e.Labels)
+		}
+		redacted[i] = e
+	}
+	return redacted
+}
+
+// redactLabels 返回按聊天的隐私模式处理过标签值的副本
+func (b *BotInstance) redactLabels(chatID int64, labels map[string]string) map[string]string {
+	redacted := make(map[string]string, len(labels))
+	for k, v := range labels {
+		redacted[k] = b.redact(chatID, v)
+	}
+	return redacted
+}
+
+// onlineInstanceSet 返回当前在线实例名称的集合
+func (b *BotInstance) onlineInstanceSet(chatID int64) (map[string]bool, error) {
+	instances, err := b.fetchInstancesForMenu(chatID, onlineInstancesMenuID)
+	if err != nil {
+		return nil, err
+	}
+	online := make(map[string]bool)
+	for _, instance := range instances {
+		online[string(instance["instance"])] = true
+	}
+	return online, nil
+}
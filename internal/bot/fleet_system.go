@@ -0,0 +1,47 @@
+package bot
+
+import (
+	"sort"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/render"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// fleetSystemMenuID 是系统更新汇总页面的菜单ID，列出需要重启、内核较旧或有待更新软件包的主机
+const fleetSystemMenuID = "fleet_system"
+
+func (b *BotInstance) fleetSystemPage(chatID int64, messageID int) tgbotapi.Chattable {
+	rows := [][]tgbotapi.InlineKeyboardButton{tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("刷新", fleetSystemMenuID),
+		tgbotapi.NewInlineKeyboardButtonData("返回", b.getPreviousMenuID(chatID)),
+		tgbotapi.NewInlineKeyboardButtonData("返回主菜单", mainMenuID),
+	)}
+
+	now := time.Now()
+	infos, err := b.prom(chatID).FleetSystemInfo(now)
+	if err != nil {
+		return b.errorPage(chatID, messageID, "查询系统信息", err, fleetSystemMenuID, 1)
+	}
+	data := render.FleetSystemData{GeneratedAt: now, Total: len(infos)}
+	for _, info := range infos {
+		if info.RebootRequired {
+			data.Reboot = append(data.Reboot, info)
+		}
+		if info.OutdatedKernel {
+			data.Outdated = append(data.Outdated, info)
+		}
+		if info.PendingUpdates > 0 {
+			data.Updates = append(data.Updates, info)
+		}
+	}
+	sort.SliceStable(data.Updates, func(i, j int) bool {
+		return data.Updates[i].PendingUpdates > data.Updates[j].PendingUpdates
+	})
+
+	text, err := b.render(chatID, render.FleetSystem, data)
+	if err != nil {
+		return b.errorPage(chatID, messageID, "渲染系统信息", err, fleetSystemMenuID, 1)
+	}
+	return b.textPage(chatID, messageID, text, rows)
+}
@@ -0,0 +1,73 @@
+package bot
+
+import (
+	"strings"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/geo"
+	"github.com/prometheus/common/model"
+)
+
+// 按 GeoIP 数据分组时使用的伪标签，以 @ 开头避免与 Prometheus 标签重名
+const (
+	geoCountryGroup = "@country"
+	geoASNGroup     = "@asn"
+)
+
+// geoInfo 返回实例的国家和 ASN，未配置 GeoIP 或无法查询时返回 nil
+func (b *BotInstance) geoInfo(instance model.Metric) *geo.Info {
+	info, ok := b.geo.Lookup(string(instance["instance"]))
+	if !ok {
+		return nil
+	}
+	return &info
+}
+
+// geoGroupLabels 返回可用的 GeoIP 分组伪标签，未配置 GeoIP 时为空
+func (b *BotInstance) geoGroupLabels() []string {
+	if b.geo == nil {
+		return nil
+	}
+	return []string{geoCountryGroup, geoASNGroup}
+}
+
+// groupLabelTitle 返回分组标签的显示名称
+func groupLabelTitle(label string) string {
+	switch label {
+	case geoCountryGroup:
+		return "国家"
+	case geoASNGroup:
+		return "ASN"
+	default:
+		return label
+	}
+}
+
+// geoGroupOf 返回按 GeoIP 伪标签分组时实例所属分组的计算函数，label 不是伪标签时返回 false
+func (b *BotInstance) geoGroupOf(label string) (func(instance model.Metric) string, bool) {
+	if b.geo == nil {
+		return nil, false
+	}
+	switch label {
+	case geoCountryGroup:
+		return func(instance model.Metric) string {
+			info := b.geoInfo(instance)
+			if info == nil || info.Country == "" {
+				return ""
+			}
+			name := info.CountryName
+			if name == "" {
+				name = info.Country
+			}
+			return strings.TrimSpace(info.Flag() + " " + name)
+		}, true
+	case geoASNGroup:
+		return func(instance model.Metric) string {
+			if info := b.geoInfo(instance); info != nil {
+				return info.Provider()
+			}
+			return ""
+		}, true
+	default:
+		return nil, false
+	}
+}
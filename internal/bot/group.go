@@ -0,0 +1,85 @@
+package bot
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/prometheus"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/render"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// groupSummaryPrefix 是分组汇总菜单的ID前缀，格式为 group:<标签名>
+const groupSummaryPrefix = "group:"
+
+// groupSummaryMenuID 返回按 label 分组汇总的菜单ID，label 为空时使用第一个配置的分组标签
+func (b *BotInstance) groupSummaryMenuID(label string) string {
+	if label == "" {
+		label = b.config.GroupLabels[0]
+	}
+	return groupSummaryPrefix + label
+}
+
+// groupSummaryText 查询并渲染按 label 分组的汇总
+func (b *BotInstance) groupSummaryText(chatID int64, label string) (string, error) {
+	now := time.Now()
+	var groups []prometheus.GroupSummary
+	var err error
+	if groupOf, ok := b.geoGroupOf(label); ok {
+		groups, err = b.prom(chatID).GroupSummariesFunc(groupOf, now)
+	} else {
+		groups, err = b.prom(chatID).GroupSummaries(label, now)
+	}
+	if err != nil {
+		return "", err
+	}
+	return b.render(chatID, render.Group, render.GroupData{Label: groupLabelTitle(label), GeneratedAt: now, Groups: groups})
+}
+
+func (b *BotInstance) groupSummaryPage(chatID int64, messageID int, label string) tgbotapi.Chattable {
+	menuID := b.groupSummaryMenuID(label)
+	text, err := b.groupSummaryText(chatID, label)
+	if refusal, ok := cardinalityRefusal(err); ok {
+		// 仍然显示切换分组的按钮，其他分组标签可能不受影响
+		text = refusal
+	} else if err != nil {
+		return b.errorPage(chatID, messageID, "查询分组汇总", err, menuID, 1)
+	}
+
+	// 切换到其他分组标签
+	var labelButtons []tgbotapi.InlineKeyboardButton
+	for _, other := range slices.Concat(b.config.GroupLabels, b.geoGroupLabels()) {
+		if other != label {
+			labelButtons = append(labelButtons, tgbotapi.NewInlineKeyboardButtonData("按 "+groupLabelTitle(other), b.groupSummaryMenuID(other)))
+		}
+	}
+	var rows [][]tgbotapi.InlineKeyboardButton
+	if len(labelButtons) > 0 {
+		rows = append(rows, labelButtons)
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("刷新", menuID),
+		tgbotapi.NewInlineKeyboardButtonData("返回", b.getPreviousMenuID(chatID)),
+		tgbotapi.NewInlineKeyboardButtonData("返回主菜单", mainMenuID),
+	))
+	return b.textPage(chatID, messageID, text, rows)
+}
+
+// handleGroupCommand 处理 /group [标签名]，发送按标签分组的汇总
+func (b *BotInstance) handleGroupCommand(chatID int64, args string) {
+	label := strings.TrimSpace(args)
+	if label == "" {
+		label = b.config.GroupLabels[0]
+	}
+	text, err := b.groupSummaryText(chatID, label)
+	if refusal, ok := cardinalityRefusal(err); ok {
+		b.sendText(chatID, refusal)
+		return
+	}
+	if err != nil {
+		b.sendError(chatID, "查询分组汇总", err)
+		return
+	}
+	b.sendText(chatID, text)
+}
@@ -0,0 +1,49 @@
+package bot
+
+import (
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/render"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/prometheus/common/model"
+)
+
+const (
+	// heatmapPrefix 是流量热力图页面的菜单ID前缀，格式为 heatmap:<instance>，instance 为空时统计所有实例
+	heatmapPrefix = "heatmap:"
+	// heatmapDays 是流量热力图显示的天数
+	heatmapDays = 7
+)
+
+// heatmapPage 显示实例（或所有实例）最近 heatmapDays 天每小时的流量热力图，用于发现使用规律和异常的流量高峰
+func (b *BotInstance) heatmapPage(chatID int64, messageID int, instanceName string) tgbotapi.Chattable {
+	menuID := heatmapPrefix + instanceName
+	rows := [][]tgbotapi.InlineKeyboardButton{tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("刷新", menuID),
+		tgbotapi.NewInlineKeyboardButtonData("返回", b.getPreviousMenuID(chatID)),
+		tgbotapi.NewInlineKeyboardButtonData("返回主菜单", mainMenuID),
+	)}
+
+	var labels model.Metric
+	if instanceName != "" {
+		instance, err := b.findInstance(chatID, instanceName)
+		if err != nil {
+			return b.errorPage(chatID, messageID, "获取实例列表", err, menuID, 1)
+		}
+		if instance == nil {
+			return b.textPage(chatID, messageID, "找不到指定的实例，请重试。", rows)
+		}
+		labels = instance
+	}
+
+	now := time.Now()
+	days, err := b.prom(chatID).TrafficHeatmap(labels, heatmapDays, now)
+	if err != nil {
+		return b.errorPage(chatID, messageID, "查询流量热力图", err, menuID, 1)
+	}
+	text, err := b.render(chatID, render.Heatmap, render.HeatmapData{Instance: instanceName, GeneratedAt: now, Days: days})
+	if err != nil {
+		return b.errorPage(chatID, messageID, "渲染流量热力图", err, menuID, 1)
+	}
+	return b.textPage(chatID, messageID, text, rows)
+}
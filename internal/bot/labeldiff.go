@@ -0,0 +1,243 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/prometheus"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/render"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/prometheus/common/model"
+)
+
+const (
+	// labelDiffPrefix 是标签对比页面的菜单ID前缀。labeldiff:<短ID> 选择要对比的另一个实例，
+	// labeldiff:<短ID>:<短ID> 显示两个实例的标签差异。实例名可能超过 64 字节的回调数据限制，这里用短ID表示实例
+	labelDiffPrefix = "labeldiff:"
+	diffUsage       = "用法: /diff &lt;实例1&gt; [实例2]\n" +
+		"对比两个实例的标签，找出新添加的主机上漏配或配错的 expiry、price、info 等标签；只指定一个实例时选择要对比的另一个实例"
+)
+
+// labelChange 是两个实例上的同一个标签，不存在的一侧为空
+type labelChange struct {
+	Name string
+	A, B string
+}
+
+// labelDiff 是两个实例标签的差异，各部分都按标签名排序
+type labelDiff struct {
+	Changed []labelChange
+	OnlyA   []labelChange
+	OnlyB   []labelChange
+	Same    []string
+}
+
+// diffLabels 对比两个实例的标签，忽略 instance、__name__ 和合并实例时添加的标签
+func diffLabels(a, b model.Metric) labelDiff {
+	ignored := func(k model.LabelName) bool {
+		return k == "instance" || k == "__name__" || k == prometheus.MergedInstancesLabel
+	}
+	var d labelDiff
+	for k, va := range a {
+		if ignored(k) {
+			continue
+		}
+		vb, ok := b[k]
+		switch {
+		case !ok:
+			d.OnlyA = append(d.OnlyA, labelChange{Name: string(k), A: string(va)})
+		case va != vb:
+			d.Changed = append(d.Changed, labelChange{Name: string(k), A: string(va), B: string(vb)})
+		default:
+			d.Same = append(d.Same, string(k))
+		}
+	}
+	for k, vb := range b {
+		if _, ok := a[k]; !ok && !ignored(k) {
+			d.OnlyB = append(d.OnlyB, labelChange{Name: string(k), B: string(vb)})
+		}
+	}
+	for _, changes := range [][]labelChange{d.Changed, d.OnlyA, d.OnlyB} {
+		sort.Slice(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+	}
+	sort.Strings(d.Same)
+	return d
+}
+
+// labelDiffText 生成标签对比页面的正文。只在一个实例上配置的续费、计费等信息标签带有警告标记
+func (b *BotInstance) labelDiffText(nameA, nameB string, d labelDiff) string {
+	warning := b.Renderer.Glyph(render.GlyphWarning)
+	var sb strings.Builder
+	sb.WriteString("<b>标签对比</b>\n")
+	sb.WriteString(fmt.Sprintf("A: <code>%s</code>\nB: <code>%s</code>\n", escapeHTML(nameA), escapeHTML(nameB)))
+	if len(d.Changed) == 0 && len(d.OnlyA) == 0 && len(d.OnlyB) == 0 {
+		sb.WriteString(fmt.Sprintf("\n两个实例的 %d 个标签完全相同。", len(d.Same)))
+		return sb.String()
+	}
+
+	warned := false
+	only := func(title string, changes []labelChange, value func(labelChange) string) {
+		if len(changes) == 0 {
+			return
+		}
+		sb.WriteString(fmt.Sprintf("\n<b>%s（%d）:</b>\n", title, len(changes)))
+		for _, c := range changes {
+			mark := ""
+			if prometheus.IsMetadataLabel(model.LabelName(c.Name)) {
+				mark = warning + " "
+				warned = true
+			}
+			sb.WriteString(fmt.Sprintf("%s%s = <code>%s</code>\n", mark, escapeHTML(c.Name), escapeHTML(value(c))))
+		}
+	}
+	if len(d.Changed) > 0 {
+		sb.WriteString(fmt.Sprintf("\n<b>值不同（%d）:</b>\n", len(d.Changed)))
+		for _, c := range d.Changed {
+			sb.WriteString(fmt.Sprintf("%s: <code>%s</code> → <code>%s</code>\n", escapeHTML(c.Name), escapeHTML(c.A), escapeHTML(c.B)))
+		}
+	}
+	only("仅 A 有", d.OnlyA, func(c labelChange) string { return c.A })
+	only("仅 B 有", d.OnlyB, func(c labelChange) string { return c.B })
+	if len(d.Same) > 0 {
+		sb.WriteString(fmt.Sprintf("\n相同（%d）: %s\n", len(d.Same), escapeHTML(strings.Join(d.Same, ", "))))
+	}
+	if warned {
+		sb.WriteString("\n" + warning + " 续费、计费等信息标签只在一个实例上配置，可能是添加实例时漏配了。")
+	}
+	return sb.String()
+}
+
+// handleDiffCommand 处理 /diff，打开两个实例的标签对比页面，只指定一个实例时打开选择另一个实例的页面
+func (b *BotInstance) handleDiffCommand(chatID int64, args string) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 || len(fields) > 2 {
+		b.sendText(chatID, diffUsage)
+		return
+	}
+	if len(fields) == 2 && fields[0] == fields[1] {
+		b.sendText(chatID, "请指定两个不同的实例。\n"+diffUsage)
+		return
+	}
+	var ids []string
+	for _, name := range fields {
+		instance, err := b.findInstance(chatID, name)
+		if err != nil {
+			b.sendError(chatID, "获取实例列表", err)
+			return
+		}
+		if instance == nil {
+			b.sendText(chatID, fmt.Sprintf("找不到实例 %s\n%s", escapeHTML(name), diffUsage))
+			return
+		}
+		id, err := b.Store.InstanceShortID(name)
+		if err != nil {
+			b.sendError(chatID, "保存实例短ID", err)
+			return
+		}
+		ids = append(ids, id)
+	}
+	b.openMenu(chatID, labelDiffPrefix+strings.Join(ids, ":"))
+}
+
+// labelDiffCallback 返回打开实例标签对比的回调数据，生成短ID失败时为空
+func (b *BotInstance) labelDiffCallback(instanceName string) string {
+	id, err := b.Store.InstanceShortID(instanceName)
+	if err != nil {
+		log.Printf("Failed to save instance short ID: %v", err)
+		return ""
+	}
+	return labelDiffPrefix + id
+}
+
+// labelDiffPage 处理 labeldiff: 之后的参数，只有一个短ID时显示选择实例的页面
+func (b *BotInstance) labelDiffPage(chatID int64, messageID int, param string, page int) tgbotapi.Chattable {
+	menuID := labelDiffPrefix + param
+	idA, idB, pair := strings.Cut(param, ":")
+	nameA, ok := b.Store.LookupShortID(idA)
+	var nameB string
+	if ok && pair {
+		nameB, ok = b.Store.LookupShortID(idB)
+	}
+	if !ok {
+		return b.textPage(chatID, messageID, "链接无效或已失效。", b.generateMenuRows([]MenuItem{
+			{Text: "返回", CallbackData: b.getPreviousMenuID(chatID)},
+			{Text: "返回主菜单", CallbackData: mainMenuID},
+		}))
+	}
+
+	instances, err := b.fetchInstancesForMenu(chatID, allInstancesMenuID)
+	if err != nil {
+		return b.errorPage(chatID, messageID, "获取实例列表", err, menuID, page)
+	}
+	var a, other model.Metric
+	var candidates []model.Metric
+	for _, instance := range instances {
+		switch string(instance["instance"]) {
+		case nameA:
+			a = instance
+		case nameB:
+			other = instance
+		default:
+			candidates = append(candidates, instance)
+		}
+	}
+	if a == nil || pair && other == nil {
+		missing := nameA
+		if a != nil {
+			missing = nameB
+		}
+		return b.textPage(chatID, messageID, fmt.Sprintf("实例 %s 已不存在。", escapeHTML(missing)), b.generateMenuRows([]MenuItem{
+			{Text: "返回", CallbackData: b.getPreviousMenuID(chatID)},
+			{Text: "返回主菜单", CallbackData: mainMenuID},
+		}))
+	}
+	if !pair {
+		return b.labelDiffPickPage(chatID, messageID, menuID, a, candidates, page)
+	}
+
+	var items []MenuItem
+	for _, name := range []string{nameA, nameB} {
+		if callbackData := instanceInfoPrefix + name; fitsCallback(callbackData) {
+			items = append(items, MenuItem{Text: "详情: " + name, CallbackData: callbackData})
+		}
+	}
+	items = append(items,
+		MenuItem{Text: "返回", CallbackData: b.getPreviousMenuID(chatID)},
+		MenuItem{Text: "返回主菜单", CallbackData: mainMenuID},
+	)
+	return b.textPage(chatID, messageID, b.labelDiffText(nameA, nameB, diffLabels(a, other)), b.generateMenuRows(items))
+}
+
+// labelDiffPickPage 分页列出可以与实例 a 对比标签的其他实例
+func (b *BotInstance) labelDiffPickPage(chatID int64, messageID int, menuID string, a model.Metric, candidates []model.Metric, page int) tgbotapi.Chattable {
+	totalPages := (len(candidates) + b.PageSize - 1) / b.PageSize
+	page = min(max(page, 1), max(totalPages, 1))
+	text := fmt.Sprintf("<b>标签对比</b>\n请选择要与 <code>%s</code> 对比标签的实例(%d)",
+		escapeHTML(string(a["instance"])), len(candidates))
+	if len(candidates) == 0 {
+		text = "没有可以对比的其他实例。"
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	start := (page - 1) * b.PageSize
+	for _, instance := range candidates[start:min(start+b.PageSize, len(candidates))] {
+		id, err := b.Store.InstanceShortID(string(instance["instance"]))
+		if err != nil {
+			log.Printf("Failed to save instance short ID: %v", err)
+			continue
+		}
+		button := tgbotapi.NewInlineKeyboardButtonData(b.instanceButtonText(instance), menuID+":"+id)
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(button))
+	}
+	if page > 1 {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("上一页", fmt.Sprintf("prev_%s_%d", menuID, page-1))))
+	}
+	if page < totalPages {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("下一页", fmt.Sprintf("next_%s_%d", menuID, page+1))))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("返回", b.getPreviousMenuID(chatID)),
+		tgbotapi.NewInlineKeyboardButtonData("返回主菜单", mainMenuID)))
+	return b.textPage(chatID, messageID, text, rows)
+}
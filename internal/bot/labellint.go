@@ -0,0 +1,61 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/prometheus"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/render"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// labelLintMenuID 是管理员可见的标签检查页面，列出续费和计费标签缺失或无法解析的实例
+const labelLintMenuID = "label_lint"
+
+// labelLintPage 检查所有实例的 expiry、price、cycle 等标签，只有管理员可以查看
+func (b *BotInstance) labelLintPage(chatID int64, messageID int) tgbotapi.Chattable {
+	if !b.isAdmin(chatID) {
+		rows := [][]tgbotapi.InlineKeyboardButton{tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("返回主菜单", mainMenuID))}
+		return b.textPage(chatID, messageID, "只有管理员可以检查实例标签。", rows)
+	}
+	rows := [][]tgbotapi.InlineKeyboardButton{tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("刷新", labelLintMenuID),
+		tgbotapi.NewInlineKeyboardButtonData("返回", b.getPreviousMenuID(chatID)),
+		tgbotapi.NewInlineKeyboardButtonData("返回主菜单", mainMenuID),
+	)}
+
+	instances, err := b.fetchInstancesForMenu(chatID, allInstancesMenuID)
+	if err != nil {
+		return b.errorPage(chatID, messageID, "获取实例列表", err, labelLintMenuID, 1)
+	}
+	data := render.LabelLintData{
+		GeneratedAt: time.Now(),
+		Total:       len(instances),
+		Instances:   prometheus.LintInstances(instances),
+	}
+	text, err := b.render(chatID, render.LabelLint, data)
+	if err != nil {
+		return b.errorPage(chatID, messageID, "生成标签检查结果", err, labelLintMenuID, 1)
+	}
+	return b.textPage(chatID, messageID, text, rows)
+}
+
+// labelProblemsText 说明实例详情因为哪些标签无法显示，没有这类问题时返回空字符串，由调用方显示原始错误
+func labelProblemsText(instance string, problems []prometheus.LabelProblem) string {
+	var lines []string
+	for _, p := range problems {
+		if !p.Blocking {
+			continue
+		}
+		line := "• " + escapeHTML(p.Label)
+		if p.Value != "" {
+			line += fmt.Sprintf(" = <code>%s</code>", escapeHTML(p.Value))
+		}
+		lines = append(lines, line+": "+escapeHTML(p.Problem))
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("<b>%s</b>\n无法显示实例详情，请修正以下标签:\n%s", escapeHTML(instance), strings.Join(lines, "\n"))
+}
@@ -0,0 +1,40 @@
+package bot
+
+import (
+	"strings"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/render"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/utils"
+	"github.com/prometheus/common/model"
+)
+
+// maxButtonText 限制实例按钮文字的长度，过长的按钮在手机上会被截断显示
+const maxButtonText = 48
+
+// displayLabels 按配置顺序返回实例上设置了值的自定义标签
+func (b *BotInstance) displayLabels(instance model.Metric) []render.LabelValue {
+	var labels []render.LabelValue
+	for _, l := range b.config.DisplayLabels {
+		if v := string(instance[model.LabelName(l.Name)]); v != "" {
+			labels = append(labels, render.LabelValue{Title: l.Title, Value: v})
+		}
+	}
+	return labels
+}
+
+// instanceButtonText 返回实例列表按钮的文字：配置了 GeoIP 时以国家旗帜开头，实例名后依次附加自定义标签的值
+func (b *BotInstance) instanceButtonText(instance model.Metric) string {
+	name := string(instance["instance"])
+	if info := b.geoInfo(instance); info != nil && info.Flag() != "" {
+		name = info.Flag() + " " + name
+	}
+	labels := b.displayLabels(instance)
+	if len(labels) == 0 {
+		return name
+	}
+	parts := []string{name}
+	for _, l := range labels {
+		parts = append(parts, l.Value)
+	}
+	return utils.TruncateString(strings.Join(parts, " · "), maxButtonText)
+}
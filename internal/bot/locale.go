@@ -0,0 +1,87 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/render"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/store"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// chatLocales 记录每个聊天最近一次消息中用户的语言
+type chatLocales struct {
+	mu      sync.Mutex
+	locales map[int64]render.Locale
+}
+
+func (c *chatLocales) get(chatID int64) (render.Locale, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	locale, ok := c.locales[chatID]
+	return locale, ok
+}
+
+func (c *chatLocales) set(chatID int64, locale render.Locale) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.locales == nil {
+		c.locales = make(map[int64]render.Locale)
+	}
+	c.locales[chatID] = locale
+}
+
+// rememberLocale 根据 Telegram 用户的 language_code 记录聊天使用的语言
+func (b *BotInstance) rememberLocale(chatID int64, user *tgbotapi.User) {
+	if user == nil || user.LanguageCode == "" {
+		return
+	}
+	b.locales.set(chatID, render.NewLocale(user.LanguageCode))
+}
+
+// chatLocale 返回聊天使用的语言，未知时使用默认语言，并应用聊天的速率单位设置和全局的流量方向名称
+func (b *BotInstance) chatLocale(chatID int64) render.Locale {
+	locale, ok := b.locales.get(chatID)
+	if !ok {
+		locale = b.Renderer.Locale()
+	}
+	return locale.WithBitRates(b.Store.ChatSettings(chatID).BitRates).WithDirections(b.Renderer.Locale().Directions())
+}
+
+// render 使用聊天的语言渲染模板，并按聊天的隐私模式隐藏地址
+func (b *BotInstance) render(chatID int64, name string, data interface{}) (string, error) {
+	text, err := b.Renderer.RenderLocale(b.chatLocale(chatID), name, data)
+	if err != nil {
+		return "", err
+	}
+	return b.redact(chatID, text), nil
+}
+
+// rateUnitLabels 是 /units 可选的网络速率单位
+var rateUnitLabels = map[string]string{
+	"bytes": "字节 (MiB/s)",
+	"bits":  "比特 (Mbps)",
+}
+
+// handleUnitsCommand 处理 /units [bits|bytes]，查看或设置当前聊天的网络速率单位
+func (b *BotInstance) handleUnitsCommand(chatID int64, args string) {
+	unit := strings.ToLower(strings.TrimSpace(args))
+	if unit == "" {
+		current := "bytes"
+		if b.Store.ChatSettings(chatID).BitRates {
+			current = "bits"
+		}
+		b.sendText(chatID, fmt.Sprintf("当前网络速率单位: %s (%s)\n用法: /units bits|bytes", current, rateUnitLabels[current]))
+		return
+	}
+	if _, ok := rateUnitLabels[unit]; !ok {
+		b.sendText(chatID, "无效的速率单位，可选: bits、bytes")
+		return
+	}
+	if err := b.Store.UpdateChatSettings(chatID, func(s *store.ChatSettings) { s.BitRates = unit == "bits" }); err != nil {
+		b.sendError(chatID, "保存速率单位", err)
+		return
+	}
+	b.sendText(chatID, fmt.Sprintf("网络速率单位已设置为: %s (%s)", unit, rateUnitLabels[unit]))
+}
@@ -6,7 +6,10 @@ import (
 	"strings"
 	"time"
 
+	"github.com/bestmjj/prometheus-telegram-bot/internal/config"
 	"github.com/bestmjj/prometheus-telegram-bot/internal/prometheus"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/render"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/utils"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/prometheus/common/model"
 )
@@ -16,6 +19,7 @@ func (b *BotInstance) mainMenuPage(chatID int64, messageID int) tgbotapi.Chattab
 	menuItems := []MenuItem{
 		{Text: "实例", CallbackData: instanceMenuID},
 		{Text: "实例详情", CallbackData: instanceDetailTableMenuID}, // 添加新菜单项
+		{Text: "事件", CallbackData: eventsMenuID},
 		{Text: "其他", CallbackData: otherMenuID},
 	}
 	rows := b.generateMenuRows(menuItems)
@@ -41,7 +45,7 @@ func (b *BotInstance) instanceMenuPage(chatID int64, messageID int) tgbotapi.Cha
 		{Text: "所有实例", CallbackData: allInstancesMenuID},
 		{Text: "在线实例", CallbackData: onlineInstancesMenuID},
 		{Text: "离线实例", CallbackData: offlineInstancesMenuID},
-		{Text: "返回", CallbackData: b.getPreviousMenuID()},
+		{Text: "返回", CallbackData: b.getPreviousMenuID(chatID)},
 		{Text: "返回主菜单", CallbackData: mainMenuID},
 	}
 	rows := b.generateMenuRows(menuItems)
@@ -62,241 +66,139 @@ func (b *BotInstance) instanceMenuPage(chatID int64, messageID int) tgbotapi.Cha
 }
 
 func (b *BotInstance) instanceOverviewMenuPage(chatID int64, messageID int) tgbotapi.Chattable {
-	instances := b.fetchInstancesForMenu(allInstancesMenuID)
-	onlineCount := len(b.fetchInstancesForMenu(onlineInstancesMenuID))
-	offlineCount := len(b.fetchInstancesForMenu(offlineInstancesMenuID))
-
-	var menuTitle string
-
-	// 添加标题
-	menuTitle += "<b>实例总览</b>\n\n"
-
-	menuTitle += fmt.Sprintf(
-		"<b>总共实例:</b> %d\n"+
-			"<b>在线实例:</b> %d\n"+
-			"<b>离线实例:</b> %d\n\n",
-		len(instances), onlineCount, offlineCount)
-
-	now := time.Now()
-	var instance model.Metric
-
-	// 获取昨日流量
-	yesterdayTransmitBytes, yesterdayReceiveBytes, err := b.PrometheusClient.GetYesterdayTraffic(instance, now)
+	instances, err := b.fetchInstancesForMenu(chatID, allInstancesMenuID)
 	if err != nil {
-		errStr := fmt.Sprintf("Failed to query yesterday traffic: %v", err)
-		return tgbotapi.NewMessage(chatID, errStr)
+		return b.errorPage(chatID, messageID, "获取实例列表", err, instanceOverviewMenuID, 1)
 	}
-	yesterdayTotalBytes := yesterdayTransmitBytes + yesterdayReceiveBytes
-
-	menuTitle += "<b>昨日流量:</b>\n"
-
-	// 查询昨日上传流量最大的实例
-	highestUploadInstance, highestUploadValue, err := b.PrometheusClient.GetHighestUploadTrafficInstance(now)
+	online, err := b.fetchInstancesForMenu(chatID, onlineInstancesMenuID)
 	if err != nil {
-		log.Printf("failed to get highest upload traffic instance: %v", err)
-		menuTitle += fmt.Sprintf("  上传: %s\n", prometheus.FormatBytes(yesterdayTransmitBytes))
-	} else if highestUploadInstance != "" {
-		menuTitle += fmt.Sprintf("  上传: %s（最多：%s (%s)）\n", prometheus.FormatBytes(yesterdayTransmitBytes), truncateString(highestUploadInstance, 30), prometheus.FormatBytes(highestUploadValue))
-	} else {
-		menuTitle += fmt.Sprintf("  上传: %s\n", prometheus.FormatBytes(yesterdayTransmitBytes))
+		return b.errorPage(chatID, messageID, "获取在线实例", err, instanceOverviewMenuID, 1)
 	}
-
-	// 查询昨日下载流量最大的实例
-	highestDownloadInstance, highestDownloadValue, err := b.PrometheusClient.GetHighestDownloadTrafficInstance(now)
+	offline, err := b.fetchInstancesForMenu(chatID, offlineInstancesMenuID)
 	if err != nil {
-		log.Printf("failed to get highest download traffic instance: %v", err)
-		menuTitle += fmt.Sprintf("  下载: %s\n", prometheus.FormatBytes(yesterdayReceiveBytes))
-	} else if highestDownloadInstance != "" {
-		menuTitle += fmt.Sprintf("  下载: %s（最多：%s (%s)）\n", prometheus.FormatBytes(yesterdayReceiveBytes), truncateString(highestDownloadInstance, 30), prometheus.FormatBytes(highestDownloadValue))
-	} else {
-		menuTitle += fmt.Sprintf("  下载: %s\n", prometheus.FormatBytes(yesterdayReceiveBytes))
+		return b.errorPage(chatID, messageID, "获取离线实例", err, instanceOverviewMenuID, 1)
 	}
 
-	// 查询昨日总流量最大的实例
-	highestTotalInstance, highestTotalValue, err := b.PrometheusClient.GetHighestTotalTrafficInstance(now)
-	if err != nil {
-		log.Printf("failed to get highest total traffic instance: %v", err)
-		menuTitle += fmt.Sprintf("  总共: %s\n", prometheus.FormatBytes(yesterdayTotalBytes))
-	} else if highestTotalInstance != "" {
-		menuTitle += fmt.Sprintf("  总共: %s（最多：%s (%s)）\n", prometheus.FormatBytes(yesterdayTotalBytes), truncateString(highestTotalInstance, 30), prometheus.FormatBytes(highestTotalValue))
-	} else {
-		menuTitle += fmt.Sprintf("  总共: %s\n", prometheus.FormatBytes(yesterdayTotalBytes))
+	data := render.OverviewData{
+		Total:   len(instances),
+		Online:  len(online),
+		Offline: len(offline),
 	}
 
-	// Get daily traffic
-	transmitBytes, receiveBytes, err := b.PrometheusClient.GetDailyTraffic(instance, now)
-	if err != nil {
-		errStr := fmt.Sprintf("failed to get daily traffic for all instance %v", err)
-		return tgbotapi.NewMessage(chatID, errStr)
-	}
+	now := time.Now()
+	locale := b.chatLocale(chatID)
+	// 合并了同一主机的实例时排除重复的实例，避免汇总时重复计算
+	instance := b.prom(chatID).FleetLabels()
 
-	// Get network rates
-	uploadRate, downloadRate, err := b.PrometheusClient.QueryNetworkRate(instance, now)
+	// 获取昨日流量
+	yesterdayTransmitBytes, yesterdayReceiveBytes, err := b.prom(chatID).GetYesterdayTraffic(instance, now)
 	if err != nil {
-		errStr := fmt.Sprintf("failed to get network rate for all instance %v", err)
-		return tgbotapi.NewMessage(chatID, errStr)
+		return b.errorPage(chatID, messageID, "查询昨日流量", err, instanceOverviewMenuID, 1)
 	}
+	yesterdayTotalBytes := yesterdayTransmitBytes + yesterdayReceiveBytes
 
-	// Add daily traffic with highest values
-	menuTitle += "\n<b>日流量:</b>\n"
-	dailyTotalBytes := transmitBytes + receiveBytes
+	// 查询昨日上传、下载、总流量最大的实例
+	directions := locale.Directions()
+	data.Yesterday = append(orderDirections(directions,
+		overviewLine(directions.Out, yesterdayTransmitBytes, locale.Bytes, "highest upload traffic instance", b.prom(chatID).GetHighestUploadTrafficInstance, now),
+		overviewLine(directions.In, yesterdayReceiveBytes, locale.Bytes, "highest download traffic instance", b.prom(chatID).GetHighestDownloadTrafficInstance, now)),
+		overviewLine("总共", yesterdayTotalBytes, locale.Bytes, "highest total traffic instance", b.prom(chatID).GetHighestTotalTrafficInstance, now),
+	)
 
-	// Daily upload traffic
-	dailyTransmitInstance, dailyTransmitValue, err := b.PrometheusClient.GetHighestDailyUploadTrafficInstance(now)
+	// Get daily traffic
+	transmitBytes, receiveBytes, err := b.prom(chatID).GetDailyTraffic(instance, now)
 	if err != nil {
-		log.Printf("failed to get highest daily upload traffic instance: %v", err)
-		menuTitle += fmt.Sprintf("  上传: %s\n", prometheus.FormatBytes(transmitBytes))
-	} else if dailyTransmitInstance != "" {
-		menuTitle += fmt.Sprintf("  上传: %s（最多：%s (%s)）\n", prometheus.FormatBytes(transmitBytes), truncateString(dailyTransmitInstance, 30), prometheus.FormatBytes(dailyTransmitValue))
-	} else {
-		menuTitle += fmt.Sprintf("  上传: %s\n", prometheus.FormatBytes(transmitBytes))
+		return b.errorPage(chatID, messageID, "查询今日流量", err, instanceOverviewMenuID, 1)
 	}
 
-	// Daily receive traffic
-	dailyReceiveInstance, dailyReceiveValue, err := b.PrometheusClient.GetHighestDailyDownloadTrafficInstance(now)
+	// Get network rates
+	uploadRate, downloadRate, err := b.prom(chatID).QueryNetworkRate(instance, now)
 	if err != nil {
-		log.Printf("failed to get highest daily download traffic instance: %v", err)
-		menuTitle += fmt.Sprintf("  下载: %s\n", prometheus.FormatBytes(receiveBytes))
-	} else if dailyReceiveInstance != "" {
-		menuTitle += fmt.Sprintf("  下载: %s（最多：%s (%s)）\n", prometheus.FormatBytes(receiveBytes), truncateString(dailyReceiveInstance, 30), prometheus.FormatBytes(dailyReceiveValue))
-	} else {
-		menuTitle += fmt.Sprintf("  下载: %s\n", prometheus.FormatBytes(receiveBytes))
+		return b.errorPage(chatID, messageID, "查询网络速率", err, instanceOverviewMenuID, 1)
 	}
 
-	// Daily total traffic
-	dailyTotalInstance, dailyTotalValue, err := b.PrometheusClient.GetHighestDailyTotalTrafficInstance(now)
-	if err != nil {
-		log.Printf("failed to get highest daily total traffic instance: %v", err)
-		menuTitle += fmt.Sprintf("  总共: %s\n", prometheus.FormatBytes(dailyTotalBytes))
-	} else if dailyTotalInstance != "" {
-		menuTitle += fmt.Sprintf("  总共: %s（最多：%s (%s)）\n", prometheus.FormatBytes(dailyTotalBytes), truncateString(dailyTotalInstance, 30), prometheus.FormatBytes(dailyTotalValue))
-	} else {
-		menuTitle += fmt.Sprintf("  总共: %s\n", prometheus.FormatBytes(dailyTotalBytes))
-	}
+	// Add daily traffic with highest values
+	data.Daily = append(orderDirections(directions,
+		overviewLine(directions.Out, transmitBytes, locale.Bytes, "highest daily upload traffic instance", b.prom(chatID).GetHighestDailyUploadTrafficInstance, now),
+		overviewLine(directions.In, receiveBytes, locale.Bytes, "highest daily download traffic instance", b.prom(chatID).GetHighestDailyDownloadTrafficInstance, now)),
+		overviewLine("总共", transmitBytes+receiveBytes, locale.Bytes, "highest daily total traffic instance", b.prom(chatID).GetHighestDailyTotalTrafficInstance, now),
+	)
 
 	// Get monthly traffic
-	naturalMonthTransmitBytes, naturalMonthReceiveBytes, err := b.PrometheusClient.GetNaturalMonthTraffic(instance, now)
+	naturalMonthTransmitBytes, naturalMonthReceiveBytes, err := b.prom(chatID).GetNaturalMonthTraffic(instance, now)
 	if err != nil {
-		errStr := fmt.Sprintf("failed to get monthly traffic for all instance %v", err)
-		return tgbotapi.NewMessage(chatID, errStr)
+		return b.errorPage(chatID, messageID, "查询本月流量", err, instanceOverviewMenuID, 1)
 	}
 
-	naturalMonthTotalBytes := naturalMonthTransmitBytes + naturalMonthReceiveBytes
-
 	// Add monthly traffic with highest values
-	menuTitle += "\n<b>月流量:</b>\n"
-
-	// Monthly upload traffic
-	monthlyTransmitInstance, monthlyTransmitValue, err := b.PrometheusClient.GetHighestMonthlyUploadTrafficInstance(now)
-	if err != nil {
-		log.Printf("failed to get highest monthly upload traffic instance: %v", err)
-		menuTitle += fmt.Sprintf("  上传: %s\n", prometheus.FormatBytes(naturalMonthTransmitBytes))
-	} else if monthlyTransmitInstance != "" {
-		menuTitle += fmt.Sprintf("  上传: %s（最多：%s (%s)）\n", prometheus.FormatBytes(naturalMonthTransmitBytes), truncateString(monthlyTransmitInstance, 30), prometheus.FormatBytes(monthlyTransmitValue))
-	} else {
-		menuTitle += fmt.Sprintf("  上传: %s\n", prometheus.FormatBytes(naturalMonthTransmitBytes))
-	}
-
-	// Monthly receive traffic
-	monthlyReceiveInstance, monthlyReceiveValue, err := b.PrometheusClient.GetHighestMonthlyDownloadTrafficInstance(now)
-	if err != nil {
-		log.Printf("failed to get highest monthly download traffic instance: %v", err)
-		menuTitle += fmt.Sprintf("  下载: %s\n", prometheus.FormatBytes(naturalMonthReceiveBytes))
-	} else if monthlyReceiveInstance != "" {
-		menuTitle += fmt.Sprintf("  下载: %s（最多：%s (%s)）\n", prometheus.FormatBytes(naturalMonthReceiveBytes), truncateString(monthlyReceiveInstance, 30), prometheus.FormatBytes(monthlyReceiveValue))
-	} else {
-		menuTitle += fmt.Sprintf("  下载: %s\n", prometheus.FormatBytes(naturalMonthReceiveBytes))
-	}
-
-	// Monthly total traffic
-	monthlyTotalInstance, monthlyTotalValue, err := b.PrometheusClient.GetHighestMonthlyTotalTrafficInstance(now)
-	if err != nil {
-		log.Printf("failed to get highest monthly total traffic instance: %v", err)
-		menuTitle += fmt.Sprintf("  总共: %s\n", prometheus.FormatBytes(naturalMonthTotalBytes))
-	} else if monthlyTotalInstance != "" {
-		menuTitle += fmt.Sprintf("  总共: %s（最多：%s (%s)）\n", prometheus.FormatBytes(naturalMonthTotalBytes), truncateString(monthlyTotalInstance, 30), prometheus.FormatBytes(monthlyTotalValue))
-	} else {
-		menuTitle += fmt.Sprintf("  总共: %s\n", prometheus.FormatBytes(naturalMonthTotalBytes))
-	}
+	data.Monthly = append(orderDirections(directions,
+		overviewLine(directions.Out, naturalMonthTransmitBytes, locale.Bytes, "highest monthly upload traffic instance", b.prom(chatID).GetHighestMonthlyUploadTrafficInstance, now),
+		overviewLine(directions.In, naturalMonthReceiveBytes, locale.Bytes, "highest monthly download traffic instance", b.prom(chatID).GetHighestMonthlyDownloadTrafficInstance, now)),
+		overviewLine("总共", naturalMonthTransmitBytes+naturalMonthReceiveBytes, locale.Bytes, "highest monthly total traffic instance", b.prom(chatID).GetHighestMonthlyTotalTrafficInstance, now),
+	)
 
 	// Add network rates with highest values
-	menuTitle += "\n<b>网络速率:</b>\n"
-
-	// Highest upload rate
-	highestUploadRateInstance, highestUploadRateValue, err := b.PrometheusClient.GetHighestUploadRateInstance(now)
-	if err != nil {
-		log.Printf("failed to get highest upload rate instance: %v", err)
-		menuTitle += fmt.Sprintf("  上传: %s/s\n", prometheus.FormatBytesPerSecond(uploadRate))
-	} else if highestUploadRateInstance != "" {
-		menuTitle += fmt.Sprintf("  上传: %s/s（最多：%s (%s/s)）\n", prometheus.FormatBytesPerSecond(uploadRate), truncateString(highestUploadRateInstance, 30), prometheus.FormatBytesPerSecond(highestUploadRateValue))
-	} else {
-		menuTitle += fmt.Sprintf("  上传: %s/s\n", prometheus.FormatBytesPerSecond(uploadRate))
-	}
-
-	// Highest download rate
-	highestDownloadRateInstance, highestDownloadRateValue, err := b.PrometheusClient.GetHighestDownloadRateInstance(now)
-	if err != nil {
-		log.Printf("failed to get highest download rate instance: %v", err)
-		menuTitle += fmt.Sprintf("  下载: %s/s\n", prometheus.FormatBytesPerSecond(downloadRate))
-	} else if highestDownloadRateInstance != "" {
-		menuTitle += fmt.Sprintf("  下载: %s/s（最多：%s (%s/s)）\n", prometheus.FormatBytesPerSecond(downloadRate), truncateString(highestDownloadRateInstance, 30), prometheus.FormatBytesPerSecond(highestDownloadRateValue))
-	} else {
-		menuTitle += fmt.Sprintf("  下载: %s/s\n", prometheus.FormatBytesPerSecond(downloadRate))
-	}
+	trends := b.prom(chatID).QueryTrends(instance, now)
+	uploadLine := overviewLine(directions.Out, uploadRate, locale.NetworkRate, "highest upload rate instance", b.prom(chatID).GetHighestUploadRateInstance, now)
+	downloadLine := overviewLine(directions.In, downloadRate, locale.NetworkRate, "highest download rate instance", b.prom(chatID).GetHighestDownloadRateInstance, now)
+	uploadLine.Trend = trends.Upload
+	downloadLine.Trend = trends.Download
+	data.Rates = orderDirections(directions, uploadLine, downloadLine)
+	data.Daily[2].Trend = trends.Traffic
 
 	// Resource metrics with highest values
-	cpuUsage, memoryUsage, diskUsage, _, _, _, _, err := b.PrometheusClient.FetchResourceMetrics(model.Metric{}, "10m", now)
+	cpuUsage, memoryUsage, diskUsage, _, _, _, _, err := b.prom(chatID).FetchResourceMetrics(instance, b.prom(chatID).ResourceRange(prometheus.ResourceViewOverview), now)
 	if err != nil {
 		log.Printf("failed to get resource metrics: %v", err)
 	}
-	menuTitle += "\n<b>资源使用情况:</b>\n"
+	data.Resources = []render.OverviewLine{
+		overviewLine("CPU 使用率", cpuUsage, locale.Percent, "highest CPU usage instance", b.prom(chatID).GetHighestCpuUsageInstance, now),
+		overviewLine("内存使用率", memoryUsage, locale.Percent, "highest memory usage instance", b.prom(chatID).GetHighestMemoryUsageInstance, now),
+		overviewLine("磁盘使用率", diskUsage, locale.Percent, "highest disk usage instance", b.prom(chatID).GetHighestDiskUsageInstance, now),
+	}
+	data.Resources[0].Trend = trends.CPU
+	data.Resources[1].Trend = trends.Memory
 
-	// Highest CPU usage
-	highestCpuInstance, highestCpuValue, err := b.PrometheusClient.GetHighestCpuUsageInstance(now)
+	// PSI 比使用率更能反映资源是否饱和，只在有实例支持时显示
+	pressure, err := b.prom(chatID).QueryPressure(instance, now)
 	if err != nil {
-		log.Printf("failed to get highest CPU usage instance: %v", err)
-		menuTitle += fmt.Sprintf("  CPU 使用率: %.2f%%\n", cpuUsage)
-	} else if highestCpuInstance != "" {
-		menuTitle += fmt.Sprintf("  CPU 使用率: %.2f%%（最多：%s (%.2f%%)）\n", cpuUsage, truncateString(highestCpuInstance, 30), highestCpuValue)
-	} else {
-		menuTitle += fmt.Sprintf("  CPU 使用率: %.2f%%\n", cpuUsage)
+		log.Printf("failed to get pressure metrics: %v", err)
 	}
-
-	// Highest memory usage
-	highestMemoryInstance, highestMemoryValue, err2 := b.PrometheusClient.GetHighestMemoryUsageInstance(now)
-	if err2 != nil {
-		log.Printf("failed to get highest memory usage instance: %v", err2)
-		menuTitle += fmt.Sprintf("  内存使用率: %.2f%%\n", memoryUsage)
-	} else if highestMemoryInstance != "" {
-		menuTitle += fmt.Sprintf("  内存使用率: %.2f%%（最多：%s (%.2f%%)）\n", memoryUsage, truncateString(highestMemoryInstance, 30), highestMemoryValue)
-	} else {
-		menuTitle += fmt.Sprintf("  内存使用率: %.2f%%\n", memoryUsage)
+	if pressure != nil {
+		highestPressure := func(resource string) func(time.Time) (string, float64, error) {
+			return func(now time.Time) (string, float64, error) {
+				return b.prom(chatID).GetHighestPressureInstance(resource, now)
+			}
+		}
+		data.Pressure = []render.OverviewLine{
+			overviewLine("CPU", pressure.CPU, locale.Percent, "highest CPU pressure instance", highestPressure(prometheus.PressureCPU), now),
+			overviewLine("内存", pressure.Memory, locale.Percent, "highest memory pressure instance", highestPressure(prometheus.PressureMemory), now),
+			overviewLine("IO", pressure.IO, locale.Percent, "highest IO pressure instance", highestPressure(prometheus.PressureIO), now),
+		}
 	}
 
-	// Highest disk usage
-	highestDiskInstance, highestDiskValue, err3 := b.PrometheusClient.GetHighestDiskUsageInstance(now)
-	if err3 != nil {
-		log.Printf("failed to get highest disk usage instance: %v", err3)
-		menuTitle += fmt.Sprintf("  磁盘使用率: %.2f%%\n", diskUsage)
-	} else if highestDiskInstance != "" {
-		menuTitle += fmt.Sprintf("  磁盘使用率: %.2f%%（最多：%s (%.2f%%)）\n", diskUsage, truncateString(highestDiskInstance, 30), highestDiskValue)
-	} else {
-		menuTitle += fmt.Sprintf("  磁盘使用率: %.2f%%\n", diskUsage)
+	menuTitle, err := b.render(chatID, render.Overview, data)
+	if err != nil {
+		return b.errorPage(chatID, messageID, "渲染总览", err, instanceOverviewMenuID, 1)
 	}
 
 	// Ensure menuTitle is not too long
 	if len(menuTitle) > 4000 {
-		menuTitle = truncateString(menuTitle, 4000)
+		menuTitle = utils.TruncateString(menuTitle, 4000)
 		menuTitle += "\n\n(Response truncated due to length limit)"
 	}
 
+	// 总览中的数字可以直接点进对应的实例列表或数值最高的实例，返回时回到总览
 	menuItems := []MenuItem{
-		{Text: "全部实例", CallbackData: allInstancesMenuID},
-		{Text: "在线实例", CallbackData: onlineInstancesMenuID},
-		{Text: "离线实例", CallbackData: offlineInstancesMenuID},
-		{Text: "返回", CallbackData: b.getPreviousMenuID()},
-		{Text: "返回主菜单", CallbackData: mainMenuID},
-	}
+		{Text: fmt.Sprintf("全部实例 (%d)", data.Total), CallbackData: allInstancesMenuID},
+		{Text: fmt.Sprintf("在线实例 (%d)", data.Online), CallbackData: onlineInstancesMenuID},
+		{Text: fmt.Sprintf("离线实例 (%d)", data.Offline), CallbackData: offlineInstancesMenuID},
+	}
+	menuItems = append(menuItems, overviewTopItems(data)...)
+	menuItems = append(menuItems,
+		MenuItem{Text: "返回", CallbackData: b.getPreviousMenuID(chatID)},
+		MenuItem{Text: "返回主菜单", CallbackData: mainMenuID},
+	)
 	rows := b.generateMenuRows(menuItems)
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
 
@@ -316,7 +218,10 @@ func (b *BotInstance) instanceOverviewMenuPage(chatID int64, messageID int) tgbo
 }
 
 func (b *BotInstance) allInstancesMenuPage(chatID int64, messageID int, page int) tgbotapi.Chattable {
-	instances := b.fetchInstancesForMenu(allInstancesMenuID)
+	instances, err := b.fetchInstancesForMenu(chatID, allInstancesMenuID)
+	if err != nil {
+		return b.errorPage(chatID, messageID, "获取实例列表", err, allInstancesMenuID, page)
+	}
 	startIndex := (page - 1) * b.PageSize
 	endIndex := startIndex + b.PageSize
 	maxInstance := len(instances)
@@ -327,7 +232,7 @@ func (b *BotInstance) allInstancesMenuPage(chatID int64, messageID int, page int
 	var rows [][]tgbotapi.InlineKeyboardButton
 	for i := startIndex; i < endIndex; i++ {
 		instanceName := string(instances[i]["instance"])
-		button := tgbotapi.NewInlineKeyboardButtonData(instanceName, instanceName)
+		button := tgbotapi.NewInlineKeyboardButtonData(b.instanceButtonText(instances[i]), instanceName)
 		rows = append(rows, tgbotapi.NewInlineKeyboardRow(button))
 	}
 	if page > 1 {
@@ -356,7 +261,10 @@ func (b *BotInstance) allInstancesMenuPage(chatID int64, messageID int, page int
 }
 
 func (b *BotInstance) onlineInstancesMenuPage(chatID int64, messageID int, page int) tgbotapi.Chattable {
-	instances := b.fetchInstancesForMenu(onlineInstancesMenuID)
+	instances, err := b.fetchInstancesForMenu(chatID, onlineInstancesMenuID)
+	if err != nil {
+		return b.errorPage(chatID, messageID, "获取实例列表", err, onlineInstancesMenuID, page)
+	}
 	startIndex := (page - 1) * b.PageSize
 	endIndex := startIndex + b.PageSize
 	maxInstance := len(instances)
@@ -367,7 +275,7 @@ func (b *BotInstance) onlineInstancesMenuPage(chatID int64, messageID int, page
 	var rows [][]tgbotapi.InlineKeyboardButton
 	for i := startIndex; i < endIndex; i++ {
 		instanceName := string(instances[i]["instance"])
-		button := tgbotapi.NewInlineKeyboardButtonData(instanceName, instanceName)
+		button := tgbotapi.NewInlineKeyboardButtonData(b.instanceButtonText(instances[i]), instanceName)
 		rows = append(rows, tgbotapi.NewInlineKeyboardRow(button))
 	}
 	if page > 1 {
@@ -396,7 +304,10 @@ func (b *BotInstance) onlineInstancesMenuPage(chatID int64, messageID int, page
 }
 
 func (b *BotInstance) offlineInstancesMenuPage(chatID int64, messageID int, page int) tgbotapi.Chattable {
-	instances := b.fetchInstancesForMenu(offlineInstancesMenuID)
+	instances, err := b.fetchInstancesForMenu(chatID, offlineInstancesMenuID)
+	if err != nil {
+		return b.errorPage(chatID, messageID, "获取实例列表", err, offlineInstancesMenuID, page)
+	}
 	startIndex := (page - 1) * b.PageSize
 	endIndex := startIndex + b.PageSize
 	maxInstance := len(instances)
@@ -407,7 +318,7 @@ func (b *BotInstance) offlineInstancesMenuPage(chatID int64, messageID int, page
 	var rows [][]tgbotapi.InlineKeyboardButton
 	for i := startIndex; i < endIndex; i++ {
 		instanceName := string(instances[i]["instance"])
-		button := tgbotapi.NewInlineKeyboardButtonData(instanceName, instanceName)
+		button := tgbotapi.NewInlineKeyboardButtonData(b.instanceButtonText(instances[i]), instanceName)
 		rows = append(rows, tgbotapi.NewInlineKeyboardRow(button))
 	}
 	if page > 1 {
@@ -438,9 +349,30 @@ func (b *BotInstance) offlineInstancesMenuPage(chatID int64, messageID int, page
 func (b *BotInstance) otherMenuPage(chatID int64, messageID int) tgbotapi.Chattable {
 	menuTitle := "请选择一个其他子菜单"
 	menuItems := []MenuItem{
-		{Text: "返回", CallbackData: b.getPreviousMenuID()},
-		{Text: "返回主菜单", CallbackData: mainMenuID},
+		{Text: "分组汇总", CallbackData: b.groupSummaryMenuID("")},
+		{Text: "系统更新", CallbackData: fleetSystemMenuID},
+		{Text: "UPS", CallbackData: upsMenuID},
+		{Text: "定时任务", CallbackData: schedulesMenuID},
+		{Text: "流量热力图", CallbackData: heatmapPrefix},
+		{Text: "CPU steal 排行", CallbackData: stealRankingMenuID},
+	}
+	// 插件和配置文件中定义的自定义按钮
+	menuItems = append(menuItems, pluginMenuItems()...)
+	menuItems = append(menuItems, b.shortcutMenuItems()...)
+	if len(b.config.ScrapeFallbackTargets) > 0 {
+		menuItems = append(menuItems, MenuItem{Text: "直接抓取状态", CallbackData: directStatusMenuID})
+	}
+	if b.isAdmin(chatID) {
+		menuItems = append(menuItems, MenuItem{Text: "使用统计", CallbackData: usageStatsMenuID(usageStatsDays[0])})
+		if b.config.SlowQueryThreshold > 0 {
+			menuItems = append(menuItems, MenuItem{Text: "慢查询", CallbackData: slowQueriesMenuID})
+		}
+		menuItems = append(menuItems, MenuItem{Text: "标签检查", CallbackData: labelLintMenuID})
 	}
+	menuItems = append(menuItems,
+		MenuItem{Text: "返回", CallbackData: b.getPreviousMenuID(chatID)},
+		MenuItem{Text: "返回主菜单", CallbackData: mainMenuID},
+	)
 	rows := b.generateMenuRows(menuItems)
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
 
@@ -458,7 +390,10 @@ func (b *BotInstance) otherMenuPage(chatID int64, messageID int) tgbotapi.Chatta
 }
 
 func (b *BotInstance) instanceDetailTableMenuPage(chatID int64, messageID int, page int) tgbotapi.Chattable {
-	instances := b.fetchInstancesForMenu(allInstancesMenuID)
+	instances, err := b.fetchInstancesForMenu(chatID, allInstancesMenuID)
+	if err != nil {
+		return b.errorPage(chatID, messageID, "获取实例列表", err, instanceDetailTableMenuID, page)
+	}
 
 	// 分页逻辑
 	// 详情页内容较多，每页只显示1个实例
@@ -497,238 +432,26 @@ func (b *BotInstance) instanceDetailTableMenuPage(chatID int64, messageID int, p
 		}
 
 		// 格式化实例名称和规格信息
-		formattedName := truncateString(name, 30)
+		formattedName := utils.TruncateString(name, 30)
 		if specInfo != "" {
-			formattedName = fmt.Sprintf("%s(%s)", formattedName, truncateString(specInfo, 20))
+			formattedName = fmt.Sprintf("%s(%s)", formattedName, utils.TruncateString(specInfo, 20))
 		}
 
-		// 获取实例的真实信息
-		info, err := b.PrometheusClient.GetInstanceInfo(instance)
+		// 获取实例的真实信息，失败时模板会显示基本的实例信息
+		entry := render.InstanceTableEntry{Index: i + 1, Name: formattedName}
+		detail, err := b.prom(chatID).GetInstanceDetail(instance)
 		if err != nil {
 			log.Printf("Failed to get instance info for %s: %v", name, err)
-
-			// 如果无法获取实例信息，则显示基本的实例信息
-			tableContent += fmt.Sprintf(
-				"<b>%d. %s</b>\n"+
-					"  • 在线时长: 无法获取\n"+
-					"  • 续费日期: 无法获取\n"+
-					"  • 续费价格: 无法获取\n"+
-					"  • 剩余时间: 无法获取\n"+
-					"  • 重置日期: 无法获取\n"+
-					"  • 重置日流量: 无法获取\n"+
-					"  • 日流量: 无法获取\n"+
-					"  • 月流量: 无法获取\n"+
-					"  • 昨日流量: 无法获取\n"+
-					"  • 资源使用: 无法获取\n\n",
-				i+1,
-				escapeHTML(formattedName),
-			)
 		} else {
-			// 直接解析信息字符串，逐行处理
-			lines := strings.Split(info, "\n")
-
-			var onlineDuration, renewalDate, price, remainingTime, resetDate, resourceUsage string
-			var resetDayTraffic, dailyTraffic, monthlyTraffic, yesterdayTraffic string
-
-			// 用于跟踪当前正在解析哪个部分
-			currentSection := ""
-			var resetDayUpload, resetDayDownload, resetDayTotal string
-			var dailyUpload, dailyDownload, dailyTotal string
-			var monthlyUpload, monthlyDownload, monthlyTotal string
-			var yesterdayUpload, yesterdayDownload, yesterdayTotal string
-
-			for _, line := range lines {
-				line = strings.TrimSpace(line)
-
-				// 检查是否是章节标题
-				if strings.Contains(line, "重置日流量:") {
-					currentSection = "resetDay"
-					continue
-				} else if strings.Contains(line, "昨日流量:") {
-					currentSection = "yesterday"
-					continue
-				} else if strings.Contains(line, "日流量:") {
-					currentSection = "daily"
-					continue
-				} else if strings.Contains(line, "月流量:") {
-					currentSection = "monthly"
-					continue
-				} else if strings.Contains(line, "网络速率:") {
-					currentSection = "network"
-					continue
-				} else if strings.Contains(line, "资源使用情况:") {
-					currentSection = "resource"
-					continue
-				}
-
-				// 解析各个字段
-				if strings.Contains(line, "在线时长:") {
-					onlineDuration = strings.TrimSpace(strings.SplitN(line, ":", 2)[1])
-					onlineDuration = strings.ReplaceAll(onlineDuration, "<b>", "")
-					onlineDuration = strings.ReplaceAll(onlineDuration, "</b>", "")
-				} else if strings.Contains(line, "续费日期:") {
-					renewalDate = strings.TrimSpace(strings.SplitN(line, ":", 2)[1])
-					renewalDate = strings.ReplaceAll(renewalDate, "<b>", "")
-					renewalDate = strings.ReplaceAll(renewalDate, "</b>", "")
-				} else if strings.Contains(line, "续费价格:") {
-					price = strings.TrimSpace(strings.SplitN(line, ":", 2)[1])
-					price = strings.ReplaceAll(price, "<b>", "")
-					price = strings.ReplaceAll(price, "</b>", "")
-				} else if strings.Contains(line, "剩余时间:") {
-					remainingTime = strings.TrimSpace(strings.SplitN(line, ":", 2)[1])
-					remainingTime = strings.ReplaceAll(remainingTime, "<b>", "")
-					remainingTime = strings.ReplaceAll(remainingTime, "</b>", "")
-				} else if strings.Contains(line, "重置日期:") {
-					resetDate = strings.TrimSpace(strings.SplitN(line, ":", 2)[1])
-					resetDate = strings.ReplaceAll(resetDate, "<b>", "")
-					resetDate = strings.ReplaceAll(resetDate, "</b>", "")
-				} else if strings.Contains(line, "上传:") && currentSection != "" {
-					uploadValue := strings.TrimSpace(strings.SplitN(line, ":", 2)[1])
-					switch currentSection {
-					case "resetDay":
-						resetDayUpload = uploadValue
-					case "daily":
-						dailyUpload = uploadValue
-					case "monthly":
-						monthlyUpload = uploadValue
-					case "yesterday":
-						yesterdayUpload = uploadValue
-					}
-				} else if strings.Contains(line, "下载:") && currentSection != "" {
-					downloadValue := strings.TrimSpace(strings.SplitN(line, ":", 2)[1])
-					switch currentSection {
-					case "resetDay":
-						resetDayDownload = downloadValue
-					case "daily":
-						dailyDownload = downloadValue
-					case "monthly":
-						monthlyDownload = downloadValue
-					case "yesterday":
-						yesterdayDownload = downloadValue
-					}
-				} else if strings.Contains(line, "总共:") && currentSection != "" {
-					totalValue := strings.TrimSpace(strings.SplitN(line, ":", 2)[1])
-					switch currentSection {
-					case "resetDay":
-						resetDayTotal = totalValue
-					case "daily":
-						dailyTotal = totalValue
-					case "monthly":
-						monthlyTotal = totalValue
-					case "yesterday":
-						yesterdayTotal = totalValue
-					}
-				}
-			}
-
-			// 组装流量信息
-			if resetDayTotal != "" {
-				resetDayTraffic = fmt.Sprintf("上传:%s 下载:%s 总共:%s", resetDayUpload, resetDayDownload, resetDayTotal)
-			} else {
-				resetDayTraffic = "N/A"
-			}
-
-			if dailyTotal != "" {
-				dailyTraffic = fmt.Sprintf("上传:%s 下载:%s 总共:%s", dailyUpload, dailyDownload, dailyTotal)
-			} else {
-				dailyTraffic = "N/A"
-			}
-
-			if monthlyTotal != "" {
-				monthlyTraffic = fmt.Sprintf("上传:%s 下载:%s 总共:%s", monthlyUpload, monthlyDownload, monthlyTotal)
-			} else {
-				monthlyTraffic = "N/A"
-			}
-
-			if yesterdayTotal != "" {
-				yesterdayTraffic = fmt.Sprintf("上传:%s 下载:%s 总共:%s", yesterdayUpload, yesterdayDownload, yesterdayTotal)
-			} else {
-				yesterdayTraffic = "N/A"
-			}
-
-			// 提取资源使用情况的详细信息
-			cpuUsage := extractField(lines, "CPU 使用率:")
-			memoryUsageDetails := extractField(lines, "内存使用率:")
-
-			// 提取内存使用率的百分比部分
-			var memoryUsage string
-			if idx := strings.Index(memoryUsageDetails, "%"); idx != -1 {
-				parts := strings.Split(memoryUsageDetails, "(")
-				if len(parts) > 0 {
-					memPercentPart := strings.TrimSpace(parts[0])
-					if strings.Contains(memPercentPart, ":") {
-						memoryUsage = strings.TrimSpace(strings.SplitN(memPercentPart, ":", 2)[1])
-					} else {
-						memoryUsage = memPercentPart
-					}
-				}
-			}
-
-			if cpuUsage != "" && memoryUsage != "" {
-				resourceUsage = fmt.Sprintf("CPU:%s MEM:%s", cpuUsage, memoryUsage)
-			} else {
-				resourceUsage = "N/A"
-			}
-
-			// 如果某些字段没有值，使用默认值
-			if onlineDuration == "" {
-				onlineDuration = "N/A"
-			}
-			if renewalDate == "" {
-				renewalDate = "N/A"
-			}
-			if price == "" {
-				price = "N/A"
-			}
-			if remainingTime == "" {
-				remainingTime = "N/A"
-			}
-			if resetDate == "" {
-				resetDate = "N/A"
-			}
-			if resetDayTraffic == "" {
-				resetDayTraffic = "N/A"
-			}
-			if dailyTraffic == "" {
-				dailyTraffic = "N/A"
-			}
-			if monthlyTraffic == "" {
-				monthlyTraffic = "N/A"
-			}
-			if yesterdayTraffic == "" {
-				yesterdayTraffic = "N/A"
-			}
-			if resourceUsage == "" {
-				resourceUsage = "N/A"
-			}
+			entry.Detail = detail
+		}
 
-			// 为每个实例创建一个垂直布局的详细信息块
-			tableContent += fmt.Sprintf(
-				"<b>%d. %s</b>\n"+
-					"  • 在线时长: %s\n"+
-					"  • 续费日期: %s\n"+
-					"  • 续费价格: %s\n"+
-					"  • 剩余时间: %s\n"+
-					"  • 重置日期: %s\n"+
-					"  • 重置日流量: %s\n"+
-					"  • 日流量: %s\n"+
-					"  • 月流量: %s\n"+
-					"  • 昨日流量: %s\n"+
-					"  • 资源使用: %s\n\n",
-				i+1,
-				escapeHTML(formattedName),
-				escapeHTML(onlineDuration),
-				escapeHTML(renewalDate),
-				escapeHTML(price),
-				escapeHTML(remainingTime),
-				escapeHTML(resetDate),
-				escapeHTML(resetDayTraffic),
-				escapeHTML(dailyTraffic),
-				escapeHTML(monthlyTraffic),
-				escapeHTML(yesterdayTraffic),
-				escapeHTML(resourceUsage),
-			)
+		content, err := b.render(chatID, render.InstanceTable, entry)
+		if err != nil {
+			log.Printf("Failed to render instance table for %s: %v", name, err)
+			content = fmt.Sprintf("<b>%d. %s</b>\n  渲染失败\n\n", i+1, escapeHTML(formattedName))
 		}
+		tableContent += content
 	}
 
 	menuTitle := tableContent
@@ -752,7 +475,7 @@ func (b *BotInstance) instanceDetailTableMenuPage(chatID int64, messageID int, p
 	}
 
 	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
-		tgbotapi.NewInlineKeyboardButtonData("返回", b.getPreviousMenuID()),
+		tgbotapi.NewInlineKeyboardButtonData("返回", b.getPreviousMenuID(chatID)),
 		tgbotapi.NewInlineKeyboardButtonData("返回主菜单", mainMenuID),
 	))
 
@@ -773,11 +496,29 @@ func (b *BotInstance) instanceDetailTableMenuPage(chatID int64, messageID int, p
 	}
 }
 
+// instanceInfoText 查询实例详情并使用 instance_detail 模板渲染，再次查看同一实例时附带与上一次相比的变化
+func (b *BotInstance) instanceInfoText(chatID int64, instance model.Metric) (string, error) {
+	detail, err := b.prom(chatID).GetInstanceDetail(instance)
+	if err != nil {
+		return "", err
+	}
+	data := render.InstanceDetailData{
+		InstanceDetail: detail,
+		Delta:          b.detailDelta(chatID, detail, time.Now()),
+		Labels:         b.displayLabels(instance),
+		Geo:            b.geoInfo(instance),
+	}
+	return b.render(chatID, render.InstanceDetail, data)
+}
+
 func (b *BotInstance) instanceInfoPage(chatID int64, messageID int, instanceName string) tgbotapi.Chattable {
 	var selectedInstance model.Metric
 
 	// Search for the instance
-	allInstances := b.fetchInstancesForMenu(allInstancesMenuID)
+	allInstances, err := b.fetchInstancesForMenu(chatID, allInstancesMenuID)
+	if err != nil {
+		return b.errorPage(chatID, messageID, "获取实例列表", err, instanceInfoPrefix+instanceName, 1)
+	}
 	for _, instance := range allInstances {
 		if string(instance["instance"]) == instanceName {
 			selectedInstance = instance
@@ -789,23 +530,58 @@ func (b *BotInstance) instanceInfoPage(chatID int64, messageID int, instanceName
 	if len(selectedInstance) == 0 {
 		info = "无效的实例，请重试。"
 	} else {
-		var err error
-		info, err = b.PrometheusClient.GetInstanceInfo(selectedInstance)
+		info, err = b.instanceInfoText(chatID, selectedInstance)
 		if err != nil {
-			info = fmt.Sprintf("获取实例信息失败: %v", err)
+			// 标签错误时列出需要修正的标签，而不是显示解析失败的原始错误
+			info = labelProblemsText(instanceName, prometheus.LintInstanceLabels(selectedInstance))
+			if info == "" {
+				return b.errorPage(chatID, messageID, "获取实例信息", err, instanceInfoPrefix+instanceName, 1)
+			}
 		}
 	}
 
-	menuItems := []MenuItem{
-		{Text: "返回", CallbackData: b.getPreviousMenuID()},
-		{Text: "返回主菜单", CallbackData: mainMenuID},
+	var menuItems []MenuItem
+	// addItem 只添加回调数据不超过长度限制的按钮
+	addItem := func(text, callbackData string) {
+		if fitsCallback(callbackData) {
+			menuItems = append(menuItems, MenuItem{Text: text, CallbackData: callbackData})
+		}
 	}
+	if len(selectedInstance) != 0 {
+		addItem("事件", eventsInstancePrefix+instanceName)
+		addItem("在线时间线", uptimePrefix+instanceName)
+		addItem("流量热力图", heatmapPrefix+instanceName)
+		addItem("连通性测试", probePrefix+instanceName)
+		if callbackData := b.labelDiffCallback(instanceName); callbackData != "" {
+			addItem("对比标签", callbackData)
+		}
+		if b.config.Enabled(config.FeatureCharts) {
+			addItem("CPU 历史", chartCallback(chartCPU, defaultChartWindow, instanceName))
+			addItem("内存历史", chartCallback(chartMemory, defaultChartWindow, instanceName))
+			addItem("磁盘IO图表", chartCallback(chartDiskIO, defaultChartWindow, instanceName))
+		}
+		if enabled, err := b.prom(chatID).HasSystemd(selectedInstance, time.Now()); err != nil {
+			log.Printf("Failed to check systemd collector: %v", err)
+		} else if enabled {
+			addItem("服务", systemdPrefix+instanceName)
+		}
+		// 只有通过 textfile 收集器上报了目录大小的实例才显示目录占用按钮
+		if sizes, err := b.prom(chatID).QueryDirectorySizes(selectedInstance, time.Now()); err != nil {
+			log.Printf("Failed to query directory sizes: %v", err)
+		} else if len(sizes) > 0 {
+			addItem("目录占用", directoriesPrefix+instanceName)
+		}
+	}
+	menuItems = append(menuItems,
+		MenuItem{Text: "返回", CallbackData: b.getPreviousMenuID(chatID)},
+		MenuItem{Text: "返回主菜单", CallbackData: mainMenuID},
+	)
 	rows := b.generateMenuRows(menuItems)
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
 
 	// Truncate info if too long
 	if len(info) > 4000 {
-		info = truncateString(info, 4000)
+		info = utils.TruncateString(info, 4000)
 		info += "\n\n(Response truncated)"
 	}
 
@@ -822,75 +598,58 @@ func (b *BotInstance) instanceInfoPage(chatID int64, messageID int, instanceName
 	}
 }
 
-// 辅助函数：从实例信息中提取特定字段的值
-func extractField(lines []string, fieldName string) string {
-	for i, line := range lines {
-		if strings.Contains(line, fieldName) {
-			value := strings.TrimSpace(strings.SplitN(line, ":", 2)[1])
-			value = strings.ReplaceAll(value, "<b>", "")
-			value = strings.ReplaceAll(value, "</b>", "")
-			// 如果字段值为空，可能在下一行
-			if value == "" && i+1 < len(lines) {
-				nextLine := lines[i+1]
-				if strings.HasPrefix(strings.TrimSpace(nextLine), "  ") {
-					value = strings.TrimSpace(nextLine)
-					value = strings.ReplaceAll(value, "<b>", "")
-					value = strings.ReplaceAll(value, "</b>", "")
-				}
-			}
-			return value
-		}
+// orderDirections 按配置的流量方向顺序排列发送和接收两行
+func orderDirections(d render.Directions, out, in render.OverviewLine) []render.OverviewLine {
+	if d.InFirst {
+		return []render.OverviewLine{in, out}
 	}
-	return ""
+	return []render.OverviewLine{out, in}
 }
 
-// 辅助函数：从文本中提取特定部分的值
-func extractValueFromSection(text, sectionTitle, valueTitle string) string {
-	sectionStartIdx := strings.Index(text, sectionTitle)
-	if sectionStartIdx == -1 {
-		return "N/A"
-	}
-
-	sectionEndIdx := strings.Index(text[sectionStartIdx:], "\n\n")
-	if sectionEndIdx == -1 {
-		sectionEndIdx = len(text)
-	} else {
-		sectionEndIdx = sectionEndIdx + sectionStartIdx
-	}
-
-	section := text[sectionStartIdx:sectionEndIdx]
-
-	valueStartIdx := strings.Index(section, valueTitle)
-	if valueStartIdx == -1 {
-		return "N/A"
+// overviewLine 生成总览中的一行，附带 topFn 查询到的数值最高的实例
+func overviewLine(label string, value float64, format func(float64) string, topName string, topFn func(time.Time) (string, float64, error), now time.Time) render.OverviewLine {
+	line := render.OverviewLine{Label: label, Value: format(value)}
+	topInstance, topValue, err := topFn(now)
+	if err != nil {
+		log.Printf("failed to get %s: %v", topName, err)
+		return line
 	}
-
-	valueStartIdx += len(valueTitle)
-	valueEndIdx := strings.Index(section[valueStartIdx:], "\n")
-	if valueEndIdx == -1 {
-		valueEndIdx = len(section) - valueStartIdx
-	} else {
-		valueEndIdx = valueEndIdx
+	if topInstance != "" {
+		line.Top = topInstance
+		line.TopValue = format(topValue)
 	}
-
-	value := strings.TrimSpace(section[valueStartIdx : valueStartIdx+valueEndIdx])
-	return value
+	return line
 }
 
-// 辅助函数：截断字符串以适应表格列宽
-func truncateString(s string, maxLength int) string {
-	runes := []rune(s)
-	if len(runes) <= maxLength {
-		return s
+// maxOverviewTopItems 限制总览中数值最高的实例按钮数量
+const maxOverviewTopItems = 6
+
+// overviewTopItems 为总览中各项数值最高的实例生成跳转到实例详情的按钮，同一实例只出现一次，
+// 实例名太长、超过 Telegram 64 字节回调数据限制的不生成按钮
+func overviewTopItems(data render.OverviewData) []MenuItem {
+	var items []MenuItem
+	seen := make(map[string]bool)
+	for _, lines := range [][]render.OverviewLine{data.Yesterday, data.Daily, data.Monthly, data.Rates, data.Resources, data.Pressure} {
+		for _, line := range lines {
+			callbackData := instanceInfoPrefix + line.Top
+			if line.Top == "" || seen[line.Top] || !fitsCallback(callbackData) {
+				continue
+			}
+			seen[line.Top] = true
+			if len(items) == maxOverviewTopItems {
+				return items
+			}
+			items = append(items, MenuItem{Text: "最多: " + utils.TruncateString(line.Top, 20), CallbackData: callbackData})
+		}
 	}
-	return string(runes[:maxLength]) + "..."
+	return items
 }
 
 // 辅助函数：转义HTML特殊字符
 func escapeHTML(s string) string {
-	s = strings.ReplaceAll(s, "&", "&")
-	s = strings.ReplaceAll(s, "<", "<")
-	s = strings.ReplaceAll(s, ">", ">")
+	s = strings.ReplaceAll(s, "&", "&amp;")
+	s = strings.ReplaceAll(s, "<", "&lt;")
+	s = strings.ReplaceAll(s, ">", "&gt;")
 	s = strings.ReplaceAll(s, "\"", "&quot;")
 	return s
 }
@@ -0,0 +1,197 @@
+package bot
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/plugin"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// menuRequest 是从回调数据解析出的菜单请求参数
+type menuRequest struct {
+	ChatID    int64
+	MessageID int
+	// MenuID 是完整的菜单ID，例如 instance_info:web-1
+	MenuID string
+	// Param 是前缀路由中前缀之后的部分，例如实例名、分组标签或事件筛选的实例
+	Param string
+	Page  int
+}
+
+type menuHandler func(b *BotInstance, req menuRequest) tgbotapi.Chattable
+
+// menuRoute 描述如何生成一个菜单页以及进入该菜单时如何调整菜单栈
+type menuRoute struct {
+	// name 是路由的名称，用于使用统计：完整ID、去掉冒号的前缀或插件视图ID
+	name string
+	// title 是菜单在导航路径中显示的名称，为空时显示前缀之后的参数，例如实例名
+	title   string
+	handler menuHandler
+	// slow 表示生成页面需要查询大量 Prometheus 数据，先显示加载提示
+	slow bool
+	// cached 表示页面可以先显示稍旧的缓存内容，再在后台刷新
+	cached bool
+}
+
+type prefixRoute struct {
+	prefix string
+	route  menuRoute
+}
+
+// menuRouter 将菜单ID和ID前缀映射到页面处理函数，找不到时再查找插件视图
+type menuRouter struct {
+	exact    map[string]menuRoute
+	prefixes []prefixRoute
+}
+
+func newMenuRouter() *menuRouter {
+	r := &menuRouter{exact: make(map[string]menuRoute)}
+
+	r.handle(mainMenuID, menuRoute{title: "主菜单", handler: func(b *BotInstance, req menuRequest) tgbotapi.Chattable {
+		return b.mainMenuPage(req.ChatID, req.MessageID)
+	}})
+	r.handle(instanceMenuID, menuRoute{title: "实例", handler: func(b *BotInstance, req menuRequest) tgbotapi.Chattable {
+		return b.instanceMenuPage(req.ChatID, req.MessageID)
+	}})
+	r.handle(instanceOverviewMenuID, menuRoute{title: "实例总览", slow: true, cached: true, handler: func(b *BotInstance, req menuRequest) tgbotapi.Chattable {
+		return b.instanceOverviewMenuPage(req.ChatID, req.MessageID)
+	}})
+	r.handle(allInstancesMenuID, menuRoute{title: "所有实例", handler: func(b *BotInstance, req menuRequest) tgbotapi.Chattable {
+		return b.allInstancesMenuPage(req.ChatID, req.MessageID, req.Page)
+	}})
+	r.handle(onlineInstancesMenuID, menuRoute{title: "在线实例", handler: func(b *BotInstance, req menuRequest) tgbotapi.Chattable {
+		return b.onlineInstancesMenuPage(req.ChatID, req.MessageID, req.Page)
+	}})
+	r.handle(offlineInstancesMenuID, menuRoute{title: "离线实例", handler: func(b *BotInstance, req menuRequest) tgbotapi.Chattable {
+		return b.offlineInstancesMenuPage(req.ChatID, req.MessageID, req.Page)
+	}})
+	r.handle(otherMenuID, menuRoute{title: "其他", handler: func(b *BotInstance, req menuRequest) tgbotapi.Chattable {
+		return b.otherMenuPage(req.ChatID, req.MessageID)
+	}})
+	r.handle(instanceDetailTableMenuID, menuRoute{title: "实例详情", slow: true, cached: true, handler: func(b *BotInstance, req menuRequest) tgbotapi.Chattable {
+		return b.instanceDetailTableMenuPage(req.ChatID, req.MessageID, req.Page)
+	}})
+	r.handle(eventsMenuID, menuRoute{title: "事件", handler: func(b *BotInstance, req menuRequest) tgbotapi.Chattable {
+		return b.eventsMenuPage(req.ChatID, req.MessageID, "", req.Page)
+	}})
+	r.handle(fleetSystemMenuID, menuRoute{title: "系统更新", slow: true, handler: func(b *BotInstance, req menuRequest) tgbotapi.Chattable {
+		return b.fleetSystemPage(req.ChatID, req.MessageID)
+	}})
+	r.handle(metadataMenuID, menuRoute{title: "指标元数据", handler: func(b *BotInstance, req menuRequest) tgbotapi.Chattable {
+		return b.queryMetadataPage(req.ChatID, req.MessageID)
+	}})
+	r.handle(slowQueriesMenuID, menuRoute{title: "慢查询", handler: func(b *BotInstance, req menuRequest) tgbotapi.Chattable {
+		return b.slowQueriesPage(req.ChatID, req.MessageID)
+	}})
+	r.handle(upsMenuID, menuRoute{title: "UPS", slow: true, handler: func(b *BotInstance, req menuRequest) tgbotapi.Chattable {
+		return b.upsPage(req.ChatID, req.MessageID)
+	}})
+	r.handle(alertHistoryMenuID, menuRoute{title: "告警历史", handler: func(b *BotInstance, req menuRequest) tgbotapi.Chattable {
+		return b.alertHistoryPage(req.ChatID, req.MessageID, req.Page)
+	}})
+	r.handle(stealRankingMenuID, menuRoute{title: "CPU steal 排行", slow: true, handler: func(b *BotInstance, req menuRequest) tgbotapi.Chattable {
+		return b.stealRankingPage(req.ChatID, req.MessageID)
+	}})
+	r.handle(schedulesMenuID, menuRoute{title: "定时任务", handler: func(b *BotInstance, req menuRequest) tgbotapi.Chattable {
+		return b.schedulesPage(req.ChatID, req.MessageID)
+	}})
+	r.handle(directStatusMenuID, menuRoute{title: "直接抓取状态", slow: true, handler: func(b *BotInstance, req menuRequest) tgbotapi.Chattable {
+		return b.directStatusPage(req.ChatID, req.MessageID)
+	}})
+	r.handle(labelLintMenuID, menuRoute{title: "标签检查", handler: func(b *BotInstance, req menuRequest) tgbotapi.Chattable {
+		return b.labelLintPage(req.ChatID, req.MessageID)
+	}})
+	r.handle(queryResultMenuID, menuRoute{title: "查询结果", handler: func(b *BotInstance, req menuRequest) tgbotapi.Chattable {
+		return b.queryResultPage(req.ChatID, req.MessageID, req.Page)
+	}})
+
+	r.handlePrefix(instanceInfoPrefix, menuRoute{slow: true, handler: func(b *BotInstance, req menuRequest) tgbotapi.Chattable {
+		return b.instanceInfoPage(req.ChatID, req.MessageID, req.Param)
+	}})
+	r.handlePrefix(eventsInstancePrefix, menuRoute{title: "事件", handler: func(b *BotInstance, req menuRequest) tgbotapi.Chattable {
+		return b.eventsMenuPage(req.ChatID, req.MessageID, req.Param, req.Page)
+	}})
+	r.handlePrefix(groupSummaryPrefix, menuRoute{title: "分组", slow: true, handler: func(b *BotInstance, req menuRequest) tgbotapi.Chattable {
+		return b.groupSummaryPage(req.ChatID, req.MessageID, req.Param)
+	}})
+	r.handlePrefix(directoriesPrefix, menuRoute{title: "目录占用", slow: true, handler: func(b *BotInstance, req menuRequest) tgbotapi.Chattable {
+		return b.directoriesPage(req.ChatID, req.MessageID, req.Param)
+	}})
+	r.handlePrefix(systemdPrefix, menuRoute{title: "服务", slow: true, handler: func(b *BotInstance, req menuRequest) tgbotapi.Chattable {
+		return b.systemdPage(req.ChatID, req.MessageID, req.Param)
+	}})
+	r.handlePrefix(uptimePrefix, menuRoute{title: "在线时间线", slow: true, handler: func(b *BotInstance, req menuRequest) tgbotapi.Chattable {
+		return b.uptimePage(req.ChatID, req.MessageID, req.Param)
+	}})
+	r.handlePrefix(uptimeRangePrefix, menuRoute{title: "在线时间线", slow: true, handler: func(b *BotInstance, req menuRequest) tgbotapi.Chattable {
+		return b.uptimeRangePage(req.ChatID, req.MessageID, req.Param)
+	}})
+	r.handlePrefix(heatmapPrefix, menuRoute{title: "流量热力图", slow: true, handler: func(b *BotInstance, req menuRequest) tgbotapi.Chattable {
+		return b.heatmapPage(req.ChatID, req.MessageID, req.Param)
+	}})
+	r.handlePrefix(probePrefix, menuRoute{title: "连通性测试", slow: true, handler: func(b *BotInstance, req menuRequest) tgbotapi.Chattable {
+		return b.probePage(req.ChatID, req.MessageID, req.Param)
+	}})
+	r.handlePrefix(schedulePrefix, menuRoute{title: "定时任务", handler: func(b *BotInstance, req menuRequest) tgbotapi.Chattable {
+		return b.scheduleDetailPage(req.ChatID, req.MessageID, req.Param)
+	}})
+	r.handlePrefix(labelDiffPrefix, menuRoute{title: "标签对比", handler: func(b *BotInstance, req menuRequest) tgbotapi.Chattable {
+		return b.labelDiffPage(req.ChatID, req.MessageID, req.Param, req.Page)
+	}})
+	r.handlePrefix(usageStatsPrefix, menuRoute{title: "使用统计", handler: func(b *BotInstance, req menuRequest) tgbotapi.Chattable {
+		return b.usageStatsPage(req.ChatID, req.MessageID, req.Param)
+	}})
+	return r
+}
+
+func (r *menuRouter) handle(menuID string, route menuRoute) {
+	route.name = menuID
+	r.exact[menuID] = route
+}
+
+func (r *menuRouter) handlePrefix(prefix string, route menuRoute) {
+	route.name = strings.TrimSuffix(prefix, ":")
+	r.prefixes = append(r.prefixes, prefixRoute{prefix: prefix, route: route})
+}
+
+// match 依次按完整ID、前缀和插件视图查找菜单，返回路由和前缀之后的参数
+func (r *menuRouter) match(menuID string) (menuRoute, string, bool) {
+	if route, ok := r.exact[menuID]; ok {
+		return route, "", true
+	}
+	for _, p := range r.prefixes {
+		if strings.HasPrefix(menuID, p.prefix) {
+			return p.route, strings.TrimPrefix(menuID, p.prefix), true
+		}
+	}
+	if v, ok := plugin.Lookup(menuID); ok {
+		// 插件视图通常需要查询 Prometheus
+		return menuRoute{name: v.ID, title: v.Title, slow: true, handler: func(b *BotInstance, req menuRequest) tgbotapi.Chattable {
+			return b.pluginPage(req.ChatID, req.MessageID, v)
+		}}, "", true
+	}
+	return menuRoute{}, "", false
+}
+
+// parsePageCallback 解析翻页回调 prev_<menuID>_<page> / next_<menuID>_<page>
+func parsePageCallback(data string) (menuID string, page int, ok bool) {
+	var rest string
+	switch {
+	case strings.HasPrefix(data, "prev_"):
+		rest = strings.TrimPrefix(data, "prev_")
+	case strings.HasPrefix(data, "next_"):
+		rest = strings.TrimPrefix(data, "next_")
+	default:
+		return "", 0, false
+	}
+	i := strings.LastIndex(rest, "_")
+	if i <= 0 {
+		return "", 0, false
+	}
+	page, err := strconv.Atoi(rest[i+1:])
+	if err != nil {
+		return "", 0, false
+	}
+	return rest[:i], page, true
+}
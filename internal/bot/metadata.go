@@ -0,0 +1,81 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/prometheus"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// metadataMenuID 显示最近一次 /query 表达式中各指标的元数据
+	metadataMenuID = "metadata"
+	// maxMetadataMetrics 是一次最多查询元数据的指标数量
+	maxMetadataMetrics = 10
+	metadataUsage      = "用法: /meta <指标名> [指标名...]"
+)
+
+// handleMetadataCommand 处理 /meta <指标名>，显示指标的类型和说明
+func (b *BotInstance) handleMetadataCommand(chatID int64, args string) {
+	names := strings.Fields(args)
+	if len(names) == 0 {
+		b.post(priorityInteractive, tgbotapi.NewMessage(chatID, metadataUsage))
+		return
+	}
+	if _, err := b.editOrSend(b.metadataPage(chatID, 0, names, nil)); err != nil {
+		b.sendError(chatID, "发送指标元数据", err)
+	}
+}
+
+// queryMetadataPage 显示最近一次 /query 表达式中引用的指标的元数据
+func (b *BotInstance) queryMetadataPage(chatID int64, messageID int) tgbotapi.Chattable {
+	result := b.queryResults.get(chatID)
+	if result == nil {
+		return b.textPage(chatID, messageID, "查询结果已过期，请重新执行 /query", nil)
+	}
+	rows := [][]tgbotapi.InlineKeyboardButton{tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("返回查询结果", queryResultMenuID),
+	)}
+	return b.metadataPage(chatID, messageID, prometheus.MetricNames(result.Query), rows)
+}
+
+func (b *BotInstance) metadataPage(chatID int64, messageID int, names []string, rows [][]tgbotapi.InlineKeyboardButton) tgbotapi.Chattable {
+	if len(names) == 0 {
+		return b.textPage(chatID, messageID, "表达式中没有找到指标名", rows)
+	}
+	var text strings.Builder
+	if len(names) > maxMetadataMetrics {
+		fmt.Fprintf(&text, "只显示前 %d 个指标\n\n", maxMetadataMetrics)
+		names = names[:maxMetadataMetrics]
+	}
+	for i, name := range names {
+		if i > 0 {
+			text.WriteString("\n")
+		}
+		fmt.Fprintf(&text, "<b>%s</b>\n", escapeHTML(name))
+		metadata, err := b.prom(chatID).Metadata(name)
+		if err != nil {
+			log.Printf("Failed to query metadata for %s: %v", name, err)
+			text.WriteString("  查询元数据失败，请稍后重试\n")
+			continue
+		}
+		if len(metadata) == 0 {
+			// recording rule 生成的指标没有 HELP/TYPE
+			text.WriteString("  未找到元数据（可能是 recording rule 生成的指标或指标不存在）\n")
+			continue
+		}
+		for _, m := range metadata {
+			fmt.Fprintf(&text, "  类型: <code>%s</code>", escapeHTML(m.Type))
+			if m.Unit != "" {
+				fmt.Fprintf(&text, "  单位: <code>%s</code>", escapeHTML(m.Unit))
+			}
+			text.WriteString("\n")
+			if m.Help != "" {
+				fmt.Fprintf(&text, "  %s\n", escapeHTML(m.Help))
+			}
+		}
+	}
+	return b.textPage(chatID, messageID, text.String(), rows)
+}
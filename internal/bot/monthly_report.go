@@ -0,0 +1,112 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/prometheus"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/render"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/report"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	reportPDFUsage = "用法: /report pdf [YYYY-MM]，默认为本月至今"
+	monthFormat    = "2006-01"
+)
+
+// handleReportPDFCommand 处理 /report pdf [YYYY-MM]，生成月度 PDF 报告并作为文件发送
+func (b *BotInstance) handleReportPDFCommand(chatID int64, args string) {
+	now := time.Now()
+	month := now
+	if args != "" {
+		m, err := time.ParseInLocation(monthFormat, args, now.Location())
+		if err != nil || m.After(now) {
+			b.sendText(chatID, reportPDFUsage)
+			return
+		}
+		month = m
+	}
+	r, err := b.prom(chatID).MonthlyReport(month, now)
+	if err != nil {
+		b.sendError(chatID, "生成月度报告", err)
+		return
+	}
+	if err := b.sendMonthlyPDF(chatID, r, b.chatLocale(chatID), now); err != nil {
+		b.sendError(chatID, "生成月度报告", err)
+	}
+}
+
+// sendMonthlyPDF 按聊天的隐私模式渲染月度报告并作为文件发送
+func (b *BotInstance) sendMonthlyPDF(chatID int64, r *prometheus.MonthlyReport, locale render.Locale, now time.Time) error {
+	redacted := *r
+	redacted.Instances = make([]prometheus.MonthlyInstance, len(r.Instances))
+	for i, m := range r.Instances {
+		m.Instance = b.redact(chatID, m.Instance)
+		redacted.Instances[i] = m
+	}
+	data, err := report.MonthlyPDF(&redacted, report.Options{
+		FontPath:    b.config.ReportFont,
+		FormatBytes: locale.Bytes,
+		GeneratedAt: now,
+		Chart:       b.chartPreferences(chatID),
+	})
+	if err != nil {
+		return err
+	}
+	month := r.Start.Format(monthFormat)
+	doc := tgbotapi.NewDocument(chatID, tgbotapi.FileBytes{Name: fmt.Sprintf("report-%s.pdf", month), Bytes: data})
+	doc.Caption = fmt.Sprintf("%s 月度报告", month)
+	if r.End.Before(r.Start.AddDate(0, 1, 0)) {
+		doc.Caption += fmt.Sprintf("（截至 %s）", r.End.Format("01-02 15:04"))
+	}
+	if _, err := b.send(priorityReport, doc); err != nil {
+		return fmt.Errorf("Failed to send report document: %v", err)
+	}
+	return nil
+}
+
+// RunMonthlyReport 每月 1 日向 MonthlyReportChatIDs 发送上月的 PDF 报告，直到 ctx 被取消。
+// 已发送的月份记录在存储中，重启后不会重复发送
+func (b *BotInstance) RunMonthlyReport(ctx context.Context) {
+	if len(b.config.MonthlyReportChatIDs) == 0 {
+		return
+	}
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	b.sendMonthlyReports(time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			b.sendMonthlyReports(now)
+		}
+	}
+}
+
+func (b *BotInstance) sendMonthlyReports(now time.Time) {
+	if now.Day() != 1 {
+		return
+	}
+	month := time.Date(now.Year(), now.Month()-1, 1, 0, 0, 0, 0, now.Location())
+	key := month.Format(monthFormat)
+	if b.Store.MonthlyReportSent(key) {
+		return
+	}
+	r, err := b.PrometheusClient.MonthlyReport(month, now)
+	if err != nil {
+		log.Printf("Failed to build monthly report %s: %v", key, err)
+		return
+	}
+	for _, chatID := range b.config.MonthlyReportChatIDs {
+		if err := b.sendMonthlyPDF(chatID, r, b.chatLocale(chatID), now); err != nil {
+			log.Printf("Failed to send monthly report to %d: %v", chatID, err)
+		}
+	}
+	if err := b.Store.MarkMonthlyReportSent(key); err != nil {
+		log.Printf("Failed to save monthly report state: %v", err)
+	}
+}
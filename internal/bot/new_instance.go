@@ -0,0 +1,61 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/prometheus"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/render"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/store"
+)
+
+// pricingLabels 是统计费用和流量周期所需的实例标签
+var pricingLabels = []string{"price", "cycle"}
+
+// adminTargets 返回接收新实例、超预算等管理类通知的聊天：管理员聊天，未配置时使用告警聊天
+func (b *BotInstance) adminTargets() []int64 {
+	if len(b.config.AdminChatIDs) > 0 {
+		return b.config.AdminChatIDs
+	}
+	return b.config.AlertChatIDs
+}
+
+// sendNewInstance 通知管理员发现了新实例，列出其标签并提示设置阈值和计费标签
+func (b *BotInstance) sendNewInstance(e store.Event) {
+	data := render.NewInstanceData{
+		Icon:     b.Renderer.Glyph(eventGlyph(e.Kind)),
+		Instance: e.Instance,
+		Time:     e.StartedAt.Local(),
+	}
+	for k, v := range e.Labels {
+		data.Labels = append(data.Labels, fmt.Sprintf("%s=%s", k, v))
+	}
+	sort.Strings(data.Labels)
+	for metric, limit := range b.Store.Thresholds(b.config.Thresholds) {
+		label, _ := prometheus.UsageLabel(metric)
+		data.Thresholds = append(data.Thresholds, fmt.Sprintf("%s ≥ %s", label, prometheus.FormatUsage(metric, limit)))
+	}
+	sort.Strings(data.Thresholds)
+	for _, label := range pricingLabels {
+		if e.Labels[label] == "" {
+			data.MissingLabels = append(data.MissingLabels, label)
+		}
+	}
+
+	rows := instanceLinkRows(e.Instance)
+	var sent []int64
+	defer func() { b.recordNotification(e, sent, nil, false) }()
+	for _, chatID := range b.adminTargets() {
+		text, err := b.render(chatID, render.NewInstance, data)
+		if err != nil {
+			log.Printf("Failed to render new instance %s: %v", e.Instance, err)
+			return
+		}
+		if _, err := b.send(priorityAlert, b.textPage(chatID, 0, text, rows)); err != nil {
+			log.Printf("Failed to send new instance %s: %v", e.Instance, err)
+			continue
+		}
+		sent = append(sent, chatID)
+	}
+}
@@ -0,0 +1,284 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/config"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/render"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/store"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/utils"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// alertPrefix 是告警消息按钮的回调前缀：alert:ack:<事件ID> 或 alert:snooze:<事件ID>:<时长>
+	alertPrefix = "alert:"
+	// openMenuPrefix 是在新消息中打开菜单的回调前缀，格式为 open:<菜单ID>。
+	// 通知消息上的按钮使用它，避免点击后通知本身被菜单页面替换
+	openMenuPrefix = "open:"
+)
+
+// snoozeOptions 是告警消息上可选的暂停时长
+var snoozeOptions = []struct {
+	Label    string
+	Duration time.Duration
+}{
+	{"1h", time.Hour},
+	{"6h", 6 * time.Hour},
+	{"24h", 24 * time.Hour},
+}
+
+// Notify 将事件发送到配置的告警聊天。配置了汇总窗口时，窗口内的多个事件合并为一条汇总消息。
+// 发现新实例的通知单独发送给管理员
+func (b *BotInstance) Notify(e store.Event) {
+	if e.Kind == store.EventNewInstance {
+		b.sendNewInstance(e)
+		return
+	}
+	if e.Resolved() {
+		b.unpinAlert(e.ID)
+		if e.ResolvesID != 0 {
+			b.unpinAlert(e.ResolvesID)
+		}
+	}
+	recipients := b.alertRecipients(e)
+	if len(recipients) == 0 {
+		return
+	}
+	// 紧急事件（例如 UPS 切换到电池供电）不等待汇总窗口
+	if b.config.AlertBatchWindow <= 0 || e.Kind.Urgent() {
+		b.sendAlert(e, recipients)
+		return
+	}
+	b.alertBatch.add(e, b.config.AlertBatchWindow, b.flushAlerts)
+}
+
+// flushAlerts 发送汇总窗口内收集到的事件，只有一个事件时按普通告警发送。
+// 告警聊天收到所有事件，订阅了实例的聊天分别收到各自订阅的事件
+func (b *BotInstance) flushAlerts() {
+	for _, d := range b.alertDeliveries(b.alertBatch.take()) {
+		switch len(d.events) {
+		case 0:
+		case 1:
+			b.sendAlert(d.events[0], d.chatIDs)
+		default:
+			b.sendDigest(d.events, d.chatIDs)
+		}
+	}
+}
+
+// sendAlert 使用 alert 模板将单个事件发送到 chatIDs
+func (b *BotInstance) sendAlert(e store.Event, chatIDs []int64) {
+	data := b.alertData(e)
+	behavior := b.alertBehavior(notifiedKind(e).Severity())
+	var sent []int64
+	var messageIDs []int
+	defer func() { b.recordNotification(e, sent, messageIDs, false) }()
+	for _, chatID := range chatIDs {
+		text, err := b.render(chatID, render.Alert, data)
+		if err != nil {
+			log.Printf("Failed to render alert for event %d: %v", e.ID, err)
+			return
+		}
+		msg := withAlertBehavior(b.textPage(chatID, 0, text, alertKeyboard(e, true)), behavior)
+		message, err := b.send(priorityAlert, msg)
+		if err != nil {
+			log.Printf("Failed to send alert for event %d: %v", e.ID, err)
+			continue
+		}
+		// 恢复通知不置顶
+		if behavior == config.AlertPin && !e.Resolved() {
+			b.pinAlert(chatID, message.MessageID, e.ID)
+		}
+		sent = append(sent, chatID)
+		messageIDs = append(messageIDs, message.MessageID)
+	}
+}
+
+// alertChatsText 列出接收告警通知的 Telegram 聊天，用于预览通知规则
+func (b *BotInstance) alertChatsText() string {
+	if len(b.config.AlertChatIDs) == 0 {
+		return "Telegram: 未配置告警聊天，不会发送"
+	}
+	var chats []string
+	for _, chatID := range b.config.AlertChatIDs {
+		chats = append(chats, fmt.Sprintf("<code>%d</code>", chatID))
+	}
+	return "Telegram: " + strings.Join(chats, ", ")
+}
+
+// sinksText 返回事件会转发到的外部渠道，例如 " → slack, email"，没有匹配的路由时为空
+func (b *BotInstance) sinksText(e store.Event) string {
+	if b.notifyRouter == nil {
+		return ""
+	}
+	sinks := b.notifyRouter.SinksFor(e)
+	if len(sinks) == 0 {
+		return ""
+	}
+	return " → " + escapeHTML(strings.Join(sinks, ", "))
+}
+
+// recordNotification 将已发送的通知记入告警历史，没有发送到任何聊天时不记录。
+// messageIDs 是各聊天中对应的消息，用于通过表情回应确认告警，不需要时可以为空
+func (b *BotInstance) recordNotification(e store.Event, chatIDs []int64, messageIDs []int, digest bool) {
+	if len(chatIDs) == 0 {
+		return
+	}
+	kind := notifiedKind(e)
+	n := store.Notification{
+		EventID:    e.ID,
+		Kind:       kind,
+		Severity:   kind.Severity(),
+		Instance:   e.Instance,
+		Message:    e.Message,
+		SentAt:     time.Now(),
+		ChatIDs:    chatIDs,
+		MessageIDs: messageIDs,
+		Digest:     digest,
+	}
+	if err := b.Store.RecordNotification(n); err != nil {
+		log.Printf("Failed to record notification for event %d: %v", e.ID, err)
+	}
+	b.countFeature(0, featureAlert+":"+string(kind))
+}
+
+// alertData 将事件转换为 alert 模板的数据
+func (b *BotInstance) alertData(e store.Event) render.AlertData {
+	data := render.AlertData{
+		Icon:     b.Renderer.Glyph(eventGlyph(notifiedKind(e))),
+		Instance: e.Instance,
+		Message:  e.Message,
+		Severity: notifiedKind(e).Severity().Label(),
+		Time:     e.StartedAt.Local(),
+	}
+	if e.Resolved() && e.ResolvedAt.After(e.StartedAt) {
+		data.Time = e.ResolvedAt.Local()
+		data.Duration = utils.ShortDuration(e.Duration(time.Now()))
+	}
+	return data
+}
+
+// notifiedKind 返回通知中使用的事件类型，已恢复的过期、阈值和 UPS 事件按恢复在线显示
+func notifiedKind(e store.Event) store.EventKind {
+	if (e.Kind == store.EventStaleMetrics || e.Kind == store.EventThresholdBreach || e.Kind.Urgent()) && e.Resolved() {
+		return store.EventInstanceUp
+	}
+	return e.Kind
+}
+
+// alertKeyboard 为未恢复的阈值告警生成确认和暂停按钮，所有关于实例的事件都带有打开实例页面的按钮
+func alertKeyboard(e store.Event, withAck bool) [][]tgbotapi.InlineKeyboardButton {
+	if e.Kind != store.EventThresholdBreach || e.Resolved() || e.ID == 0 {
+		return instanceLinkRows(e.Instance)
+	}
+	var rows [][]tgbotapi.InlineKeyboardButton
+	if withAck {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("确认", fmt.Sprintf("%sack:%d", alertPrefix, e.ID)),
+		))
+	}
+	var snoozeButtons []tgbotapi.InlineKeyboardButton
+	for _, option := range snoozeOptions {
+		snoozeButtons = append(snoozeButtons, tgbotapi.NewInlineKeyboardButtonData(
+			"暂停 "+option.Label, fmt.Sprintf("%ssnooze:%d:%s", alertPrefix, e.ID, option.Label)))
+	}
+	rows = append(rows, snoozeButtons)
+	return append(rows, instanceLinkRows(e.Instance)...)
+}
+
+// instanceLinkRows 返回通知消息上打开实例详情、流量历史和在线时间线的按钮，
+// 回调数据超过 Telegram 限制的 64 字节时不显示对应按钮
+func instanceLinkRows(instance string) [][]tgbotapi.InlineKeyboardButton {
+	if instance == "" {
+		return nil
+	}
+	var row []tgbotapi.InlineKeyboardButton
+	for _, link := range []struct{ text, data string }{
+		{"详情", openMenuPrefix + instanceInfoPrefix + instance},
+		{"流量历史", trafficRangePrefix + "7d:" + instance},
+		{"在线时间线", openMenuPrefix + uptimePrefix + instance},
+	} {
+		if fitsCallback(link.data) {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(link.text, link.data))
+		}
+	}
+	if len(row) == 0 {
+		return nil
+	}
+	return [][]tgbotapi.InlineKeyboardButton{row}
+}
+
+// handleAlertCallback 处理告警消息上的确认和暂停按钮，返回按钮点击后的提示文字
+func (b *BotInstance) handleAlertCallback(callback *tgbotapi.CallbackQuery, args string) string {
+	chatID := callback.Message.Chat.ID
+	messageID := callback.Message.MessageID
+	parts := strings.Split(args, ":")
+	if len(parts) < 2 {
+		return "无效的操作"
+	}
+	id, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return "无效的操作"
+	}
+	now := time.Now()
+
+	switch parts[0] {
+	case "ack":
+		e, found, err := b.Store.AcknowledgeEvent(id, userName(callback.From), now)
+		if err != nil {
+			reportError("确认告警", err)
+			return "确认失败，请稍后重试"
+		}
+		if !found {
+			return "事件已过期"
+		}
+		b.unpinAlert(e.ID)
+		b.editAlertKeyboard(chatID, messageID, alertKeyboard(e, false))
+		return fmt.Sprintf("已由 %s 确认", e.AckedBy)
+	case "snooze":
+		if len(parts) != 3 {
+			return "无效的操作"
+		}
+		var duration time.Duration
+		for _, option := range snoozeOptions {
+			if option.Label == parts[2] {
+				duration = option.Duration
+			}
+		}
+		e, found := b.Store.Event(id)
+		if duration == 0 || !found {
+			return "事件已过期"
+		}
+		if err := b.Store.SnoozeAlerts(e.Instance, e.Metric, now.Add(duration)); err != nil {
+			reportError("暂停告警", err)
+			return "暂停失败，请稍后重试"
+		}
+		b.editAlertKeyboard(chatID, messageID, instanceLinkRows(e.Instance))
+		return fmt.Sprintf("已暂停 %s 的通知 %s", e.Instance, parts[2])
+	default:
+		return "无效的操作"
+	}
+}
+
+func (b *BotInstance) editAlertKeyboard(chatID int64, messageID int, rows [][]tgbotapi.InlineKeyboardButton) {
+	edit := tgbotapi.NewEditMessageReplyMarkup(chatID, messageID, tgbotapi.NewInlineKeyboardMarkup(rows...))
+	if _, err := b.request(priorityInteractive, edit); err != nil && !isNotModified(err) {
+		log.Printf("Failed to update alert buttons: %v", err)
+	}
+}
+
+// userName 返回用于记录操作人的用户名称
+func userName(user *tgbotapi.User) string {
+	if user == nil {
+		return "未知用户"
+	}
+	if user.UserName != "" {
+		return "@" + user.UserName
+	}
+	return strings.TrimSpace(user.FirstName + " " + user.LastName)
+}
@@ -0,0 +1,132 @@
+package bot
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// cachedPage 是已生成的菜单页面内容
+type cachedPage struct {
+	text           string
+	keyboard       *tgbotapi.InlineKeyboardMarkup
+	disablePreview bool
+	generatedAt    time.Time
+}
+
+// pageCache 缓存耗时菜单（总览、详情表）的页面，按聊天、菜单和页码区分
+type pageCache struct {
+	mu    sync.Mutex
+	pages map[string]cachedPage
+}
+
+func pageCacheKey(chatID int64, menuID string, page int) string {
+	return fmt.Sprintf("%d/%s/%d", chatID, menuID, page)
+}
+
+func (c *pageCache) get(key string) (cachedPage, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p, ok := c.pages[key]
+	return p, ok
+}
+
+func (c *pageCache) set(key string, p cachedPage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.pages == nil {
+		c.pages = make(map[string]cachedPage)
+	}
+	c.pages[key] = p
+}
+
+// edit 生成显示缓存内容的编辑消息，footer 不为空时附加在正文后
+func (p cachedPage) edit(chatID int64, messageID int, footer string) tgbotapi.EditMessageTextConfig {
+	editMsg := tgbotapi.NewEditMessageText(chatID, messageID, p.text+footer)
+	editMsg.ParseMode = "HTML"
+	editMsg.ReplyMarkup = p.keyboard
+	editMsg.DisableWebPagePreview = p.disablePreview
+	return editMsg
+}
+
+// staleFooter 是缓存页面的提示，例如 "更新于 12s 前"
+func staleFooter(age time.Duration) string {
+	return fmt.Sprintf("\n\n<i>更新于 %s 前</i>", age.Round(time.Second))
+}
+
+// cachePageResult 保存生成的页面。带有指向自身重试按钮的错误页面不缓存
+func (b *BotInstance) cachePageResult(chatID int64, menuID string, page int, msg tgbotapi.Chattable, now time.Time) {
+	var p cachedPage
+	switch m := msg.(type) {
+	case tgbotapi.MessageConfig:
+		keyboard, ok := m.ReplyMarkup.(tgbotapi.InlineKeyboardMarkup)
+		if ok {
+			p.keyboard = &keyboard
+		}
+		p.text, p.disablePreview = m.Text, m.DisableWebPagePreview
+	case tgbotapi.EditMessageTextConfig:
+		p.text, p.keyboard, p.disablePreview = m.Text, m.ReplyMarkup, m.DisableWebPagePreview
+	default:
+		return
+	}
+	if p.keyboard != nil {
+		retry := retryCallback(menuID, page)
+		for _, row := range p.keyboard.InlineKeyboard {
+			for _, button := range row {
+				if button.CallbackData != nil && *button.CallbackData == retry {
+					return
+				}
+			}
+		}
+	}
+	p.generatedAt = now
+	b.pageCache.set(pageCacheKey(chatID, menuID, page), p)
+}
+
+// buildMenuPage 生成菜单页面，对支持缓存的菜单同时保存结果。
+// 生成期间出现慢查询时在页面末尾标注耗时，开启解释模式时附加执行的查询，这些标注不进入缓存
+func (b *BotInstance) buildMenuPage(chatID int64, messageID int, menuID string, page int) tgbotapi.Chattable {
+	started := time.Now()
+	msg := b.editMenuPage(chatID, messageID, menuID, page)
+	if route, _, ok := b.menus.match(menuID); ok && route.cached && b.config.PageCacheMaxStale > 0 {
+		b.cachePageResult(chatID, menuID, page, msg, time.Now())
+	}
+	return b.withExplain(chatID, withFooter(msg, b.slowQueryFooter(chatID, started)), started)
+}
+
+// showCachedPage 用缓存立即显示页面：缓存未超过 PageCacheTTL 时直接使用；
+// 未超过 PageCacheMaxStale 时先显示旧内容并标注更新时间，同时在后台刷新，完成后再编辑消息。
+// 没有可用缓存时返回 false
+func (b *BotInstance) showCachedPage(chatID int64, messageID int, menuID string, page int) bool {
+	if b.config.PageCacheMaxStale <= 0 {
+		return false
+	}
+	route, _, ok := b.menus.match(menuID)
+	if !ok || !route.cached {
+		return false
+	}
+	cached, ok := b.pageCache.get(pageCacheKey(chatID, menuID, page))
+	if !ok {
+		return false
+	}
+	age := time.Since(cached.generatedAt)
+	if age > b.config.PageCacheMaxStale {
+		return false
+	}
+
+	b.requestMenu(chatID, menuID, page, cached.edit(chatID, messageID, staleFooter(age)))
+	if age <= b.config.PageCacheTTL {
+		return true
+	}
+	go func() {
+		msg := b.buildMenuPage(chatID, messageID, menuID, page)
+		// 用户已离开该菜单时不再覆盖消息，新结果留在缓存中
+		if b.currentMenu(chatID) != menuID {
+			return
+		}
+		b.requestMenu(chatID, menuID, page, msg)
+	}()
+	return true
+}
@@ -0,0 +1,52 @@
+package bot
+
+import (
+	"github.com/bestmjj/prometheus-telegram-bot/internal/plugin"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// pluginContext 返回插件视图渲染时使用的资源
+func (b *BotInstance) pluginContext(chatID int64, args string) plugin.Context {
+	return plugin.Context{
+		ChatID:     chatID,
+		Args:       args,
+		Prometheus: b.prom(chatID),
+		Renderer:   b.Renderer,
+		Store:      b.Store,
+		Locale:     b.chatLocale(chatID),
+	}
+}
+
+// pluginMenuItems 返回需要在"其他"菜单中显示的插件按钮
+func pluginMenuItems() []MenuItem {
+	var items []MenuItem
+	for _, v := range plugin.Views() {
+		if v.Title != "" {
+			items = append(items, MenuItem{Text: v.Title, CallbackData: v.ID})
+		}
+	}
+	return items
+}
+
+func (b *BotInstance) pluginPage(chatID int64, messageID int, v plugin.View) tgbotapi.Chattable {
+	text, err := v.Render(b.pluginContext(chatID, ""))
+	if err != nil {
+		return b.errorPage(chatID, messageID, v.Title, err, v.ID, 1)
+	}
+	rows := [][]tgbotapi.InlineKeyboardButton{tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("刷新", v.ID),
+		tgbotapi.NewInlineKeyboardButtonData("返回", b.getPreviousMenuID(chatID)),
+		tgbotapi.NewInlineKeyboardButtonData("返回主菜单", mainMenuID),
+	)}
+	return b.textPage(chatID, messageID, text, rows)
+}
+
+// handlePluginCommand 执行插件注册的命令
+func (b *BotInstance) handlePluginCommand(chatID int64, v plugin.View, args string) {
+	text, err := v.Render(b.pluginContext(chatID, args))
+	if err != nil {
+		b.sendError(chatID, v.Command, err)
+		return
+	}
+	b.sendText(chatID, text)
+}
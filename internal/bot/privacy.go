@@ -0,0 +1,166 @@
+package bot
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/store"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/utils"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/prometheus/common/model"
+)
+
+// 隐私模式
+const (
+	privacyOff   = "off"
+	privacyMask  = "mask"
+	privacyAlias = "alias"
+)
+
+var privacyModeLabels = map[string]string{
+	privacyOff:   "关闭",
+	privacyMask:  "隐藏 IP 后两段和端口",
+	privacyAlias: "只显示实例别名",
+}
+
+// privacyLevels 是隐私模式隐藏信息的程度，数值越大隐藏得越多
+var privacyLevels = map[string]int{
+	privacyOff:   0,
+	privacyMask:  1,
+	privacyAlias: 2,
+}
+
+// instanceAliases 记录实例地址到别名的映射，在每次查询实例列表时更新
+type instanceAliases struct {
+	mu      sync.Mutex
+	aliases map[string]string
+}
+
+func (a *instanceAliases) update(instances []model.Metric, label string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.aliases == nil {
+		a.aliases = make(map[string]string)
+	}
+	for _, instance := range instances {
+		if alias := string(instance[model.LabelName(label)]); alias != "" {
+			a.aliases[string(instance["instance"])] = alias
+		}
+	}
+}
+
+// lookup 返回实例的别名
+func (a *instanceAliases) lookup(instance string) (string, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	alias, ok := a.aliases[instance]
+	return alias, ok
+}
+
+// replace 将文本中的实例地址替换为别名，较长的地址先替换，避免 1.2.3.4 截断 1.2.3.4:9100
+func (a *instanceAliases) replace(text string) string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	instances := make([]string, 0, len(a.aliases))
+	for instance := range a.aliases {
+		instances = append(instances, instance)
+	}
+	sort.Slice(instances, func(i, j int) bool { return len(instances[i]) > len(instances[j]) })
+	for _, instance := range instances {
+		text = strings.ReplaceAll(text, instance, a.aliases[instance])
+	}
+	return text
+}
+
+// privacyMode 返回聊天的隐私模式，管理员聊天始终显示完整信息。
+// 非管理员聊天只能设置比 PrivacyMode 更严格的模式，之前保存的较宽松的设置不再生效
+func (b *BotInstance) privacyMode(chatID int64) string {
+	if b.isAdmin(chatID) {
+		return privacyOff
+	}
+	if b.isPublicStatusChat(chatID) {
+		return privacyAlias
+	}
+	if mode := b.Store.ChatSettings(chatID).Privacy; privacyLevels[mode] > privacyLevels[b.config.PrivacyMode] {
+		return mode
+	}
+	return b.config.PrivacyMode
+}
+
+// redact 按聊天的隐私模式处理要发送的文本。alias 模式下没有别名的地址仍会被隐藏
+func (b *BotInstance) redact(chatID int64, text string) string {
+	switch b.privacyMode(chatID) {
+	case privacyAlias:
+		return utils.MaskIPs(b.aliases.replace(text))
+	case privacyMask:
+		return utils.MaskIPs(text)
+	default:
+		return text
+	}
+}
+
+// redactMessage 处理消息正文和按钮文字，回调数据保持不变
+func (b *BotInstance) redactMessage(chatID int64, msg tgbotapi.Chattable) tgbotapi.Chattable {
+	if b.privacyMode(chatID) == privacyOff {
+		return msg
+	}
+	switch m := msg.(type) {
+	case tgbotapi.MessageConfig:
+		m.Text = b.redact(chatID, m.Text)
+		if keyboard, ok := m.ReplyMarkup.(tgbotapi.InlineKeyboardMarkup); ok {
+			m.ReplyMarkup = b.redactKeyboard(chatID, keyboard)
+		}
+		return m
+	case tgbotapi.EditMessageTextConfig:
+		m.Text = b.redact(chatID, m.Text)
+		if m.ReplyMarkup != nil {
+			keyboard := b.redactKeyboard(chatID, *m.ReplyMarkup)
+			m.ReplyMarkup = &keyboard
+		}
+		return m
+	default:
+		return msg
+	}
+}
+
+func (b *BotInstance) redactKeyboard(chatID int64, keyboard tgbotapi.InlineKeyboardMarkup) tgbotapi.InlineKeyboardMarkup {
+	rows := make([][]tgbotapi.InlineKeyboardButton, len(keyboard.InlineKeyboard))
+	for i, row := range keyboard.InlineKeyboard {
+		rows[i] = make([]tgbotapi.InlineKeyboardButton, len(row))
+		for j, button := range row {
+			button.Text = b.redact(chatID, button.Text)
+			rows[i][j] = button
+		}
+	}
+	return tgbotapi.InlineKeyboardMarkup{InlineKeyboard: rows}
+}
+
+// handlePrivacyCommand 处理 /privacy [off|mask|alias]，查看或设置当前聊天的隐私模式
+func (b *BotInstance) handlePrivacyCommand(chatID int64, args string) {
+	mode := strings.ToLower(strings.TrimSpace(args))
+	if mode == "" {
+		current := b.privacyMode(chatID)
+		b.sendText(chatID, fmt.Sprintf("当前隐私模式: %s (%s)\n用法: /privacy off|mask|alias", current, privacyModeLabels[current]))
+		return
+	}
+	if _, ok := privacyModeLabels[mode]; !ok {
+		b.sendText(chatID, "无效的隐私模式，可选: off、mask、alias")
+		return
+	}
+	if !b.isAdmin(chatID) && privacyLevels[mode] < privacyLevels[b.config.PrivacyMode] {
+		b.sendText(chatID, fmt.Sprintf("只有管理员可以将隐私模式设置为比默认的 %s (%s) 更宽松。",
+			b.config.PrivacyMode, privacyModeLabels[b.config.PrivacyMode]))
+		return
+	}
+	if err := b.Store.UpdateChatSettings(chatID, func(s *store.ChatSettings) { s.Privacy = mode }); err != nil {
+		b.sendError(chatID, "保存隐私模式", err)
+		return
+	}
+	text := fmt.Sprintf("隐私模式已设置为: %s (%s)", mode, privacyModeLabels[mode])
+	if b.isAdmin(chatID) {
+		text += "\n管理员聊天始终显示完整信息。"
+	}
+	b.sendText(chatID, text)
+}
@@ -0,0 +1,67 @@
+package bot
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/probe"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/render"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/utils"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// probePrefix 是连通性测试页面的菜单ID前缀，格式为 probe:<instance>
+	probePrefix = "probe:"
+	// probeAttempts 是每个端口的连接次数，probeTimeout 是每次连接的超时时间
+	probeAttempts = 3
+	probeTimeout  = 3 * time.Second
+)
+
+// probePage 从机器人所在主机测试实例的 exporter 端口和 ProbePorts，
+// 用于判断离线的实例是真的宕机还是只是抓取失败
+func (b *BotInstance) probePage(chatID int64, messageID int, instanceName string) tgbotapi.Chattable {
+	menuID := probePrefix + instanceName
+	instance, err := b.findInstance(chatID, instanceName)
+	if err != nil {
+		return b.errorPage(chatID, messageID, "获取实例列表", err, menuID, 1)
+	}
+	rows := [][]tgbotapi.InlineKeyboardButton{tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("重新测试", menuID),
+		tgbotapi.NewInlineKeyboardButtonData("返回", b.getPreviousMenuID(chatID)),
+		tgbotapi.NewInlineKeyboardButtonData("返回主菜单", mainMenuID),
+	)}
+	if instance == nil {
+		return b.textPage(chatID, messageID, "找不到指定的实例，请重试。", rows)
+	}
+	online, err := b.onlineInstanceSet(chatID)
+	if err != nil {
+		return b.errorPage(chatID, messageID, "获取实例列表", err, menuID, 1)
+	}
+
+	host, port := utils.SplitInstance(instanceName)
+	var ports []string
+	if port != "" {
+		ports = append(ports, port)
+	}
+	for _, p := range b.config.ProbePorts {
+		if !slices.Contains(ports, p) {
+			ports = append(ports, p)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), probeAttempts*probeTimeout)
+	defer cancel()
+	data := render.ProbeData{
+		Instance:    instanceName,
+		Host:        host,
+		Online:      online[instanceName],
+		GeneratedAt: time.Now(),
+		Results:     probe.Ports(ctx, host, ports, probeAttempts, probeTimeout),
+	}
+	text, err := b.render(chatID, render.Probe, data)
+	if err != nil {
+		return b.errorPage(chatID, messageID, "渲染连通性测试", err, menuID, 1)
+	}
+	return b.textPage(chatID, messageID, text, rows)
+}
@@ -0,0 +1,117 @@
+package bot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/prometheus"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/render"
+)
+
+const (
+	projectUsage = "用法: /project &lt;实例&gt; [倍数]\n" +
+		"按本周期至今的平均速度推算周期末的流量，倍数为剩余时间内速度的变化，例如 2x、0.5x 或 150%\n" +
+		"例如 /project node1:9100 2x"
+	// maxProjectFactor 限制倍数的上限，避免输入错误时推算出无意义的结果
+	maxProjectFactor = 1000
+)
+
+// defaultProjectFactors 是没有指定倍数时列出的几种情况
+var defaultProjectFactors = []float64{0.5, 2, 3}
+
+// handleProjectCommand 处理 /project，推算剩余时间内流量速度变化后周期末的用量，便于在运行大流量任务前评估是否会超出配额
+func (b *BotInstance) handleProjectCommand(chatID int64, args string) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 || len(fields) > 2 {
+		b.sendText(chatID, projectUsage)
+		return
+	}
+	factors := defaultProjectFactors
+	if len(fields) == 2 {
+		factor, ok := parseProjectFactor(fields[1])
+		if !ok {
+			b.sendText(chatID, "无效的倍数\n"+projectUsage)
+			return
+		}
+		factors = []float64{factor}
+	}
+	instance, err := b.findInstance(chatID, fields[0])
+	if err != nil {
+		b.sendError(chatID, "获取实例列表", err)
+		return
+	}
+	if instance == nil {
+		b.sendText(chatID, fmt.Sprintf("找不到实例 %s\n%s", escapeHTML(fields[0]), projectUsage))
+		return
+	}
+	p, err := b.prom(chatID).QueryTrafficProjection(instance, time.Now())
+	if err != nil {
+		b.sendError(chatID, "推算流量", err)
+		return
+	}
+	b.sendText(chatID, b.projectText(chatID, fields[0], p, factors))
+}
+
+// parseProjectFactor 解析 "2x"、"x2"、"0.5" 或 "150%" 形式的倍数
+func parseProjectFactor(s string) (float64, bool) {
+	s = strings.ToLower(s)
+	divisor := 1.0
+	if v, ok := strings.CutSuffix(s, "%"); ok {
+		s, divisor = v, 100
+	} else {
+		s = strings.TrimPrefix(strings.TrimSuffix(s, "x"), "x")
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v < 0 || v/divisor > maxProjectFactor {
+		return 0, false
+	}
+	return v / divisor, true
+}
+
+// projectText 显示本周期的用量和各倍数下周期末的用量，设置了流量配额时同时显示是否超出和预计的超额费用
+func (b *BotInstance) projectText(chatID int64, name string, p *prometheus.TrafficProjection, factors []float64) string {
+	locale := b.chatLocale(chatID)
+	bullet := b.Renderer.Glyph(render.GlyphBullet)
+	text := fmt.Sprintf("<b>流量推算</b> %s\n\n", escapeHTML(name))
+	text += fmt.Sprintf("周期: %s — %s（剩余 %s 天）\n", p.Since.Format("01-02"), p.Until.Format("01-02"),
+		locale.Number(p.Remaining().Hours()/24, 1))
+	text += fmt.Sprintf("已用: %s", locale.Bytes(p.Used))
+	if p.Quota != nil {
+		text += fmt.Sprintf(" / %s（%s）", escapeHTML(p.Quota.Label), locale.Percent(p.Used/p.Quota.Included*100))
+	}
+	text += fmt.Sprintf("\n平均每天: %s · 最近 24 小时: %s\n\n", locale.Bytes(p.AverageRate()*86400), locale.Bytes(p.Recent))
+
+	baseline := p.Project(1)
+	text += fmt.Sprintf("%s 按当前速度，周期末 %s%s\n", bullet, locale.Bytes(baseline), b.projectQuotaText(chatID, p.Quota, baseline))
+	for _, factor := range factors {
+		projected := p.Project(factor)
+		sign := "+"
+		if projected < baseline {
+			sign = "-"
+		}
+		text += fmt.Sprintf("%s 速度变为 %sx，周期末 %s（%s%s）%s\n", bullet, locale.Number(factor, 2), locale.Bytes(projected),
+			sign, locale.Bytes(max(projected-baseline, baseline-projected)), b.projectQuotaText(chatID, p.Quota, projected))
+	}
+	if p.Quota == nil {
+		text += "\n设置 traffic_quota 标签后会显示是否超出配额。"
+	}
+	return text
+}
+
+// projectQuotaText 返回推算用量相对配额的说明，未设置配额时为空
+func (b *BotInstance) projectQuotaText(chatID int64, quota *prometheus.TrafficQuota, usage float64) string {
+	if quota == nil {
+		return ""
+	}
+	overage := quota.Overage(usage)
+	if overage <= 0 {
+		return "，不会超出配额"
+	}
+	text := fmt.Sprintf("，%s 超出 %s", b.Renderer.Glyph(render.GlyphWarning), b.chatLocale(chatID).Bytes(overage))
+	if quota.Priced {
+		text += "，超额费用 " + b.formatMoney(chatID, quota.OverageCost(usage), quota.Currency)
+	}
+	return text
+}
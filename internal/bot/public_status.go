@@ -0,0 +1,119 @@
+package bot
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/render"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/store"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/utils"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// publicStatusCooldown 是公开聊天中两次 /status 之间的最短间隔，期间的命令直接忽略，避免刷屏
+const publicStatusCooldown = 30 * time.Second
+
+// publicStatusLimiter 记录每个公开聊天最近一次回复 /status 的时间
+type publicStatusLimiter struct {
+	mu   sync.Mutex
+	last map[int64]time.Time
+}
+
+// allow 判断聊天是否可以回复，可以时记录本次时间
+func (l *publicStatusLimiter) allow(chatID int64, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.last == nil {
+		l.last = make(map[int64]time.Time)
+	}
+	if now.Sub(l.last[chatID]) < publicStatusCooldown {
+		return false
+	}
+	l.last[chatID] = now
+	return true
+}
+
+// isPublicStatusChat 判断聊天是否为公开状态群组或频道。这些聊天中任何人都可以使用 /status，
+// 但只显示在线状态，不显示 IP、价格等信息，其他功能一律不可用
+func (b *BotInstance) isPublicStatusChat(chatID int64) bool {
+	return slices.Contains(b.config.PublicStatusChatIDs, chatID)
+}
+
+// handlePublicMessage 处理公开状态聊天中的消息，只响应 /status，其他消息忽略，不提示没有权限
+func (b *BotInstance) handlePublicMessage(message *tgbotapi.Message) {
+	if !message.IsCommand() || message.Command() != "status" {
+		return
+	}
+	chatID := message.Chat.ID
+	if !b.publicStatus.allow(chatID, time.Now()) {
+		return
+	}
+	b.recordUsage(chatID, store.UsageCommand, "status")
+	b.sendText(chatID, b.publicStatusText(chatID, time.Now()))
+}
+
+// publicStatusText 生成公开的服务状态：在线和离线数量，以及每个实例的状态和离线时长。
+// 公开聊天中只显示实例别名，没有别名的实例显示为"实例 N"，不显示任何地址或主机名
+func (b *BotInstance) publicStatusText(chatID int64, now time.Time) string {
+	// 公开聊天中不显示错误详情，只在日志中记录
+	instances, err := b.fetchInstancesForMenu(chatID, allInstancesMenuID)
+	if err != nil {
+		reportError("获取公开状态", err)
+		return "暂时无法获取服务状态，请稍后再试。"
+	}
+	online, err := b.onlineInstanceSet(chatID)
+	if err != nil {
+		reportError("获取公开状态", err)
+		return "暂时无法获取服务状态，请稍后再试。"
+	}
+	downSince := make(map[string]time.Time)
+	for _, e := range b.Store.OpenEvents(store.EventInstanceDown) {
+		downSince[e.Instance] = e.StartedAt
+	}
+
+	names := make([]string, 0, len(instances))
+	for _, instance := range instances {
+		names = append(names, string(instance["instance"]))
+	}
+	sort.Strings(names)
+	// 先确定显示名称再截断，截断后的地址无法再匹配别名
+	display := make(map[string]string, len(names))
+	var unnamed int
+	var onlineNames, offlineNames []string
+	for _, name := range names {
+		if alias, ok := b.aliases.lookup(name); ok {
+			display[name] = alias
+		} else {
+			unnamed++
+			display[name] = fmt.Sprintf("实例 %d", unnamed)
+		}
+		if online[name] {
+			onlineNames = append(onlineNames, name)
+		} else {
+			offlineNames = append(offlineNames, name)
+		}
+	}
+
+	locale := b.chatLocale(chatID)
+	text := "<b>服务状态</b>\n\n"
+	if len(offlineNames) == 0 {
+		text += fmt.Sprintf("%s 全部 %d 个服务运行正常\n\n", b.Renderer.Glyph(render.GlyphUp), len(onlineNames))
+	} else {
+		text += fmt.Sprintf("<b>在线:</b> %d  <b>离线:</b> %d\n\n", len(onlineNames), len(offlineNames))
+	}
+	for _, name := range offlineNames {
+		text += fmt.Sprintf("%s %s", b.Renderer.Glyph(render.GlyphDown), escapeHTML(utils.TruncateString(display[name], 40)))
+		if since, ok := downSince[name]; ok {
+			text += fmt.Sprintf("（%s离线）", locale.Relative(since, now))
+		}
+		text += "\n"
+	}
+	for _, name := range onlineNames {
+		text += fmt.Sprintf("%s %s\n", b.Renderer.Glyph(render.GlyphUp), escapeHTML(utils.TruncateString(display[name], 40)))
+	}
+	text += fmt.Sprintf("\n<i>更新于 %s</i>", locale.DateTime(now))
+	return text
+}
@@ -0,0 +1,188 @@
+package bot
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/prometheus"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/utils"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/prometheus/common/model"
+)
+
+const (
+	queryResultMenuID  = "query_result"
+	queryResultPerPage = 10
+	// queryHintThreshold 结果序列数超过该值时提示使用 topk 缩小结果
+	queryHintThreshold = 20
+	queryUsage         = "用法: /query <PromQL 表达式>"
+)
+
+// queryResult 缓存某个聊天最近一次 /query 的结果，翻页时不再重复查询
+type queryResult struct {
+	Query     string
+	Lines     []string
+	QueriedAt time.Time
+}
+
+type queryCache struct {
+	mu      sync.Mutex
+	results map[int64]*queryResult
+}
+
+func (c *queryCache) get(chatID int64) *queryResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.results[chatID]
+}
+
+func (c *queryCache) set(chatID int64, result *queryResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.results == nil {
+		c.results = make(map[int64]*queryResult)
+	}
+	c.results[chatID] = result
+}
+
+func (b *BotInstance) handleQueryCommand(chatID int64, query string) {
+	if query == "" {
+		b.post(priorityInteractive, tgbotapi.NewMessage(chatID, queryUsage))
+		return
+	}
+
+	now := time.Now()
+	if err := b.prom(chatID).CheckQueryCardinality(query, now); err != nil {
+		if refusal, ok := cardinalityRefusal(err); ok {
+			b.sendText(chatID, refusal)
+		} else {
+			b.sendError(chatID, "检查查询的序列数", err)
+		}
+		return
+	}
+	result, err := b.prom(chatID).QueryPrometheus(query, now)
+	if err != nil {
+		b.sendText(chatID, b.queryFailure(err))
+		return
+	}
+
+	b.queryResults.set(chatID, &queryResult{Query: query, Lines: formatQueryResult(result), QueriedAt: now})
+	msg := b.queryResultPage(chatID, 0, 1)
+	if _, err := b.send(priorityInteractive, msg); err != nil {
+		b.sendError(chatID, "发送查询结果", err)
+	}
+}
+
+func (b *BotInstance) queryResultPage(chatID int64, messageID int, page int) tgbotapi.Chattable {
+	result := b.queryResults.get(chatID)
+	if result == nil {
+		return b.textPage(chatID, messageID, "查询结果已过期，请重新执行 /query", nil)
+	}
+
+	totalPages := (len(result.Lines) + queryResultPerPage - 1) / queryResultPerPage
+	if page < 1 || page > totalPages {
+		page = 1
+	}
+	startIndex := (page - 1) * queryResultPerPage
+	endIndex := startIndex + queryResultPerPage
+	if endIndex > len(result.Lines) {
+		endIndex = len(result.Lines)
+	}
+
+	text := fmt.Sprintf("<b>查询:</b> <code>%s</code>\n", escapeHTML(result.Query))
+	text += fmt.Sprintf("<b>结果:</b> %d 条", len(result.Lines))
+	if totalPages > 1 {
+		text += fmt.Sprintf(" (%d/%d)", page, totalPages)
+	}
+	text += "\n\n"
+	if len(result.Lines) == 0 {
+		text += "无数据\n"
+	}
+	for _, line := range result.Lines[startIndex:endIndex] {
+		text += line + "\n"
+	}
+	if len(result.Lines) > queryHintThreshold {
+		text += "\n提示: 结果较多，可使用 <code>topk(10, ...)</code> 或 <code>limitk(10, ...)</code> 缩小结果"
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var pageButtons []tgbotapi.InlineKeyboardButton
+	if page > 1 {
+		pageButtons = append(pageButtons, tgbotapi.NewInlineKeyboardButtonData("上一页", fmt.Sprintf("prev_%s_%d", queryResultMenuID, page-1)))
+	}
+	if endIndex < len(result.Lines) {
+		pageButtons = append(pageButtons, tgbotapi.NewInlineKeyboardButtonData("下一页", fmt.Sprintf("next_%s_%d", queryResultMenuID, page+1)))
+	}
+	if len(pageButtons) > 0 {
+		rows = append(rows, pageButtons)
+	}
+	if len(prometheus.MetricNames(result.Query)) > 0 {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("指标元数据", metadataMenuID)))
+	}
+	return b.textPage(chatID, messageID, text, rows)
+}
+
+// textPage 根据 messageID 生成新消息或编辑已有消息
+func (b *BotInstance) textPage(chatID int64, messageID int, text string, rows [][]tgbotapi.InlineKeyboardButton) tgbotapi.Chattable {
+	text = b.redact(chatID, text)
+	if len(text) > 4000 {
+		text = utils.TruncateString(text, 4000)
+		text += "\n\n(Response truncated)"
+	}
+	if messageID == 0 {
+		msg := tgbotapi.NewMessage(chatID, text)
+		msg.ParseMode = "HTML"
+		if len(rows) > 0 {
+			msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+		}
+		return msg
+	}
+	editMsg := tgbotapi.NewEditMessageText(chatID, messageID, text)
+	editMsg.ParseMode = "HTML"
+	if len(rows) > 0 {
+		keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+		editMsg.ReplyMarkup = &keyboard
+	}
+	return editMsg
+}
+
+// formatQueryResult 将查询结果转换为每条序列一行的文本
+func formatQueryResult(result model.Value) []string {
+	var lines []string
+	switch v := result.(type) {
+	case model.Vector:
+		sort.Slice(v, func(i, j int) bool { return v[i].Value > v[j].Value })
+		for _, sample := range v {
+			lines = append(lines, fmt.Sprintf("%s => <b>%s</b>", escapeHTML(formatLabels(sample.Metric)), sample.Value))
+		}
+	case model.Matrix:
+		for _, series := range v {
+			if len(series.Values) == 0 {
+				continue
+			}
+			last := series.Values[len(series.Values)-1]
+			lines = append(lines, fmt.Sprintf("%s => <b>%s</b>", escapeHTML(formatLabels(series.Metric)), last.Value))
+		}
+	case *model.Scalar:
+		lines = append(lines, fmt.Sprintf("<b>%s</b>", v.Value))
+	case *model.String:
+		lines = append(lines, escapeHTML(v.Value))
+	}
+	return lines
+}
+
+func formatLabels(metric model.Metric) string {
+	name := string(metric[model.MetricNameLabel])
+	var pairs []string
+	for k, v := range metric {
+		if k == model.MetricNameLabel {
+			continue
+		}
+		pairs = append(pairs, fmt.Sprintf("%s=%q", k, string(v)))
+	}
+	sort.Strings(pairs)
+	return name + "{" + strings.Join(pairs, ", ") + "}"
+}
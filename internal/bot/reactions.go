@@ -0,0 +1,107 @@
+package bot
+
+import (
+	"encoding/json"
+	"log"
+	"slices"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// ackReaction 是确认告警的表情回应
+const ackReaction = "👍"
+
+// allowedUpdates 是机器人接收的更新类型。message_reaction 不在 Telegram 的默认列表中，需要显式订阅；
+// 群组中只有机器人是管理员时才会收到表情回应
+var allowedUpdates = []string{"message", "edited_message", "channel_post", "edited_channel_post", "callback_query", "message_reaction"}
+
+// botUpdate 在 tgbotapi.Update 的基础上增加了 tgbotapi 尚不支持的 message_reaction 更新
+type botUpdate struct {
+	tgbotapi.Update
+	MessageReaction *messageReaction `json:"message_reaction,omitempty"`
+}
+
+// messageReaction 是用户修改了对消息的表情回应，OldReaction 和 NewReaction 是修改前后的全部回应
+type messageReaction struct {
+	Chat      tgbotapi.Chat  `json:"chat"`
+	MessageID int            `json:"message_id"`
+	User      *tgbotapi.User `json:"user,omitempty"`
+	// ActorChat 是以频道或群组身份匿名回应时的聊天，此时 User 为空
+	ActorChat   *tgbotapi.Chat `json:"actor_chat,omitempty"`
+	OldReaction []reactionType `json:"old_reaction"`
+	NewReaction []reactionType `json:"new_reaction"`
+}
+
+type reactionType struct {
+	// Type 是 emoji、custom_emoji 或 paid
+	Type  string `json:"type"`
+	Emoji string `json:"emoji,omitempty"`
+}
+
+func hasAckReaction(reactions []reactionType) bool {
+	return slices.ContainsFunc(reactions, func(r reactionType) bool {
+		return r.Type == "emoji" && r.Emoji == ackReaction
+	})
+}
+
+// pollUpdates 以长轮询获取更新，与 tgbotapi 的 GetUpdatesChan 相同，但解码为 botUpdate 以接收表情回应
+func (b *BotInstance) pollUpdates() <-chan botUpdate {
+	ch := make(chan botUpdate, b.BotAPI.Buffer)
+	go func() {
+		config := tgbotapi.UpdateConfig{Timeout: 60, AllowedUpdates: allowedUpdates}
+		for {
+			resp, err := b.BotAPI.Request(config)
+			var updates []botUpdate
+			if err == nil {
+				err = json.Unmarshal(resp.Result, &updates)
+			}
+			if err != nil {
+				log.Printf("Failed to get updates, retrying in 3 seconds: %v", err)
+				time.Sleep(3 * time.Second)
+				continue
+			}
+			for _, update := range updates {
+				if update.UpdateID >= config.Offset {
+					config.Offset = update.UpdateID + 1
+					ch <- update
+				}
+			}
+		}
+	}()
+	return ch
+}
+
+// handleReaction 在用户对告警消息回应 👍 时确认消息中的事件，效果与点击确认按钮相同。
+// 汇总消息中的所有事件一起确认
+func (b *BotInstance) handleReaction(r *messageReaction) {
+	if !hasAckReaction(r.NewReaction) || hasAckReaction(r.OldReaction) || !b.hasFullAccess(r.Chat.ID) {
+		return
+	}
+	notifications := b.Store.NotificationsForMessage(r.Chat.ID, r.MessageID)
+	if len(notifications) == 0 {
+		return
+	}
+	by := "匿名"
+	switch {
+	case r.User != nil:
+		by = userName(r.User)
+	case r.ActorChat != nil:
+		by = r.ActorChat.Title
+	}
+	now := time.Now()
+	for _, n := range notifications {
+		e, found, err := b.Store.AcknowledgeEvent(n.EventID, by, now)
+		if err != nil {
+			reportError("确认告警", err)
+			return
+		}
+		if !found {
+			continue
+		}
+		b.unpinAlert(e.ID)
+		if !n.Digest {
+			b.editAlertKeyboard(r.Chat.ID, r.MessageID, alertKeyboard(e, false))
+		}
+	}
+}
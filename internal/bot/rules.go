@@ -0,0 +1,169 @@
+package bot
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/prometheus"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/render"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/store"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/utils"
+)
+
+const rulesUsage = "用法: /rules 列出 Prometheus 告警规则\n/rules preview &lt;规则名&gt;... 预览规则当前触发的告警和通知接收方\n/rules add &lt;规则名&gt;... 预览并订阅规则，触发时由机器人通知\n/rules del &lt;规则名&gt;... 取消订阅"
+
+// maxListedRules 限制 /rules 列出的规则数量，避免超过 Telegram 的消息长度限制
+const maxListedRules = 60
+
+// handleRulesCommand 列出 Prometheus 中的告警规则，或订阅、取消订阅规则。
+// 订阅后无需修改 Alertmanager 配置，已有的告警规则即可通过 Telegram 通知
+func (b *BotInstance) handleRulesCommand(chatID int64, args string) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		b.sendRuleList(chatID)
+		return
+	}
+	action, names := fields[0], fields[1:]
+	if (action != "add" && action != "del" && action != "preview") || len(names) == 0 {
+		b.sendText(chatID, rulesUsage)
+		return
+	}
+	if action != "preview" && !b.isAdmin(chatID) {
+		b.sendText(chatID, "只有管理员可以修改规则订阅。")
+		return
+	}
+
+	if action == "del" {
+		b.confirmAction(chatID, fmt.Sprintf("确认取消订阅以下规则？取消后规则触发时不再通知。\n%s", escapeHTML(strings.Join(names, ", "))), func() {
+			b.unsubscribeRules(chatID, names)
+		})
+		return
+	}
+
+	rules, err := b.prom(chatID).QueryAlertingRules()
+	if err != nil {
+		b.sendError(chatID, "获取告警规则", err)
+		return
+	}
+	var unknown []string
+	for _, name := range names {
+		if !slices.ContainsFunc(rules, func(r prometheus.PrometheusRule) bool { return r.Name == name }) {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		b.sendText(chatID, fmt.Sprintf("Prometheus 中没有这些告警规则: %s", escapeHTML(strings.Join(unknown, ", "))))
+		return
+	}
+	preview := b.rulePreview(rules, names, time.Now())
+	if action == "preview" {
+		b.sendText(chatID, preview)
+		return
+	}
+	b.confirmAction(chatID, preview+"\n确认订阅这些规则？", func() {
+		b.subscribeRules(chatID, names)
+	})
+}
+
+// rulePreview 列出规则当前正在触发的告警，以及订阅后通知的接收方
+func (b *BotInstance) rulePreview(rules []prometheus.PrometheusRule, names []string, now time.Time) string {
+	subscribed := b.Store.RuleSubscriptions()
+	text := "<b>规则预览</b>\n\n"
+	for _, rule := range rules {
+		if !slices.Contains(names, rule.Name) {
+			continue
+		}
+		text += fmt.Sprintf("<code>%s</code> (%s)", escapeHTML(rule.Name), escapeHTML(rule.Group))
+		if slices.Contains(subscribed, rule.Name) {
+			text += " 已订阅"
+		}
+		if len(rule.Firing) == 0 {
+			text += ": 当前没有触发\n"
+			continue
+		}
+		text += fmt.Sprintf(": 当前触发 %d 个\n", len(rule.Firing))
+		for i, a := range rule.Firing {
+			if i == maxPreviewInstances {
+				text += fmt.Sprintf("  … 还有 %d 个\n", len(rule.Firing)-i)
+				break
+			}
+			// 与监控一致，没有 instance 标签的告警以规则名作为实例
+			instance := a.Instance
+			if instance == "" {
+				instance = rule.Name
+			}
+			text += fmt.Sprintf("  %s %s", b.Renderer.Glyph(render.GlyphWarning), escapeHTML(utils.TruncateString(instance, 30)))
+			if b.Store.Snoozed(instance, rule.Name, now) {
+				text += "（已暂停通知）"
+			}
+			text += b.sinksText(store.Event{Instance: instance, Kind: store.EventPrometheusAlert, Metric: rule.Name}) + "\n"
+		}
+	}
+	return text + "\n<b>通知接收方</b>\n" + b.alertChatsText() + "\n"
+}
+
+func (b *BotInstance) subscribeRules(chatID int64, names []string) {
+	added, err := b.Store.SubscribeRules(names)
+	if err != nil {
+		b.sendError(chatID, "订阅规则", err)
+		return
+	}
+	if len(added) == 0 {
+		b.sendText(chatID, "这些规则已经订阅过了。")
+		return
+	}
+	b.sendText(chatID, fmt.Sprintf("已订阅: %s\n规则触发时将发送到告警聊天。", escapeHTML(strings.Join(added, ", "))))
+}
+
+func (b *BotInstance) unsubscribeRules(chatID int64, names []string) {
+	removed, err := b.Store.UnsubscribeRules(names)
+	if err != nil {
+		b.sendError(chatID, "取消订阅规则", err)
+		return
+	}
+	if len(removed) == 0 {
+		b.sendText(chatID, "这些规则没有被订阅。")
+		return
+	}
+	b.sendText(chatID, fmt.Sprintf("已取消订阅: %s", escapeHTML(strings.Join(removed, ", "))))
+}
+
+// sendRuleList 列出告警规则及其状态，已订阅的规则排在前面
+func (b *BotInstance) sendRuleList(chatID int64) {
+	rules, err := b.prom(chatID).QueryAlertingRules()
+	if err != nil {
+		b.sendError(chatID, "获取告警规则", err)
+		return
+	}
+	subscribed := b.Store.RuleSubscriptions()
+	sort.SliceStable(rules, func(i, j int) bool {
+		return slices.Contains(subscribed, rules[i].Name) && !slices.Contains(subscribed, rules[j].Name)
+	})
+
+	text := fmt.Sprintf("<b>Prometheus 告警规则</b>（%d 条，已订阅 %d 条）\n\n", len(rules), len(subscribed))
+	for i, rule := range rules {
+		if i == maxListedRules {
+			text += fmt.Sprintf("… 还有 %d 条未列出\n", len(rules)-i)
+			break
+		}
+		glyph := render.GlyphBullet
+		if slices.Contains(subscribed, rule.Name) {
+			glyph = render.GlyphUp
+		}
+		text += fmt.Sprintf("%s <code>%s</code> (%s)", b.Renderer.Glyph(glyph), escapeHTML(rule.Name), escapeHTML(rule.Group))
+		if len(rule.Firing) > 0 {
+			text += fmt.Sprintf(" 触发中 %d", len(rule.Firing))
+		}
+		text += "\n"
+	}
+	// 已订阅但 Prometheus 中已不存在的规则也列出，方便取消订阅
+	for _, name := range subscribed {
+		if !slices.ContainsFunc(rules, func(r prometheus.PrometheusRule) bool { return r.Name == name }) {
+			text += fmt.Sprintf("%s <code>%s</code> (规则已不存在)\n", b.Renderer.Glyph(render.GlyphWarning), escapeHTML(name))
+		}
+	}
+	b.sendText(chatID, text+"\n"+rulesUsage)
+}
@@ -0,0 +1,339 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/chart"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/config"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/store"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/utils"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/prometheus/common/model"
+)
+
+const (
+	// schedulesMenuID 是"定时任务"菜单，列出当前聊天的定时任务
+	schedulesMenuID = "schedules"
+	// schedulePrefix 是定时任务详情页的菜单ID前缀，格式为 schedule:<id>
+	schedulePrefix = "schedule:"
+	// scheduleActionPrefix 是定时任务操作按钮的回调前缀：sched:new、sched:run:<id>、sched:del:<id>
+	scheduleActionPrefix = "sched:"
+	// maxSchedulesPerChat 是每个聊天最多可以设置的定时任务数量
+	maxSchedulesPerChat = 20
+	// maxScheduleChartWindow 是定时任务图表的最长时间范围
+	maxScheduleChartWindow = 31 * 24 * time.Hour
+	// scheduleResultLines 是定时任务发送数值结果时最多显示的序列数
+	scheduleResultLines = 20
+)
+
+// schedulesPage 列出聊天的定时任务及下次执行时间
+func (b *BotInstance) schedulesPage(chatID int64, messageID int) tgbotapi.Chattable {
+	now := time.Now()
+	queries := b.Store.ScheduledQueries(chatID)
+	text := "<b>定时任务</b>\n\n"
+	if len(queries) == 0 {
+		text += "还没有定时任务。点击\"新建\"按 cron 表达式定时执行 PromQL，并将数值或图表发送到本聊天。\n"
+	}
+	var menuItems []MenuItem
+	for _, q := range queries {
+		text += fmt.Sprintf("%s <code>%s</code>\n", escapeHTML(q.Name), escapeHTML(q.Cron))
+		if next := scheduleNext(q, now); !next.IsZero() {
+			text += fmt.Sprintf("    下次执行: %s\n", next.Format("01-02 15:04"))
+		}
+		menuItems = append(menuItems, MenuItem{Text: utils.TruncateString(q.Name, 30), CallbackData: schedulePrefix + strconv.FormatInt(q.ID, 10)})
+	}
+	if len(queries) < maxSchedulesPerChat {
+		menuItems = append(menuItems, MenuItem{Text: "新建", CallbackData: scheduleActionPrefix + "new"})
+	}
+	menuItems = append(menuItems,
+		MenuItem{Text: "返回", CallbackData: b.getPreviousMenuID(chatID)},
+		MenuItem{Text: "返回主菜单", CallbackData: mainMenuID},
+	)
+	return b.textPage(chatID, messageID, text, b.generateMenuRows(menuItems))
+}
+
+// scheduleDetailPage 显示定时任务的详情，param 为任务ID
+func (b *BotInstance) scheduleDetailPage(chatID int64, messageID int, param string) tgbotapi.Chattable {
+	back := tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("返回", schedulesMenuID),
+		tgbotapi.NewInlineKeyboardButtonData("返回主菜单", mainMenuID),
+	)
+	id, err := strconv.ParseInt(param, 10, 64)
+	if err != nil {
+		return b.textPage(chatID, messageID, "找不到该定时任务。", [][]tgbotapi.InlineKeyboardButton{back})
+	}
+	q, ok := b.Store.ScheduledQuery(chatID, id)
+	if !ok {
+		return b.textPage(chatID, messageID, "找不到该定时任务，可能已被删除。", [][]tgbotapi.InlineKeyboardButton{back})
+	}
+
+	text := fmt.Sprintf("<b>定时任务: %s</b>\n\n", escapeHTML(q.Name))
+	text += fmt.Sprintf("<b>查询:</b> <code>%s</code>\n", escapeHTML(q.Query))
+	text += fmt.Sprintf("<b>时间:</b> <code>%s</code>\n", escapeHTML(q.Cron))
+	if q.ChartWindow != "" {
+		text += fmt.Sprintf("<b>发送:</b> 最近 %s 的图表\n", escapeHTML(q.ChartWindow))
+	} else {
+		text += "<b>发送:</b> 数值\n"
+	}
+	if next := scheduleNext(q, time.Now()); !next.IsZero() {
+		text += fmt.Sprintf("<b>下次执行:</b> %s\n", next.Format("2006-01-02 15:04"))
+	}
+	if !q.LastRun.IsZero() {
+		text += fmt.Sprintf("<b>上次执行:</b> %s\n", q.LastRun.Format("2006-01-02 15:04"))
+	}
+	rows := [][]tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("立即运行", scheduleActionPrefix+"run:"+param),
+			tgbotapi.NewInlineKeyboardButtonData("删除", scheduleActionPrefix+"del:"+param),
+		),
+		back,
+	}
+	return b.textPage(chatID, messageID, text, rows)
+}
+
+// scheduleNext 返回定时任务在 now 之后的下次执行时间，表达式无效时返回零值
+func scheduleNext(q store.ScheduledQuery, now time.Time) time.Time {
+	s, err := utils.ParseCron(q.Cron)
+	if err != nil {
+		return time.Time{}
+	}
+	return s.Next(now)
+}
+
+// handleScheduleCallback 处理定时任务的新建、立即运行和删除按钮
+func (b *BotInstance) handleScheduleCallback(chatID int64, data string) {
+	action, param, _ := strings.Cut(data, ":")
+	if action == "new" {
+		b.askScheduleQuery(chatID)
+		return
+	}
+	id, err := strconv.ParseInt(param, 10, 64)
+	if err != nil {
+		log.Printf("Invalid schedule callback data: %v", data)
+		return
+	}
+	q, ok := b.Store.ScheduledQuery(chatID, id)
+	if !ok {
+		b.sendText(chatID, "找不到该定时任务，可能已被删除。")
+		return
+	}
+	switch action {
+	case "run":
+		go b.runScheduledQuery(q, time.Now())
+	case "del":
+		b.confirmAction(chatID, fmt.Sprintf("确定删除定时任务 <b>%s</b> 吗？", escapeHTML(q.Name)), func() {
+			if _, err := b.Store.DeleteScheduledQuery(chatID, id); err != nil {
+				b.sendError(chatID, "删除定时任务", err)
+				return
+			}
+			b.sendText(chatID, fmt.Sprintf("已删除定时任务 %s。", escapeHTML(q.Name)))
+		})
+	}
+}
+
+// askScheduleQuery 是新建定时任务的第一步：输入 PromQL 或快捷方式名称
+func (b *BotInstance) askScheduleQuery(chatID int64) {
+	if len(b.Store.ScheduledQueries(chatID)) >= maxSchedulesPerChat {
+		b.sendText(chatID, fmt.Sprintf("每个聊天最多 %d 个定时任务，请先删除不需要的任务。", maxSchedulesPerChat))
+		return
+	}
+	b.ask(chatID, "请输入要定时执行的 PromQL，或者快捷方式的名称。", func(text string) bool {
+		q := store.ScheduledQuery{ChatID: chatID, Query: strings.TrimSpace(text)}
+		if s, ok := b.findShortcutQuery(q.Query); ok {
+			var query bytes.Buffer
+			if err := s.query.Execute(&query, struct{ Args string }{}); err != nil {
+				b.sendError(chatID, "生成查询", err)
+				return false
+			}
+			q.Name, q.Query = s.Name, query.String()
+		} else {
+			q.Name = utils.TruncateString(q.Query, 40)
+		}
+		// 先执行一次，语法错误或序列过多时立即提示
+		now := time.Now()
+		if err := b.prom(chatID).CheckQueryCardinality(q.Query, now); err != nil {
+			if refusal, ok := cardinalityRefusal(err); ok {
+				b.sendText(chatID, refusal)
+			} else {
+				b.sendError(chatID, "检查查询的序列数", err)
+			}
+			return false
+		}
+		if _, err := b.prom(chatID).QueryPrometheus(q.Query, now); err != nil {
+			b.sendText(chatID, b.queryFailure(err)+"\n请重新输入。")
+			return false
+		}
+		b.askScheduleCron(chatID, q)
+		return true
+	})
+}
+
+// askScheduleCron 是新建定时任务的第二步：输入 cron 表达式
+func (b *BotInstance) askScheduleCron(chatID int64, q store.ScheduledQuery) {
+	prompt := "请输入执行时间的 cron 表达式（分 时 日 月 周，按服务器时区），例如:\n" +
+		"<code>0 18 * * 5</code> 每周五 18:00\n" +
+		"<code>0 9 * * *</code> 每天 9:00\n" +
+		"<code>0 9 1 * *</code> 每月 1 日 9:00"
+	b.ask(chatID, prompt, func(text string) bool {
+		s, err := utils.ParseCron(text)
+		if err != nil {
+			b.sendText(chatID, fmt.Sprintf("无效的 cron 表达式: %s\n请重新输入。", escapeHTML(err.Error())))
+			return false
+		}
+		if s.Next(time.Now()).IsZero() {
+			b.sendText(chatID, "该表达式在一年内不会执行，请重新输入。")
+			return false
+		}
+		q.Cron = strings.Join(strings.Fields(text), " ")
+		b.askScheduleOutput(chatID, q)
+		return true
+	})
+}
+
+// askScheduleOutput 是新建定时任务的最后一步：选择发送数值还是图表
+func (b *BotInstance) askScheduleOutput(chatID int64, q store.ScheduledQuery) {
+	b.ask(chatID, "请选择发送的内容: 输入 <code>数值</code> 发送当前结果，或 <code>图表 7d</code> 发送最近一段时间的图表（默认 24h）。", func(text string) bool {
+		kind, windowText, _ := strings.Cut(strings.TrimSpace(text), " ")
+		switch kind {
+		case "数值":
+		case "图表":
+			windowText = strings.TrimSpace(windowText)
+			if windowText == "" {
+				windowText = "24h"
+			}
+			window, err := model.ParseDuration(windowText)
+			if err != nil || window <= 0 || time.Duration(window) > maxScheduleChartWindow {
+				b.sendText(chatID, "无效的时间范围，例如 6h、24h、7d，最长 31d。")
+				return false
+			}
+			q.ChartWindow = windowText
+		default:
+			b.sendText(chatID, "请输入 数值 或 图表。")
+			return false
+		}
+
+		q.CreatedAt = time.Now()
+		saved, err := b.Store.AddScheduledQuery(q)
+		if err != nil {
+			b.sendError(chatID, "保存定时任务", err)
+			return true
+		}
+		b.sendText(chatID, fmt.Sprintf("已创建定时任务 %s，下次执行: %s", escapeHTML(saved.Name), scheduleNext(saved, time.Now()).Format("2006-01-02 15:04")))
+		return true
+	})
+}
+
+// findShortcutQuery 按名称查找查询类快捷方式
+func (b *BotInstance) findShortcutQuery(name string) (shortcut, bool) {
+	for _, s := range b.shortcuts {
+		if s.Query != "" && s.Name == name {
+			return s, true
+		}
+	}
+	return shortcut{}, false
+}
+
+// RunScheduledQueries 每分钟检查一次定时任务，执行到期的任务，直到 ctx 被取消。
+// 机器人停止期间错过的执行不会补发
+func (b *BotInstance) RunScheduledQueries(ctx context.Context) {
+	ticker := time.NewTicker(15 * time.Second)
+	defer ticker.Stop()
+	last := time.Now().Truncate(time.Minute)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			minute := now.Truncate(time.Minute)
+			// 系统休眠等原因跳过多分钟时，同样只执行最近一分钟到期的任务
+			if !minute.After(last) {
+				continue
+			}
+			last = minute
+			for _, q := range b.Store.ScheduledQueries(0) {
+				s, err := utils.ParseCron(q.Cron)
+				if err != nil {
+					log.Printf("Invalid cron expression for scheduled query %d: %v", q.ID, err)
+					continue
+				}
+				if s.Matches(minute) {
+					go b.runScheduledQuery(q, now)
+				}
+			}
+		}
+	}
+}
+
+// runScheduledQuery 执行定时任务并将结果发送到任务所属的聊天
+func (b *BotInstance) runScheduledQuery(q store.ScheduledQuery, now time.Time) {
+	if err := b.Store.MarkScheduledQueryRun(q.ID, now); err != nil {
+		log.Printf("Failed to save scheduled query state: %v", err)
+	}
+	b.countFeature(q.ChatID, featureScheduledQuery)
+	title := fmt.Sprintf("<b>定时任务: %s</b>", escapeHTML(q.Name))
+	// 关闭图表后图表任务改为发送当前的查询结果
+	if q.ChartWindow != "" && b.config.Enabled(config.FeatureCharts) {
+		b.sendScheduledChart(q, title, now)
+		return
+	}
+
+	result, err := b.prom(q.ChatID).QueryPrometheus(q.Query, now)
+	if err != nil {
+		b.sendError(q.ChatID, "执行定时任务 "+q.Name, err)
+		return
+	}
+	lines := formatQueryResult(result)
+	text := fmt.Sprintf("%s\n<code>%s</code>\n%s\n\n", title, escapeHTML(q.Query), now.Format("2006-01-02 15:04"))
+	if len(lines) == 0 {
+		text += "无数据\n"
+	}
+	for i, line := range lines {
+		if i == scheduleResultLines {
+			text += fmt.Sprintf("…… 共 %d 条，仅显示前 %d 条\n", len(lines), scheduleResultLines)
+			break
+		}
+		text += line + "\n"
+	}
+	if _, err := b.send(priorityReport, b.textPage(q.ChatID, 0, text, nil)); err != nil {
+		log.Printf("Failed to send scheduled query %d: %v", q.ID, err)
+	}
+}
+
+// sendScheduledChart 发送定时任务最近 ChartWindow 时间的图表
+func (b *BotInstance) sendScheduledChart(q store.ScheduledQuery, title string, now time.Time) {
+	window, err := model.ParseDuration(q.ChartWindow)
+	if err != nil {
+		b.sendError(q.ChatID, "执行定时任务 "+q.Name, err)
+		return
+	}
+	matrix, err := b.prom(q.ChatID).QueryHistory(q.Query, time.Duration(window), now)
+	if err != nil {
+		b.sendError(q.ChatID, "执行定时任务 "+q.Name, err)
+		return
+	}
+	if len(matrix) == 0 {
+		b.sendText(q.ChatID, fmt.Sprintf("%s\n最近 %s 无数据", title, escapeHTML(q.ChartWindow)))
+		return
+	}
+	series := chart.FromMatrix(matrix, func(m model.Metric) string { return formatLabels(m) })
+	png, err := chart.RenderPNG(series, chart.Options{Preferences: b.chartPreferences(q.ChatID), Title: "Scheduled query - last " + q.ChartWindow})
+	if err != nil {
+		b.sendError(q.ChatID, "生成图表", err)
+		return
+	}
+	photo := tgbotapi.NewPhoto(q.ChatID, tgbotapi.FileBytes{
+		Name:  fmt.Sprintf("schedule-%d-%s.png", q.ID, now.Format("20060102-150405")),
+		Bytes: png,
+	})
+	photo.Caption = b.redact(q.ChatID, fmt.Sprintf("%s\n%s\n最近 %s", title, escapeHTML(q.Query), escapeHTML(q.ChartWindow)))
+	photo.ParseMode = "HTML"
+	if _, err := b.send(priorityReport, photo); err != nil {
+		b.sendError(q.ChatID, "发送图表", err)
+	}
+}
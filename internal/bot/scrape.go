@@ -0,0 +1,43 @@
+package bot
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/render"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/scrape"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// directStatusMenuID 是直接抓取 node_exporter 的状态页面，用于 Prometheus 不可用的时候
+	directStatusMenuID = "direct_status"
+	// scrapeTimeout 是直接抓取所有目标的超时时间
+	scrapeTimeout = 10 * time.Second
+)
+
+// directStatusPage 直接抓取 ScrapeFallbackTargets 中的 node_exporter，显示负载、内存和磁盘等基本状态。
+// 不依赖 Prometheus，监控中断时仍然可以查看
+func (b *BotInstance) directStatusPage(chatID int64, messageID int) tgbotapi.Chattable {
+	rows := [][]tgbotapi.InlineKeyboardButton{tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("刷新", directStatusMenuID),
+		tgbotapi.NewInlineKeyboardButtonData("返回", b.getPreviousMenuID(chatID)),
+		tgbotapi.NewInlineKeyboardButtonData("返回主菜单", mainMenuID),
+	)}
+	if len(b.config.ScrapeFallbackTargets) == 0 {
+		return b.textPage(chatID, messageID, "未配置直接抓取的目标（SCRAPE_FALLBACK_TARGETS）。", rows)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), scrapeTimeout)
+	defer cancel()
+	data := render.ScrapeData{
+		GeneratedAt: time.Now(),
+		Statuses:    scrape.FetchAll(ctx, http.DefaultClient, b.config.ScrapeFallbackTargets),
+	}
+	text, err := b.render(chatID, render.Scrape, data)
+	if err != nil {
+		return b.errorPage(chatID, messageID, "渲染直接抓取状态", err, directStatusMenuID, 1)
+	}
+	return b.textPage(chatID, messageID, text, rows)
+}
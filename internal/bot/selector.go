@@ -0,0 +1,82 @@
+package bot
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/prometheus/common/model"
+)
+
+// instanceSelector 用于批量命令选择实例，支持以下形式:
+//
+//	all 或留空      所有实例
+//	re:<正则>       实例名匹配正则表达式
+//	<标签>=<值>     标签等于指定值，例如 provider=hetzner
+//	<实例名>        精确匹配实例名
+type instanceSelector struct {
+	raw     string
+	pattern *regexp.Regexp
+	label   model.LabelName
+	value   string
+	name    string
+}
+
+func parseInstanceSelector(s string) (*instanceSelector, error) {
+	s = strings.TrimSpace(s)
+	sel := &instanceSelector{raw: s}
+	switch {
+	case s == "" || s == "all":
+		sel.raw = "all"
+	case strings.HasPrefix(s, "re:"):
+		pattern, err := regexp.Compile(strings.TrimPrefix(s, "re:"))
+		if err != nil {
+			return nil, fmt.Errorf("无效的正则表达式: %v", err)
+		}
+		sel.pattern = pattern
+	case strings.Contains(s, "="):
+		label, value, _ := strings.Cut(s, "=")
+		label = strings.TrimSpace(label)
+		if !model.LabelName(label).IsValid() {
+			return nil, fmt.Errorf("无效的标签名: %s", label)
+		}
+		sel.label = model.LabelName(label)
+		sel.value = strings.TrimSpace(value)
+	default:
+		sel.name = s
+	}
+	return sel, nil
+}
+
+func (s *instanceSelector) matches(instance model.Metric) bool {
+	name := string(instance["instance"])
+	switch {
+	case s.pattern != nil:
+		return s.pattern.MatchString(name)
+	case s.label != "":
+		return string(instance[s.label]) == s.value
+	case s.name != "":
+		return name == s.name
+	default:
+		return true
+	}
+}
+
+func (s *instanceSelector) String() string {
+	return s.raw
+}
+
+// selectInstances 返回所有匹配选择器的实例
+func (b *BotInstance) selectInstances(chatID int64, sel *instanceSelector) ([]model.Metric, error) {
+	instances, err := b.fetchInstancesForMenu(chatID, allInstancesMenuID)
+	if err != nil {
+		return nil, err
+	}
+	var selected []model.Metric
+	for _, instance := range instances {
+		if sel.matches(instance) {
+			selected = append(selected, instance)
+		}
+	}
+	return selected, nil
+}
@@ -0,0 +1,341 @@
+package bot
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// sendPriority 是发送队列中消息的优先级，数值越小越先发送
+type sendPriority int
+
+const (
+	// priorityInteractive 是对用户操作的回复：菜单、命令结果和按钮应答
+	priorityInteractive sendPriority = iota
+	// priorityAlert 是告警通知
+	priorityAlert
+	// priorityReport 是月度报告、定时任务、广播等不需要立即送达的消息
+	priorityReport
+	sendPriorities
+)
+
+const (
+	// sendWorkers 是同时向 Telegram 发送请求的数量，同一聊天的消息始终按顺序逐条发送
+	sendWorkers = 4
+	// globalSendRate 和 globalSendBurst 是所有聊天合计的发送速率（条/秒）和突发上限，Telegram 的限制约为 30 条/秒
+	globalSendRate  = 30.0
+	globalSendBurst = 30
+	// privateSendRate 和 groupSendRate 是单个私聊和群组的发送速率，Telegram 建议私聊不超过 1 条/秒，群组不超过 20 条/分钟
+	privateSendRate = 1.0
+	groupSendRate   = 20.0 / 60
+	// chatSendBurst 允许单个聊天短时间内连续发送几条新消息，编辑和按钮应答不受单个聊天的速率限制
+	chatSendBurst = 3
+	// maxSendAttempts 是遇到限流或 Telegram 服务端错误时的最多尝试次数
+	maxSendAttempts = 4
+	// sendRetryBase 是重试的初始等待时间，之后每次翻倍；限流时按 Telegram 返回的时间等待
+	sendRetryBase = time.Second
+)
+
+// tokenBucket 是令牌桶限速器，blockedUntil 之前不发放令牌（收到限流响应时设置）
+type tokenBucket struct {
+	rate         float64
+	burst        float64
+	tokens       float64
+	updated      time.Time
+	blockedUntil time.Time
+}
+
+func newTokenBucket(rate float64, burst int, now time.Time) *tokenBucket {
+	return &tokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), updated: now}
+}
+
+// wait 返回距离下一个令牌可用还需等待的时间，为 0 表示可以立即发送
+func (t *tokenBucket) wait(now time.Time) time.Duration {
+	if now.Before(t.blockedUntil) {
+		return t.blockedUntil.Sub(now)
+	}
+	t.tokens = min(t.burst, t.tokens+now.Sub(t.updated).Seconds()*t.rate)
+	t.updated = now
+	if t.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - t.tokens) / t.rate * float64(time.Second))
+}
+
+func (t *tokenBucket) take() {
+	t.tokens--
+}
+
+// block 在 until 之前暂停发放令牌，并清空已积累的令牌
+func (t *tokenBucket) block(until time.Time) {
+	if until.After(t.blockedUntil) {
+		t.blockedUntil = until
+	}
+	t.tokens = 0
+	t.updated = until
+}
+
+type sendResult struct {
+	message  tgbotapi.Message
+	response *tgbotapi.APIResponse
+	err      error
+}
+
+// sendJob 是队列中的一条待发送消息。request 为 true 时使用 Request 发送（编辑、按钮应答等不返回消息的请求）
+type sendJob struct {
+	chatID int64
+	c      tgbotapi.Chattable
+	// limited 表示发送新消息，受单个聊天的速率限制
+	limited   bool
+	request   bool
+	attempts  int
+	notBefore time.Time
+	// done 接收发送结果，为 nil 时不等待结果，发送失败只记录日志
+	done chan sendResult
+}
+
+// sendQueue 是所有发往 Telegram 的消息共用的发送队列：按优先级发送，
+// 同时遵守全局和每个聊天的速率限制，遇到限流或临时错误时自动重试
+type sendQueue struct {
+	api *tgbotapi.BotAPI
+
+	mu     sync.Mutex
+	jobs   [sendPriorities][]*sendJob
+	global *tokenBucket
+	chats  map[int64]*tokenBucket
+	// busy 记录正在发送的聊天，保证同一聊天的消息按顺序送达
+	busy map[int64]bool
+	wake chan struct{}
+}
+
+func newSendQueue(api *tgbotapi.BotAPI) *sendQueue {
+	q := &sendQueue{
+		api:    api,
+		global: newTokenBucket(globalSendRate, globalSendBurst, time.Now()),
+		chats:  make(map[int64]*tokenBucket),
+		busy:   make(map[int64]bool),
+		wake:   make(chan struct{}, 1),
+	}
+	for range sendWorkers {
+		go q.work()
+	}
+	return q
+}
+
+// send 将消息加入队列并等待发送完成
+func (q *sendQueue) send(p sendPriority, c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	job := &sendJob{c: c, done: make(chan sendResult, 1)}
+	q.enqueue(p, job)
+	r := <-job.done
+	return r.message, r.err
+}
+
+// request 与 send 相同，但用于编辑消息、应答按钮等只返回 API 响应的请求
+func (q *sendQueue) request(p sendPriority, c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	job := &sendJob{c: c, request: true, done: make(chan sendResult, 1)}
+	q.enqueue(p, job)
+	r := <-job.done
+	return r.response, r.err
+}
+
+// post 将消息加入队列后立即返回，不等待发送结果。更新循环中不需要结果的回复使用 post，
+// 避免某个聊天的速率限制阻塞其他聊天的更新
+func (q *sendQueue) post(p sendPriority, c tgbotapi.Chattable) {
+	q.enqueue(p, &sendJob{c: c})
+}
+
+func (q *sendQueue) enqueue(p sendPriority, job *sendJob) {
+	job.chatID = chatIDOf(job.c)
+	job.limited = isNewMessage(job.c)
+	q.mu.Lock()
+	q.jobs[p] = append(q.jobs[p], job)
+	q.mu.Unlock()
+	q.notify()
+}
+
+func (q *sendQueue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (q *sendQueue) work() {
+	for {
+		job, p, wait := q.next(time.Now())
+		if job == nil {
+			timer := time.NewTimer(wait)
+			select {
+			case <-q.wake:
+			case <-timer.C:
+			}
+			timer.Stop()
+			continue
+		}
+		r := q.do(job)
+		q.finish(job, p, r, time.Now())
+	}
+}
+
+// next 取出下一条可以发送的消息。没有可发送的消息时返回最多需要等待的时间
+func (q *sendQueue) next(now time.Time) (*sendJob, sendPriority, time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	wait := time.Minute
+	if d := q.global.wait(now); d > 0 {
+		return nil, 0, d
+	}
+	for p := range q.jobs {
+		// 同一聊天中排在前面的消息还不能发送时，后面的消息也要等待，避免乱序
+		skipped := make(map[int64]bool)
+		for i, job := range q.jobs[p] {
+			if job.chatID != 0 && (skipped[job.chatID] || q.busy[job.chatID]) {
+				skipped[job.chatID] = true
+				continue
+			}
+			d := job.notBefore.Sub(now)
+			if bucket := q.chatBucket(job.chatID, now); bucket != nil && job.limited {
+				d = max(d, bucket.wait(now))
+			}
+			if d > 0 {
+				wait = min(wait, d)
+				skipped[job.chatID] = true
+				continue
+			}
+			q.jobs[p] = append(q.jobs[p][:i], q.jobs[p][i+1:]...)
+			q.global.take()
+			if job.chatID != 0 {
+				if job.limited {
+					q.chatBucket(job.chatID, now).take()
+				}
+				q.busy[job.chatID] = true
+			}
+			// 可能还有其他可以发送的消息，唤醒下一个空闲的发送者
+			q.notify()
+			return job, sendPriority(p), 0
+		}
+	}
+	return nil, 0, wait
+}
+
+// chatBucket 返回聊天的限速器，不属于某个聊天的请求（例如按钮应答）返回 nil。调用方需持有锁
+func (q *sendQueue) chatBucket(chatID int64, now time.Time) *tokenBucket {
+	if chatID == 0 {
+		return nil
+	}
+	bucket, ok := q.chats[chatID]
+	if !ok {
+		rate := privateSendRate
+		if chatID < 0 {
+			rate = groupSendRate
+		}
+		bucket = newTokenBucket(rate, chatSendBurst, now)
+		q.chats[chatID] = bucket
+	}
+	return bucket
+}
+
+func (q *sendQueue) do(job *sendJob) sendResult {
+	if job.request {
+		resp, err := q.api.Request(job.c)
+		return sendResult{response: resp, err: err}
+	}
+	msg, err := q.api.Send(job.c)
+	return sendResult{message: msg, err: err}
+}
+
+// finish 返回发送结果；遇到临时错误且未超过尝试次数时放回队首稍后重试
+func (q *sendQueue) finish(job *sendJob, p sendPriority, r sendResult, now time.Time) {
+	q.mu.Lock()
+	defer q.notify()
+	defer q.mu.Unlock()
+
+	delete(q.busy, job.chatID)
+	job.attempts++
+	retryAfter, transient := sendRetryDelay(r.err, job.attempts)
+	if !transient || job.attempts >= maxSendAttempts {
+		if job.done != nil {
+			job.done <- r
+		} else if r.err != nil && !isNotModified(r.err) {
+			log.Printf("Failed to send message to chat %d: %v", job.chatID, r.err)
+		}
+		return
+	}
+	until := now.Add(retryAfter)
+	var apiErr *tgbotapi.Error
+	if errors.As(r.err, &apiErr) && apiErr.RetryAfter > 0 {
+		// 限流针对的是整个聊天，不属于某个聊天的请求则暂停所有发送
+		if bucket := q.chatBucket(job.chatID, now); bucket != nil {
+			bucket.block(until)
+		} else {
+			q.global.block(until)
+		}
+	}
+	job.notBefore = until
+	q.jobs[p] = append([]*sendJob{job}, q.jobs[p]...)
+}
+
+// sendRetryDelay 判断发送错误是否值得重试，并返回重试前需要等待的时间。
+// 只有带 retry_after 的限流和 Telegram 服务端错误可以重试。网络错误时请求可能已经送达，
+// 重试会发出重复的消息，与其他错误（消息格式错误、机器人被屏蔽等）一样直接返回
+func sendRetryDelay(err error, attempts int) (time.Duration, bool) {
+	var apiErr *tgbotapi.Error
+	if !errors.As(err, &apiErr) {
+		return 0, false
+	}
+	switch {
+	case apiErr.RetryAfter > 0:
+		return time.Duration(apiErr.RetryAfter) * time.Second, true
+	case apiErr.Code >= 500:
+		return sendRetryBase << (attempts - 1), true
+	}
+	return 0, false
+}
+
+// isNewMessage 判断请求是否发送新消息。Telegram 的单个聊天速率限制针对新消息，编辑和删除不计入
+func isNewMessage(c tgbotapi.Chattable) bool {
+	switch c.(type) {
+	case tgbotapi.MessageConfig, tgbotapi.PhotoConfig, tgbotapi.DocumentConfig:
+		return true
+	}
+	return false
+}
+
+// chatIDOf 返回请求所属的聊天，按钮应答等不属于某个聊天的请求返回 0
+func chatIDOf(c tgbotapi.Chattable) int64 {
+	switch m := c.(type) {
+	case tgbotapi.MessageConfig:
+		return m.ChatID
+	case tgbotapi.PhotoConfig:
+		return m.ChatID
+	case tgbotapi.DocumentConfig:
+		return m.ChatID
+	case tgbotapi.EditMessageTextConfig:
+		return m.ChatID
+	case tgbotapi.EditMessageReplyMarkupConfig:
+		return m.ChatID
+	case tgbotapi.DeleteMessageConfig:
+		return m.ChatID
+	default:
+		return 0
+	}
+}
+
+// send 通过发送队列发送消息并等待结果
+func (b *BotInstance) send(p sendPriority, c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	return b.sender.send(p, c)
+}
+
+// request 通过发送队列发送编辑、按钮应答等请求并等待结果
+func (b *BotInstance) request(p sendPriority, c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	return b.sender.request(p, c)
+}
+
+// post 通过发送队列发送消息，不等待结果
+func (b *BotInstance) post(p sendPriority, c tgbotapi.Chattable) {
+	b.sender.post(p, c)
+}
@@ -0,0 +1,90 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/store"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const menuExpiredText = "会话已过期,点击 /start 重新开始"
+
+// touchMenu 记录菜单消息的最后使用时间，未启用菜单过期时不记录
+func (b *BotInstance) touchMenu(chatID int64, messageID int) {
+	if b.config.MenuExpiry <= 0 || messageID == 0 {
+		return
+	}
+	if err := b.Store.TouchMenuSession(chatID, messageID, time.Now()); err != nil {
+		log.Printf("Failed to save menu session: %v", err)
+	}
+}
+
+// expireIfStale 检查回调所在的菜单是否已过期，过期时立即移除其按钮。
+// 机器人停止期间过期检查不会运行，所以点击时也需要检查
+func (b *BotInstance) expireIfStale(chatID int64, messageID int, now time.Time) bool {
+	if b.config.MenuExpiry <= 0 {
+		return false
+	}
+	ms, ok := b.Store.MenuSession(chatID, messageID)
+	if !ok || now.Sub(ms.LastActive) < b.config.MenuExpiry {
+		return false
+	}
+	b.expireMenu(ms)
+	return true
+}
+
+// expireMenu 将菜单消息改为过期提示（不带按钮）并删除其会话记录
+func (b *BotInstance) expireMenu(ms store.MenuSession) {
+	// 消息可能已被用户删除，编辑失败时只需删除会话记录
+	b.request(priorityReport, tgbotapi.NewEditMessageText(ms.ChatID, ms.MessageID, menuExpiredText))
+	if err := b.Store.DropMenuSession(ms.ChatID, ms.MessageID); err != nil {
+		log.Printf("Failed to drop menu session: %v", err)
+	}
+}
+
+// RunMenuExpiry 定期让超过 MenuExpiry 未操作的菜单过期，直到 ctx 被取消
+func (b *BotInstance) RunMenuExpiry(ctx context.Context) {
+	expiry := b.config.MenuExpiry
+	if expiry <= 0 {
+		return
+	}
+	ticker := time.NewTicker(min(expiry, time.Minute))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			for _, ms := range b.Store.StaleMenuSessions(now.Add(-expiry)) {
+				b.expireMenu(ms)
+			}
+		}
+	}
+}
+
+// resetMenus 重置菜单栈并移除聊天中旧菜单消息的按钮，然后发送一个新的主菜单。
+// 用户在流程中卡住时可以用 /menu 或 /cancel 重新开始
+func (b *BotInstance) resetMenus(chatID int64) {
+	b.navigateTo(chatID, mainMenuID)
+	for _, ms := range b.Store.ChatMenuSessions(chatID) {
+		b.clearKeyboard(ms.ChatID, ms.MessageID)
+		if err := b.Store.DropMenuSession(ms.ChatID, ms.MessageID); err != nil {
+			log.Printf("Failed to drop menu session: %v", err)
+		}
+	}
+	b.currentMessageID = b.sendMenuPage(chatID, 1)
+}
+
+// handleCancelCommand 处理 /cancel，放弃进行中的对话和等待确认的操作并回到主菜单
+func (b *BotInstance) handleCancelCommand(chatID int64) {
+	if b.conversations.cancel(chatID) {
+		b.sendText(chatID, "已取消当前输入。")
+	}
+	if n := b.confirms.cancelChat(chatID); n > 0 {
+		b.sendText(chatID, fmt.Sprintf("已取消 %d 个等待确认的操作。", n))
+	}
+	b.resetMenus(chatID)
+}
@@ -0,0 +1,85 @@
+package bot
+
+import (
+	"log"
+	"slices"
+	"sync"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/config"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/store"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// alertBehavior 返回该严重程度告警的通知方式，未配置时为普通发送
+func (b *BotInstance) alertBehavior(severity store.Severity) string {
+	if behavior, ok := b.config.AlertBehaviors[severity]; ok {
+		return behavior
+	}
+	return config.AlertNormal
+}
+
+// highestSeverity 返回一组事件通知中最高的严重程度，用于决定汇总消息的通知方式
+func highestSeverity(events []store.Event) store.Severity {
+	highest := store.SeverityInfo
+	for _, e := range events {
+		// Severities 从低到高排列
+		if severity := notifiedKind(e).Severity(); slices.Index(store.Severities, severity) > slices.Index(store.Severities, highest) {
+			highest = severity
+		}
+	}
+	return highest
+}
+
+// withAlertBehavior 按通知方式设置告警消息，静默发送时不发出提醒
+func withAlertBehavior(msg tgbotapi.Chattable, behavior string) tgbotapi.Chattable {
+	if m, ok := msg.(tgbotapi.MessageConfig); ok && behavior == config.AlertSilent {
+		m.DisableNotification = true
+		return m
+	}
+	return msg
+}
+
+// pinnedMessage 是已置顶的一条告警消息
+type pinnedMessage struct {
+	chatID    int64
+	messageID int
+}
+
+// pinnedAlerts 记录置顶的告警消息，事件恢复后取消置顶，避免置顶消息越积越多。
+// 只保存在内存中，重启前置顶的消息需要手动取消
+type pinnedAlerts struct {
+	mu       sync.Mutex
+	messages map[int64][]pinnedMessage
+}
+
+// pinAlert 置顶告警消息并记录到 eventIDs 下。消息本身已经发出提醒，置顶时不再通知
+func (b *BotInstance) pinAlert(chatID int64, messageID int, eventIDs ...int64) {
+	pin := tgbotapi.PinChatMessageConfig{ChatID: chatID, MessageID: messageID, DisableNotification: true}
+	if _, err := b.request(priorityAlert, pin); err != nil {
+		// 群组中需要机器人拥有置顶消息的权限
+		log.Printf("Failed to pin alert message in chat %d: %v", chatID, err)
+		return
+	}
+	b.pinned.mu.Lock()
+	defer b.pinned.mu.Unlock()
+	if b.pinned.messages == nil {
+		b.pinned.messages = make(map[int64][]pinnedMessage)
+	}
+	for _, id := range eventIDs {
+		b.pinned.messages[id] = append(b.pinned.messages[id], pinnedMessage{chatID: chatID, messageID: messageID})
+	}
+}
+
+// unpinAlert 取消置顶事件的告警消息
+func (b *BotInstance) unpinAlert(eventID int64) {
+	b.pinned.mu.Lock()
+	messages := b.pinned.messages[eventID]
+	delete(b.pinned.messages, eventID)
+	b.pinned.mu.Unlock()
+	for _, m := range messages {
+		unpin := tgbotapi.UnpinChatMessageConfig{ChatID: m.chatID, MessageID: m.messageID}
+		if _, err := b.request(priorityAlert, unpin); err != nil {
+			log.Printf("Failed to unpin alert message in chat %d: %v", m.chatID, err)
+		}
+	}
+}
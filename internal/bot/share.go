@@ -0,0 +1,196 @@
+package bot
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/prometheus"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/render"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/store"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// shareStartPrefix 是分享链接 /start 参数的前缀，格式为 share_<token>
+	shareStartPrefix = "share_"
+	// shareRefreshCallback 是只读分享页面唯一可用的按钮
+	shareRefreshCallback = "share:refresh"
+	defaultShareDuration = 24 * time.Hour
+)
+
+// hasFullAccess 判断聊天是否拥有完整访问权限，未配置 ALLOWED_CHAT_IDS 时所有聊天都有
+func (b *BotInstance) hasFullAccess(chatID int64) bool {
+	if b.isPublicStatusChat(chatID) {
+		return false
+	}
+	return len(b.config.AllowedChatIDs) == 0 || slices.Contains(b.config.AllowedChatIDs, chatID)
+}
+
+// parseShareArgs 解析 /share 的参数：<实例名> [时长] 或 group <标签>=<值> [时长]
+func parseShareArgs(args string) (store.ShareTarget, time.Duration, error) {
+	fields := strings.Fields(args)
+	var target store.ShareTarget
+	if len(fields) >= 2 && fields[0] == "group" {
+		label, value, ok := strings.Cut(fields[1], "=")
+		if !ok || label == "" || value == "" {
+			return target, 0, fmt.Errorf("分组格式应为 <标签>=<值>，例如 provider=aws")
+		}
+		target.GroupLabel, target.GroupValue = label, value
+		fields = fields[2:]
+	} else if len(fields) >= 1 {
+		target.Instance = fields[0]
+		fields = fields[1:]
+	} else {
+		return target, 0, fmt.Errorf("缺少分享对象")
+	}
+
+	duration := defaultShareDuration
+	if len(fields) > 0 {
+		d, err := time.ParseDuration(fields[0])
+		if err != nil || d <= 0 {
+			return target, 0, fmt.Errorf("无效的有效期 %q，例如 2h、24h", fields[0])
+		}
+		duration = d
+	}
+	return target, duration, nil
+}
+
+// shareTargetName 返回分享对象的显示名称
+func shareTargetName(t store.ShareTarget) string {
+	if t.Instance != "" {
+		return t.Instance
+	}
+	return t.GroupLabel + "=" + t.GroupValue
+}
+
+// handleShareCommand 处理 /share，生成一次性的只读分享链接
+func (b *BotInstance) handleShareCommand(chatID int64, args string) {
+	target, duration, err := parseShareArgs(args)
+	if err != nil {
+		b.sendText(chatID, fmt.Sprintf("%s\n用法: /share &lt;实例名&gt; [有效期] 或 /share group &lt;标签&gt;=&lt;值&gt; [有效期]", html.EscapeString(err.Error())))
+		return
+	}
+	if target.Instance != "" {
+		instance, err := b.findInstance(chatID, target.Instance)
+		if err != nil {
+			b.sendError(chatID, "获取实例列表", err)
+			return
+		}
+		if instance == nil {
+			b.sendText(chatID, "找不到指定的实例，请重试。")
+			return
+		}
+	}
+
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		b.sendError(chatID, "生成分享链接", err)
+		return
+	}
+	token := store.ShareToken{
+		Token:     hex.EncodeToString(buf),
+		Target:    target,
+		CreatedBy: chatID,
+		ExpiresAt: time.Now().Add(duration),
+	}
+	if err := b.Store.AddShareToken(token); err != nil {
+		b.sendError(chatID, "保存分享链接", err)
+		return
+	}
+	link := fmt.Sprintf("https://t.me/%s?start=%s%s", b.BotAPI.Self.UserName, shareStartPrefix, token.Token)
+	b.sendText(chatID, fmt.Sprintf("%s 的只读分享链接（仅可使用一次，有效期至 %s）:\n%s",
+		html.EscapeString(shareTargetName(target)), b.chatLocale(chatID).DateTime(token.ExpiresAt), link))
+}
+
+// shareStartToken 从 /start share_<token> 中取出分享令牌
+func shareStartToken(message *tgbotapi.Message) (string, bool) {
+	if message.Command() != "start" {
+		return "", false
+	}
+	return strings.CutPrefix(strings.TrimSpace(message.CommandArguments()), shareStartPrefix)
+}
+
+// redeemShare 使用分享令牌并发送只读页面
+func (b *BotInstance) redeemShare(chatID int64, token string) {
+	grant, ok, err := b.Store.RedeemShareToken(token, chatID, time.Now())
+	if err != nil {
+		b.sendError(chatID, "使用分享链接", err)
+		return
+	}
+	if !ok {
+		b.sendText(chatID, "分享链接无效、已被使用或已过期。")
+		return
+	}
+	b.post(priorityInteractive, b.sharedPage(chatID, 0, grant))
+}
+
+// sharedPage 生成只读分享页面，只有刷新按钮
+func (b *BotInstance) sharedPage(chatID int64, messageID int, grant store.Grant) tgbotapi.Chattable {
+	text, err := b.sharedText(chatID, grant.Target)
+	if err != nil {
+		id := reportError("生成分享页面", err)
+		text = b.errorText("查询", err, id)
+	}
+	text = fmt.Sprintf("%s\n\n<i>只读分享，有效期至 %s</i>", text, b.chatLocale(chatID).DateTime(grant.Until))
+	rows := [][]tgbotapi.InlineKeyboardButton{tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("刷新", shareRefreshCallback),
+	)}
+	return b.textPage(chatID, messageID, text, rows)
+}
+
+func (b *BotInstance) sharedText(chatID int64, target store.ShareTarget) (string, error) {
+	if target.Instance != "" {
+		instance, err := b.findInstance(chatID, target.Instance)
+		if err != nil {
+			return "", err
+		}
+		if instance == nil {
+			return fmt.Sprintf("实例 %s 已不存在。", html.EscapeString(target.Instance)), nil
+		}
+		return b.instanceInfoText(chatID, instance)
+	}
+
+	now := time.Now()
+	groups, err := b.prom(chatID).GroupSummaries(target.GroupLabel, now)
+	if err != nil {
+		return "", err
+	}
+	var shared []prometheus.GroupSummary
+	for _, g := range groups {
+		if g.Name == target.GroupValue {
+			shared = append(shared, g)
+		}
+	}
+	return b.render(chatID, render.Group, render.GroupData{Label: target.GroupLabel, GeneratedAt: now, Groups: shared})
+}
+
+// handleGuestMessage 处理没有完整访问权限的聊天发来的消息
+func (b *BotInstance) handleGuestMessage(chatID int64) {
+	grant, ok := b.Store.ActiveGrant(chatID, time.Now())
+	if !ok {
+		b.sendText(chatID, "你没有访问此机器人的权限。")
+		return
+	}
+	b.post(priorityInteractive, b.sharedPage(chatID, 0, grant))
+}
+
+// handleGuestCallback 处理只读访问聊天的按钮点击，只允许刷新分享页面
+func (b *BotInstance) handleGuestCallback(callback *tgbotapi.CallbackQuery) {
+	chatID := callback.Message.Chat.ID
+	grant, ok := b.Store.ActiveGrant(chatID, time.Now())
+	if !ok {
+		b.post(priorityInteractive, tgbotapi.NewCallback(callback.ID, "分享已过期"))
+		return
+	}
+	if callback.Data != shareRefreshCallback {
+		b.post(priorityInteractive, tgbotapi.NewCallback(callback.ID, "只读分享，无法执行此操作"))
+		return
+	}
+	b.post(priorityInteractive, tgbotapi.NewCallback(callback.ID, ""))
+	b.editOrSend(b.sharedPage(chatID, callback.Message.MessageID, grant))
+}
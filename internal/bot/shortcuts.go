@@ -0,0 +1,130 @@
+package bot
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// shortcutPrefix 是自定义菜单按钮的回调前缀，格式为 shortcut:<序号>
+const shortcutPrefix = "shortcut:"
+
+// commandPattern 是 Telegram 命令名允许的字符
+var commandPattern = regexp.MustCompile(`^[a-z0-9_]{1,32}$`)
+
+// shortcut 是运维在配置文件中定义的快捷方式，可以作为"其他"菜单中的按钮，也可以作为斜杠命令别名。
+// Query 和 View 二选一：Query 是 PromQL 模板（可用 {{.Args}} 引用命令参数），View 是已有菜单的ID
+type shortcut struct {
+	Name    string `json:"name"`
+	Command string `json:"command,omitempty"`
+	Query   string `json:"query,omitempty"`
+	View    string `json:"view,omitempty"`
+	// Menu 为 false 时只作为命令别名，不在菜单中显示
+	Menu *bool `json:"menu,omitempty"`
+
+	query *template.Template
+}
+
+func (s shortcut) inMenu() bool {
+	return s.Menu == nil || *s.Menu
+}
+
+// loadShortcuts 读取快捷方式配置文件，path 为空时返回空列表
+func loadShortcuts(path string) ([]shortcut, error) {
+	if path == "" {
+		return nil, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read shortcuts %s: %v", path, err)
+	}
+	var file struct {
+		Shortcuts []shortcut `json:"shortcuts"`
+	}
+	if err := json.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("Failed to parse shortcuts %s: %v", path, err)
+	}
+
+	commands := make(map[string]bool)
+	for i := range file.Shortcuts {
+		s := &file.Shortcuts[i]
+		if s.Name == "" {
+			return nil, fmt.Errorf("shortcut %d has no name", i+1)
+		}
+		if (s.Query == "") == (s.View == "") {
+			return nil, fmt.Errorf("shortcut %s must set exactly one of query and view", s.Name)
+		}
+		if s.Command != "" {
+			if !commandPattern.MatchString(s.Command) {
+				return nil, fmt.Errorf("shortcut %s has invalid command %q", s.Name, s.Command)
+			}
+			if commands[s.Command] {
+				return nil, fmt.Errorf("command %q is defined twice", s.Command)
+			}
+			commands[s.Command] = true
+		}
+		if s.Query != "" {
+			s.query, err = template.New(s.Name).Option("missingkey=error").Parse(s.Query)
+			if err != nil {
+				return nil, fmt.Errorf("shortcut %s has invalid query template: %v", s.Name, err)
+			}
+		}
+	}
+	return file.Shortcuts, nil
+}
+
+// shortcutMenuItems 返回需要在"其他"菜单中显示的快捷方式按钮
+func (b *BotInstance) shortcutMenuItems() []MenuItem {
+	var items []MenuItem
+	for i, s := range b.shortcuts {
+		if s.inMenu() {
+			items = append(items, MenuItem{Text: s.Name, CallbackData: shortcutPrefix + strconv.Itoa(i)})
+		}
+	}
+	return items
+}
+
+// findShortcutCommand 按命令名查找快捷方式
+func (b *BotInstance) findShortcutCommand(command string) (shortcut, bool) {
+	for _, s := range b.shortcuts {
+		if s.Command != "" && s.Command == command {
+			return s, true
+		}
+	}
+	return shortcut{}, false
+}
+
+// runShortcut 执行快捷方式：查询类发送查询结果，视图类在 messageID 指定的消息中打开对应菜单（为 0 时发送新消息）
+func (b *BotInstance) runShortcut(chatID int64, messageID int, s shortcut, args string) {
+	if s.View != "" {
+		b.navigateTo(chatID, s.View)
+		if messageID == 0 {
+			b.currentMessageID = b.sendMenuPage(chatID, 1)
+			return
+		}
+		b.showMenuPage(chatID, messageID, s.View, 1)
+		return
+	}
+
+	var query bytes.Buffer
+	if err := s.query.Execute(&query, struct{ Args string }{strings.TrimSpace(args)}); err != nil {
+		b.sendError(chatID, "生成查询", err)
+		return
+	}
+	b.handleQueryCommand(chatID, query.String())
+}
+
+// handleShortcutCallback 处理"其他"菜单中的快捷方式按钮
+func (b *BotInstance) handleShortcutCallback(chatID int64, messageID int, data string) {
+	i, err := strconv.Atoi(strings.TrimPrefix(data, shortcutPrefix))
+	if err != nil || i < 0 || i >= len(b.shortcuts) {
+		b.sendText(chatID, "快捷方式已失效，请重新打开菜单。")
+		return
+	}
+	b.runShortcut(chatID, messageID, b.shortcuts[i], "")
+}
@@ -0,0 +1,66 @@
+package bot
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/render"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// slowQueriesMenuID 是管理员可见的慢查询页面
+	slowQueriesMenuID = "slow_queries"
+	// slowQueriesShown 是慢查询页面最多显示的条数
+	slowQueriesShown = 15
+)
+
+// slowQueryFooter 返回页面生成期间最慢查询的提示，没有慢查询时为空
+func (b *BotInstance) slowQueryFooter(chatID int64, since time.Time) string {
+	q, ok := b.prom(chatID).SlowestSince(since)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("\n\n<i>慢查询: %.1fs</i>", q.Duration.Seconds())
+}
+
+// withFooter 在页面正文后附加 footer，不是文本消息时原样返回
+func withFooter(msg tgbotapi.Chattable, footer string) tgbotapi.Chattable {
+	if footer == "" {
+		return msg
+	}
+	switch m := msg.(type) {
+	case tgbotapi.MessageConfig:
+		m.Text += footer
+		return m
+	case tgbotapi.EditMessageTextConfig:
+		m.Text += footer
+		return m
+	default:
+		return msg
+	}
+}
+
+// slowQueriesPage 列出最近记录的慢查询，只有管理员可以查看
+func (b *BotInstance) slowQueriesPage(chatID int64, messageID int) tgbotapi.Chattable {
+	if !b.isAdmin(chatID) {
+		rows := [][]tgbotapi.InlineKeyboardButton{tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("返回主菜单", mainMenuID))}
+		return b.textPage(chatID, messageID, "只有管理员可以查看慢查询。", rows)
+	}
+	rows := [][]tgbotapi.InlineKeyboardButton{tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("刷新", slowQueriesMenuID),
+		tgbotapi.NewInlineKeyboardButtonData("返回", b.getPreviousMenuID(chatID)),
+		tgbotapi.NewInlineKeyboardButtonData("返回主菜单", mainMenuID),
+	)}
+
+	queries := b.PrometheusClient.SlowQueries()
+	if len(queries) > slowQueriesShown {
+		queries = queries[:slowQueriesShown]
+	}
+	data := render.SlowQueryData{Threshold: b.config.SlowQueryThreshold, GeneratedAt: time.Now(), Queries: queries}
+	text, err := b.render(chatID, render.SlowQueries, data)
+	if err != nil {
+		return b.errorPage(chatID, messageID, "生成慢查询列表", err, slowQueriesMenuID, 1)
+	}
+	return b.textPage(chatID, messageID, text, rows)
+}
@@ -0,0 +1,76 @@
+package bot
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/prometheus"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/render"
+)
+
+// maxSnapshotAge 内的上一次详情才用于计算变化，太旧的对比没有参考意义
+const maxSnapshotAge = time.Hour
+
+// detailSnapshot 是某个聊天上一次看到的实例详情中用于对比的数值
+type detailSnapshot struct {
+	at             time.Time
+	dailyTraffic   float64
+	monthlyTraffic float64
+	cpu            float64
+	memory         float64
+	disk           float64
+}
+
+func snapshotOf(d *prometheus.InstanceDetail, at time.Time) detailSnapshot {
+	return detailSnapshot{
+		at:             at,
+		dailyTraffic:   d.DailyTraffic.Total(),
+		monthlyTraffic: d.MonthlyTraffic.Total(),
+		cpu:            d.CPUUsage,
+		memory:         d.MemoryUsage,
+		disk:           d.DiskUsage,
+	}
+}
+
+// detailSnapshots 按聊天和实例保存上一次渲染的详情，只保存在内存中
+type detailSnapshots struct {
+	mu        sync.Mutex
+	snapshots map[string]detailSnapshot
+}
+
+// swap 保存新的快照并返回之前的快照
+func (s *detailSnapshots) swap(chatID int64, instance string, snap detailSnapshot) (detailSnapshot, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.snapshots == nil {
+		s.snapshots = make(map[string]detailSnapshot)
+	}
+	key := fmt.Sprintf("%d/%s", chatID, instance)
+	prev, ok := s.snapshots[key]
+	s.snapshots[key] = snap
+	// 顺便清理过期的快照，避免长期运行时无限增长
+	for k, v := range s.snapshots {
+		if snap.at.Sub(v.at) > maxSnapshotAge {
+			delete(s.snapshots, k)
+		}
+	}
+	return prev, ok && snap.at.Sub(prev.at) <= maxSnapshotAge
+}
+
+// detailDelta 记录本次详情并返回与该聊天上一次查看同一实例时相比的变化，没有可对比的快照时返回 nil
+func (b *BotInstance) detailDelta(chatID int64, d *prometheus.InstanceDetail, now time.Time) *render.DetailDelta {
+	cur := snapshotOf(d, now)
+	prev, ok := b.snapshots.swap(chatID, d.Instance, cur)
+	if !ok {
+		return nil
+	}
+	return &render.DetailDelta{
+		Since:          prev.at,
+		DailyTraffic:   cur.dailyTraffic - prev.dailyTraffic,
+		MonthlyTraffic: cur.monthlyTraffic - prev.monthlyTraffic,
+		CPU:            cur.cpu - prev.cpu,
+		Memory:         cur.memory - prev.memory,
+		Disk:           cur.disk - prev.disk,
+	}
+}
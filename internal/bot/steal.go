@@ -0,0 +1,70 @@
+package bot
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/prometheus"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/render"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/utils"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// stealRankingMenuID 是 CPU steal 排行页面的菜单ID
+	stealRankingMenuID = "steal_ranking"
+	// stealRankingTopN 是 CPU steal 排行显示的实例数量
+	stealRankingTopN = 10
+)
+
+// stealRankingPage 按 CPU steal 时间占比从高到低列出实例，用于找出受宿主机超售或邻居影响的机器
+func (b *BotInstance) stealRankingPage(chatID int64, messageID int) tgbotapi.Chattable {
+	instances, err := b.fetchInstancesForMenu(chatID, allInstancesMenuID)
+	if err != nil {
+		return b.errorPage(chatID, messageID, "获取实例列表", err, stealRankingMenuID, 1)
+	}
+	steal, err := b.prom(chatID).UsageByInstance(prometheus.UsageCPUSteal, time.Now())
+	if err != nil {
+		return b.errorPage(chatID, messageID, "查询 CPU steal", err, stealRankingMenuID, 1)
+	}
+
+	// 只列出当前聊天可以查看的实例
+	var names []string
+	for _, instance := range instances {
+		name := string(instance["instance"])
+		if _, ok := steal[name]; ok {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool { return steal[names[i]] > steal[names[j]] })
+	if len(names) > stealRankingTopN {
+		names = names[:stealRankingTopN]
+	}
+
+	text := fmt.Sprintf("<b>CPU steal 排行</b>（最近 5 分钟，前 %d 名）\n\n", stealRankingTopN)
+	if len(names) == 0 {
+		text += "没有实例上报 CPU steal 数据"
+	}
+	var menuItems []MenuItem
+	for i, name := range names {
+		line := fmt.Sprintf("%d. %s: %s", i+1, escapeHTML(utils.TruncateString(name, 30)), prometheus.FormatUsage(prometheus.UsageCPUSteal, steal[name]))
+		if steal[name] >= prometheus.HighStealPercent {
+			line += " " + b.Renderer.Glyph(render.GlyphWarning)
+		}
+		text += line + "\n"
+		if callbackData := instanceInfoPrefix + name; fitsCallback(callbackData) {
+			menuItems = append(menuItems, MenuItem{Text: fmt.Sprintf("%d. %s", i+1, utils.TruncateString(name, 30)), CallbackData: callbackData})
+		}
+	}
+	if len(names) > 0 {
+		text += "\nsteal 偏高说明虚拟机经常等待宿主机调度 CPU，通常是宿主机超售或邻居占用过多。"
+	}
+
+	menuItems = append(menuItems,
+		MenuItem{Text: "刷新", CallbackData: stealRankingMenuID},
+		MenuItem{Text: "返回", CallbackData: b.getPreviousMenuID(chatID)},
+		MenuItem{Text: "返回主菜单", CallbackData: mainMenuID},
+	)
+	return b.textPage(chatID, messageID, text, b.generateMenuRows(menuItems))
+}
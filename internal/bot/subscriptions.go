@@ -0,0 +1,327 @@
+package bot
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/render"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/store"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// subscribePrefix 是批量选择实例页面按钮的回调前缀：
+	// subsel:t:<序号> 切换实例，subsel:p:<页码> 翻页，subsel:all、subsel:invert、subsel:ok、subsel:cancel
+	subscribePrefix = "subsel:"
+	// selectionPageSize 是批量选择页面每页显示的实例数，每行两个
+	selectionPageSize = 20
+	subscribeUsage    = "用法: /subscribe offline|threshold\n" +
+		"选择要接收离线告警（offline）或阈值告警（threshold）的实例，不在告警聊天中的聊天也会收到所选实例的告警"
+)
+
+// subscriptionKinds 是可以按实例订阅的告警类型及其名称
+var subscriptionKinds = map[string]string{
+	"offline":   "离线告警",
+	"threshold": "阈值告警",
+}
+
+// subscriptionKindOf 返回事件对应的订阅类型，不能按实例订阅的事件为空
+func subscriptionKindOf(kind store.EventKind) string {
+	switch kind {
+	case store.EventInstanceDown, store.EventInstanceUp:
+		return "offline"
+	case store.EventThresholdBreach, store.EventQuotaCrossing:
+		return "threshold"
+	}
+	return ""
+}
+
+// subscribedInstances 返回聊天订阅了某类告警的实例
+func subscribedInstances(s store.AlertSubscriptions, kind string) []string {
+	switch kind {
+	case "offline":
+		return s.Offline
+	case "threshold":
+		return s.Threshold
+	}
+	return nil
+}
+
+// alertSubscribers 返回订阅了事件所属实例、但不在告警聊天中的聊天
+func (b *BotInstance) alertSubscribers(e store.Event) []int64 {
+	kind := subscriptionKindOf(e.Kind)
+	if kind == "" {
+		return nil
+	}
+	var chats []int64
+	for chatID, settings := range b.Store.AllChatSettings() {
+		if slices.Contains(b.config.AlertChatIDs, chatID) || !b.hasFullAccess(chatID) {
+			continue
+		}
+		if slices.Contains(subscribedInstances(settings.Subscriptions, kind), e.Instance) {
+			chats = append(chats, chatID)
+		}
+	}
+	slices.Sort(chats)
+	return chats
+}
+
+// alertRecipients 返回接收事件通知的所有聊天：告警聊天和订阅了该实例的聊天
+func (b *BotInstance) alertRecipients(e store.Event) []int64 {
+	return append(slices.Clone(b.config.AlertChatIDs), b.alertSubscribers(e)...)
+}
+
+// alertDelivery 是发送到同一组聊天的一批事件
+type alertDelivery struct {
+	chatIDs []int64
+	events  []store.Event
+}
+
+// alertDeliveries 将汇总窗口内的事件按接收的聊天分组：告警聊天收到所有事件，
+// 订阅了实例的聊天各自只收到订阅的事件
+func (b *BotInstance) alertDeliveries(events []store.Event) []alertDelivery {
+	var deliveries []alertDelivery
+	if len(b.config.AlertChatIDs) > 0 {
+		deliveries = append(deliveries, alertDelivery{chatIDs: b.config.AlertChatIDs, events: events})
+	}
+	var chats []int64
+	subscribed := make(map[int64][]store.Event)
+	for _, e := range events {
+		for _, chatID := range b.alertSubscribers(e) {
+			if _, ok := subscribed[chatID]; !ok {
+				chats = append(chats, chatID)
+			}
+			subscribed[chatID] = append(subscribed[chatID], e)
+		}
+	}
+	for _, chatID := range chats {
+		deliveries = append(deliveries, alertDelivery{chatIDs: []int64{chatID}, events: subscribed[chatID]})
+	}
+	return deliveries
+}
+
+// instanceSelection 是批量选择实例页面尚未确定的选择
+type instanceSelection struct {
+	kind     string
+	names    []string
+	selected map[string]bool
+	page     int
+}
+
+// count 返回已选择的实例数
+func (s *instanceSelection) count() int {
+	n := 0
+	for _, name := range s.names {
+		if s.selected[name] {
+			n++
+		}
+	}
+	return n
+}
+
+// instanceSelections 保存每个聊天正在进行的批量选择，确定或取消后删除
+type instanceSelections struct {
+	mu         sync.Mutex
+	selections map[int64]*instanceSelection
+}
+
+// update 在持有锁时修改聊天的选择，没有进行中的选择时返回 false
+func (c *instanceSelections) update(chatID int64, fn func(s *instanceSelection)) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.selections[chatID]
+	if ok {
+		fn(s)
+	}
+	return ok
+}
+
+func (c *instanceSelections) set(chatID int64, s *instanceSelection) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.selections == nil {
+		c.selections = make(map[int64]*instanceSelection)
+	}
+	c.selections[chatID] = s
+}
+
+// take 取出并删除聊天的选择
+func (c *instanceSelections) take(chatID int64) (*instanceSelection, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.selections[chatID]
+	delete(c.selections, chatID)
+	return s, ok
+}
+
+// handleSubscribeCommand 处理 /subscribe，不带参数时列出当前的订阅，
+// 指定告警类型时打开批量选择实例的页面
+func (b *BotInstance) handleSubscribeCommand(chatID int64, args string) {
+	kind := strings.ToLower(strings.TrimSpace(args))
+	if kind == "" {
+		b.sendText(chatID, b.subscriptionsText(chatID))
+		return
+	}
+	if _, ok := subscriptionKinds[kind]; !ok {
+		b.sendText(chatID, subscribeUsage)
+		return
+	}
+	instances, err := b.fetchInstancesForMenu(chatID, allInstancesMenuID)
+	if err != nil {
+		b.sendError(chatID, "获取实例列表", err)
+		return
+	}
+	if len(instances) == 0 {
+		b.sendText(chatID, "没有可以订阅的实例。")
+		return
+	}
+	s := &instanceSelection{kind: kind, selected: make(map[string]bool), page: 1}
+	for _, instance := range instances {
+		s.names = append(s.names, string(instance["instance"]))
+	}
+	sort.Strings(s.names)
+	for _, name := range subscribedInstances(b.Store.ChatSettings(chatID).Subscriptions, kind) {
+		s.selected[name] = true
+	}
+	b.selections.set(chatID, s)
+	b.post(priorityInteractive, b.selectionPage(chatID, 0, s))
+}
+
+// subscriptionsText 列出聊天当前订阅的实例
+func (b *BotInstance) subscriptionsText(chatID int64) string {
+	subscriptions := b.Store.ChatSettings(chatID).Subscriptions
+	text := "<b>告警订阅</b>\n\n"
+	for _, kind := range []string{"offline", "threshold"} {
+		names := subscribedInstances(subscriptions, kind)
+		if len(names) == 0 {
+			text += fmt.Sprintf("%s: 未订阅\n", subscriptionKinds[kind])
+			continue
+		}
+		text += fmt.Sprintf("%s（%d 个）: %s\n", subscriptionKinds[kind], len(names), escapeHTML(strings.Join(names, ", ")))
+	}
+	if slices.Contains(b.config.AlertChatIDs, chatID) {
+		text += "\n当前聊天是告警聊天，会收到所有实例的告警。"
+	}
+	return text + "\n" + subscribeUsage
+}
+
+// selectionPage 显示批量选择实例的页面，已选择的实例带有勾选标记
+func (b *BotInstance) selectionPage(chatID int64, messageID int, s *instanceSelection) tgbotapi.Chattable {
+	totalPages := (len(s.names) + selectionPageSize - 1) / selectionPageSize
+	page := min(max(s.page, 1), totalPages)
+	text := fmt.Sprintf("<b>订阅%s</b>\n\n已选择 %d / %d 个实例，点击实例切换，完成后点击确定。",
+		subscriptionKinds[s.kind], s.count(), len(s.names))
+	if totalPages > 1 {
+		text += fmt.Sprintf("\n第 %d / %d 页", page, totalPages)
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+	start := (page - 1) * selectionPageSize
+	for i := start; i < min(start+selectionPageSize, len(s.names)); i++ {
+		label := s.names[i]
+		if s.selected[label] {
+			label = b.Renderer.Glyph(render.GlyphSelected) + " " + label
+		}
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(label, subscribePrefix+"t:"+strconv.Itoa(i)))
+		if len(row) == 2 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	var pageButtons []tgbotapi.InlineKeyboardButton
+	if page > 1 {
+		pageButtons = append(pageButtons, tgbotapi.NewInlineKeyboardButtonData("上一页", subscribePrefix+"p:"+strconv.Itoa(page-1)))
+	}
+	if page < totalPages {
+		pageButtons = append(pageButtons, tgbotapi.NewInlineKeyboardButtonData("下一页", subscribePrefix+"p:"+strconv.Itoa(page+1)))
+	}
+	if len(pageButtons) > 0 {
+		rows = append(rows, pageButtons)
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("全选", subscribePrefix+"all"),
+		tgbotapi.NewInlineKeyboardButtonData("反选", subscribePrefix+"invert"),
+	), tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("确定", subscribePrefix+"ok"),
+		tgbotapi.NewInlineKeyboardButtonData("取消", subscribePrefix+"cancel"),
+	))
+	return b.textPage(chatID, messageID, text, rows)
+}
+
+// handleSubscribeCallback 处理批量选择页面上的按钮，args 为去掉前缀的回调数据
+func (b *BotInstance) handleSubscribeCallback(chatID int64, messageID int, args string) string {
+	switch args {
+	case "ok":
+		s, ok := b.selections.take(chatID)
+		if !ok {
+			b.editMessage(chatID, messageID, "选择已过期，请重新执行 /subscribe")
+			return ""
+		}
+		var names []string
+		for _, name := range s.names {
+			if s.selected[name] {
+				names = append(names, name)
+			}
+		}
+		err := b.Store.UpdateChatSettings(chatID, func(settings *store.ChatSettings) {
+			switch s.kind {
+			case "offline":
+				settings.Subscriptions.Offline = names
+			case "threshold":
+				settings.Subscriptions.Threshold = names
+			}
+		})
+		if err != nil {
+			b.sendError(chatID, "保存告警订阅", err)
+			return ""
+		}
+		b.editMessage(chatID, messageID, fmt.Sprintf("已订阅 %d 个实例的%s。", len(names), subscriptionKinds[s.kind]))
+		return "已保存"
+	case "cancel":
+		b.selections.take(chatID)
+		b.editMessage(chatID, messageID, "已取消，订阅没有修改。")
+		return ""
+	}
+
+	action, value, _ := strings.Cut(args, ":")
+	n, _ := strconv.Atoi(value)
+	var current *instanceSelection
+	ok := b.selections.update(chatID, func(s *instanceSelection) {
+		switch action {
+		case "t":
+			if n >= 0 && n < len(s.names) {
+				s.selected[s.names[n]] = !s.selected[s.names[n]]
+			}
+		case "p":
+			s.page = n
+		case "all":
+			for _, name := range s.names {
+				s.selected[name] = true
+			}
+		case "invert":
+			for _, name := range s.names {
+				s.selected[name] = !s.selected[name]
+			}
+		}
+		copied := *s
+		copied.selected = make(map[string]bool, len(s.selected))
+		for name, selected := range s.selected {
+			copied.selected[name] = selected
+		}
+		current = &copied
+	})
+	if !ok {
+		b.editMessage(chatID, messageID, "选择已过期，请重新执行 /subscribe")
+		return ""
+	}
+	b.editOrSend(b.selectionPage(chatID, messageID, current))
+	return ""
+}
@@ -0,0 +1,39 @@
+package bot
+
+import (
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/render"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// systemdPrefix 是服务状态页面的菜单ID前缀，格式为 systemd:<instance>
+const systemdPrefix = "systemd:"
+
+// systemdPage 显示实例失败的 systemd 单元和配置中关键服务的状态
+func (b *BotInstance) systemdPage(chatID int64, messageID int, instanceName string) tgbotapi.Chattable {
+	menuID := systemdPrefix + instanceName
+	instance, err := b.findInstance(chatID, instanceName)
+	if err != nil {
+		return b.errorPage(chatID, messageID, "获取实例列表", err, menuID, 1)
+	}
+	rows := [][]tgbotapi.InlineKeyboardButton{tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("刷新", menuID),
+		tgbotapi.NewInlineKeyboardButtonData("返回", b.getPreviousMenuID(chatID)),
+		tgbotapi.NewInlineKeyboardButtonData("返回主菜单", mainMenuID),
+	)}
+	if instance == nil {
+		return b.textPage(chatID, messageID, "找不到指定的实例，请重试。", rows)
+	}
+
+	now := time.Now()
+	status, err := b.prom(chatID).QuerySystemd(instance, b.config.SystemdServices, now)
+	if err != nil {
+		return b.errorPage(chatID, messageID, "查询服务状态", err, menuID, 1)
+	}
+	text, err := b.render(chatID, render.Systemd, render.SystemdData{Instance: instanceName, GeneratedAt: now, Status: status})
+	if err != nil {
+		return b.errorPage(chatID, messageID, "渲染服务状态", err, menuID, 1)
+	}
+	return b.textPage(chatID, messageID, text, rows)
+}
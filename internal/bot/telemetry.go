@@ -0,0 +1,64 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/store"
+)
+
+// 本地匿名功能计数的名称，菜单和命令分别以 menu: 和 command: 加上名称记录
+const (
+	featureChart          = "chart"
+	featureScheduledQuery = "scheduled_query"
+	featureAlert          = "alert"
+)
+
+const telemetryUsage = "用法: /telemetry off 本聊天的操作不再计入功能计数\n/telemetry on 恢复计入"
+
+// countFeature 在开启 TELEMETRY_ENABLED 时将功能计数加一。计数只保存在本地，不记录是哪个聊天，
+// 选择退出的聊天不计入；chatID 为 0 表示不由某个聊天触发（例如告警通知）
+func (b *BotInstance) countFeature(chatID int64, feature string) {
+	if !b.config.TelemetryEnabled {
+		return
+	}
+	if chatID != 0 && b.Store.ChatSettings(chatID).TelemetryOptOut {
+		return
+	}
+	if err := b.Store.RecordTelemetry(feature, time.Now()); err != nil {
+		log.Printf("Failed to record telemetry: %v", err)
+	}
+}
+
+// handleTelemetryCommand 查看功能计数是否开启，并允许每个聊天选择退出
+func (b *BotInstance) handleTelemetryCommand(chatID int64, args string) {
+	switch strings.ToLower(strings.TrimSpace(args)) {
+	case "":
+		status := "未开启"
+		if b.config.TelemetryEnabled {
+			status = "已开启"
+		}
+		counted := "计入"
+		if b.Store.ChatSettings(chatID).TelemetryOptOut {
+			counted = "不计入"
+		}
+		b.sendText(chatID, fmt.Sprintf("<b>功能计数</b>: %s（TELEMETRY_ENABLED）\n本聊天的操作: %s\n\n"+
+			"功能计数只在本机统计各菜单、命令和功能的使用次数，不记录聊天、用户和实例信息，不会发送到任何外部服务，"+
+			"仅供管理员在使用统计页面中查看。\n\n%s", status, counted, telemetryUsage))
+	case "off", "on":
+		optOut := strings.EqualFold(strings.TrimSpace(args), "off")
+		if err := b.Store.UpdateChatSettings(chatID, func(s *store.ChatSettings) { s.TelemetryOptOut = optOut }); err != nil {
+			b.sendError(chatID, "保存功能计数设置", err)
+			return
+		}
+		if optOut {
+			b.sendText(chatID, "本聊天的操作将不再计入功能计数。")
+		} else {
+			b.sendText(chatID, "本聊天的操作将计入功能计数。")
+		}
+	default:
+		b.sendText(chatID, telemetryUsage)
+	}
+}
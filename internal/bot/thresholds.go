@@ -0,0 +1,166 @@
+package bot
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/prometheus"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/render"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/store"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/utils"
+)
+
+const thresholdsUsage = "用法: /thresholds 列出告警阈值\n" +
+	"/thresholds preview &lt;指标&gt; &lt;阈值&gt; 预览按当前数据会触发的实例\n" +
+	"/thresholds set &lt;指标&gt; &lt;阈值&gt; 预览并保存\n" +
+	"/thresholds reset &lt;指标&gt; 恢复配置文件中的阈值\n" +
+	"/subscribe threshold 选择当前聊天接收阈值告警的实例"
+
+// maxPreviewInstances 限制预览中列出的实例数量
+const maxPreviewInstances = 30
+
+// handleThresholdsCommand 查看和修改使用率告警阈值。修改前先预览新阈值按当前数据会触发哪些实例、通知到哪里，确认后才保存
+func (b *BotInstance) handleThresholdsCommand(chatID int64, args string) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		b.sendThresholdList(chatID)
+		return
+	}
+	if !b.isAdmin(chatID) {
+		b.sendText(chatID, "只有管理员可以修改告警阈值。")
+		return
+	}
+	action := fields[0]
+	if action == "reset" && len(fields) == 2 {
+		b.resetThreshold(chatID, fields[1])
+		return
+	}
+	if (action != "preview" && action != "set") || len(fields) != 3 {
+		b.sendText(chatID, thresholdsUsage)
+		return
+	}
+	metric := fields[1]
+	if _, ok := prometheus.UsageLabel(metric); !ok {
+		b.sendText(chatID, fmt.Sprintf("未知的指标: %s\n%s", escapeHTML(metric), thresholdsUsage))
+		return
+	}
+	limit, err := strconv.ParseFloat(strings.TrimSuffix(fields[2], "%"), 64)
+	if err != nil || limit <= 0 {
+		b.sendText(chatID, "无效的阈值，例如 90 或 90%")
+		return
+	}
+
+	preview, err := b.thresholdPreview(chatID, metric, limit, time.Now())
+	if err != nil {
+		b.sendError(chatID, "预览告警阈值", err)
+		return
+	}
+	if action == "preview" {
+		b.sendText(chatID, preview)
+		return
+	}
+	b.confirmAction(chatID, preview+"\n确认保存该阈值？", func() {
+		if err := b.Store.SetThreshold(metric, limit); err != nil {
+			b.sendError(chatID, "保存告警阈值", err)
+			return
+		}
+		label, _ := prometheus.UsageLabel(metric)
+		b.sendText(chatID, fmt.Sprintf("已保存: %s ≥ %s，下一次轮询时生效。", label, prometheus.FormatUsage(metric, limit)))
+	})
+}
+
+// sendThresholdList 列出生效的告警阈值
+func (b *BotInstance) sendThresholdList(chatID int64) {
+	thresholds := b.Store.Thresholds(b.config.Thresholds)
+	metrics := make([]string, 0, len(thresholds))
+	for metric := range thresholds {
+		metrics = append(metrics, metric)
+	}
+	sort.Strings(metrics)
+
+	text := "<b>告警阈值</b>\n\n"
+	if len(metrics) == 0 {
+		text += "没有设置告警阈值\n"
+	}
+	for _, metric := range metrics {
+		label, _ := prometheus.UsageLabel(metric)
+		text += fmt.Sprintf("%s %s (<code>%s</code>) ≥ %s", b.Renderer.Glyph(render.GlyphBullet), label, metric, prometheus.FormatUsage(metric, thresholds[metric]))
+		if b.Store.ThresholdOverridden(metric) {
+			text += "（已在机器人中修改）"
+		}
+		text += "\n"
+	}
+	b.sendText(chatID, text+"\n"+thresholdsUsage)
+}
+
+func (b *BotInstance) resetThreshold(chatID int64, metric string) {
+	found, err := b.Store.ResetThreshold(metric)
+	if err != nil {
+		b.sendError(chatID, "恢复告警阈值", err)
+		return
+	}
+	if !found {
+		b.sendText(chatID, "该指标的阈值没有在机器人中修改过。")
+		return
+	}
+	b.sendText(chatID, fmt.Sprintf("已恢复 %s 的阈值为配置文件中的设置。", escapeHTML(metric)))
+}
+
+// thresholdPreview 按当前数据评估新阈值：列出会触发的实例、保存后会恢复的实例，以及通知的接收方
+func (b *BotInstance) thresholdPreview(chatID int64, metric string, limit float64, now time.Time) (string, error) {
+	usage, err := b.prom(chatID).UsageByInstance(metric, now)
+	if err != nil {
+		return "", err
+	}
+	current, hasCurrent := b.Store.Thresholds(b.config.Thresholds)[metric]
+	instances := make([]string, 0, len(usage))
+	for instance := range usage {
+		instances = append(instances, instance)
+	}
+	sort.Slice(instances, func(i, j int) bool { return usage[instances[i]] > usage[instances[j]] })
+
+	label, _ := prometheus.UsageLabel(metric)
+	text := fmt.Sprintf("<b>阈值预览</b>: %s ≥ %s", label, prometheus.FormatUsage(metric, limit))
+	if hasCurrent {
+		text += fmt.Sprintf("（当前 %s）", prometheus.FormatUsage(metric, current))
+	}
+	text += "\n\n"
+
+	var triggered, resolved []string
+	for _, instance := range instances {
+		value := usage[instance]
+		wasBreached := hasCurrent && value >= current
+		if value < limit {
+			if wasBreached {
+				resolved = append(resolved, fmt.Sprintf("%s (%s)", escapeHTML(utils.TruncateString(instance, 30)), prometheus.FormatUsage(metric, value)))
+			}
+			continue
+		}
+		line := fmt.Sprintf("%s %s: %s", b.Renderer.Glyph(render.GlyphWarning), escapeHTML(utils.TruncateString(instance, 30)), prometheus.FormatUsage(metric, value))
+		if !wasBreached {
+			line += "（新触发）"
+		}
+		if b.Store.Snoozed(instance, metric, now) {
+			line += "（已暂停通知）"
+		}
+		line += b.sinksText(store.Event{Instance: instance, Kind: store.EventThresholdBreach, Metric: metric})
+		triggered = append(triggered, line)
+	}
+
+	text += fmt.Sprintf("按当前数据，%d 个实例中有 %d 个会触发:\n", len(instances), len(triggered))
+	for i, line := range triggered {
+		if i == maxPreviewInstances {
+			text += fmt.Sprintf("… 还有 %d 个\n", len(triggered)-i)
+			break
+		}
+		text += line + "\n"
+	}
+	if len(resolved) > 0 {
+		text += fmt.Sprintf("\n保存后会恢复 %d 个: %s\n", len(resolved), strings.Join(resolved, ", "))
+	}
+	text += "\n<b>通知接收方</b>\n" + b.alertChatsText() + "\n"
+	return text, nil
+}
@@ -0,0 +1,157 @@
+package bot
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// customRangeKey 是时间范围选择器中"自定义"按钮的键，点击后通过对话询问日期
+	customRangeKey = "custom"
+	// customRangeFormat 是自定义范围在回调数据中的格式，例如 20261001-20261015（两端日期都包含）
+	customRangeFormat = "20060102"
+)
+
+// timeRange 是时间范围选择器选中的范围，End 不超过当前时间
+type timeRange struct {
+	Key   string
+	Label string
+	Start time.Time
+	End   time.Time
+}
+
+// Duration 返回范围的长度
+func (r timeRange) Duration() time.Duration {
+	return r.End.Sub(r.Start)
+}
+
+// timeRangePresets 是选择器中的预设范围，按钮按此顺序显示
+var timeRangePresets = []struct {
+	Key   string
+	Label string
+}{
+	{"today", "今天"},
+	{"yesterday", "昨天"},
+	{"7d", "7天"},
+	{"30d", "30天"},
+	{"month", "本月"},
+	{"lastmonth", "上月"},
+}
+
+// dateInText 匹配用户输入中的日期
+var dateInText = regexp.MustCompile(`\d{4}-\d{1,2}-\d{1,2}`)
+
+// resolveTimeRange 将选择器的键转换为具体的时间范围，键可以是预设范围或自定义范围
+func resolveTimeRange(key string, now time.Time) (timeRange, bool) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	thisMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	r := timeRange{Key: key, End: now}
+	switch key {
+	case "today":
+		r.Start = today
+	case "yesterday":
+		r.Start, r.End = today.AddDate(0, 0, -1), today
+	case "7d":
+		r.Start = today.AddDate(0, 0, -6)
+	case "30d":
+		r.Start = today.AddDate(0, 0, -29)
+	case "month":
+		r.Start = thisMonth
+	case "lastmonth":
+		r.Start, r.End = thisMonth.AddDate(0, -1, 0), thisMonth
+	default:
+		startText, endText, ok := strings.Cut(key, "-")
+		if !ok {
+			return timeRange{}, false
+		}
+		start, err := time.ParseInLocation(customRangeFormat, startText, now.Location())
+		if err != nil {
+			return timeRange{}, false
+		}
+		end, err := time.ParseInLocation(customRangeFormat, endText, now.Location())
+		if err != nil || end.Before(start) || !start.Before(now) {
+			return timeRange{}, false
+		}
+		r.Start, r.End = start, end.AddDate(0, 0, 1)
+		if r.End.After(now) {
+			r.End = now
+		}
+		r.Label = start.Format("2006-01-02") + " ~ " + end.Format("2006-01-02")
+		return r, true
+	}
+	for _, p := range timeRangePresets {
+		if p.Key == key {
+			r.Label = p.Label
+		}
+	}
+	return r, true
+}
+
+// parseCustomRange 解析用户输入的日期范围，例如 "2026-10-01 2026-10-15"，只有一个日期时表示当天
+func parseCustomRange(text string, now time.Time) (timeRange, error) {
+	dates := dateInText.FindAllString(text, -1)
+	if len(dates) == 0 || len(dates) > 2 {
+		return timeRange{}, fmt.Errorf("请输入一个或两个日期，例如 2026-10-01 2026-10-15")
+	}
+	var parsed []time.Time
+	for _, d := range dates {
+		t, err := time.ParseInLocation("2006-1-2", d, now.Location())
+		if err != nil {
+			return timeRange{}, fmt.Errorf("无效的日期 %s", d)
+		}
+		parsed = append(parsed, t)
+	}
+	start, end := parsed[0], parsed[len(parsed)-1]
+	if end.Before(start) {
+		return timeRange{}, fmt.Errorf("结束日期早于开始日期")
+	}
+	key := start.Format(customRangeFormat) + "-" + end.Format(customRangeFormat)
+	r, ok := resolveTimeRange(key, now)
+	if !ok {
+		return timeRange{}, fmt.Errorf("开始日期不能晚于今天")
+	}
+	return r, nil
+}
+
+// timeRangeKeyboard 生成时间范围选择器的按钮，callback 根据范围的键生成回调数据，当前范围不显示
+func timeRangeKeyboard(current string, callback func(key string) string) [][]tgbotapi.InlineKeyboardButton {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+	for _, p := range timeRangePresets {
+		if p.Key == current {
+			continue
+		}
+		if !fitsCallback(callback(p.Key)) {
+			continue
+		}
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(p.Label, callback(p.Key)))
+		if len(row) == 4 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if fitsCallback(callback(customRangeKey)) {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("自定义", callback(customRangeKey)))
+	}
+	if len(row) == 0 {
+		return rows
+	}
+	return append(rows, row)
+}
+
+// askTimeRange 询问自定义日期范围，输入有效时用自定义范围的键调用 apply
+func (b *BotInstance) askTimeRange(chatID int64, apply func(key string)) {
+	b.ask(chatID, "请输入日期范围，例如 2026-10-01 2026-10-15，或者只输入一个日期。", func(text string) bool {
+		r, err := parseCustomRange(text, time.Now())
+		if err != nil {
+			b.sendText(chatID, escapeHTML(err.Error()))
+			return false
+		}
+		apply(r.Key)
+		return true
+	})
+}
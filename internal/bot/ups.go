@@ -0,0 +1,31 @@
+package bot
+
+import (
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/render"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// upsMenuID 是 UPS 状态页面的菜单ID
+const upsMenuID = "ups"
+
+// upsPage 显示所有 UPS 的电量、负载、剩余时间和供电状态
+func (b *BotInstance) upsPage(chatID int64, messageID int) tgbotapi.Chattable {
+	rows := [][]tgbotapi.InlineKeyboardButton{tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("刷新", upsMenuID),
+		tgbotapi.NewInlineKeyboardButtonData("返回", b.getPreviousMenuID(chatID)),
+		tgbotapi.NewInlineKeyboardButtonData("返回主菜单", mainMenuID),
+	)}
+
+	now := time.Now()
+	upses, err := b.prom(chatID).QueryUPS(now)
+	if err != nil {
+		return b.errorPage(chatID, messageID, "查询 UPS 状态", err, upsMenuID, 1)
+	}
+	text, err := b.render(chatID, render.UPS, render.UPSData{GeneratedAt: now, UPS: upses})
+	if err != nil {
+		return b.errorPage(chatID, messageID, "渲染 UPS 状态", err, upsMenuID, 1)
+	}
+	return b.textPage(chatID, messageID, text, rows)
+}
@@ -0,0 +1,94 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/prometheus"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/render"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/utils"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// uptimePrefix 是在线时间线页面的菜单ID前缀，格式为 uptime:<instance>
+	uptimePrefix = "uptime:"
+	// uptimeRangePrefix 是指定时间范围的在线时间线，格式为 uptime_range:<范围>:<instance>
+	uptimeRangePrefix = "uptime_range:"
+	// uptimeDays 是在线时间线默认显示的天数
+	uptimeDays = 7
+	// maxUptimeDays 是在线时间线最多显示的天数，每天一行
+	maxUptimeDays = 31
+)
+
+// uptimePage 显示实例最近 uptimeDays 天每小时的在线状态
+func (b *BotInstance) uptimePage(chatID int64, messageID int, instanceName string) tgbotapi.Chattable {
+	return b.uptimeTimelinePage(chatID, messageID, "", instanceName)
+}
+
+// uptimeRangePage 显示实例在时间范围选择器选中的范围内每小时的在线状态，param 为 <范围>:<instance>
+func (b *BotInstance) uptimeRangePage(chatID int64, messageID int, param string) tgbotapi.Chattable {
+	key, instanceName, _ := strings.Cut(param, ":")
+	if key == customRangeKey {
+		b.askTimeRange(chatID, func(key string) {
+			menuID := uptimeRangePrefix + key + ":" + instanceName
+			b.navigateTo(chatID, menuID)
+			b.currentMessageID = b.sendMenuPage(chatID, 1)
+		})
+		rows := [][]tgbotapi.InlineKeyboardButton{tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("返回", uptimePrefix+instanceName),
+		)}
+		return b.textPage(chatID, messageID, "请在下一条消息中输入日期范围。", rows)
+	}
+	return b.uptimeTimelinePage(chatID, messageID, key, instanceName)
+}
+
+// uptimeTimelinePage 生成在线时间线页面，rangeKey 为空时显示最近 uptimeDays 天
+func (b *BotInstance) uptimeTimelinePage(chatID int64, messageID int, rangeKey, instanceName string) tgbotapi.Chattable {
+	menuID := uptimePrefix + instanceName
+	if rangeKey != "" {
+		menuID = uptimeRangePrefix + rangeKey + ":" + instanceName
+	}
+	instance, err := b.findInstance(chatID, instanceName)
+	if err != nil {
+		return b.errorPage(chatID, messageID, "获取实例列表", err, menuID, 1)
+	}
+	rows := timeRangeKeyboard(rangeKey, func(key string) string { return uptimeRangePrefix + key + ":" + instanceName })
+	var navRow []tgbotapi.InlineKeyboardButton
+	if fitsCallback(menuID) {
+		navRow = append(navRow, tgbotapi.NewInlineKeyboardButtonData("刷新", menuID))
+	}
+	rows = append(rows, append(navRow,
+		tgbotapi.NewInlineKeyboardButtonData("返回", b.getPreviousMenuID(chatID)),
+		tgbotapi.NewInlineKeyboardButtonData("返回主菜单", mainMenuID),
+	))
+	if instance == nil {
+		return b.textPage(chatID, messageID, "找不到指定的实例，请重试。", rows)
+	}
+
+	now := time.Now()
+	var days []prometheus.UptimeDay
+	var rangeLabel string
+	if rangeKey == "" {
+		days, err = b.prom(chatID).UptimeTimeline(instance, uptimeDays, now)
+	} else {
+		r, ok := resolveTimeRange(rangeKey, now)
+		if !ok {
+			return b.textPage(chatID, messageID, "无效的时间范围", rows)
+		}
+		if utils.DaysBetween(r.Start, r.End) > maxUptimeDays {
+			return b.textPage(chatID, messageID, fmt.Sprintf("时间范围太长，在线时间线最多显示 %d 天", maxUptimeDays), rows)
+		}
+		rangeLabel = r.Label
+		days, err = b.prom(chatID).UptimeTimelineBetween(instance, r.Start, r.End)
+	}
+	if err != nil {
+		return b.errorPage(chatID, messageID, "查询在线时间线", err, menuID, 1)
+	}
+	text, err := b.render(chatID, render.Uptime, render.UptimeData{Instance: instanceName, GeneratedAt: now, Range: rangeLabel, Days: days})
+	if err != nil {
+		return b.errorPage(chatID, messageID, "渲染在线时间线", err, menuID, 1)
+	}
+	return b.textPage(chatID, messageID, text, rows)
+}
@@ -0,0 +1,111 @@
+package bot
+
+import (
+	"log"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/render"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/store"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// usageStatsPrefix 是使用统计页面的菜单ID前缀，格式为 usage:<天数>
+	usageStatsPrefix = "usage:"
+	// usageTopN 是使用统计每个分类显示的条数
+	usageTopN = 10
+)
+
+// usageStatsDays 是使用统计可选的时间范围
+var usageStatsDays = []int{7, 30}
+
+func usageStatsMenuID(days int) string {
+	return usageStatsPrefix + strconv.Itoa(days)
+}
+
+// recordUsage 记录功能使用次数，失败时只记录日志
+func (b *BotInstance) recordUsage(chatID int64, kind store.UsageKind, name string) {
+	if err := b.Store.RecordUsage(chatID, kind, name, time.Now()); err != nil {
+		log.Printf("Failed to record usage: %v", err)
+	}
+	// 实例名可以识别具体的机器，不计入匿名功能计数
+	if kind != store.UsageInstance {
+		b.countFeature(chatID, string(kind)+":"+name)
+	}
+}
+
+// usageData 汇总最近 days 天的使用统计
+func (b *BotInstance) usageData(days int, now time.Time) render.UsageData {
+	since := now.AddDate(0, 0, -(days - 1))
+	chats := make(map[string]int)
+	byKind := make(map[store.UsageKind]map[string]int)
+	for _, u := range b.Store.UsageSince(since) {
+		chats[strconv.FormatInt(u.ChatID, 10)] += u.Count
+		if byKind[u.Kind] == nil {
+			byKind[u.Kind] = make(map[string]int)
+		}
+		byKind[u.Kind][u.Name] += u.Count
+	}
+	return render.UsageData{
+		Days:        days,
+		GeneratedAt: now,
+		Chats:       len(chats),
+		TopChats:    topUsage(chats),
+		Commands:    topUsage(byKind[store.UsageCommand]),
+		Menus:       topUsage(byKind[store.UsageMenu]),
+		Instances:   topUsage(byKind[store.UsageInstance]),
+
+		TelemetryEnabled: b.config.TelemetryEnabled,
+		Features:         topUsage(b.Store.TelemetrySince(since)),
+	}
+}
+
+// topUsage 按次数从高到低返回前 usageTopN 项
+func topUsage(counts map[string]int) []render.UsageItem {
+	items := make([]render.UsageItem, 0, len(counts))
+	for name, count := range counts {
+		items = append(items, render.UsageItem{Name: name, Count: count})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Count != items[j].Count {
+			return items[i].Count > items[j].Count
+		}
+		return items[i].Name < items[j].Name
+	})
+	if len(items) > usageTopN {
+		items = items[:usageTopN]
+	}
+	return items
+}
+
+// usageStatsPage 是管理员可见的使用统计页面
+func (b *BotInstance) usageStatsPage(chatID int64, messageID int, param string) tgbotapi.Chattable {
+	rows := [][]tgbotapi.InlineKeyboardButton{}
+	if !b.isAdmin(chatID) {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("返回主菜单", mainMenuID)))
+		return b.textPage(chatID, messageID, "只有管理员可以查看使用统计。", rows)
+	}
+	days, err := strconv.Atoi(param)
+	if err != nil || days <= 0 {
+		days = usageStatsDays[0]
+	}
+	menuID := usageStatsMenuID(days)
+	text, err := b.render(chatID, render.Usage, b.usageData(days, time.Now()))
+	if err != nil {
+		return b.errorPage(chatID, messageID, "生成使用统计", err, menuID, 1)
+	}
+
+	var rangeButtons []tgbotapi.InlineKeyboardButton
+	for _, d := range usageStatsDays {
+		if d != days {
+			rangeButtons = append(rangeButtons, tgbotapi.NewInlineKeyboardButtonData("最近 "+strconv.Itoa(d)+" 天", usageStatsMenuID(d)))
+		}
+	}
+	rows = append(rows, rangeButtons, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("返回", b.getPreviousMenuID(chatID)),
+		tgbotapi.NewInlineKeyboardButtonData("返回主菜单", mainMenuID),
+	))
+	return b.textPage(chatID, messageID, text, rows)
+}
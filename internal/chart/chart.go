@@ -0,0 +1,309 @@
+package chart
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/common/model"
+	gochart "github.com/wcharczuk/go-chart/v2"
+	"github.com/wcharczuk/go-chart/v2/drawing"
+)
+
+// Point 是时间序列上的一个数据点
+type Point struct {
+	Time  time.Time
+	Value float64
+}
+
+// Series 是图表中的一条曲线
+type Series struct {
+	Name   string
+	Points []Point
+}
+
+// Window 是一段时间，例如实例离线的时段
+type Window struct {
+	Start time.Time
+	End   time.Time
+}
+
+// 图表尺寸
+const (
+	SizeSmall  = "small"
+	SizeMedium = "medium"
+	SizeLarge  = "large"
+)
+
+// sizes 是各尺寸的宽和高（像素），未知尺寸按中等处理
+var sizes = map[string][2]int{
+	SizeSmall:  {640, 320},
+	SizeMedium: {960, 480},
+	SizeLarge:  {1440, 720},
+}
+
+// Preferences 是用户可以调整的图表样式，零值为浅色主题、中等尺寸、折线图并显示图例
+type Preferences struct {
+	Dark bool
+	// Size 是 SizeSmall、SizeMedium 或 SizeLarge，为空时为中等
+	Size string
+	// Bars 为 true 时以柱状图显示每个数据点
+	Bars       bool
+	HideLegend bool
+}
+
+// theme 是图表背景、坐标轴和文字的颜色
+type theme struct {
+	background drawing.Color
+	foreground drawing.Color
+	grid       drawing.Color
+}
+
+var (
+	lightTheme = theme{background: drawing.ColorWhite, foreground: drawing.ColorFromHex("333333"), grid: drawing.ColorFromHex("cccccc")}
+	darkTheme  = theme{background: drawing.ColorFromHex("1e1e1e"), foreground: drawing.ColorFromHex("dddddd"), grid: drawing.ColorFromHex("555555")}
+)
+
+// Options 控制图表的标题和纵轴格式，标题只支持 ASCII（内置字体不含中文）
+type Options struct {
+	Preferences
+	Title string
+	// FormatValue 格式化纵轴刻度，为空时保留两位小数
+	FormatValue func(float64) string
+	// Reboots 是以竖线标出的重启时间，Downtime 是以阴影标出的离线时段，超出曲线时间范围的部分不显示
+	Reboots  []time.Time
+	Downtime []Window
+}
+
+// 重启竖线和离线阴影的颜色
+var (
+	rebootColor   = drawing.ColorFromHex("9467bd")
+	downtimeColor = drawing.Color{R: 214, G: 39, B: 40, A: 48}
+)
+
+// palette 是多条曲线依次使用的颜色
+var palette = []drawing.Color{
+	drawing.ColorFromHex("1f77b4"),
+	drawing.ColorFromHex("ff7f0e"),
+	drawing.ColorFromHex("2ca02c"),
+	drawing.ColorFromHex("d62728"),
+	drawing.ColorFromHex("9467bd"),
+}
+
+// RenderPNG 将时间序列渲染为 PNG 图片
+func RenderPNG(series []Series, opts Options) ([]byte, error) {
+	formatValue := opts.FormatValue
+	if formatValue == nil {
+		formatValue = func(v float64) string { return fmt.Sprintf("%.2f", v) }
+	}
+
+	var chartSeries []gochart.Series
+	var start, end time.Time
+	for i, s := range series {
+		if len(s.Points) == 0 {
+			continue
+		}
+		color := palette[i%len(palette)]
+		ts := gochart.TimeSeries{
+			Name: s.Name,
+			Style: gochart.Style{
+				StrokeColor: color,
+				StrokeWidth: 2,
+			},
+		}
+		for _, p := range s.Points {
+			ts.XValues = append(ts.XValues, p.Time)
+			ts.YValues = append(ts.YValues, p.Value)
+			if start.IsZero() || p.Time.Before(start) {
+				start = p.Time
+			}
+			if p.Time.After(end) {
+				end = p.Time
+			}
+		}
+		if opts.Bars {
+			ts.Style.FillColor = color.WithAlpha(160)
+			chartSeries = append(chartSeries, barSeries{ts})
+		} else {
+			chartSeries = append(chartSeries, ts)
+		}
+	}
+	if len(chartSeries) == 0 {
+		return nil, fmt.Errorf("no data points to render")
+	}
+	// 标注放在曲线之前绘制，不遮挡曲线
+	if len(opts.Reboots) > 0 || len(opts.Downtime) > 0 {
+		chartSeries = append([]gochart.Series{annotationSeries{reboots: opts.Reboots, downtime: opts.Downtime}}, chartSeries...)
+	}
+
+	// 超过一天的窗口显示日期，否则只显示时间
+	timeLayout := "15:04"
+	if end.Sub(start) > 24*time.Hour {
+		timeLayout = "01-02 15:04"
+	}
+
+	size, ok := sizes[opts.Size]
+	if !ok {
+		size = sizes[SizeMedium]
+	}
+	colors := lightTheme
+	if opts.Dark {
+		colors = darkTheme
+	}
+	axisStyle := gochart.Style{FontColor: colors.foreground, StrokeColor: colors.grid}
+
+	graph := gochart.Chart{
+		Title:      opts.Title,
+		TitleStyle: gochart.Style{FontColor: colors.foreground},
+		Width:      size[0],
+		Height:     size[1],
+		Background: gochart.Style{
+			Padding:   gochart.Box{Top: 50, Left: 20, Right: 20, Bottom: 20},
+			FillColor: colors.background,
+		},
+		Canvas: gochart.Style{FillColor: colors.background},
+		XAxis: gochart.XAxis{
+			Style: axisStyle,
+			ValueFormatter: func(v interface{}) string {
+				if f, ok := v.(float64); ok {
+					return time.Unix(0, int64(f)).Local().Format(timeLayout)
+				}
+				return ""
+			},
+		},
+		YAxis: gochart.YAxis{
+			Style: axisStyle,
+			ValueFormatter: func(v interface{}) string {
+				if f, ok := v.(float64); ok {
+					return formatValue(f)
+				}
+				return ""
+			},
+		},
+		Series: chartSeries,
+	}
+	if len(chartSeries) > 1 && !opts.HideLegend {
+		graph.Elements = []gochart.Renderable{gochart.LegendLeft(&graph, gochart.Style{
+			FillColor:   colors.background,
+			FontColor:   colors.foreground,
+			StrokeColor: colors.grid,
+		})}
+	}
+
+	var buf bytes.Buffer
+	if err := graph.Render(gochart.PNG, &buf); err != nil {
+		return nil, fmt.Errorf("Failed to render chart: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// barSeries 以柱状图绘制时间序列，坐标范围和图例与折线相同
+type barSeries struct {
+	gochart.TimeSeries
+}
+
+func (s barSeries) Render(r gochart.Renderer, canvasBox gochart.Box, xrange, yrange gochart.Range, defaults gochart.Style) {
+	n := s.Len()
+	if n == 0 {
+		return
+	}
+	style := s.Style.InheritFrom(defaults)
+	// 柱宽为数据点平均间距的 80%，至少 1 像素
+	width := max(int(float64(canvasBox.Width())/float64(n)*0.8), 1)
+	r.SetFillColor(style.FillColor)
+	r.SetStrokeWidth(0)
+	for i := range n {
+		x, y := s.GetValues(i)
+		center := canvasBox.Left + xrange.Translate(x)
+		top := canvasBox.Bottom - yrange.Translate(y)
+		left, right := max(center-width/2, canvasBox.Left), min(center-width/2+width, canvasBox.Right)
+		r.MoveTo(left, top)
+		r.LineTo(right, top)
+		r.LineTo(right, canvasBox.Bottom)
+		r.LineTo(left, canvasBox.Bottom)
+		r.Close()
+		r.Fill()
+	}
+}
+
+// annotationSeries 在图上绘制重启竖线和离线阴影，不参与坐标范围的计算
+type annotationSeries struct {
+	reboots  []time.Time
+	downtime []Window
+}
+
+func (s annotationSeries) GetName() string {
+	switch {
+	case len(s.reboots) == 0:
+		return "down"
+	case len(s.downtime) == 0:
+		return "reboot"
+	default:
+		return "reboot / down"
+	}
+}
+
+func (s annotationSeries) GetYAxis() gochart.YAxisType { return gochart.YAxisPrimary }
+
+// GetStyle 返回图例中显示的样式：有重启时为竖线颜色，否则为阴影颜色
+func (s annotationSeries) GetStyle() gochart.Style {
+	color := rebootColor
+	if len(s.reboots) == 0 {
+		color = downtimeColor.WithAlpha(255)
+	}
+	return gochart.Style{StrokeColor: color, StrokeWidth: 2}
+}
+
+func (s annotationSeries) Validate() error { return nil }
+
+func (s annotationSeries) Render(r gochart.Renderer, canvasBox gochart.Box, xrange, yrange gochart.Range, _ gochart.Style) {
+	// x 返回时间在画布上的横坐标，超出曲线时间范围时截断到边缘
+	x := func(t time.Time) int {
+		v := min(max(gochart.TimeToFloat64(t), xrange.GetMin()), xrange.GetMax())
+		return canvasBox.Left + xrange.Translate(v)
+	}
+	inRange := func(t time.Time) bool {
+		v := gochart.TimeToFloat64(t)
+		return v >= xrange.GetMin() && v <= xrange.GetMax()
+	}
+
+	for _, w := range s.downtime {
+		left, right := x(w.Start), x(w.End)
+		if right <= left {
+			continue
+		}
+		r.SetFillColor(downtimeColor)
+		r.SetStrokeWidth(0)
+		r.MoveTo(left, canvasBox.Top)
+		r.LineTo(right, canvasBox.Top)
+		r.LineTo(right, canvasBox.Bottom)
+		r.LineTo(left, canvasBox.Bottom)
+		r.Close()
+		r.Fill()
+	}
+	for _, t := range s.reboots {
+		if !inRange(t) {
+			continue
+		}
+		r.SetStrokeColor(rebootColor)
+		r.SetStrokeWidth(2)
+		r.SetStrokeDashArray([]float64{6, 4})
+		r.MoveTo(x(t), canvasBox.Top)
+		r.LineTo(x(t), canvasBox.Bottom)
+		r.Stroke()
+	}
+	r.SetStrokeDashArray(nil)
+}
+
+// FromMatrix 将 Prometheus 范围查询结果转换为曲线，name 为每条曲线命名
+func FromMatrix(matrix model.Matrix, name func(model.Metric) string) []Series {
+	series := make([]Series, 0, len(matrix))
+	for _, stream := range matrix {
+		s := Series{Name: name(stream.Metric)}
+		for _, v := range stream.Values {
+			s.Points = append(s.Points, Point{Time: v.Timestamp.Time(), Value: float64(v.Value)})
+		}
+		series = append(series, s)
+	}
+	return series
+}
@@ -0,0 +1,677 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/prometheus"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/scrape"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/store"
+	"github.com/prometheus/common/model"
+)
+
+type Config struct {
+	PrometheusURL string
+	// FallbackURL 是可选的长期存储查询端点（如 Thanos Query），用于本地保留期外的历史数据
+	FallbackURL  string
+	BotToken     string
+	PageSize     int
+	StorePath    string
+	PollInterval time.Duration
+	// MenuTimeout 是耗时菜单后台加载的最长等待时间
+	MenuTimeout time.Duration
+	// PageCacheTTL 内重复打开总览等耗时菜单时直接使用缓存；超过 TTL 但未超过 PageCacheMaxStale 时
+	// 先显示缓存内容再在后台刷新。PageCacheMaxStale 为 0 时不缓存
+	PageCacheTTL      time.Duration
+	PageCacheMaxStale time.Duration
+	// MenuExpiry 是菜单消息无操作后过期的时间，过期后按钮被移除，为 0 时不过期
+	MenuExpiry   time.Duration
+	TemplatesDir string
+	// Theme 为图标主题名称（default 或 plain），ThemeOverrides 用于单独覆盖某些图标
+	Theme          string
+	ThemeOverrides string
+	// TrafficDirections 是流量方向的名称和顺序，例如 "out=出站,in=入站"，为空时为上传/下载
+	TrafficDirections string
+	// MaxConcurrency 是同时发往 Prometheus 的查询总数上限，MaxConcurrencyPerChat 是单个聊天的上限
+	MaxConcurrency        int
+	MaxConcurrencyPerChat int
+	// MaxQuerySeries 是分组汇总和 /query 允许涉及的最大序列数，超过时拒绝查询，为 0 时不检查
+	MaxQuerySeries int
+	// ResourceWindows 是各视图（overview、detail、group、export）计算 CPU 使用率的窗口，未设置的视图为 5m
+	ResourceWindows map[string]time.Duration
+	// NetworkRateWindows 是实例详情中同时显示的网络速率窗口，默认为 1m、5m 和 1h
+	NetworkRateWindows []time.Duration
+	// SlowQueryThreshold 是慢查询的判断阈值，超过的查询会记录到管理员的慢查询页面并在消息末尾标注，为 0 时不记录
+	SlowQueryThreshold time.Duration
+	// StaleThreshold 是指标数据过期的判断阈值，为 0 时不检查
+	StaleThreshold time.Duration
+	// StaleNotify 为 true 时在实例指标过期时记录事件并发送通知
+	StaleNotify bool
+	// UPSMinRuntime 是 UPS 电池供电时剩余时间的告警阈值，为 0 时不检查 UPS
+	UPSMinRuntime time.Duration
+	// ProbePorts 是连通性测试时除 exporter 端口外额外测试的 TCP 端口
+	ProbePorts []string
+	// ScrapeFallbackTargets 是 Prometheus 不可用时直接抓取的 node_exporter 地址（host:port 或完整 URL），
+	// 为空时不提供直接抓取
+	ScrapeFallbackTargets []string
+	// AlertChatIDs 是接收事件通知的聊天ID列表
+	AlertChatIDs []int64
+	// AllowedChatIDs 是拥有完整访问权限的聊天ID列表，为空时不限制。
+	// 不在列表中的聊天只能通过 /share 分享链接获得只读访问
+	AllowedChatIDs []int64
+	// AdminChatIDs 是可以使用 /broadcast 等管理命令的聊天ID列表
+	AdminChatIDs []int64
+	// PublicStatusChatIDs 是公开的群组或频道，其中任何人都可以使用简化的 /status（只显示实例别名和在线状态），
+	// 其他功能不可用
+	PublicStatusChatIDs []int64
+	// MonthlyReportChatIDs 是每月 1 日接收上月 PDF 报告的聊天ID列表，为空时不自动发送
+	MonthlyReportChatIDs []int64
+	// GeoIPCountryDB 和 GeoIPASNDB 是 MaxMind 格式（例如 GeoLite2）的国家和 ASN 数据库路径，
+	// 设置后在实例列表和详情中显示国家旗帜和运营商，并可按国家或 ASN 分组
+	GeoIPCountryDB string
+	GeoIPASNDB     string
+	// ReportFont 是 PDF 报告使用的 UTF-8 TrueType 字体路径，为空时使用内置字体（不支持中文）
+	ReportFont string
+	// PrivacyMode 是非管理员聊天默认的隐私模式：off、mask（隐藏 IP 后两段和端口）或 alias（用别名代替实例地址）
+	PrivacyMode string
+	// PrivacyAliasLabel 是 alias 隐私模式下作为实例别名的标签
+	PrivacyAliasLabel string
+	// AlertBatchWindow 内的多个事件合并为一条汇总消息发送，为 0 时逐条发送
+	AlertBatchWindow time.Duration
+	// AlertBehaviors 是各严重程度告警的通知方式，例如 info=silent,critical=pin，未设置的严重程度为 normal
+	AlertBehaviors map[store.Severity]string
+	// NotifyConfig 是外部通知渠道（webhook、Discord、Slack、邮件）路由配置文件的路径，为空时只发送 Telegram 通知
+	NotifyConfig string
+	// ShortcutsFile 是自定义菜单按钮和命令别名配置文件的路径
+	ShortcutsFile string
+	// GroupLabels 是分组汇总可用的标签，第一个为默认标签
+	GroupLabels []string
+	// DisplayLabels 是按顺序显示在实例列表按钮和详情页中的自定义标签，为空时详情页只显示 info 标签
+	DisplayLabels []DisplayLabel
+	// PushgatewayURL 不为空时每隔 PushInterval 将机器人自身的心跳指标推送到 Pushgateway
+	PushgatewayURL string
+	PushInterval   time.Duration
+	// MetricsAddr 不为空时在该地址通过 /metrics 暴露心跳指标和机器人计算的实例指标（到期天数、健康分等），例如 ":9099"
+	MetricsAddr string
+	// Locale 是无法得知用户语言时使用的默认语言，用于格式化数字和日期
+	Locale string
+	// FilesystemFilter 决定哪些文件系统计入磁盘统计，环境变量设为空字符串表示不过滤
+	FilesystemFilter prometheus.FilesystemFilter
+	// DirectorySizeMetric 和 DirectorySizeLabel 是 textfile 收集器上报目录大小的指标名称和目录标签，为空时使用默认值
+	DirectorySizeMetric string
+	DirectorySizeLabel  string
+	// DedupeLabel 是主机名标签（例如 nodename），设置后同一主机通过多个端口或 job 抓取的实例合并为一个，为空时不合并
+	DedupeLabel string
+	// Thresholds 是使用率告警阈值，键为指标名称，例如 fd、inode、steal（百分比）、systemd（失败单元数）、
+	// clock（时钟偏差毫秒数）或 neterr（5 分钟内网卡错误包数）。
+	// 默认在有 systemd 单元失败、时钟偏差超过 500ms 或网卡出现错误包时告警，环境变量设为空字符串表示不告警
+	Thresholds map[string]float64
+	// SystemdServices 是服务页面中单独显示状态的关键服务，例如 nginx.service
+	SystemdServices []string
+	// TelemetryEnabled 为 true 时在本地统计各功能的使用次数（不含聊天信息），显示在管理员的使用统计页面，不会发送到任何外部服务
+	TelemetryEnabled bool
+	// Features 是功能开关，例如 enable_charts=false,enable_query_command=false，用于在某个部署中关闭有风险或开销较大的功能，
+	// 未设置的功能默认开启，通过 Enabled 判断
+	Features map[string]bool
+	// BackupInterval 是向管理员发送加密备份的间隔，为 0 时不自动备份；BackupPassphrase 是加密备份的口令
+	BackupInterval   time.Duration
+	BackupPassphrase string
+	// PrintConfig 为 true 时（--print-config）输出生效的配置后退出，用于排查部署问题
+	PrintConfig bool
+}
+
+// Load 从命令行参数和环境变量读取配置，未设置的可选项使用默认值
+func Load(args []string) (*Config, error) {
+	cfg := &Config{
+		PageSize:          5,
+		StorePath:         "data/store.json",
+		PollInterval:      time.Minute,
+		MenuTimeout:       30 * time.Second,
+		PageCacheTTL:      15 * time.Second,
+		PageCacheMaxStale: 5 * time.Minute,
+		TemplatesDir:      "templates",
+
+		MaxConcurrency:        4,
+		MaxConcurrencyPerChat: 2,
+		MaxQuerySeries:        10000,
+		StaleThreshold:        3 * time.Minute,
+		SlowQueryThreshold:    2 * time.Second,
+		UPSMinRuntime:         10 * time.Minute,
+		ProbePorts:            []string{"22"},
+		NetworkRateWindows:    []time.Duration{time.Minute, 5 * time.Minute, time.Hour},
+		AlertBatchWindow:      15 * time.Second,
+		PushInterval:          time.Minute,
+		GroupLabels:           []string{"provider", "region", "dc"},
+		Thresholds:            map[string]float64{prometheus.UsageFailedUnits: 1, prometheus.UsageClockDrift: 500, prometheus.UsageNetworkErrors: 1},
+		PrivacyMode:           "off",
+		PrivacyAliasLabel:     "alias",
+		Locale:                "zh",
+		FilesystemFilter:      prometheus.DefaultFilesystemFilter,
+	}
+
+	src, printConfig, err := parseFlags(args)
+	if err != nil {
+		return nil, err
+	}
+	cfg.PrintConfig = printConfig
+	if cfg.PrometheusURL, err = src.secret("PROMETHEUS_URL"); err != nil {
+		return nil, err
+	}
+	if cfg.PrometheusURL == "" {
+		return nil, fmt.Errorf("PROMETHEUS_URL environment variable not set")
+	}
+	if cfg.FallbackURL, err = src.secret("PROMETHEUS_FALLBACK_URL"); err != nil {
+		return nil, err
+	}
+	if cfg.BotToken, err = src.secret("BOT_TOKEN"); err != nil {
+		return nil, err
+	}
+	if cfg.BotToken == "" {
+		return nil, fmt.Errorf("BOT_TOKEN environment variable not set")
+	}
+
+	if v := src.getenv("PAGE_SIZE"); v != "" {
+		pageSize, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("PAGE_SIZE is invalid %v", err)
+		}
+		cfg.PageSize = pageSize
+	}
+	if v := src.getenv("STORE_PATH"); v != "" {
+		cfg.StorePath = v
+	}
+	if v := src.getenv("TEMPLATES_DIR"); v != "" {
+		cfg.TemplatesDir = v
+	}
+	cfg.NotifyConfig = src.getenv("NOTIFY_CONFIG")
+	cfg.ShortcutsFile = src.getenv("SHORTCUTS_FILE")
+	if v := src.getenv("GROUP_LABELS"); v != "" {
+		cfg.GroupLabels = nil
+		for _, label := range strings.Split(v, ",") {
+			if label = strings.TrimSpace(label); label != "" {
+				cfg.GroupLabels = append(cfg.GroupLabels, label)
+			}
+		}
+		if len(cfg.GroupLabels) == 0 {
+			return nil, fmt.Errorf("GROUP_LABELS is invalid %v", v)
+		}
+	}
+	if v := src.getenv("DISPLAY_LABELS"); v != "" {
+		labels, err := parseDisplayLabels(v)
+		if err != nil {
+			return nil, fmt.Errorf("DISPLAY_LABELS is invalid %v", err)
+		}
+		cfg.DisplayLabels = labels
+	}
+	if cfg.PushgatewayURL, err = src.secret("PUSHGATEWAY_URL"); err != nil {
+		return nil, err
+	}
+	cfg.MetricsAddr = src.getenv("METRICS_ADDR")
+	if v := src.getenv("PUSH_INTERVAL"); v != "" {
+		interval, err := time.ParseDuration(v)
+		if err != nil || interval <= 0 {
+			return nil, fmt.Errorf("PUSH_INTERVAL is invalid %v", v)
+		}
+		cfg.PushInterval = interval
+	}
+	if v := src.getenv("LOCALE"); v != "" {
+		cfg.Locale = v
+	}
+	cfg.Theme = src.getenv("THEME")
+	cfg.ThemeOverrides = src.getenv("THEME_OVERRIDES")
+	cfg.TrafficDirections = src.getenv("TRAFFIC_DIRECTIONS")
+	if v := src.getenv("POLL_INTERVAL"); v != "" {
+		interval, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("POLL_INTERVAL is invalid %v", err)
+		}
+		cfg.PollInterval = interval
+	}
+	if v := src.getenv("MENU_TIMEOUT"); v != "" {
+		timeout, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("MENU_TIMEOUT is invalid %v", err)
+		}
+		cfg.MenuTimeout = timeout
+	}
+	if v := src.getenv("PAGE_CACHE_TTL"); v != "" {
+		ttl, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("PAGE_CACHE_TTL is invalid %v", err)
+		}
+		cfg.PageCacheTTL = ttl
+	}
+	if v := src.getenv("PAGE_CACHE_MAX_STALE"); v != "" {
+		maxStale, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("PAGE_CACHE_MAX_STALE is invalid %v", err)
+		}
+		cfg.PageCacheMaxStale = maxStale
+	}
+	if v := src.getenv("MENU_EXPIRY"); v != "" {
+		expiry, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("MENU_EXPIRY is invalid %v", err)
+		}
+		cfg.MenuExpiry = expiry
+	}
+	if v := src.getenv("PROMETHEUS_MAX_CONCURRENCY"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("PROMETHEUS_MAX_CONCURRENCY is invalid %v", err)
+		}
+		cfg.MaxConcurrency = n
+	}
+	if v := src.getenv("PROMETHEUS_MAX_CONCURRENCY_PER_CHAT"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("PROMETHEUS_MAX_CONCURRENCY_PER_CHAT is invalid %v", err)
+		}
+		cfg.MaxConcurrencyPerChat = n
+	}
+	if v := src.getenv("MAX_QUERY_SERIES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("MAX_QUERY_SERIES is invalid %v", v)
+		}
+		cfg.MaxQuerySeries = n
+	}
+	for name, field := range map[string]*string{
+		"FS_TYPES_INCLUDE":    &cfg.FilesystemFilter.IncludeFSTypes,
+		"FS_TYPES_EXCLUDE":    &cfg.FilesystemFilter.ExcludeFSTypes,
+		"MOUNTPOINTS_EXCLUDE": &cfg.FilesystemFilter.ExcludeMountpoints,
+	} {
+		v, ok := src.lookup(name)
+		if !ok {
+			continue
+		}
+		// PromQL 与 Go 使用相同的 RE2 正则语法，启动时即可发现无效的表达式
+		if _, err := regexp.Compile(v); err != nil {
+			return nil, fmt.Errorf("%s is invalid %v", name, err)
+		}
+		*field = v
+	}
+	if v := src.getenv("STALE_THRESHOLD"); v != "" {
+		threshold, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("STALE_THRESHOLD is invalid %v", err)
+		}
+		cfg.StaleThreshold = threshold
+	}
+	if v := src.getenv("RESOURCE_WINDOWS"); v != "" {
+		windows, err := parseResourceWindows(v)
+		if err != nil {
+			return nil, fmt.Errorf("RESOURCE_WINDOWS is invalid %v", err)
+		}
+		cfg.ResourceWindows = windows
+	}
+	if v := src.getenv("NETWORK_RATE_WINDOWS"); v != "" {
+		cfg.NetworkRateWindows = nil
+		for _, field := range strings.Split(v, ",") {
+			field = strings.TrimSpace(field)
+			if field == "" {
+				continue
+			}
+			window, err := time.ParseDuration(field)
+			if err != nil || window <= 0 {
+				return nil, fmt.Errorf("NETWORK_RATE_WINDOWS is invalid %v", field)
+			}
+			cfg.NetworkRateWindows = append(cfg.NetworkRateWindows, window)
+		}
+	}
+	if v := src.getenv("SLOW_QUERY_THRESHOLD"); v != "" {
+		threshold, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("SLOW_QUERY_THRESHOLD is invalid %v", err)
+		}
+		cfg.SlowQueryThreshold = threshold
+	}
+	if v := src.getenv("UPS_MIN_RUNTIME"); v != "" {
+		runtime, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("UPS_MIN_RUNTIME is invalid %v", err)
+		}
+		cfg.UPSMinRuntime = runtime
+	}
+	// PROBE_PORTS 设为空字符串表示只测试 exporter 端口
+	if v, ok := src.lookup("PROBE_PORTS"); ok {
+		cfg.ProbePorts = nil
+		for _, field := range strings.Split(v, ",") {
+			field = strings.TrimSpace(field)
+			if field == "" {
+				continue
+			}
+			if port, err := strconv.Atoi(field); err != nil || port < 1 || port > 65535 {
+				return nil, fmt.Errorf("PROBE_PORTS is invalid %v", field)
+			}
+			cfg.ProbePorts = append(cfg.ProbePorts, field)
+		}
+	}
+	if v := src.getenv("SCRAPE_FALLBACK_TARGETS"); v != "" {
+		for _, field := range strings.Split(v, ",") {
+			target := strings.TrimSpace(field)
+			if target == "" {
+				continue
+			}
+			if u, err := url.Parse(scrape.URL(target)); err != nil || u.Host == "" {
+				return nil, fmt.Errorf("SCRAPE_FALLBACK_TARGETS is invalid %v", target)
+			}
+			cfg.ScrapeFallbackTargets = append(cfg.ScrapeFallbackTargets, target)
+		}
+	}
+	if v := src.getenv("STALE_NOTIFY"); v != "" {
+		notify, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("STALE_NOTIFY is invalid %v", err)
+		}
+		cfg.StaleNotify = notify
+	}
+	if v := src.getenv("ALERT_CHAT_IDS"); v != "" {
+		for _, field := range strings.Split(v, ",") {
+			chatID, err := strconv.ParseInt(strings.TrimSpace(field), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("ALERT_CHAT_IDS is invalid %v", err)
+			}
+			cfg.AlertChatIDs = append(cfg.AlertChatIDs, chatID)
+		}
+	}
+	if v := src.getenv("ALLOWED_CHAT_IDS"); v != "" {
+		for _, field := range strings.Split(v, ",") {
+			chatID, err := strconv.ParseInt(strings.TrimSpace(field), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("ALLOWED_CHAT_IDS is invalid %v", err)
+			}
+			cfg.AllowedChatIDs = append(cfg.AllowedChatIDs, chatID)
+		}
+	}
+	if v := src.getenv("ADMIN_CHAT_IDS"); v != "" {
+		for _, field := range strings.Split(v, ",") {
+			chatID, err := strconv.ParseInt(strings.TrimSpace(field), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("ADMIN_CHAT_IDS is invalid %v", err)
+			}
+			cfg.AdminChatIDs = append(cfg.AdminChatIDs, chatID)
+		}
+	}
+	if v := src.getenv("PUBLIC_STATUS_CHAT_IDS"); v != "" {
+		for _, field := range strings.Split(v, ",") {
+			chatID, err := strconv.ParseInt(strings.TrimSpace(field), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("PUBLIC_STATUS_CHAT_IDS is invalid %v", err)
+			}
+			cfg.PublicStatusChatIDs = append(cfg.PublicStatusChatIDs, chatID)
+		}
+	}
+	if v := src.getenv("MONTHLY_REPORT_CHAT_IDS"); v != "" {
+		for _, field := range strings.Split(v, ",") {
+			chatID, err := strconv.ParseInt(strings.TrimSpace(field), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("MONTHLY_REPORT_CHAT_IDS is invalid %v", err)
+			}
+			cfg.MonthlyReportChatIDs = append(cfg.MonthlyReportChatIDs, chatID)
+		}
+	}
+	if v := src.getenv("REPORT_FONT"); v != "" {
+		if _, err := os.Stat(v); err != nil {
+			return nil, fmt.Errorf("REPORT_FONT is invalid %v", err)
+		}
+		cfg.ReportFont = v
+	}
+	if v := src.getenv("GEOIP_COUNTRY_DB"); v != "" {
+		if _, err := os.Stat(v); err != nil {
+			return nil, fmt.Errorf("GEOIP_COUNTRY_DB is invalid %v", err)
+		}
+		cfg.GeoIPCountryDB = v
+	}
+	if v := src.getenv("GEOIP_ASN_DB"); v != "" {
+		if _, err := os.Stat(v); err != nil {
+			return nil, fmt.Errorf("GEOIP_ASN_DB is invalid %v", err)
+		}
+		cfg.GeoIPASNDB = v
+	}
+	if v := src.getenv("PRIVACY_MODE"); v != "" {
+		switch v {
+		case "off", "mask", "alias":
+			cfg.PrivacyMode = v
+		default:
+			return nil, fmt.Errorf("PRIVACY_MODE is invalid %q, expected off, mask or alias", v)
+		}
+	}
+	if v := src.getenv("PRIVACY_ALIAS_LABEL"); v != "" {
+		cfg.PrivacyAliasLabel = v
+	}
+	if v := src.getenv("ALERT_BATCH_WINDOW"); v != "" {
+		window, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("ALERT_BATCH_WINDOW is invalid %v", err)
+		}
+		cfg.AlertBatchWindow = window
+	}
+	if v := src.getenv("ALERT_SEVERITY_BEHAVIOR"); v != "" {
+		behaviors, err := parseAlertBehaviors(v)
+		if err != nil {
+			return nil, fmt.Errorf("ALERT_SEVERITY_BEHAVIOR is invalid %v", err)
+		}
+		cfg.AlertBehaviors = behaviors
+	}
+	cfg.DirectorySizeMetric = src.getenv("DIRECTORY_SIZE_METRIC")
+	cfg.DirectorySizeLabel = src.getenv("DIRECTORY_SIZE_LABEL")
+	cfg.DedupeLabel = src.getenv("DEDUPE_LABEL")
+	if v := src.getenv("SYSTEMD_SERVICES"); v != "" {
+		for _, field := range strings.Split(v, ",") {
+			if name := strings.TrimSpace(field); name != "" {
+				cfg.SystemdServices = append(cfg.SystemdServices, name)
+			}
+		}
+	}
+	// THRESHOLDS 设为空字符串表示关闭所有阈值告警
+	if v, ok := src.lookup("THRESHOLDS"); ok {
+		thresholds, err := parseThresholds(v)
+		if err != nil {
+			return nil, fmt.Errorf("THRESHOLDS is invalid %v", err)
+		}
+		cfg.Thresholds = thresholds
+	}
+	if v := src.getenv("TELEMETRY_ENABLED"); v != "" {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("TELEMETRY_ENABLED is invalid %v", err)
+		}
+		cfg.TelemetryEnabled = enabled
+	}
+	if v := src.getenv("FEATURE_FLAGS"); v != "" {
+		features, err := parseFeatureFlags(v)
+		if err != nil {
+			return nil, fmt.Errorf("FEATURE_FLAGS is invalid %v", err)
+		}
+		cfg.Features = features
+	}
+	if cfg.BackupPassphrase, err = src.secret("BACKUP_PASSPHRASE"); err != nil {
+		return nil, err
+	}
+	if v := src.getenv("BACKUP_INTERVAL"); v != "" {
+		interval, err := time.ParseDuration(v)
+		if err != nil || interval < 0 {
+			return nil, fmt.Errorf("BACKUP_INTERVAL is invalid %v", v)
+		}
+		cfg.BackupInterval = interval
+	}
+	if cfg.BackupInterval > 0 && cfg.BackupPassphrase == "" {
+		return nil, fmt.Errorf("BACKUP_PASSPHRASE must be set when BACKUP_INTERVAL is set")
+	}
+	if cfg.BackupInterval > 0 && len(cfg.AdminChatIDs) == 0 {
+		return nil, fmt.Errorf("ADMIN_CHAT_IDS must be set when BACKUP_INTERVAL is set")
+	}
+
+	return cfg, nil
+}
+
+// parseResourceWindows 解析 "overview=10m,detail=5m" 格式的资源窗口配置，
+// 不带视图名的单个时长（例如 "10m"）应用于所有视图
+func parseResourceWindows(v string) (map[string]time.Duration, error) {
+	windows := make(map[string]time.Duration)
+	for _, field := range strings.Split(v, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		view, value, ok := strings.Cut(field, "=")
+		if !ok {
+			view, value = "", field
+		} else if !prometheus.IsResourceView(view) {
+			return nil, fmt.Errorf("unknown view %q", view)
+		}
+		window, err := time.ParseDuration(value)
+		if err != nil || window < time.Minute {
+			return nil, fmt.Errorf("window for %q must be at least 1m, got %q", field, value)
+		}
+		if view != "" {
+			windows[view] = window
+			continue
+		}
+		for _, name := range prometheus.ResourceViews {
+			if _, set := windows[name]; !set {
+				windows[name] = window
+			}
+		}
+	}
+	return windows, nil
+}
+
+// DisplayLabel 是一个需要显示的实例标签及其显示名称
+type DisplayLabel struct {
+	Name  string
+	Title string
+}
+
+// parseDisplayLabels 解析 "owner=负责人,dc,asn" 格式的标签列表，未指定显示名称时使用标签名
+func parseDisplayLabels(v string) ([]DisplayLabel, error) {
+	var labels []DisplayLabel
+	for _, field := range strings.Split(v, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		name, title, _ := strings.Cut(field, "=")
+		name, title = strings.TrimSpace(name), strings.TrimSpace(title)
+		if !model.LabelName(name).IsValid() {
+			return nil, fmt.Errorf("invalid label name %q", name)
+		}
+		if title == "" {
+			title = name
+		}
+		labels = append(labels, DisplayLabel{Name: name, Title: title})
+	}
+	if len(labels) == 0 {
+		return nil, fmt.Errorf("no labels in %q", v)
+	}
+	return labels, nil
+}
+
+// 告警的通知方式：静默发送（不发出提醒）、普通发送，或发送后置顶到聊天
+const (
+	AlertSilent = "silent"
+	AlertNormal = "normal"
+	AlertPin    = "pin"
+)
+
+// parseAlertBehaviors 解析 <严重程度>=<通知方式>，多项之间用逗号分隔
+func parseAlertBehaviors(v string) (map[store.Severity]string, error) {
+	behaviors := make(map[store.Severity]string)
+	for _, field := range strings.Split(v, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		name, behavior, ok := strings.Cut(field, "=")
+		if !ok {
+			return nil, fmt.Errorf("expected severity=behavior, got %q", field)
+		}
+		severity := store.Severity(strings.TrimSpace(name))
+		if !slices.Contains(store.Severities, severity) {
+			return nil, fmt.Errorf("unknown severity %q, expected info, warning or critical", name)
+		}
+		switch behavior = strings.TrimSpace(behavior); behavior {
+		case AlertSilent, AlertNormal, AlertPin:
+			behaviors[severity] = behavior
+		default:
+			return nil, fmt.Errorf("unknown behavior %q for %s, expected silent, normal or pin", behavior, severity)
+		}
+	}
+	return behaviors, nil
+}
+
+// parseThresholds 解析 "fd=90,inode=85" 格式的阈值配置
+func parseThresholds(v string) (map[string]float64, error) {
+	thresholds := make(map[string]float64)
+	if strings.TrimSpace(v) == "" {
+		return thresholds, nil
+	}
+	for _, field := range strings.Split(v, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(field), "=")
+		if !ok {
+			return nil, fmt.Errorf("expected name=percent, got %q", field)
+		}
+		if _, known := prometheus.UsageLabel(name); !known {
+			return nil, fmt.Errorf("unknown metric %q", name)
+		}
+		limit, err := strconv.ParseFloat(value, 64)
+		if err != nil || limit <= 0 || (prometheus.UsageIsPercent(name) && limit > 100) {
+			return nil, fmt.Errorf("threshold for %s must be positive (at most 100 for percentages), got %q", name, value)
+		}
+		thresholds[name] = limit
+	}
+	return thresholds, nil
+}
+
+// 可以通过 FEATURE_FLAGS 关闭的功能
+const (
+	// FeatureCharts 控制图表图片：实例详情中的历史图表、/charts 和定时任务的图表
+	FeatureCharts = "enable_charts"
+	// FeatureQueryCommand 控制可以执行任意 PromQL 的 /query
+	FeatureQueryCommand = "enable_query_command"
+	// FeatureWebhooks 控制通知路由中通过 HTTP 发送的渠道（webhook、Discord、Slack）
+	FeatureWebhooks = "enable_webhooks"
+	// FeatureK8s 为 Kubernetes 相关功能预留。可以在 FEATURE_FLAGS 中设置，但目前没有受其控制的功能，设置后不影响任何行为
+	FeatureK8s = "enable_k8s"
+)
+
+// Features 是所有功能开关
+var Features = []string{FeatureCharts, FeatureQueryCommand, FeatureWebhooks, FeatureK8s}
+
+// Enabled 判断功能是否开启，未在 FEATURE_FLAGS 中设置的功能默认开启
+func (c *Config) Enabled(feature string) bool {
+	enabled, ok := c.Features[feature]
+	return !ok || enabled
+}
+
+// parseFeatureFlags 解析 "enable_charts=false,enable_webhooks=0" 格式的功能开关
+func parseFeatureFlags(v string) (map[string]bool, error) {
+	features := make(map[string]bool)
+	for _, field := range strings.Split(v, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return nil, fmt.Errorf("expected feature=true|false, got %q", field)
+		}
+		name = strings.TrimSpace(name)
+		if !slices.Contains(Features, name) {
+			return nil, fmt.Errorf("unknown feature %q, expected one of %s", name, strings.Join(Features, ", "))
+		}
+		enabled, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for %s: %v", name, err)
+		}
+		features[name] = enabled
+	}
+	return features, nil
+}
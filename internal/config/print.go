@@ -0,0 +1,53 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"reflect"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/scrape"
+)
+
+// Print 按字段输出生效的配置，令牌和口令只显示是否设置，地址中的密码被隐藏
+func (c *Config) Print(w io.Writer) error {
+	v := reflect.ValueOf(c).Elem()
+	t := v.Type()
+	for i := range t.NumField() {
+		name := t.Field(i).Name
+		if name == "PrintConfig" {
+			continue
+		}
+		value := fmt.Sprintf("%+v", v.Field(i).Interface())
+		switch name {
+		case "BotToken", "BackupPassphrase":
+			if value != "" {
+				value = "******"
+			}
+		case "PrometheusURL", "FallbackURL", "PushgatewayURL":
+			value = redactURL(value)
+		case "ScrapeFallbackTargets":
+			var targets []string
+			for _, target := range c.ScrapeFallbackTargets {
+				targets = append(targets, redactURL(scrape.URL(target)))
+			}
+			value = fmt.Sprintf("%+v", targets)
+		}
+		if _, err := fmt.Fprintf(w, "%s: %s\n", name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// redactURL 隐藏地址中基本认证的密码，无法解析的地址整体隐藏
+func redactURL(v string) string {
+	if v == "" {
+		return v
+	}
+	u, err := url.Parse(v)
+	if err != nil {
+		return "******"
+	}
+	return u.Redacted()
+}
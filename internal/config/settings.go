@@ -0,0 +1,103 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// settingNames 是所有可以通过环境变量设置的配置项。每一项都有对应的命令行参数，
+// 例如 BOT_TOKEN 对应 --bot-token，命令行参数优先于环境变量。敏感配置的 *_FILE 也有对应的命令行参数，
+// 与直接设置的值属于同一来源，见 secret
+var settingNames = []string{
+	"PROMETHEUS_URL", "PROMETHEUS_FALLBACK_URL", "BOT_TOKEN", "PAGE_SIZE", "STORE_PATH", "TEMPLATES_DIR",
+	"NOTIFY_CONFIG", "SHORTCUTS_FILE", "GROUP_LABELS", "DISPLAY_LABELS", "PUSHGATEWAY_URL", "METRICS_ADDR", "TRAFFIC_DIRECTIONS",
+	"PUSH_INTERVAL", "LOCALE", "THEME", "THEME_OVERRIDES", "POLL_INTERVAL", "MENU_TIMEOUT", "PAGE_CACHE_TTL",
+	"PAGE_CACHE_MAX_STALE", "MENU_EXPIRY", "PROMETHEUS_MAX_CONCURRENCY", "PROMETHEUS_MAX_CONCURRENCY_PER_CHAT",
+	"MAX_QUERY_SERIES", "FS_TYPES_INCLUDE", "FS_TYPES_EXCLUDE", "MOUNTPOINTS_EXCLUDE", "STALE_THRESHOLD",
+	"RESOURCE_WINDOWS", "NETWORK_RATE_WINDOWS", "SLOW_QUERY_THRESHOLD", "UPS_MIN_RUNTIME", "PROBE_PORTS", "SCRAPE_FALLBACK_TARGETS", "STALE_NOTIFY",
+	"ALERT_CHAT_IDS", "ALLOWED_CHAT_IDS", "ADMIN_CHAT_IDS", "PUBLIC_STATUS_CHAT_IDS", "MONTHLY_REPORT_CHAT_IDS", "REPORT_FONT",
+	"GEOIP_COUNTRY_DB", "GEOIP_ASN_DB", "PRIVACY_MODE", "PRIVACY_ALIAS_LABEL", "ALERT_BATCH_WINDOW", "ALERT_SEVERITY_BEHAVIOR",
+	"DIRECTORY_SIZE_METRIC", "DIRECTORY_SIZE_LABEL", "DEDUPE_LABEL", "SYSTEMD_SERVICES", "THRESHOLDS", "TELEMETRY_ENABLED",
+	"FEATURE_FLAGS", "BACKUP_INTERVAL", "BACKUP_PASSPHRASE",
+}
+
+// secretNames 是敏感配置，可以通过 <name>_FILE 从文件读取（Docker/Kubernetes secrets 的挂载方式），
+// 避免令牌以明文出现在环境变量和 compose 文件中。地址中可以包含基本认证的用户名和密码
+var secretNames = []string{"PROMETHEUS_URL", "PROMETHEUS_FALLBACK_URL", "BOT_TOKEN", "PUSHGATEWAY_URL", "BACKUP_PASSPHRASE"}
+
+// source 按命令行参数、环境变量的顺序查找配置项
+type source struct {
+	flags map[string]*string
+	set   map[string]bool
+}
+
+// flagName 返回配置项对应的命令行参数名，例如 BOT_TOKEN 对应 bot-token
+func flagName(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, "_", "-"))
+}
+
+// parseFlags 解析命令行参数，返回配置来源和是否指定了 --print-config
+func parseFlags(args []string) (source, bool, error) {
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	src := source{flags: make(map[string]*string), set: make(map[string]bool)}
+	for _, name := range settingNames {
+		src.flags[name] = fs.String(flagName(name), "", "同环境变量 "+name)
+	}
+	for _, name := range secretNames {
+		src.flags[name+"_FILE"] = fs.String(flagName(name+"_FILE"), "", "从文件读取 "+name)
+	}
+	printConfig := fs.Bool("print-config", false, "输出生效的配置（隐藏敏感信息）后退出")
+	if err := fs.Parse(args); err != nil {
+		return source{}, false, err
+	}
+	if fs.NArg() > 0 {
+		return source{}, false, fmt.Errorf("unexpected argument %s", fs.Arg(0))
+	}
+	fs.Visit(func(f *flag.Flag) {
+		for name := range src.flags {
+			if flagName(name) == f.Name {
+				src.set[name] = true
+			}
+		}
+	})
+	return src, *printConfig, nil
+}
+
+// lookup 返回配置项的值，命令行参数优先于环境变量
+func (s source) lookup(name string) (string, bool) {
+	if s.set[name] {
+		return *s.flags[name], true
+	}
+	return os.LookupEnv(name)
+}
+
+func (s source) getenv(name string) string {
+	v, _ := s.lookup(name)
+	return v
+}
+
+// secret 读取敏感配置，可以直接设置，也可以通过 <name>_FILE 从文件读取。
+// 按命令行参数、环境变量的顺序查找，--bot-token-file 这类参数与 --bot-token 同属命令行参数；
+// 同一来源中同时设置了 <name> 和 <name>_FILE 时返回错误
+func (s source) secret(name string) (string, error) {
+	value, path := *s.flags[name], *s.flags[name+"_FILE"]
+	valueSet, pathSet := s.set[name], s.set[name+"_FILE"]
+	if !valueSet && !pathSet {
+		value, path = os.Getenv(name), os.Getenv(name+"_FILE")
+		valueSet, pathSet = value != "", path != ""
+	}
+	if valueSet && pathSet {
+		return "", fmt.Errorf("%s and %s_FILE are both set", name, name)
+	}
+	if !pathSet {
+		return value, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("%s_FILE is invalid %v", name, err)
+	}
+	// secret 文件通常以换行结尾
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
@@ -0,0 +1,157 @@
+package geo
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/utils"
+	"github.com/oschwald/geoip2-golang"
+)
+
+// resolveTimeout 限制解析实例主机名的时间，避免拖慢菜单页面
+const resolveTimeout = 2 * time.Second
+
+// cacheTTL 是查询结果的缓存时间，实例地址很少变化
+const cacheTTL = time.Hour
+
+// Info 是实例 IP 所在的国家和网络运营商
+type Info struct {
+	// Country 是 ISO 3166 两位国家代码，例如 DE
+	Country     string
+	CountryName string
+	ASN         uint
+	Org         string
+}
+
+// Flag 返回国家代码对应的旗帜 emoji，未知国家时为空
+func (i Info) Flag() string {
+	if len(i.Country) != 2 {
+		return ""
+	}
+	var flag strings.Builder
+	for _, c := range strings.ToUpper(i.Country) {
+		if c < 'A' || c > 'Z' {
+			return ""
+		}
+		flag.WriteRune(0x1F1E6 + c - 'A')
+	}
+	return flag.String()
+}
+
+// Provider 返回 "AS24940 Hetzner Online GmbH" 形式的运营商信息，没有 ASN 数据时为空
+func (i Info) Provider() string {
+	if i.ASN == 0 {
+		return ""
+	}
+	if i.Org == "" {
+		return fmt.Sprintf("AS%d", i.ASN)
+	}
+	return fmt.Sprintf("AS%d %s", i.ASN, i.Org)
+}
+
+type cacheEntry struct {
+	info    Info
+	ok      bool
+	expires time.Time
+}
+
+// Resolver 使用本地 MaxMind 格式（GeoLite2 等）数据库查询实例的国家和 ASN。
+// nil Resolver 表示未开启，所有查询都返回 false
+type Resolver struct {
+	country *geoip2.Reader
+	asn     *geoip2.Reader
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry
+}
+
+// Open 打开国家和 ASN 数据库，两者都可以为空；都为空时返回 nil
+func Open(countryPath, asnPath string) (*Resolver, error) {
+	if countryPath == "" && asnPath == "" {
+		return nil, nil
+	}
+	r := &Resolver{cache: make(map[string]cacheEntry)}
+	var err error
+	if countryPath != "" {
+		if r.country, err = geoip2.Open(countryPath); err != nil {
+			return nil, fmt.Errorf("Failed to open GeoIP country database: %v", err)
+		}
+	}
+	if asnPath != "" {
+		if r.asn, err = geoip2.Open(asnPath); err != nil {
+			r.Close()
+			return nil, fmt.Errorf("Failed to open GeoIP ASN database: %v", err)
+		}
+	}
+	return r, nil
+}
+
+// Close 关闭打开的数据库
+func (r *Resolver) Close() {
+	if r == nil {
+		return
+	}
+	if r.country != nil {
+		r.country.Close()
+	}
+	if r.asn != nil {
+		r.asn.Close()
+	}
+}
+
+// Lookup 查询 instance 标签（host:port 或 host）所指地址的国家和 ASN，
+// 主机名先解析为 IP。私有地址和无法解析的实例返回 false
+func (r *Resolver) Lookup(instance string) (Info, bool) {
+	if r == nil {
+		return Info{}, false
+	}
+	host, _ := utils.SplitInstance(instance)
+	now := time.Now()
+	r.mu.Lock()
+	entry, found := r.cache[host]
+	r.mu.Unlock()
+	if found && now.Before(entry.expires) {
+		return entry.info, entry.ok
+	}
+
+	info, ok := r.lookupHost(host)
+	r.mu.Lock()
+	r.cache[host] = cacheEntry{info: info, ok: ok, expires: now.Add(cacheTTL)}
+	r.mu.Unlock()
+	return info, ok
+}
+
+func (r *Resolver) lookupHost(host string) (Info, bool) {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+		defer cancel()
+		addrs, err := net.DefaultResolver.LookupIP(ctx, "ip", host)
+		if err != nil || len(addrs) == 0 {
+			return Info{}, false
+		}
+		ip = addrs[0]
+	}
+	if ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsUnspecified() {
+		return Info{}, false
+	}
+
+	var info Info
+	if r.country != nil {
+		if record, err := r.country.Country(ip); err == nil {
+			info.Country = record.Country.IsoCode
+			info.CountryName = record.Country.Names["en"]
+		}
+	}
+	if r.asn != nil {
+		if record, err := r.asn.ASN(ip); err == nil {
+			info.ASN = record.AutonomousSystemNumber
+			info.Org = record.AutonomousSystemOrganization
+		}
+	}
+	return info, info.Country != "" || info.ASN != 0
+}
@@ -0,0 +1,115 @@
+package heartbeat
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+// pushJob 是推送到 Pushgateway 时使用的 job 标签
+const pushJob = "prometheus_telegram_bot"
+
+// Heartbeat 记录机器人自身的运行状态，可推送到 Pushgateway 或通过 /metrics 暴露，
+// 以便在机器人停止工作时由外部告警发现
+type Heartbeat struct {
+	registry *prometheus.Registry
+
+	up              prometheus.Gauge
+	startTime       prometheus.Gauge
+	lastPoll        prometheus.Gauge
+	lastSuccessPoll prometheus.Gauge
+	pollFailures    prometheus.Counter
+	pushedTimestamp prometheus.Gauge
+
+	// lastPushFailedAt 用于限制推送失败日志的频率
+	lastPushFailedAt time.Time
+}
+
+func New() *Heartbeat {
+	h := &Heartbeat{
+		registry: prometheus.NewRegistry(),
+		up: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "telegram_bot_up",
+			Help: "Whether the bot process is running.",
+		}),
+		startTime: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "telegram_bot_start_time_seconds",
+			Help: "Unix time when the bot started.",
+		}),
+		lastPoll: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "telegram_bot_last_poll_timestamp_seconds",
+			Help: "Unix time of the last monitor poll, successful or not.",
+		}),
+		lastSuccessPoll: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "telegram_bot_last_successful_poll_timestamp_seconds",
+			Help: "Unix time of the last successful monitor poll.",
+		}),
+		pollFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "telegram_bot_poll_failures_total",
+			Help: "Number of failed monitor polls.",
+		}),
+		pushedTimestamp: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "telegram_bot_heartbeat_timestamp_seconds",
+			Help: "Unix time of the last heartbeat.",
+		}),
+	}
+	h.registry.MustRegister(h.up, h.startTime, h.lastPoll, h.lastSuccessPoll, h.pollFailures, h.pushedTimestamp)
+	h.up.Set(1)
+	h.startTime.SetToCurrentTime()
+	return h
+}
+
+// Registry 返回心跳指标所在的注册表，其他模块可以在其中注册自己的指标
+func (h *Heartbeat) Registry() *prometheus.Registry {
+	return h.registry
+}
+
+// ObservePoll 记录一次监控轮询的结果
+func (h *Heartbeat) ObservePoll(at time.Time, err error) {
+	h.lastPoll.Set(float64(at.Unix()))
+	if err != nil {
+		h.pollFailures.Inc()
+		return
+	}
+	h.lastSuccessPoll.Set(float64(at.Unix()))
+}
+
+// Serve 在 addr 上通过 /metrics 暴露心跳指标，阻塞直到服务退出
+func (h *Heartbeat) Serve(addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(h.registry, promhttp.HandlerOpts{}))
+	server := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	if err := server.ListenAndServe(); err != nil {
+		return fmt.Errorf("Failed to serve metrics on %s: %v", addr, err)
+	}
+	return nil
+}
+
+// RunPush 每隔 interval 将心跳指标推送到 Pushgateway，直到 ctx 结束
+func (h *Heartbeat) RunPush(ctx context.Context, url string, interval time.Duration) {
+	pusher := push.New(url, pushJob).Gatherer(h.registry)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		h.pushedTimestamp.SetToCurrentTime()
+		if err := pusher.PushContext(ctx); err != nil {
+			// 避免 Pushgateway 长时间不可用时刷屏，每小时最多记录一次
+			if time.Since(h.lastPushFailedAt) > time.Hour {
+				log.Printf("Failed to push heartbeat to %s: %v", url, err)
+				h.lastPushFailedAt = time.Now()
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package monitor
+
+import (
+	"log"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/store"
+	"github.com/prometheus/common/model"
+)
+
+// checkNewInstances 记录 up 查询结果中之前从未见过的实例并发送通知。
+// 首次运行时只记录已有实例，不发送通知
+func (m *Monitor) checkNewInstances(vector model.Vector, now time.Time) error {
+	first := !m.store.HasKnownInstances()
+	names := make([]string, 0, len(vector))
+	labels := make(map[string]model.Metric, len(vector))
+	for _, sample := range vector {
+		name := string(sample.Metric["instance"])
+		names = append(names, name)
+		labels[name] = sample.Metric
+	}
+	added, err := m.store.AddKnownInstances(names, now)
+	if err != nil {
+		return err
+	}
+	if first {
+		if len(added) > 0 {
+			log.Printf("Recorded %d existing instances", len(added))
+		}
+		return nil
+	}
+
+	for _, instance := range added {
+		e := store.Event{
+			Instance:   instance,
+			Kind:       store.EventNewInstance,
+			Message:    "发现新实例",
+			Labels:     make(map[string]string),
+			StartedAt:  now,
+			ResolvedAt: now,
+		}
+		for k, v := range labels[instance] {
+			if k != model.MetricNameLabel && k != "instance" {
+				e.Labels[string(k)] = string(v)
+			}
+		}
+		m.record(e)
+	}
+	return nil
+}
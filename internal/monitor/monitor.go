@@ -0,0 +1,265 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/prometheus"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/store"
+	"github.com/prometheus/common/model"
+)
+
+const upQuery = `up{job="node-exporter"}`
+
+// Notifier 接收监控记录的事件，用于向用户发送通知
+type Notifier interface {
+	Notify(e store.Event)
+}
+
+// PollObserver 接收每次轮询的结果，用于记录监控自身的运行状态
+type PollObserver interface {
+	ObservePoll(at time.Time, err error)
+}
+
+// Monitor 定期轮询 Prometheus，检测实例状态变化并记录事件
+type Monitor struct {
+	client   *prometheus.Client
+	store    *store.Store
+	interval time.Duration
+	notifier Notifier
+	observer PollObserver
+
+	// staleThreshold 大于 0 时检查在线实例的指标是否过期
+	staleThreshold time.Duration
+	// thresholds 是配置中的使用率告警阈值，与机器人中保存的阈值合并后使用
+	thresholds map[string]float64
+	// upsMinRuntime 大于 0 时检查 UPS 供电状态和电池剩余时间
+	upsMinRuntime time.Duration
+
+	// online 记录每个实例上一次观察到的在线状态
+	online map[string]bool
+	// stale 记录每个实例上一次观察到的数据过期状态
+	stale map[string]bool
+	// breached 记录每个指标、每个实例上一次观察到的超阈值状态
+	breached map[string]map[string]bool
+	// upsStates 记录每类 UPS 事件、每台 UPS 上一次观察到的状态
+	upsStates map[store.EventKind]map[string]bool
+	// oomKills 记录每个实例上一次观察到的 OOM Kill 累计次数
+	oomKills map[string]float64
+	// quotaCrossed 记录本周期流量已超过配额的实例及超过的时间
+	quotaCrossed map[string]time.Time
+}
+
+func New(client *prometheus.Client, st *store.Store, interval time.Duration) *Monitor {
+	return &Monitor{
+		client:   client,
+		store:    st,
+		interval: interval,
+	}
+}
+
+// SetNotifier 设置事件通知的接收者，未设置时只记录事件
+func (m *Monitor) SetNotifier(n Notifier) {
+	m.notifier = n
+}
+
+// SetPollObserver 设置轮询结果的接收者
+func (m *Monitor) SetPollObserver(o PollObserver) {
+	m.observer = o
+}
+
+// WatchStaleness 开启指标过期检测，在线实例的数据超过 threshold 未更新时记录事件
+func (m *Monitor) WatchStaleness(threshold time.Duration) {
+	m.staleThreshold = threshold
+}
+
+func (m *Monitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		now := time.Now()
+		err := m.poll(now)
+		if err != nil {
+			log.Printf("Monitor poll failed: %v", err)
+		}
+		if m.observer != nil {
+			m.observer.ObservePoll(now, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *Monitor) poll(now time.Time) error {
+	result, err := m.client.QueryPrometheus(upQuery, now)
+	if err != nil {
+		return err
+	}
+	vector, ok := result.(model.Vector)
+	if !ok {
+		return fmt.Errorf("unexpected result type %s for %s", result.Type(), upQuery)
+	}
+
+	if m.online == nil {
+		m.online = m.restoreStates()
+	}
+
+	for _, sample := range vector {
+		instance := string(sample.Metric["instance"])
+		online := sample.Value == 1
+		previous, known := m.online[instance]
+		m.online[instance] = online
+		if !known {
+			// 首次见到的实例默认视为在线，离线时才需要记录事件
+			previous = true
+		}
+		if previous == online {
+			continue
+		}
+		if online {
+			m.recordUp(instance, now)
+		} else {
+			m.recordDown(instance, now)
+		}
+	}
+
+	// 各项检查互不依赖，一项查询失败只记录日志，不影响其他检查
+	if err := m.checkNewInstances(vector, now); err != nil {
+		log.Printf("Failed to check new instances: %v", err)
+	}
+	if m.staleThreshold > 0 {
+		if err := m.checkStaleness(now); err != nil {
+			log.Printf("Failed to check metric staleness: %v", err)
+		}
+	}
+	if err := m.checkThresholds(now); err != nil {
+		log.Printf("Failed to check thresholds: %v", err)
+	}
+	m.checkQuotaCrossings(vector, now)
+	if m.upsMinRuntime > 0 {
+		if err := m.checkUPS(now); err != nil {
+			log.Printf("Failed to check UPS runtime: %v", err)
+		}
+	}
+	if err := m.checkOOMKills(now); err != nil {
+		log.Printf("Failed to check OOM kills: %v", err)
+	}
+	if err := m.checkRuleAlerts(now); err != nil {
+		log.Printf("Failed to check rule alerts: %v", err)
+	}
+	return nil
+}
+
+// checkStaleness 检查在线实例的指标是否过期，状态变化时记录或恢复事件
+func (m *Monitor) checkStaleness(now time.Time) error {
+	freshness, err := m.client.GetFreshness(nil, now)
+	if err != nil {
+		return err
+	}
+	if m.stale == nil {
+		m.stale = make(map[string]bool)
+		for _, e := range m.store.OpenEvents(store.EventStaleMetrics) {
+			m.stale[e.Instance] = true
+		}
+	}
+
+	for instance, online := range m.online {
+		f := prometheus.FreshnessOf(freshness, instance)
+		// 离线实例的数据必然过期，已由离线事件覆盖
+		stale := online && f.Stale(m.staleThreshold)
+		if stale == m.stale[instance] {
+			continue
+		}
+		m.stale[instance] = stale
+		if stale {
+			m.record(store.Event{
+				Instance:  instance,
+				Kind:      store.EventStaleMetrics,
+				Message:   "实例在线但指标" + f.Label(),
+				StartedAt: now,
+			})
+			continue
+		}
+		staleEvent, found, err := m.store.ResolveEvent(instance, store.EventStaleMetrics, now)
+		if err != nil {
+			log.Printf("Failed to resolve stale event for %s: %v", instance, err)
+			continue
+		}
+		if found && m.notifier != nil {
+			staleEvent.Message = "指标数据恢复更新"
+			m.notifier.Notify(staleEvent)
+		}
+	}
+	return nil
+}
+
+// record 保存事件并发送通知
+func (m *Monitor) record(e store.Event) {
+	m.recordEvent(e, true)
+}
+
+// recordEvent 保存事件，notify 为 true 时发送通知
+func (m *Monitor) recordEvent(e store.Event, notify bool) {
+	id, err := m.store.AddEvent(e)
+	if err != nil {
+		log.Printf("Failed to record %s event for %s: %v", e.Kind, e.Instance, err)
+		return
+	}
+	e.ID = id
+	if notify && m.notifier != nil {
+		m.notifier.Notify(e)
+	}
+}
+
+// restoreStates 根据存储中未恢复的离线事件还原实例状态，避免重启后重复记录
+func (m *Monitor) restoreStates() map[string]bool {
+	states := make(map[string]bool)
+	for _, e := range m.store.OpenEvents(store.EventInstanceDown) {
+		states[e.Instance] = false
+	}
+	return states
+}
+
+func (m *Monitor) recordDown(instance string, now time.Time) {
+	m.record(store.Event{
+		Instance:  instance,
+		Kind:      store.EventInstanceDown,
+		Message:   "实例离线",
+		StartedAt: now,
+	})
+}
+
+func (m *Monitor) recordUp(instance string, now time.Time) {
+	message := "实例恢复在线"
+	downEvent, found, err := m.store.ResolveEvent(instance, store.EventInstanceDown, now)
+	if err != nil {
+		log.Printf("Failed to resolve down event for %s: %v", instance, err)
+	}
+	var resolves int64
+	if found {
+		message = fmt.Sprintf("实例恢复在线，离线 %s", formatEventDuration(downEvent.Duration(now)))
+		resolves = downEvent.ID
+	}
+	m.record(store.Event{
+		Instance:   instance,
+		Kind:       store.EventInstanceUp,
+		Message:    message,
+		StartedAt:  now,
+		ResolvedAt: now,
+		ResolvesID: resolves,
+	})
+}
+
+func formatEventDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	if d < time.Minute {
+		return d.String()
+	}
+	return d.Round(time.Minute).String()
+}
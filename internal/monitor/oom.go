@@ -0,0 +1,36 @@
+package monitor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/store"
+)
+
+// checkOOMKills 比较各实例 OOM Kill 累计次数的变化，有新的 OOM Kill 时记录事件并发送通知。
+// 首次轮询和新出现的实例只记录当前次数；计数器变小说明实例重启过，重新开始计数
+func (m *Monitor) checkOOMKills(now time.Time) error {
+	counters, err := m.client.OOMKillCounters(now)
+	if err != nil {
+		return err
+	}
+	first := m.oomKills == nil
+	if first {
+		m.oomKills = make(map[string]float64)
+	}
+	for instance, count := range counters {
+		previous, known := m.oomKills[instance]
+		m.oomKills[instance] = count
+		if first || !known || count <= previous {
+			continue
+		}
+		m.record(store.Event{
+			Instance:   instance,
+			Kind:       store.EventOOMKill,
+			Message:    fmt.Sprintf("内核因内存不足终止了进程（OOM Kill %.0f 次）", count-previous),
+			StartedAt:  now,
+			ResolvedAt: now,
+		})
+	}
+	return nil
+}
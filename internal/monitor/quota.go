@@ -0,0 +1,68 @@
+package monitor
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/prometheus"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/store"
+	"github.com/prometheus/common/model"
+)
+
+// checkQuotaCrossings 检查设置了 traffic_quota 标签的实例本周期的计费流量，用量超过配额时记录事件，
+// 流量重置后恢复事件。vector 是本次轮询的 up 样本，续费和计费标签在 up 上。
+// 单个实例查询失败只记录日志，不影响其他实例
+func (m *Monitor) checkQuotaCrossings(vector model.Vector, now time.Time) {
+	if m.quotaCrossed == nil {
+		m.quotaCrossed = make(map[string]time.Time)
+		for _, e := range m.store.OpenEvents(store.EventQuotaCrossing) {
+			m.quotaCrossed[e.Instance] = e.StartedAt
+		}
+	}
+
+	for _, sample := range vector {
+		labels := sample.Metric
+		if labels["traffic_quota"] == "" {
+			continue
+		}
+		instance := string(labels["instance"])
+		usage, err := m.client.QueryMeteredUsage(labels, now)
+		if err != nil {
+			log.Printf("Failed to check traffic quota for %s: %v", instance, err)
+			continue
+		}
+		policy, err := prometheus.ResetPolicyFor(labels, now)
+		if err != nil {
+			log.Printf("Failed to check traffic quota for %s: %v", instance, err)
+			continue
+		}
+
+		crossedAt, crossed := m.quotaCrossed[instance]
+		if crossed && crossedAt.Before(policy.LastReset(now)) {
+			delete(m.quotaCrossed, instance)
+			crossed = false
+			e, found, err := m.store.ResolveEvent(instance, store.EventQuotaCrossing, now)
+			if err != nil {
+				log.Printf("Failed to resolve quota event for %s: %v", instance, err)
+			} else if found && m.notifier != nil {
+				e.Message = fmt.Sprintf("流量已重置，本周期已用 %s / %s", prometheus.FormatBytes(usage.Used), usage.Label)
+				m.notifier.Notify(e)
+			}
+		}
+		if crossed || usage.Used < usage.Included {
+			continue
+		}
+		m.quotaCrossed[instance] = now
+		message := fmt.Sprintf("本周期流量 %s，超过配额 %s", prometheus.FormatBytes(usage.Used), usage.Label)
+		if usage.Priced {
+			message += fmt.Sprintf("，超出部分按 %.2f %s/GB 计费", usage.PricePerGB, usage.Currency)
+		}
+		m.record(store.Event{
+			Instance:  instance,
+			Kind:      store.EventQuotaCrossing,
+			Message:   message,
+			StartedAt: now,
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package monitor
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/prometheus"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/store"
+)
+
+// ruleAlertKey 标识一条订阅规则在一个实例上的告警，同一实例的多条告警（例如不同挂载点）合并为一个事件
+type ruleAlertKey struct {
+	rule, instance string
+}
+
+// checkRuleAlerts 检查订阅的 Prometheus 告警规则，告警开始触发或不再触发时记录或恢复事件。
+// 状态直接保存在事件日志中，重启后不会重复通知
+func (m *Monitor) checkRuleAlerts(now time.Time) error {
+	subscribed := m.store.RuleSubscriptions()
+	open := make(map[ruleAlertKey]bool)
+	for _, e := range m.store.OpenEvents(store.EventPrometheusAlert) {
+		open[ruleAlertKey{e.Metric, e.Instance}] = true
+	}
+	if len(subscribed) == 0 && len(open) == 0 {
+		return nil
+	}
+
+	firing := make(map[ruleAlertKey]bool)
+	if len(subscribed) > 0 {
+		rules, err := m.client.QueryAlertingRules()
+		if err != nil {
+			return err
+		}
+		for _, rule := range rules {
+			if !slices.Contains(subscribed, rule.Name) {
+				continue
+			}
+			for _, a := range rule.Firing {
+				key := ruleAlertKey{rule.Name, a.Instance}
+				// 没有 instance 标签的告警以规则名作为事件的实例
+				if key.instance == "" {
+					key.instance = rule.Name
+				}
+				if firing[key] {
+					continue
+				}
+				firing[key] = true
+				if open[key] {
+					continue
+				}
+				m.recordEvent(store.Event{
+					Instance:  key.instance,
+					Kind:      store.EventPrometheusAlert,
+					Metric:    rule.Name,
+					Message:   ruleAlertMessage(rule, a),
+					StartedAt: a.ActiveAt,
+				}, !m.store.Snoozed(key.instance, rule.Name, now))
+			}
+		}
+	}
+
+	for key := range open {
+		if firing[key] {
+			continue
+		}
+		e, found, err := m.store.ResolveMetricEvent(key.instance, store.EventPrometheusAlert, key.rule, now)
+		if err != nil {
+			log.Printf("Failed to resolve %s rule event for %s: %v", key.rule, key.instance, err)
+			continue
+		}
+		// 取消订阅后仍会恢复遗留的事件，但不再通知
+		if found && m.notifier != nil && slices.Contains(subscribed, key.rule) && !m.store.Snoozed(key.instance, key.rule, now) {
+			e.Message = fmt.Sprintf("规则 %s 不再触发", key.rule)
+			m.notifier.Notify(e)
+		}
+	}
+	return nil
+}
+
+// ruleAlertMessage 优先使用告警的 summary 注解，没有时显示规则名和当前值
+func ruleAlertMessage(rule prometheus.PrometheusRule, a prometheus.RuleAlert) string {
+	if a.Summary != "" {
+		return fmt.Sprintf("%s: %s", rule.Name, a.Summary)
+	}
+	return fmt.Sprintf("规则 %s 触发，当前值 %s", rule.Name, a.Value)
+}
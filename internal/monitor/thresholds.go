@@ -0,0 +1,89 @@
+package monitor
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/prometheus"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/store"
+)
+
+// SetThresholds 设置使用率告警阈值，键为指标名称（见 prometheus.UsageLabel），值为百分比或数量。
+// 在机器人中修改过的阈值保存在存储中，优先于这里的设置
+func (m *Monitor) SetThresholds(thresholds map[string]float64) {
+	m.thresholds = thresholds
+}
+
+// checkThresholds 检查各实例的使用率是否超过阈值，状态变化时记录或恢复事件。
+// 阈值为配置与机器人中保存的阈值合并的结果，都没有设置时不检查
+func (m *Monitor) checkThresholds(now time.Time) error {
+	thresholds := m.store.Thresholds(m.thresholds)
+	if len(thresholds) == 0 {
+		return nil
+	}
+	if m.breached == nil {
+		m.breached = make(map[string]map[string]bool)
+		for _, e := range m.store.OpenEvents(store.EventThresholdBreach) {
+			m.markBreached(e.Metric, e.Instance, true)
+		}
+	}
+
+	// 按名称排序，保证通知顺序稳定
+	metrics := make([]string, 0, len(thresholds))
+	for metric := range thresholds {
+		metrics = append(metrics, metric)
+	}
+	sort.Strings(metrics)
+
+	for _, metric := range metrics {
+		limit := thresholds[metric]
+		usage, err := m.client.UsageByInstance(metric, now)
+		if err != nil {
+			return err
+		}
+		label, _ := prometheus.UsageLabel(metric)
+
+		for instance, value := range usage {
+			// 离线实例的数据不再更新，保持原有状态
+			if online, known := m.online[instance]; known && !online {
+				continue
+			}
+			breached := value >= limit
+			if breached == m.breached[metric][instance] {
+				continue
+			}
+			m.markBreached(metric, instance, breached)
+			// 用户暂停通知期间仍记录事件，只是不再发送
+			notify := !m.store.Snoozed(instance, metric, now)
+			if breached {
+				m.recordEvent(store.Event{
+					Instance:  instance,
+					Kind:      store.EventThresholdBreach,
+					Metric:    metric,
+					Message:   fmt.Sprintf("%s %s，超过阈值 %s", label, prometheus.FormatUsage(metric, value), prometheus.FormatUsage(metric, limit)),
+					StartedAt: now,
+				}, notify)
+				continue
+			}
+			breachEvent, found, err := m.store.ResolveMetricEvent(instance, store.EventThresholdBreach, metric, now)
+			if err != nil {
+				log.Printf("Failed to resolve %s threshold event for %s: %v", metric, instance, err)
+				continue
+			}
+			if found && notify && m.notifier != nil {
+				breachEvent.Message = fmt.Sprintf("%s 恢复到 %s", label, prometheus.FormatUsage(metric, value))
+				m.notifier.Notify(breachEvent)
+			}
+		}
+	}
+	return nil
+}
+
+func (m *Monitor) markBreached(metric, instance string, breached bool) {
+	if m.breached[metric] == nil {
+		m.breached[metric] = make(map[string]bool)
+	}
+	m.breached[metric][instance] = breached
+}
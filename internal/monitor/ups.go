@@ -0,0 +1,69 @@
+package monitor
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/store"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/utils"
+)
+
+// WatchUPS 开启 UPS 检测，UPS 切换到电池供电或电池剩余时间低于 minRuntime 时记录事件
+func (m *Monitor) WatchUPS(minRuntime time.Duration) {
+	m.upsMinRuntime = minRuntime
+}
+
+// checkUPS 检查各 UPS 的供电状态和剩余时间，状态变化时记录或恢复事件
+func (m *Monitor) checkUPS(now time.Time) error {
+	if m.upsStates == nil {
+		m.upsStates = make(map[store.EventKind]map[string]bool)
+		for _, kind := range []store.EventKind{store.EventUPSOnBattery, store.EventUPSLowRuntime} {
+			m.upsStates[kind] = make(map[string]bool)
+			for _, e := range m.store.OpenEvents(kind) {
+				m.upsStates[kind][e.Instance] = true
+			}
+		}
+	}
+
+	upses, err := m.client.QueryUPS(now)
+	if err != nil {
+		return err
+	}
+	for _, ups := range upses {
+		m.updateUPSState(ups.Name, store.EventUPSOnBattery, ups.OnBattery, now,
+			fmt.Sprintf("UPS 切换到电池供电，电量 %.0f%%，剩余 %s", ups.Charge, utils.ShortDuration(ups.Runtime)),
+			"UPS 恢复市电供电")
+		// 市电供电时剩余时间只反映电池容量，只在电池供电时检查
+		low := ups.OnBattery && ups.Runtime < m.upsMinRuntime
+		m.updateUPSState(ups.Name, store.EventUPSLowRuntime, low, now,
+			fmt.Sprintf("UPS 电池剩余 %s，低于 %s，电量 %.0f%%", utils.ShortDuration(ups.Runtime), utils.ShortDuration(m.upsMinRuntime), ups.Charge),
+			"UPS 电池续航恢复")
+	}
+	return nil
+}
+
+func (m *Monitor) updateUPSState(name string, kind store.EventKind, active bool, now time.Time, message, resolvedMessage string) {
+	if active == m.upsStates[kind][name] {
+		return
+	}
+	m.upsStates[kind][name] = active
+	if active {
+		m.record(store.Event{
+			Instance:  name,
+			Kind:      kind,
+			Message:   message,
+			StartedAt: now,
+		})
+		return
+	}
+	e, found, err := m.store.ResolveEvent(name, kind, now)
+	if err != nil {
+		log.Printf("Failed to resolve %s event for %s: %v", kind, name, err)
+		return
+	}
+	if found && m.notifier != nil {
+		e.Message = resolvedMessage
+		m.notifier.Notify(e)
+	}
+}
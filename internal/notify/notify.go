@@ -0,0 +1,211 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/store"
+)
+
+// sendTimeout 是单个外部通知渠道发送一条消息的最长时间
+const sendTimeout = 15 * time.Second
+
+// Notifier 接收监控记录的事件，Telegram 机器人和 Router 都实现了该接口
+type Notifier interface {
+	Notify(e store.Event)
+}
+
+// Sink 是 Telegram 之外的通知渠道
+type Sink interface {
+	Send(ctx context.Context, m Message) error
+}
+
+// Message 是发送到外部渠道的通知内容
+type Message struct {
+	// Title 和 Text 为纯文本，Event 供需要结构化数据的渠道（如通用 webhook）使用
+	Title string
+	Text  string
+	Event store.Event
+}
+
+// Config 是外部通知渠道和路由规则的配置，从 JSON 文件读取
+type Config struct {
+	Sinks  map[string]SinkConfig `json:"sinks"`
+	Routes []Route               `json:"routes"`
+}
+
+// SinkConfig 描述一个通知渠道，Type 可以是 webhook、discord、slack 或 email
+type SinkConfig struct {
+	Type string `json:"type"`
+	URL  string `json:"url,omitempty"`
+
+	// 以下字段只用于 email
+	SMTPAddr string   `json:"smtp_addr,omitempty"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	From     string   `json:"from,omitempty"`
+	To       []string `json:"to,omitempty"`
+}
+
+// Route 决定哪些事件发送到哪些渠道，Kinds 为空匹配所有类型，Instances 为空匹配所有实例
+type Route struct {
+	Kinds     []store.EventKind `json:"kinds,omitempty"`
+	Instances string            `json:"instances,omitempty"`
+	Sinks     []string          `json:"sinks"`
+
+	instances *regexp.Regexp
+}
+
+func (r Route) matches(e store.Event) bool {
+	if r.instances != nil && !r.instances.MatchString(e.Instance) {
+		return false
+	}
+	if len(r.Kinds) == 0 {
+		return true
+	}
+	for _, kind := range r.Kinds {
+		if kind == e.Kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Router 将事件交给 Telegram 通知，同时按路由规则转发到外部渠道
+type Router struct {
+	telegram Notifier
+	sinks    map[string]Sink
+	routes   []Route
+}
+
+// LoadRouter 读取 path 中的路由配置并创建 Router，path 为空时只使用 Telegram 通知
+func LoadRouter(path string, telegram Notifier) (*Router, error) {
+	router := &Router{telegram: telegram, sinks: make(map[string]Sink)}
+	if path == "" {
+		return router, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read notify config %s: %v", path, err)
+	}
+	var cfg Config
+	if err := json.Unmarshal(content, &cfg); err != nil {
+		return nil, fmt.Errorf("Failed to parse notify config %s: %v", path, err)
+	}
+
+	for name, sc := range cfg.Sinks {
+		sink, err := newSink(sc)
+		if err != nil {
+			return nil, fmt.Errorf("sink %s is invalid: %v", name, err)
+		}
+		router.sinks[name] = sink
+	}
+	for i, route := range cfg.Routes {
+		for _, name := range route.Sinks {
+			if _, ok := router.sinks[name]; !ok {
+				return nil, fmt.Errorf("route %d refers to unknown sink %q", i+1, name)
+			}
+		}
+		if route.Instances != "" {
+			route.instances, err = regexp.Compile(route.Instances)
+			if err != nil {
+				return nil, fmt.Errorf("route %d has invalid instances pattern: %v", i+1, err)
+			}
+		}
+		router.routes = append(router.routes, route)
+	}
+	return router, nil
+}
+
+// DisableWebhooks 移除通过 HTTP 发送的渠道（webhook、Discord、Slack），路由到这些渠道的事件只发送到其余渠道
+func (r *Router) DisableWebhooks() {
+	for name, sink := range r.sinks {
+		if _, ok := sink.(*webhookSink); ok {
+			delete(r.sinks, name)
+		}
+	}
+}
+
+func newSink(sc SinkConfig) (Sink, error) {
+	switch sc.Type {
+	case "webhook", "discord", "slack":
+		if sc.URL == "" {
+			return nil, fmt.Errorf("url is required")
+		}
+		return &webhookSink{kind: sc.Type, url: sc.URL}, nil
+	case "email":
+		if sc.SMTPAddr == "" || sc.From == "" || len(sc.To) == 0 {
+			return nil, fmt.Errorf("smtp_addr, from and to are required")
+		}
+		return &emailSink{config: sc}, nil
+	default:
+		return nil, fmt.Errorf("unknown sink type %q", sc.Type)
+	}
+}
+
+// Notify 实现 Notifier，外部渠道在后台发送，不阻塞监控轮询
+func (r *Router) Notify(e store.Event) {
+	if r.telegram != nil {
+		r.telegram.Notify(e)
+	}
+
+	for _, name := range r.SinksFor(e) {
+		go r.send(name, r.sinks[name], newMessage(e))
+	}
+}
+
+// SinksFor 返回事件会被转发到的外部渠道名称，按路由顺序排列，也用于预览通知规则
+func (r *Router) SinksFor(e store.Event) []string {
+	var names []string
+	sent := make(map[string]bool)
+	for _, route := range r.routes {
+		if !route.matches(e) {
+			continue
+		}
+		for _, name := range route.Sinks {
+			if _, ok := r.sinks[name]; !ok {
+				// 已停用的渠道
+				continue
+			}
+			// 同一事件匹配多条路由时每个渠道只发送一次
+			if !sent[name] {
+				sent[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+func (r *Router) send(name string, sink Sink, m Message) {
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+	if err := sink.Send(ctx, m); err != nil {
+		log.Printf("Failed to send event %d to sink %s: %v", m.Event.ID, name, err)
+	}
+}
+
+// newMessage 生成事件的纯文本通知
+func newMessage(e store.Event) Message {
+	kind := e.Kind.Label()
+	at := e.StartedAt
+	if e.Resolved() && e.ResolvedAt.After(e.StartedAt) {
+		kind = "恢复"
+		at = e.ResolvedAt
+	}
+	text := fmt.Sprintf("%s\n时间: %s", e.Message, at.Local().Format("2006-01-02 15:04:05"))
+	if e.Resolved() && e.ResolvedAt.After(e.StartedAt) {
+		text += fmt.Sprintf("\n持续: %s", e.ResolvedAt.Sub(e.StartedAt).Round(time.Second))
+	}
+	return Message{
+		Title: fmt.Sprintf("[%s] %s", kind, e.Instance),
+		Text:  text,
+		Event: e,
+	}
+}
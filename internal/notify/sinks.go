@@ -0,0 +1,95 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+)
+
+// webhookSink 通过 HTTP POST 发送通知，请求体格式取决于渠道类型
+type webhookSink struct {
+	kind string
+	url  string
+}
+
+func (s *webhookSink) Send(ctx context.Context, m Message) error {
+	var payload interface{}
+	switch s.kind {
+	case "discord":
+		payload = map[string]string{"content": m.Title + "\n" + m.Text}
+	case "slack":
+		payload = map[string]string{"text": m.Title + "\n" + m.Text}
+	default:
+		payload = struct {
+			Title string      `json:"title"`
+			Text  string      `json:"text"`
+			Event interface{} `json:"event"`
+		}{m.Title, m.Text, m.Event}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("Failed to encode webhook payload: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("Failed to create webhook request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("Failed to post webhook: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook returned %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// emailSink 通过 SMTP 发送邮件，配置了用户名时使用 PLAIN 认证
+type emailSink struct {
+	config SinkConfig
+}
+
+func (s *emailSink) Send(ctx context.Context, m Message) error {
+	host, _, err := net.SplitHostPort(s.config.SMTPAddr)
+	if err != nil {
+		return fmt.Errorf("Failed to parse smtp address: %v", err)
+	}
+	var auth smtp.Auth
+	if s.config.Username != "" {
+		auth = smtp.PlainAuth("", s.config.Username, s.config.Password, host)
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", s.config.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(s.config.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Title))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(m.Text, "\n", "\r\n"))
+
+	// net/smtp 不支持 context，在后台发送并在超时后放弃等待
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(s.config.SMTPAddr, auth, s.config.From, s.config.To, msg.Bytes())
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("Failed to send email: %v", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("Failed to send email: %v", ctx.Err())
+	}
+}
@@ -0,0 +1,101 @@
+// Package plugin 允许下游分支在不修改机器人核心代码的情况下添加自己的菜单页面和命令。
+//
+// 插件包在 init 中调用 Register 注册视图，然后在 cmd/main.go 中以空白导入的方式引入：
+//
+//	import _ "github.com/bestmjj/prometheus-telegram-bot/plugins/backup"
+package plugin
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/prometheus"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/render"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/store"
+)
+
+// Context 是视图渲染时可以使用的资源
+type Context struct {
+	ChatID int64
+	// Args 是命令参数，从菜单打开时为空
+	Args       string
+	Prometheus *prometheus.Client
+	Renderer   *render.Renderer
+	Store      *store.Store
+	// Locale 是聊天使用的语言，用于格式化数字和日期
+	Locale render.Locale
+}
+
+// View 是插件提供的页面
+type View struct {
+	// ID 是菜单ID，同时作为按钮的回调数据，不能与内置菜单重复，长度不超过 64 字节
+	ID string
+	// Title 是在"其他"菜单中显示的按钮文字，为空时不显示按钮
+	Title string
+	// Command 是斜杠命令名（不含 /），为空时不注册命令
+	Command string
+	// Render 返回页面的 HTML 文本
+	Render func(ctx Context) (string, error)
+}
+
+var (
+	mu    sync.RWMutex
+	views = make(map[string]View)
+)
+
+// Register 注册一个视图，通常在插件包的 init 中调用。ID 或命令重复时 panic
+func Register(v View) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if v.ID == "" || v.Render == nil {
+		panic("plugin: view must have an ID and a Render func")
+	}
+	if len(v.ID) > 64 {
+		panic(fmt.Sprintf("plugin: view ID %q is longer than 64 bytes", v.ID))
+	}
+	if _, exists := views[v.ID]; exists {
+		panic(fmt.Sprintf("plugin: view %q registered twice", v.ID))
+	}
+	if v.Command != "" {
+		for _, other := range views {
+			if other.Command == v.Command {
+				panic(fmt.Sprintf("plugin: command %q registered by both %q and %q", v.Command, other.ID, v.ID))
+			}
+		}
+	}
+	views[v.ID] = v
+}
+
+// Lookup 按菜单ID查找视图
+func Lookup(id string) (View, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	v, ok := views[id]
+	return v, ok
+}
+
+// LookupCommand 按命令名查找视图
+func LookupCommand(command string) (View, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, v := range views {
+		if v.Command != "" && v.Command == command {
+			return v, true
+		}
+	}
+	return View{}, false
+}
+
+// Views 返回所有已注册的视图，按ID排序
+func Views() []View {
+	mu.RLock()
+	defer mu.RUnlock()
+	list := make([]View, 0, len(views))
+	for _, v := range views {
+		list = append(list, v)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
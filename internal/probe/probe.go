@@ -0,0 +1,82 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Result 是对一个端口多次 TCP 连接的结果
+type Result struct {
+	Port      string
+	Attempts  int
+	Succeeded int
+	// Min 和 Avg 是成功连接的耗时
+	Min time.Duration
+	Avg time.Duration
+	// Refused 表示主机拒绝了连接：主机可达，但端口没有服务在监听
+	Refused bool
+	// Err 是最后一次失败的原因，全部成功时为空
+	Err string
+}
+
+// Open 判断端口是否可以连接
+func (r Result) Open() bool {
+	return r.Succeeded > 0
+}
+
+// Reachable 判断主机是否可达，端口拒绝连接也说明主机在线
+func (r Result) Reachable() bool {
+	return r.Open() || r.Refused
+}
+
+// TCP 从本机向 host:port 发起 attempts 次 TCP 连接，每次最多等待 timeout
+func TCP(ctx context.Context, host, port string, attempts int, timeout time.Duration) Result {
+	r := Result{Port: port, Attempts: attempts}
+	address := net.JoinHostPort(host, port)
+	dialer := net.Dialer{Timeout: timeout}
+	var total time.Duration
+	for i := 0; i < attempts; i++ {
+		start := time.Now()
+		conn, err := dialer.DialContext(ctx, "tcp", address)
+		elapsed := time.Since(start)
+		if err != nil {
+			r.Err = err.Error()
+			if errors.Is(err, syscall.ECONNREFUSED) {
+				r.Refused = true
+			}
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		conn.Close()
+		r.Succeeded++
+		total += elapsed
+		if r.Min == 0 || elapsed < r.Min {
+			r.Min = elapsed
+		}
+	}
+	if r.Succeeded > 0 {
+		r.Avg = total / time.Duration(r.Succeeded)
+	}
+	return r
+}
+
+// Ports 并发测试 host 上的多个端口，结果与 ports 的顺序一致
+func Ports(ctx context.Context, host string, ports []string, attempts int, timeout time.Duration) []Result {
+	results := make([]Result, len(ports))
+	var wg sync.WaitGroup
+	for i, port := range ports {
+		wg.Add(1)
+		go func(i int, port string) {
+			defer wg.Done()
+			results[i] = TCP(ctx, host, port, attempts, timeout)
+		}(i, port)
+	}
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,86 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+)
+
+// RuleAlert 是告警规则当前触发的一条告警
+type RuleAlert struct {
+	// Instance 是告警的 instance 标签，没有该标签时为空
+	Instance string
+	Labels   model.LabelSet
+	Summary  string
+	Value    string
+	ActiveAt time.Time
+}
+
+// PrometheusRule 是 Prometheus 中配置的一条告警规则及其正在触发的告警
+type PrometheusRule struct {
+	Name  string
+	Group string
+	Query string
+	For   time.Duration
+	// State 是规则的状态：inactive、pending 或 firing
+	State string
+	// Firing 只包含已经触发（超过 for 持续时间）的告警，不包含 pending 状态的告警
+	Firing []RuleAlert
+}
+
+// QueryAlertingRules 从 Prometheus 的 /api/v1/rules 读取所有告警规则，按规则名排序，同名规则按分组排序
+func (c *Client) QueryAlertingRules() ([]PrometheusRule, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	release, err := c.acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query alerting rules: %v", err)
+	}
+	defer release()
+
+	result, err := c.api.Rules(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query alerting rules: %v", err)
+	}
+	var rules []PrometheusRule
+	for _, group := range result.Groups {
+		for _, r := range group.Rules {
+			ar, ok := r.(promv1.AlertingRule)
+			if !ok {
+				continue
+			}
+			rule := PrometheusRule{
+				Name:  ar.Name,
+				Group: group.Name,
+				Query: ar.Query,
+				For:   time.Duration(ar.Duration * float64(time.Second)),
+				State: ar.State,
+			}
+			for _, a := range ar.Alerts {
+				if a.State != promv1.AlertStateFiring {
+					continue
+				}
+				rule.Firing = append(rule.Firing, RuleAlert{
+					Instance: string(a.Labels["instance"]),
+					Labels:   a.Labels,
+					Summary:  string(a.Annotations["summary"]),
+					Value:    a.Value,
+					ActiveAt: a.ActiveAt,
+				})
+			}
+			rules = append(rules, rule)
+		}
+	}
+	sort.SliceStable(rules, func(i, j int) bool {
+		if rules[i].Name != rules[j].Name {
+			return rules[i].Name < rules[j].Name
+		}
+		return rules[i].Group < rules[j].Group
+	})
+	return rules, nil
+}
@@ -0,0 +1,86 @@
+package prometheus
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+)
+
+// bootTimeTolerance 是 node_boot_time_seconds 的抖动范围，相差不超过该值的启动时间视为同一次启动
+const bootTimeTolerance = time.Minute
+
+// Outage 是实例离线（up == 0）的一段时间
+type Outage struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Annotations 是用于在图表上标注的实例重启时间和离线时段
+type Annotations struct {
+	Reboots []time.Time
+	Outages []Outage
+}
+
+// QueryAnnotations 查询实例在 start 到 end 之间的重启和离线时段，步长与同一时间窗口的曲线相同
+func (c *Client) QueryAnnotations(labels model.Metric, start, end time.Time) (*Annotations, error) {
+	labelMatchers := BuildLabelMatchers(labels)
+	step := rangeStep(end.Sub(start))
+	r := promv1.Range{Start: start, End: end, Step: step}
+	a := &Annotations{}
+
+	// 重启后 node_boot_time_seconds 的值即为启动时间，因此只需收集范围内出现过的不同值
+	boots, err := c.queryMatrix(fmt.Sprintf(`node_boot_time_seconds{%s}`, labelMatchers), r)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query boot time history: %v", err)
+	}
+	var bootTimes []float64
+	for _, series := range boots {
+		for _, p := range series.Values {
+			bootTimes = append(bootTimes, float64(p.Value))
+		}
+	}
+	sort.Float64s(bootTimes)
+	for i, v := range bootTimes {
+		if i > 0 && v-bootTimes[i-1] <= bootTimeTolerance.Seconds() {
+			continue
+		}
+		if t := time.Unix(0, int64(v*float64(time.Second))); !t.Before(start) && !t.After(end) {
+			a.Reboots = append(a.Reboots, t)
+		}
+	}
+
+	// 每个点取前一个步长内的最小值，避免遗漏两个点之间的短暂离线
+	if labelMatchers != "" {
+		labelMatchers = "," + labelMatchers
+	}
+	up, err := c.queryMatrix(fmt.Sprintf(`min(min_over_time(up{job="node-exporter"%s}[%s]))`, labelMatchers, model.Duration(step)), r)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query up history: %v", err)
+	}
+	for _, series := range up {
+		var current *Outage
+		for _, p := range series.Values {
+			t := p.Timestamp.Time()
+			if float64(p.Value) < 1 && !math.IsNaN(float64(p.Value)) {
+				if current == nil {
+					// 每个点统计的是前一个步长内的情况，离线可能从上一个点之后就开始了
+					current = &Outage{Start: t.Add(-step)}
+				}
+				current.End = t
+				continue
+			}
+			if current != nil {
+				a.Outages = append(a.Outages, *current)
+				current = nil
+			}
+		}
+		if current != nil {
+			a.Outages = append(a.Outages, *current)
+		}
+	}
+	return a, nil
+}
@@ -0,0 +1,40 @@
+package prometheus
+
+import "github.com/prometheus/common/model"
+
+// TrafficBilling 是服务商统计流量用量的方式，由实例的 billing 标签指定
+type TrafficBilling string
+
+const (
+	// BillingSum 按上传和下载之和计费，是未指定 billing 标签时的默认方式
+	BillingSum TrafficBilling = "sum"
+	// BillingMax 按上传和下载中较大的一个计费
+	BillingMax TrafficBilling = "max"
+	// BillingOut 和 BillingIn 只统计上传或下载
+	BillingOut TrafficBilling = "out"
+	BillingIn  TrafficBilling = "in"
+)
+
+// TrafficBillingFor 返回实例的流量计费方式，billing 标签为空、p95 或无法识别时按上传和下载之和计费
+func TrafficBillingFor(labels model.Metric) TrafficBilling {
+	switch m := TrafficBilling(labels["billing"]); m {
+	case BillingMax, BillingOut, BillingIn:
+		return m
+	default:
+		return BillingSum
+	}
+}
+
+// Usage 返回按计费方式统计的用量
+func (m TrafficBilling) Usage(transmit, receive float64) float64 {
+	switch m {
+	case BillingMax:
+		return max(transmit, receive)
+	case BillingOut:
+		return transmit
+	case BillingIn:
+		return receive
+	default:
+		return transmit + receive
+	}
+}
@@ -0,0 +1,112 @@
+package prometheus
+
+import (
+	"sort"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/utils"
+)
+
+// SpendKind 是月度支出的类型
+type SpendKind string
+
+const (
+	// SpendRenewal 是按 price 和 cycle 标签推算的续费
+	SpendRenewal SpendKind = "renewal"
+	// SpendOverage 是按 95 计费的实例按当前 95 值预计的超额费用
+	SpendOverage SpendKind = "overage"
+	// SpendTrafficOverage 是设置了流量配额的实例按本周期用量推算的超额流量费用
+	SpendTrafficOverage SpendKind = "traffic_overage"
+)
+
+// SpendItem 是一个月内的一笔支出
+type SpendItem struct {
+	Instance string
+	Kind     SpendKind
+	// Date 是续费日期，95 超额费用为月末，流量超额费用为下一次流量重置日（不晚于月末）
+	Date     time.Time
+	Amount   float64
+	Currency string
+}
+
+// MonthlySpend 是一个自然月内预计的全部支出
+type MonthlySpend struct {
+	Start time.Time
+	End   time.Time
+	Items []SpendItem
+	// Failed 是无法推算支出的实例数，例如价格标签无法解析或流量、95 值查询失败
+	Failed int
+}
+
+// Totals 返回按货币汇总的支出
+func (s MonthlySpend) Totals() map[string]float64 {
+	totals := make(map[string]float64)
+	for _, item := range s.Items {
+		totals[item.Currency] += item.Amount
+	}
+	return totals
+}
+
+// ProjectedSpend 推算 now 所在自然月的支出：本月内的续费（包括已经续费的）加上 95 计费实例和
+// 设置了流量配额的实例预计的超额费用
+func (c *Client) ProjectedSpend(now time.Time) (*MonthlySpend, error) {
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	end := start.AddDate(0, 1, 0)
+	instances, err := c.FetchInstances(`up{job="node-exporter"}`)
+	if err != nil {
+		return nil, err
+	}
+	spend := &MonthlySpend{Start: start, End: end}
+	for _, labels := range instances {
+		name := string(labels["instance"])
+		if v := string(labels["price"]); v != "" {
+			amount, currency, ok := utils.ParsePrice(v)
+			months := CycleMonths(string(labels["cycle"]))
+			next, err := ActualExpiryDate(labels, now)
+			if !ok || months == 0 || err != nil {
+				spend.Failed++
+			} else {
+				// 本月已经发生的上一次续费和即将到来的下一次续费都计入本月
+				for _, date := range []time.Time{utils.AddMonthsClamped(next, -months), next} {
+					if !date.Before(start) && date.Before(end) {
+						spend.Items = append(spend.Items, SpendItem{Instance: name, Kind: SpendRenewal, Date: date, Amount: amount, Currency: currency})
+					}
+				}
+			}
+		}
+
+		if metered, err := c.QueryMeteredUsage(labels, now); err != nil {
+			spend.Failed++
+		} else if metered != nil && metered.Priced && metered.ProjectedOverageCost() > 0 {
+			date := end.AddDate(0, 0, -1)
+			if policy, err := ResetPolicyFor(labels, now); err == nil && policy.NextReset(now).Before(date) {
+				date = policy.NextReset(now)
+			}
+			spend.Items = append(spend.Items, SpendItem{Instance: name, Kind: SpendTrafficOverage, Date: date, Amount: metered.ProjectedOverageCost(), Currency: metered.Currency})
+		}
+
+		if TrafficBilling(labels["billing"]) != BillingP95 {
+			continue
+		}
+		policy, err := ResetPolicyFor(labels, now)
+		if err != nil {
+			spend.Failed++
+			continue
+		}
+		p, err := c.QueryPercentile95(labels, policy.LastReset(now), now)
+		if err != nil {
+			spend.Failed++
+			continue
+		}
+		if p != nil && p.Priced && p.OverageCost() > 0 {
+			spend.Items = append(spend.Items, SpendItem{Instance: name, Kind: SpendOverage, Date: end.AddDate(0, 0, -1), Amount: p.OverageCost(), Currency: p.Currency})
+		}
+	}
+	sort.Slice(spend.Items, func(i, j int) bool {
+		if !spend.Items[i].Date.Equal(spend.Items[j].Date) {
+			return spend.Items[i].Date.Before(spend.Items[j].Date)
+		}
+		return spend.Items[i].Instance < spend.Items[j].Instance
+	})
+	return spend, nil
+}
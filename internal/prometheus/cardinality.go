@@ -0,0 +1,70 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+)
+
+// cardinalityWindow 是统计序列数的时间范围，只计算最近仍有数据的序列
+const cardinalityWindow = 5 * time.Minute
+
+// groupSeriesSelectors 是分组汇总查询涉及的序列中数量最多的几类，按实例的 CPU 核数和网卡数增长
+var groupSeriesSelectors = []string{
+	`node_cpu_seconds_total{mode="idle"}`,
+	fmt.Sprintf(`node_network_transmit_bytes_total{%s}`, networkDeviceMatcher),
+	fmt.Sprintf(`node_network_receive_bytes_total{%s}`, networkDeviceMatcher),
+}
+
+// CardinalityError 表示查询涉及的序列数超过上限，查询没有被执行
+type CardinalityError struct {
+	// Series 是统计到的序列数，series API 支持 limit 时最多为 Limit+1
+	Series int
+	Limit  int
+}
+
+func (e *CardinalityError) Error() string {
+	return fmt.Sprintf("query touches more than %d series (found %d)", e.Limit, e.Series)
+}
+
+// SetMaxSeries 设置分组、表格和自定义查询允许涉及的最大序列数，为 0 时不检查
+func (c *Client) SetMaxSeries(limit int) {
+	c.maxSeries = limit
+}
+
+// CheckCardinality 通过 series API 统计 selectors 最近匹配的序列数，超过上限时返回 *CardinalityError。
+// 请求带有 limit 参数，支持的 Prometheus 版本不会为此返回全部序列
+func (c *Client) CheckCardinality(selectors []string, now time.Time) error {
+	if c.maxSeries <= 0 || len(selectors) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	release, err := c.acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("Failed to query series count: %v", err)
+	}
+	defer release()
+
+	series, _, err := c.api.Series(ctx, selectors, now.Add(-cardinalityWindow), now, promv1.WithLimit(uint64(c.maxSeries)+1))
+	if err != nil {
+		return fmt.Errorf("Failed to query series count: %v", err)
+	}
+	if len(series) > c.maxSeries {
+		return &CardinalityError{Series: len(series), Limit: c.maxSeries}
+	}
+	return nil
+}
+
+// CheckQueryCardinality 检查 PromQL 中引用的指标的序列数。只按指标名统计，忽略标签过滤，
+// 所以结果偏大；无法识别指标名的查询不检查
+func (c *Client) CheckQueryCardinality(query string, now time.Time) error {
+	var selectors []string
+	for _, name := range MetricNames(query) {
+		selectors = append(selectors, fmt.Sprintf(`{__name__=%q}`, name))
+	}
+	return c.CheckCardinality(selectors, now)
+}
@@ -0,0 +1,76 @@
+package prometheus
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+// HighStealPercent 是 steal 时间占比偏高的判断标准，超过时通常说明宿主机超售或有邻居抢占 CPU
+const HighStealPercent = 10
+
+// CPUInfo 是 CPU steal 时间、频率和降频情况
+type CPUInfo struct {
+	// Steal 是虚拟机等待宿主机调度的时间占比（百分比）
+	Steal float64
+	// Frequency 和 MaxFrequency 是各核心当前和最高频率的平均值（Hz），没有 cpufreq 指标时为 0
+	Frequency    float64
+	MaxFrequency float64
+	// Throttles 是最近一小时因过热或功耗限制降频的次数，HasThrottles 为 false 表示没有 thermal_throttle 指标
+	Throttles    float64
+	HasThrottles bool
+}
+
+// HighSteal 判断 steal 时间占比是否偏高
+func (c CPUInfo) HighSteal() bool {
+	return c.Steal >= HighStealPercent
+}
+
+// FrequencyGHz 返回当前频率（GHz）
+func (c CPUInfo) FrequencyGHz() float64 {
+	return c.Frequency / 1e9
+}
+
+// MaxFrequencyGHz 返回最高频率（GHz）
+func (c CPUInfo) MaxFrequencyGHz() float64 {
+	return c.MaxFrequency / 1e9
+}
+
+// QueryCPUInfo 查询实例在 window 时间内的 CPU steal 占比以及当前频率和降频次数，没有任何相关指标时返回 nil
+func (c *Client) QueryCPUInfo(labels model.Metric, window string, now time.Time) (*CPUInfo, error) {
+	labelMatchers := BuildLabelMatchers(labels)
+	stealMatchers := `mode="steal"`
+	if labelMatchers != "" {
+		stealMatchers = labelMatchers + "," + stealMatchers
+	}
+	var info CPUInfo
+	found := false
+	for _, item := range []struct {
+		name  string
+		query string
+		value *float64
+		found *bool
+	}{
+		{"CPU steal", fmt.Sprintf(`avg(rate(node_cpu_seconds_total{%s}[%s])) * 100`, stealMatchers, window), &info.Steal, nil},
+		{"CPU frequency", fmt.Sprintf(`avg(node_cpu_scaling_frequency_hertz{%s})`, labelMatchers), &info.Frequency, nil},
+		{"CPU max frequency", fmt.Sprintf(`avg(node_cpu_scaling_frequency_max_hertz{%s})`, labelMatchers), &info.MaxFrequency, nil},
+		{"CPU throttles", fmt.Sprintf(`sum(increase(node_cpu_core_throttles_total{%s}[1h])) + sum(increase(node_cpu_package_throttles_total{%s}[1h]))`, labelMatchers, labelMatchers), &info.Throttles, &info.HasThrottles},
+	} {
+		result, err := c.QueryPrometheus(item.query, now)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to query %s: %v", item.name, err)
+		}
+		if vector, ok := result.(model.Vector); ok && vector.Len() > 0 {
+			found = true
+			*item.value = float64(vector[0].Value)
+			if item.found != nil {
+				*item.found = true
+			}
+		}
+	}
+	if !found {
+		return nil, nil
+	}
+	return &info, nil
+}
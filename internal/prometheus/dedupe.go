@@ -0,0 +1,199 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+)
+
+const (
+	// MergedInstancesLabel 是合并后的实例上记录被合并的其他实例的标签，值为逗号分隔的实例名
+	MergedInstancesLabel = "__merged_instances__"
+	// excludedInstancesLabel 表示查询时要排除的实例，值为匹配这些实例的正则表达式，
+	// BuildLabelMatchers 将其转换为 instance!~ 匹配器
+	excludedInstancesLabel = "__excluded_instances__"
+)
+
+// instanceDedupe 记录同一主机通过多个端口或 job 抓取时，重复的实例与保留的主实例的对应关系
+type instanceDedupe struct {
+	// label 是表示主机名的标签，up 上没有该标签时从 node_uname_info 中查找
+	label model.LabelName
+
+	mu sync.RWMutex
+	// duplicates 是重复实例到主实例的映射
+	duplicates map[string]string
+}
+
+// SetDedupeLabel 设置主机名标签（例如 nodename）。设置后主机名相同的实例合并为一个，
+// 实例列表、详情和汇总中只出现主实例，为空时不合并
+func (c *Client) SetDedupeLabel(label string) {
+	if label == "" {
+		c.dedupe = nil
+		return
+	}
+	c.dedupe = &instanceDedupe{label: model.LabelName(label), duplicates: make(map[string]string)}
+}
+
+// FleetLabels 返回查询所有实例的汇总数据时使用的标签。合并了实例时排除重复的实例，
+// 避免同一主机的流量和资源被计算多次
+func (c *Client) FleetLabels() model.Metric {
+	if c.dedupe == nil {
+		return model.Metric{}
+	}
+	c.dedupe.mu.RLock()
+	defer c.dedupe.mu.RUnlock()
+	if len(c.dedupe.duplicates) == 0 {
+		return model.Metric{}
+	}
+	var names []string
+	for name := range c.dedupe.duplicates {
+		names = append(names, regexp.QuoteMeta(name))
+	}
+	sort.Strings(names)
+	return model.Metric{excludedInstancesLabel: model.LabelValue(strings.Join(names, "|"))}
+}
+
+// lookupHostnames 为 up 上没有主机名标签的实例从 node_uname_info 中查找主机名
+func (d *instanceDedupe) lookupHostnames(ctx context.Context, api promv1.API, vector model.Vector) (map[string]string, error) {
+	hostnames := make(map[string]string)
+	missing := false
+	for _, sample := range vector {
+		if host := sample.Metric[d.label]; host != "" {
+			hostnames[string(sample.Metric["instance"])] = string(host)
+		} else {
+			missing = true
+		}
+	}
+	if !missing {
+		return hostnames, nil
+	}
+	result, _, err := api.Query(ctx, fmt.Sprintf(`group by (instance, %s) (node_uname_info)`, d.label), model.Now().Time())
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query hostnames: %v", err)
+	}
+	if info, ok := result.(model.Vector); ok {
+		for _, sample := range info {
+			instance := string(sample.Metric["instance"])
+			if _, ok := hostnames[instance]; !ok && sample.Metric[d.label] != "" {
+				hostnames[instance] = string(sample.Metric[d.label])
+			}
+		}
+	}
+	return hostnames, nil
+}
+
+// merge 将主机名相同的 up 样本合并为一个实例。优先保留在线的实例，其次按实例名排序，
+// 主实例上缺少的续费和计费标签从其他实例补充，并更新重复实例的映射
+func (d *instanceDedupe) merge(vector model.Vector, hostnames map[string]string) []model.Metric {
+	var order []string
+	hosts := make(map[string][]*model.Sample)
+	for _, sample := range vector {
+		instance := string(sample.Metric["instance"])
+		host, ok := hostnames[instance]
+		if !ok {
+			// 找不到主机名的实例不与其他实例合并
+			host = "\x00" + instance
+		}
+		if _, ok := hosts[host]; !ok {
+			order = append(order, host)
+		}
+		hosts[host] = append(hosts[host], sample)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var metrics []model.Metric
+	for _, host := range order {
+		samples := hosts[host]
+		sort.SliceStable(samples, func(i, j int) bool {
+			if samples[i].Value != samples[j].Value {
+				return samples[i].Value > samples[j].Value
+			}
+			return samples[i].Metric["instance"] < samples[j].Metric["instance"]
+		})
+		primary := samples[0].Metric.Clone()
+		delete(d.duplicates, string(primary["instance"]))
+		var merged []string
+		for _, sample := range samples[1:] {
+			name := string(sample.Metric["instance"])
+			d.duplicates[name] = string(primary["instance"])
+			merged = append(merged, name)
+			for k, v := range sample.Metric {
+				if _, ok := primary[k]; !ok && IsMetadataLabel(k) {
+					primary[k] = v
+				}
+			}
+		}
+		if len(merged) > 0 {
+			primary[MergedInstancesLabel] = model.LabelValue(strings.Join(merged, ","))
+		}
+		metrics = append(metrics, primary)
+	}
+	return metrics
+}
+
+// apply 将查询结果中重复实例的序列归到主实例下：结果中已有主实例的序列时丢弃重复实例的序列，
+// 否则将其 instance 标签改为主实例，这样按实例统计的列表和排名不会重复计算同一主机。
+// 映射在 FetchInstances 时更新，此前的查询结果不做处理
+func (d *instanceDedupe) apply(v model.Value) model.Value {
+	if d == nil {
+		return v
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if len(d.duplicates) == 0 {
+		return v
+	}
+	switch v := v.(type) {
+	case model.Vector:
+		present := make(map[model.LabelValue]model.LabelValue, len(v))
+		for _, sample := range v {
+			present[sample.Metric["instance"]] = sample.Metric["instance"]
+		}
+		out := make(model.Vector, 0, len(v))
+		for _, sample := range v {
+			if metric, ok := d.remap(sample.Metric, present); ok {
+				sample.Metric = metric
+				out = append(out, sample)
+			}
+		}
+		return out
+	case model.Matrix:
+		present := make(map[model.LabelValue]model.LabelValue, len(v))
+		for _, series := range v {
+			present[series.Metric["instance"]] = series.Metric["instance"]
+		}
+		out := make(model.Matrix, 0, len(v))
+		for _, series := range v {
+			if metric, ok := d.remap(series.Metric, present); ok {
+				series.Metric = metric
+				out = append(out, series)
+			}
+		}
+		return out
+	}
+	return v
+}
+
+// remap 返回序列归到主实例后的标签。present 记录结果中每个实例名的序列来自哪个实例，
+// 主实例或另一个重复实例已有序列时返回 false
+func (d *instanceDedupe) remap(metric model.Metric, present map[model.LabelValue]model.LabelValue) (model.Metric, bool) {
+	instance := metric["instance"]
+	primary, ok := d.duplicates[string(instance)]
+	if !ok {
+		return metric, true
+	}
+	if owner, ok := present[model.LabelValue(primary)]; ok && owner != instance {
+		return nil, false
+	}
+	present[model.LabelValue(primary)] = instance
+	metric = metric.Clone()
+	metric["instance"] = model.LabelValue(primary)
+	return metric, true
+}
@@ -0,0 +1,57 @@
+package prometheus
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+// 默认的目录大小指标，由 node_exporter textfile 收集器的 directory-size 脚本生成
+const (
+	DefaultDirectorySizeMetric = "node_directory_size_bytes"
+	DefaultDirectorySizeLabel  = "directory"
+)
+
+// DirectorySize 是单个目录占用的磁盘空间
+type DirectorySize struct {
+	Path  string
+	Bytes float64
+}
+
+// SetDirectorySizeMetric 设置目录大小的指标名称和表示目录路径的标签，为空时使用默认值
+func (c *Client) SetDirectorySizeMetric(metric, label string) {
+	c.dirSizeMetric = metric
+	c.dirSizeLabel = label
+}
+
+func (c *Client) directorySizeMetric() (metric, label string) {
+	metric, label = c.dirSizeMetric, c.dirSizeLabel
+	if metric == "" {
+		metric = DefaultDirectorySizeMetric
+	}
+	if label == "" {
+		label = DefaultDirectorySizeLabel
+	}
+	return metric, label
+}
+
+// QueryDirectorySizes 返回实例通过 textfile 收集器上报的各目录大小，按大小从大到小排序。
+// 实例没有上报该指标时返回空列表
+func (c *Client) QueryDirectorySizes(labels model.Metric, now time.Time) ([]DirectorySize, error) {
+	metric, label := c.directorySizeMetric()
+	query := fmt.Sprintf(`max by (%s) (%s{%s})`, label, metric, BuildLabelMatchers(labels))
+	result, err := c.QueryPrometheus(query, now)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query directory sizes: %v", err)
+	}
+	var sizes []DirectorySize
+	if vector, ok := result.(model.Vector); ok {
+		for _, sample := range vector {
+			sizes = append(sizes, DirectorySize{Path: string(sample.Metric[model.LabelName(label)]), Bytes: float64(sample.Value)})
+		}
+	}
+	sort.Slice(sizes, func(i, j int) bool { return sizes[i].Bytes > sizes[j].Bytes })
+	return sizes, nil
+}
@@ -0,0 +1,96 @@
+package prometheus
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+)
+
+// diskDeviceMatcher 排除 loop、光驱、内存盘等不反映真实磁盘负载的设备
+const diskDeviceMatcher = `device!~"loop.*|ram.*|fd.*|sr.*|zram.*|nbd.*"`
+
+// DiskIO 是单个块设备过去 5 分钟的平均 IO 情况
+type DiskIO struct {
+	Device     string
+	ReadBytes  float64
+	WriteBytes float64
+	ReadIOPS   float64
+	WriteIOPS  float64
+	// Utilization 是设备处于忙碌状态的时间占比（百分比），接近 100% 说明磁盘已饱和
+	Utilization float64
+}
+
+// diskIOMatchers 合并实例标签匹配器和设备过滤条件
+func diskIOMatchers(labels model.Metric) string {
+	if labelMatchers := BuildLabelMatchers(labels); labelMatchers != "" {
+		return labelMatchers + "," + diskDeviceMatcher
+	}
+	return diskDeviceMatcher
+}
+
+// QueryDiskIO 返回实例每个块设备的读写速率、IOPS 和繁忙度，按繁忙度从高到低排序
+func (c *Client) QueryDiskIO(labels model.Metric, now time.Time) ([]DiskIO, error) {
+	matchers := diskIOMatchers(labels)
+	devices := make(map[string]*DiskIO)
+	for _, item := range []struct {
+		name   string
+		metric string
+		scale  float64
+		field  func(*DiskIO) *float64
+	}{
+		{"read bytes", "node_disk_read_bytes_total", 1, func(d *DiskIO) *float64 { return &d.ReadBytes }},
+		{"written bytes", "node_disk_written_bytes_total", 1, func(d *DiskIO) *float64 { return &d.WriteBytes }},
+		{"reads", "node_disk_reads_completed_total", 1, func(d *DiskIO) *float64 { return &d.ReadIOPS }},
+		{"writes", "node_disk_writes_completed_total", 1, func(d *DiskIO) *float64 { return &d.WriteIOPS }},
+		{"io time", "node_disk_io_time_seconds_total", 100, func(d *DiskIO) *float64 { return &d.Utilization }},
+	} {
+		query := fmt.Sprintf(`sum by (device) (rate(%s{%s}[%s]))`, item.metric, matchers, c.RateRange(defaultRateWindow, labels))
+		result, err := c.QueryPrometheus(query, now)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to query disk %s: %v", item.name, err)
+		}
+		vector, ok := result.(model.Vector)
+		if !ok {
+			continue
+		}
+		for _, sample := range vector {
+			device := string(sample.Metric["device"])
+			if devices[device] == nil {
+				devices[device] = &DiskIO{Device: device}
+			}
+			*item.field(devices[device]) = float64(sample.Value) * item.scale
+		}
+	}
+
+	ios := make([]DiskIO, 0, len(devices))
+	for _, d := range devices {
+		ios = append(ios, *d)
+	}
+	sort.Slice(ios, func(i, j int) bool {
+		if ios[i].Utilization != ios[j].Utilization {
+			return ios[i].Utilization > ios[j].Utilization
+		}
+		return ios[i].Device < ios[j].Device
+	})
+	return ios, nil
+}
+
+// DiskIOHistory 返回实例在 window 时间内的磁盘读、写速率（所有设备之和）
+func (c *Client) DiskIOHistory(labels model.Metric, window time.Duration, now time.Time) (read, write model.Matrix, err error) {
+	matchers := diskIOMatchers(labels)
+	r := promv1.Range{Start: now.Add(-window), End: now, Step: rangeStep(window)}
+
+	rateRange := c.RateRange(defaultRateWindow, labels)
+	read, err = c.queryMatrix(fmt.Sprintf(`sum(rate(node_disk_read_bytes_total{%s}[%s]))`, matchers, rateRange), r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to query disk read history: %v", err)
+	}
+	write, err = c.queryMatrix(fmt.Sprintf(`sum(rate(node_disk_written_bytes_total{%s}[%s]))`, matchers, rateRange), r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to query disk write history: %v", err)
+	}
+	return DownsampleMatrix(read, chartPoints), DownsampleMatrix(write, chartPoints), nil
+}
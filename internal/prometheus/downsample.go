@@ -0,0 +1,98 @@
+package prometheus
+
+import (
+	"math"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+const (
+	// maxRangePoints 是范围查询每条曲线最多请求的点数，步长按此自动选择
+	maxRangePoints = 720
+	// chartPoints 是返回给图表的每条曲线的点数上限，多出的点用 LTTB 算法降采样
+	chartPoints = 240
+)
+
+// rangeSteps 是可选的查询步长，选择整齐的步长使相邻查询的时间点对齐，便于 Prometheus 缓存
+var rangeSteps = []time.Duration{
+	15 * time.Second,
+	30 * time.Second,
+	time.Minute,
+	2 * time.Minute,
+	5 * time.Minute,
+	10 * time.Minute,
+	15 * time.Minute,
+	30 * time.Minute,
+	time.Hour,
+	2 * time.Hour,
+	3 * time.Hour,
+	6 * time.Hour,
+	12 * time.Hour,
+	24 * time.Hour,
+}
+
+// rangeStep 根据时间窗口选择最小的整齐步长，使每条曲线不超过 maxRangePoints 个点
+func rangeStep(window time.Duration) time.Duration {
+	for _, step := range rangeSteps {
+		if window/step <= maxRangePoints {
+			return step
+		}
+	}
+	return rangeSteps[len(rangeSteps)-1]
+}
+
+// DownsampleMatrix 将矩阵中每条曲线降采样到最多 threshold 个点
+func DownsampleMatrix(matrix model.Matrix, threshold int) model.Matrix {
+	for _, stream := range matrix {
+		stream.Values = LTTB(stream.Values, threshold)
+	}
+	return matrix
+}
+
+// LTTB 使用 Largest-Triangle-Three-Buckets 算法将数据降采样到 threshold 个点，
+// 保留首尾两点和峰谷形状。点数不超过 threshold 或 threshold 小于 3 时原样返回
+func LTTB(values []model.SamplePair, threshold int) []model.SamplePair {
+	if threshold < 3 || len(values) <= threshold {
+		return values
+	}
+
+	sampled := make([]model.SamplePair, 0, threshold)
+	sampled = append(sampled, values[0])
+	// 除首尾两点外，其余点平均分到 threshold-2 个桶中
+	bucketSize := float64(len(values)-2) / float64(threshold-2)
+	prev := 0
+	for i := 0; i < threshold-2; i++ {
+		bucketStart := int(float64(i)*bucketSize) + 1
+		bucketEnd := int(float64(i+1)*bucketSize) + 1
+
+		// 下一个桶的平均点，最后一个桶使用最后一个数据点
+		nextStart, nextEnd := bucketEnd, int(float64(i+2)*bucketSize)+1
+		if nextEnd > len(values) {
+			nextEnd = len(values)
+		}
+		if nextStart >= nextEnd {
+			nextStart, nextEnd = len(values)-1, len(values)
+		}
+		var avgX, avgY float64
+		for _, v := range values[nextStart:nextEnd] {
+			avgX += float64(v.Timestamp)
+			avgY += float64(v.Value)
+		}
+		avgX /= float64(nextEnd - nextStart)
+		avgY /= float64(nextEnd - nextStart)
+
+		// 选择与前一个选中点和下一个桶平均点组成三角形面积最大的点
+		prevX, prevY := float64(values[prev].Timestamp), float64(values[prev].Value)
+		maxArea, selected := -1.0, bucketStart
+		for j := bucketStart; j < bucketEnd; j++ {
+			area := math.Abs((prevX-avgX)*(float64(values[j].Value)-prevY) - (prevX-float64(values[j].Timestamp))*(avgY-prevY))
+			if area > maxArea {
+				maxArea, selected = area, j
+			}
+		}
+		sampled = append(sampled, values[selected])
+		prev = selected
+	}
+	return append(sampled, values[len(values)-1])
+}
@@ -0,0 +1,65 @@
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// ErrorKind 是 Prometheus 查询错误的分类，用于向用户给出对应的提示
+type ErrorKind int
+
+const (
+	// ErrorUnknown 是无法归类的错误
+	ErrorUnknown ErrorKind = iota
+	// ErrorBadQuery 是 PromQL 语法错误或参数无效（bad_data，Prometheus 以 HTTP 400/422 返回），重试没有意义
+	ErrorBadQuery
+	// ErrorTimeout 是查询超时
+	ErrorTimeout
+	// ErrorTooLarge 是查询的数据点或样本数超过 Prometheus 的限制
+	ErrorTooLarge
+	// ErrorUnavailable 是 Prometheus 无法连接或返回 5xx
+	ErrorUnavailable
+)
+
+// errorPatterns 按顺序匹配错误信息。各层调用大多用 %v 包装错误，原始错误类型会丢失，因此按错误文本分类；
+// 数据点过多也以 bad_data 返回，需要排在语法错误之前
+var errorPatterns = []struct {
+	kind     ErrorKind
+	patterns []string
+}{
+	{ErrorTooLarge, []string{"exceeded maximum resolution", "too many samples", "would load too many"}},
+	{ErrorTimeout, []string{"timeout:", "context deadline exceeded", "Client.Timeout", "i/o timeout", "query timed out"}},
+	{ErrorBadQuery, []string{"bad_data:", "parse error", "invalid parameter"}},
+	{ErrorUnavailable, []string{"server_error:", "connection refused", "no such host", "connection reset", "server misbehaving"}},
+}
+
+// ClassifyError 判断查询错误的类型
+func ClassifyError(err error) ErrorKind {
+	if err == nil {
+		return ErrorUnknown
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrorTimeout
+	}
+	msg := err.Error()
+	for _, p := range errorPatterns {
+		for _, pattern := range p.patterns {
+			if strings.Contains(msg, pattern) {
+				return p.kind
+			}
+		}
+	}
+	return ErrorUnknown
+}
+
+// QueryErrorDetail 返回 Prometheus 给出的错误原因（例如 PromQL 解析错误的位置），去掉各层调用添加的前缀
+func QueryErrorDetail(err error) string {
+	msg := err.Error()
+	for _, prefix := range []string{"bad_data: ", "execution: "} {
+		if i := strings.LastIndex(msg, prefix); i >= 0 {
+			return msg[i+len(prefix):]
+		}
+	}
+	return msg
+}
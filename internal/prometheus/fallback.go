@@ -0,0 +1,121 @@
+package prometheus
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+// retentionTTL 是本地保留期缓存的有效期
+const retentionTTL = time.Hour
+
+// localRetention 缓存本地 Prometheus 的数据保留期，由同一个 Client 派生的所有客户端共享
+type localRetention struct {
+	mu sync.Mutex
+	// duration 为 0 表示保留期未知，例如只按大小限制保留或获取失败
+	duration  time.Duration
+	fetchedAt time.Time
+}
+
+// parseRetention 解析 runtimeinfo 中的 storageRetention，例如 "15d" 或 "15d or 10GiB"，只按大小限制时返回 0
+func parseRetention(s string) time.Duration {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return 0
+	}
+	d, err := model.ParseDuration(fields[0])
+	if err != nil {
+		return 0
+	}
+	return time.Duration(d)
+}
+
+// localRetentionStart 返回本地 Prometheus 最早保留的数据时间，保留期未知时返回 false。
+// 缓存过期后重新获取，获取在锁外进行，同一时间只有一个调用方获取；获取失败时在有效期内不再重试
+func (c *Client) localRetentionStart(ctx context.Context, now time.Time) (time.Time, bool) {
+	c.retention.mu.Lock()
+	duration := c.retention.duration
+	refresh := time.Since(c.retention.fetchedAt) > retentionTTL
+	if refresh {
+		// 先更新获取时间，避免并发的调用方重复获取
+		c.retention.fetchedAt = time.Now()
+	}
+	c.retention.mu.Unlock()
+
+	if refresh {
+		duration = 0
+		info, err := c.api.Runtimeinfo(ctx)
+		if err != nil {
+			log.Printf("Failed to detect local retention: %v", err)
+		} else {
+			duration = parseRetention(info.StorageRetention)
+		}
+		c.retention.mu.Lock()
+		c.retention.duration = duration
+		c.retention.mu.Unlock()
+	}
+	if duration <= 0 {
+		return time.Time{}, false
+	}
+	return now.Add(-duration), true
+}
+
+// needsFallback 判断从 start 开始的数据是否可能超出本地保留期，需要查询长期存储。
+// 保留期未知时也查询，长期存储的结果只用来补充本地缺少的部分
+func (c *Client) needsFallback(ctx context.Context, start time.Time) bool {
+	if c.fallback == nil {
+		return false
+	}
+	retentionStart, ok := c.localRetentionStart(ctx, time.Now())
+	return !ok || start.Before(retentionStart)
+}
+
+// mergeFallbackMatrix 按序列合并范围查询结果：本地已有的序列只用长期存储中早于本地第一个样本的点补齐，
+// 只在长期存储中存在的序列（例如已下线的实例）直接加入，本地的样本保持不变
+func mergeFallbackMatrix(local, fallback model.Matrix) model.Matrix {
+	byFingerprint := make(map[model.Fingerprint]*model.SampleStream, len(local))
+	for _, series := range local {
+		byFingerprint[series.Metric.Fingerprint()] = series
+	}
+	for _, series := range fallback {
+		existing, ok := byFingerprint[series.Metric.Fingerprint()]
+		if !ok {
+			local = append(local, series)
+			continue
+		}
+		if len(existing.Values) == 0 {
+			existing.Values = series.Values
+			continue
+		}
+		first := existing.Values[0].Timestamp
+		var earlier []model.SamplePair
+		for _, p := range series.Values {
+			if p.Timestamp.Before(first) {
+				earlier = append(earlier, p)
+			}
+		}
+		existing.Values = append(earlier, existing.Values...)
+	}
+	return local
+}
+
+// mergeFallbackVector 按序列合并即时查询结果。窗口超出本地保留期时本地的值只统计了保留期内的部分，
+// 长期存储中有的序列使用长期存储的值，只在本地存在的序列（例如新添加的实例）保留本地的值
+func mergeFallbackVector(local, fallback model.Vector) model.Vector {
+	byFingerprint := make(map[model.Fingerprint]int, len(local))
+	for i, sample := range local {
+		byFingerprint[sample.Metric.Fingerprint()] = i
+	}
+	for _, sample := range fallback {
+		if i, ok := byFingerprint[sample.Metric.Fingerprint()]; ok {
+			local[i] = sample
+		} else {
+			local = append(local, sample)
+		}
+	}
+	return local
+}
@@ -0,0 +1,83 @@
+package prometheus
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/common/model"
+)
+
+// FilesystemFilter 决定哪些文件系统计入磁盘使用统计，各字段均为 PromQL 正则表达式，为空时不过滤
+type FilesystemFilter struct {
+	IncludeFSTypes     string
+	ExcludeFSTypes     string
+	ExcludeMountpoints string
+}
+
+// DefaultFilesystemFilter 统计常见的本地文件系统（包括 btrfs 和 zfs），
+// 排除容器 overlay、内存文件系统以及 /boot 下的 vfat 引导分区
+var DefaultFilesystemFilter = FilesystemFilter{
+	IncludeFSTypes:     "ext[234]|xfs|btrfs|zfs|f2fs|jfs|reiserfs|bcachefs",
+	ExcludeFSTypes:     "rootfs|tmpfs|devtmpfs|overlay|squashfs|vfat|nsfs|fuse\\..*",
+	ExcludeMountpoints: "/boot(/.*)?|/run(/.*)?|/snap/.*|/var/lib/docker/.*",
+}
+
+// 实例可通过以下标签覆盖全局的文件系统过滤规则
+const (
+	fsTypesIncludeLabel     = "fs_types_include"
+	fsTypesExcludeLabel     = "fs_types_exclude"
+	mountpointsExcludeLabel = "mountpoints_exclude"
+)
+
+// SetFilesystemFilter 设置全局的文件系统过滤规则
+func (c *Client) SetFilesystemFilter(f FilesystemFilter) {
+	c.filesystemFilter = f
+}
+
+// filesystemFilterFor 返回实例实际使用的过滤规则，实例标签优先于全局配置
+func (c *Client) filesystemFilterFor(labels model.Metric) FilesystemFilter {
+	f := c.filesystemFilter
+	if v, ok := labels[fsTypesIncludeLabel]; ok {
+		f.IncludeFSTypes = string(v)
+	}
+	if v, ok := labels[fsTypesExcludeLabel]; ok {
+		f.ExcludeFSTypes = string(v)
+	}
+	if v, ok := labels[mountpointsExcludeLabel]; ok {
+		f.ExcludeMountpoints = string(v)
+	}
+	return f
+}
+
+// Matchers 返回过滤规则对应的标签匹配器，例如 fstype=~"ext4|xfs",mountpoint!~"/boot"
+func (f FilesystemFilter) Matchers() string {
+	var matchers []string
+	if f.IncludeFSTypes != "" {
+		matchers = append(matchers, fmt.Sprintf(`fstype=~"%s"`, escapeMatcherValue(f.IncludeFSTypes)))
+	}
+	if f.ExcludeFSTypes != "" {
+		matchers = append(matchers, fmt.Sprintf(`fstype!~"%s"`, escapeMatcherValue(f.ExcludeFSTypes)))
+	}
+	if f.ExcludeMountpoints != "" {
+		matchers = append(matchers, fmt.Sprintf(`mountpoint!~"%s"`, escapeMatcherValue(f.ExcludeMountpoints)))
+	}
+	return strings.Join(matchers, ",")
+}
+
+// filesystemMatchers 将实例标签匹配器与文件系统过滤规则合并
+func (c *Client) filesystemMatchers(labels model.Metric) string {
+	var parts []string
+	if labelMatchers := BuildLabelMatchers(labels); labelMatchers != "" {
+		parts = append(parts, labelMatchers)
+	}
+	if fsMatchers := c.filesystemFilterFor(labels).Matchers(); fsMatchers != "" {
+		parts = append(parts, fsMatchers)
+	}
+	return strings.Join(parts, ",")
+}
+
+// escapeMatcherValue 转义正则中的反斜杠和双引号，使其可以放入 PromQL 字符串
+func escapeMatcherValue(v string) string {
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	return strings.ReplaceAll(v, `"`, `\"`)
+}
@@ -0,0 +1,116 @@
+package prometheus
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+// freshnessWindow 是查找最近样本的回溯窗口，超过该窗口没有样本的实例视为数据缺失
+const freshnessWindow = "1h"
+
+// Freshness 描述实例指标数据的新鲜程度
+type Freshness struct {
+	// Absent 表示回溯窗口内没有任何样本
+	Absent bool
+	// Age 是最近一次样本距离现在的时间
+	Age time.Duration
+	// Skew 是节点时钟与 Prometheus 抓取时间的偏差
+	Skew time.Duration
+}
+
+// Stale 判断数据是否已过期或节点时钟偏差超过阈值
+func (f Freshness) Stale(threshold time.Duration) bool {
+	if threshold <= 0 {
+		return false
+	}
+	skew := f.Skew
+	if skew < 0 {
+		skew = -skew
+	}
+	return f.Absent || f.Age > threshold || skew > threshold
+}
+
+// Label 返回展示在过期数据旁的标记，例如 "数据过期(5m前)"
+func (f Freshness) Label() string {
+	switch {
+	case f.Absent:
+		return fmt.Sprintf("数据过期(%s以上)", freshnessWindow)
+	case f.Skew > f.Age || -f.Skew > f.Age:
+		return fmt.Sprintf("时钟偏差(%s)", formatAge(f.Skew))
+	default:
+		return fmt.Sprintf("数据过期(%s前)", formatAge(f.Age))
+	}
+}
+
+// SetStaleThreshold 设置数据过期的判断阈值，为 0 时不检查数据是否过期
+func (c *Client) SetStaleThreshold(threshold time.Duration) {
+	c.staleThreshold = threshold
+}
+
+// StaleThreshold 返回数据过期的判断阈值
+func (c *Client) StaleThreshold() time.Duration {
+	return c.staleThreshold
+}
+
+// GetFreshness 查询匹配 labels 的实例最近一次样本的时间和节点时钟偏差。
+// 返回结果以实例名为键，回溯窗口内没有样本的实例不会出现在结果中，可以用 FreshnessOf 查询
+func (c *Client) GetFreshness(labels model.Metric, now time.Time) (map[string]Freshness, error) {
+	selector := `node_time_seconds{job="node-exporter"}`
+	if labelMatchers := BuildLabelMatchers(labels); labelMatchers != "" {
+		selector = fmt.Sprintf(`node_time_seconds{job="node-exporter",%s}`, labelMatchers)
+	}
+
+	// timestamp() 返回样本本身的时间戳，取窗口内的最大值即为最近一次抓取的时间
+	ageQuery := fmt.Sprintf(`max by (instance) (max_over_time(timestamp(%s)[%s:1m]))`, selector, freshnessWindow)
+	ageResult, err := c.QueryPrometheus(ageQuery, now)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query sample timestamps: %v", err)
+	}
+	// node_time_seconds 的值是节点自身的时钟，与抓取时间戳相减得到时钟偏差
+	skewQuery := fmt.Sprintf(`max by (instance) (%s - timestamp(%s))`, selector, selector)
+	skewResult, err := c.QueryPrometheus(skewQuery, now)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query clock skew: %v", err)
+	}
+
+	freshness := make(map[string]Freshness)
+	if vector, ok := ageResult.(model.Vector); ok {
+		for _, sample := range vector {
+			last := time.Unix(0, int64(float64(sample.Value)*float64(time.Second)))
+			freshness[string(sample.Metric["instance"])] = Freshness{Age: now.Sub(last)}
+		}
+	}
+	if vector, ok := skewResult.(model.Vector); ok {
+		for _, sample := range vector {
+			instance := string(sample.Metric["instance"])
+			f := freshness[instance]
+			f.Skew = time.Duration(float64(sample.Value) * float64(time.Second))
+			freshness[instance] = f
+		}
+	}
+	return freshness, nil
+}
+
+// FreshnessOf 从 GetFreshness 的结果中取出指定实例的数据，缺失的实例视为数据缺失
+func FreshnessOf(freshness map[string]Freshness, instance string) Freshness {
+	if f, ok := freshness[instance]; ok {
+		return f
+	}
+	return Freshness{Absent: true}
+}
+
+func formatAge(d time.Duration) string {
+	if d < 0 {
+		d = -d
+	}
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	default:
+		return fmt.Sprintf("%dh%dm", int(d.Hours()), int(d.Minutes())%60)
+	}
+}
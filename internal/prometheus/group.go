@@ -0,0 +1,213 @@
+package prometheus
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/utils"
+	"github.com/prometheus/common/model"
+)
+
+// UngroupedName 是没有分组标签的实例所在的分组名称
+const UngroupedName = "未分组"
+
+// GroupSummary 是按标签（provider、region、dc 等）分组汇总的实例数据
+type GroupSummary struct {
+	Name      string
+	Instances int
+	Online    int
+	// Transmit 和 Receive 是本自然月的流量
+	Transmit float64
+	Receive  float64
+	// CPUUsage 和 MemoryUsage 是组内实例的平均使用率
+	CPUUsage    float64
+	MemoryUsage float64
+	// MonthlyCost 是按货币汇总的月均费用，年付等套餐按周期折算到每月
+	MonthlyCost map[string]float64
+	// Unpriced 是价格标签无法解析的实例数
+	Unpriced int
+}
+
+// TotalTraffic 返回本月上传和下载流量之和
+func (g GroupSummary) TotalTraffic() float64 {
+	return g.Transmit + g.Receive
+}
+
+// groupQuery 是一个按分组标签聚合的查询，set 将结果写入对应分组
+type groupQuery struct {
+	name  string
+	query string
+	set   func(g *GroupSummary, v float64)
+}
+
+// GroupSummaries 按 label 分组汇总所有实例的数量、本月流量、费用和资源使用率，按名称排序
+func (c *Client) GroupSummaries(label string, now time.Time) ([]GroupSummary, error) {
+	if !model.LabelName(label).IsValid() {
+		return nil, fmt.Errorf("invalid group label %q", label)
+	}
+	if err := c.CheckCardinality(groupSeriesSelectors, now); err != nil {
+		return nil, err
+	}
+	groups, _, err := c.groupInstances(func(instance model.Metric) string {
+		return string(instance[model.LabelName(label)])
+	}, now)
+	if err != nil {
+		return nil, err
+	}
+
+	startOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	queries := []groupQuery{
+		{"CPU usage", fmt.Sprintf(`100 * (1 - avg by (%s) (rate(node_cpu_seconds_total{mode="idle"}[%s])))`, label, c.ResourceRange(ResourceViewGroup)),
+			func(g *GroupSummary, v float64) { g.CPUUsage = v }},
+		{"memory usage", fmt.Sprintf(`100 * (1 - sum by (%s) (node_memory_MemAvailable_bytes) / sum by (%s) (node_memory_MemTotal_bytes))`, label, label),
+			func(g *GroupSummary, v float64) { g.MemoryUsage = v }},
+	}
+	if duration := getDurationString(now, startOfMonth); duration != "" {
+		queries = append(queries,
+			groupQuery{"upload traffic", fmt.Sprintf(`sum by (%s) (increase(node_network_transmit_bytes_total{%s}[%s]))`, label, networkDeviceMatcher, duration),
+				func(g *GroupSummary, v float64) { g.Transmit = v }},
+			groupQuery{"download traffic", fmt.Sprintf(`sum by (%s) (increase(node_network_receive_bytes_total{%s}[%s]))`, label, networkDeviceMatcher, duration),
+				func(g *GroupSummary, v float64) { g.Receive = v }},
+		)
+	}
+	for _, q := range queries {
+		result, err := c.QueryPrometheus(q.query, now)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to query group %s: %v", q.name, err)
+		}
+		if vector, ok := result.(model.Vector); ok {
+			for _, sample := range vector {
+				q.set(groups.get(string(sample.Metric[model.LabelName(label)])), float64(sample.Value))
+			}
+		}
+	}
+	return groups.sorted(), nil
+}
+
+// GroupSummariesFunc 与 GroupSummaries 相同，但由 groupOf 根据实例标签决定分组，
+// 用于按 IP 所在国家等不在 Prometheus 标签中的属性分组。各项数据按实例查询后在本地汇总
+func (c *Client) GroupSummariesFunc(groupOf func(instance model.Metric) string, now time.Time) ([]GroupSummary, error) {
+	if err := c.CheckCardinality(groupSeriesSelectors, now); err != nil {
+		return nil, err
+	}
+	groups, members, err := c.groupInstances(groupOf, now)
+	if err != nil {
+		return nil, err
+	}
+
+	// 按实例查询的原始数据，CPU 取组内实例的平均值，内存按组内总量计算
+	cpuSum := make(map[string]float64)
+	cpuCount := make(map[string]int)
+	memAvailable := make(map[string]float64)
+	memTotal := make(map[string]float64)
+	startOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	queries := []groupQuery{
+		{"CPU usage", fmt.Sprintf(`100 * (1 - avg by (instance) (rate(node_cpu_seconds_total{mode="idle"}[%s])))`, c.ResourceRange(ResourceViewGroup)),
+			func(g *GroupSummary, v float64) { cpuSum[g.Name] += v; cpuCount[g.Name]++ }},
+		{"memory available", `sum by (instance) (node_memory_MemAvailable_bytes)`,
+			func(g *GroupSummary, v float64) { memAvailable[g.Name] += v }},
+		{"memory total", `sum by (instance) (node_memory_MemTotal_bytes)`,
+			func(g *GroupSummary, v float64) { memTotal[g.Name] += v }},
+	}
+	if duration := getDurationString(now, startOfMonth); duration != "" {
+		queries = append(queries,
+			groupQuery{"upload traffic", fmt.Sprintf(`sum by (instance) (increase(node_network_transmit_bytes_total{%s}[%s]))`, networkDeviceMatcher, duration),
+				func(g *GroupSummary, v float64) { g.Transmit += v }},
+			groupQuery{"download traffic", fmt.Sprintf(`sum by (instance) (increase(node_network_receive_bytes_total{%s}[%s]))`, networkDeviceMatcher, duration),
+				func(g *GroupSummary, v float64) { g.Receive += v }},
+		)
+	}
+	for _, q := range queries {
+		result, err := c.QueryPrometheus(q.query, now)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to query group %s: %v", q.name, err)
+		}
+		if vector, ok := result.(model.Vector); ok {
+			for _, sample := range vector {
+				name, ok := members[string(sample.Metric["instance"])]
+				if !ok {
+					continue
+				}
+				q.set(groups.get(name), float64(sample.Value))
+			}
+		}
+	}
+	for name, g := range groups {
+		if cpuCount[name] > 0 {
+			g.CPUUsage = cpuSum[name] / float64(cpuCount[name])
+		}
+		if memTotal[name] > 0 {
+			g.MemoryUsage = 100 * (1 - memAvailable[name]/memTotal[name])
+		}
+	}
+	return groups.sorted(), nil
+}
+
+// groupSet 是按名称索引的分组汇总
+type groupSet map[string]*GroupSummary
+
+// get 返回名称对应的分组，不存在时创建，名称为空的实例归入未分组
+func (s groupSet) get(name string) *GroupSummary {
+	if name == "" {
+		name = UngroupedName
+	}
+	if s[name] == nil {
+		s[name] = &GroupSummary{Name: name, MonthlyCost: make(map[string]float64)}
+	}
+	return s[name]
+}
+
+// sorted 按名称返回所有分组，未分组的实例放在最后
+func (s groupSet) sorted() []GroupSummary {
+	summaries := make([]GroupSummary, 0, len(s))
+	for _, g := range s {
+		summaries = append(summaries, *g)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		if (summaries[i].Name == UngroupedName) != (summaries[j].Name == UngroupedName) {
+			return summaries[j].Name == UngroupedName
+		}
+		return summaries[i].Name < summaries[j].Name
+	})
+	return summaries
+}
+
+// groupInstances 将所有实例按 groupOf 分组，统计实例数、在线数和月费用，
+// 同时返回实例名到分组名称的映射
+func (c *Client) groupInstances(groupOf func(instance model.Metric) string, now time.Time) (groupSet, map[string]string, error) {
+	instances, err := c.FetchInstances(`up{job="node-exporter"}`)
+	if err != nil {
+		return nil, nil, err
+	}
+	online, err := c.QueryPrometheus(`up{job="node-exporter"} == 1`, now)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to query online instances: %v", err)
+	}
+	onlineSet := make(map[string]bool)
+	if vector, ok := online.(model.Vector); ok {
+		for _, sample := range vector {
+			onlineSet[string(sample.Metric["instance"])] = true
+		}
+	}
+
+	groups := make(groupSet)
+	members := make(map[string]string, len(instances))
+	for _, instance := range instances {
+		name := string(instance["instance"])
+		g := groups.get(groupOf(instance))
+		members[name] = g.Name
+		g.Instances++
+		if onlineSet[name] {
+			g.Online++
+		}
+		amount, currency, ok := utils.ParsePrice(string(instance["price"]))
+		months := CycleMonths(string(instance["cycle"]))
+		if !ok || months == 0 {
+			g.Unpriced++
+			continue
+		}
+		g.MonthlyCost[currency] += amount / float64(months)
+	}
+	return groups, members, nil
+}
@@ -0,0 +1,64 @@
+package prometheus
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/utils"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+)
+
+// TrafficDay 是一天内每小时的总流量（上传加下载）
+type TrafficDay struct {
+	Date time.Time
+	// Slots 是 24 个小时的流量字节数，没有数据（未来时间或未被抓取）为 NaN
+	Slots [24]float64
+}
+
+// Total 返回当天有数据的小时的流量之和
+func (d TrafficDay) Total() float64 {
+	var sum float64
+	for _, v := range d.Slots {
+		if !math.IsNaN(v) {
+			sum += v
+		}
+	}
+	return sum
+}
+
+// TrafficHeatmap 返回最近 days 天（含今天）每小时的总流量，按日期从早到晚排列。labels 为空时统计所有实例
+func (c *Client) TrafficHeatmap(labels model.Metric, days int, now time.Time) ([]TrafficDay, error) {
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).AddDate(0, 0, -(days - 1))
+	heatmap := make([]TrafficDay, days)
+	for i := range heatmap {
+		heatmap[i].Date = start.AddDate(0, 0, i)
+		for h := range heatmap[i].Slots {
+			heatmap[i].Slots[h] = math.NaN()
+		}
+	}
+
+	networkMatchers := networkDeviceMatcher
+	if labelMatchers := BuildLabelMatchers(labels); labelMatchers != "" {
+		networkMatchers = labelMatchers + "," + networkDeviceMatcher
+	}
+	// 每个点是截至该时刻前一小时的流量，因此第一个点在起始时间后一小时
+	query := fmt.Sprintf(`sum(increase(node_network_transmit_bytes_total{%s}[1h])) + sum(increase(node_network_receive_bytes_total{%s}[1h]))`, networkMatchers, networkMatchers)
+	r := promv1.Range{Start: start.Add(time.Hour), End: now, Step: time.Hour}
+	matrix, err := c.queryMatrix(query, r)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query traffic heatmap: %v", err)
+	}
+	for _, series := range matrix {
+		for _, p := range series.Values {
+			slotStart := p.Timestamp.Time().In(now.Location()).Add(-time.Hour)
+			day := utils.DaysBetween(start, slotStart)
+			if day < 0 || day >= days {
+				continue
+			}
+			heatmap[day].Slots[slotStart.Hour()] = float64(p.Value)
+		}
+	}
+	return heatmap, nil
+}
@@ -0,0 +1,147 @@
+package prometheus
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/utils"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+)
+
+// 可查询历史曲线的资源名称
+const (
+	HistoryCPU      = "cpu"
+	HistoryMemory   = "memory"
+	HistoryUpload   = "upload"
+	HistoryDownload = "download"
+	// HistoryTraffic 是每日总流量（上传加下载），步长固定为一天
+	HistoryTraffic = "traffic"
+)
+
+// networkDeviceMatcher 只统计物理网卡和常见虚拟化网卡的流量
+const networkDeviceMatcher = `device=~"eth.*|ens.*|eno.*|enp.*|enx.*|enX.*|wlan.*|venet.*"`
+
+// trendWidth 是趋势迷你图的字符数
+const trendWidth = 12
+
+// ResourceHistory 返回实例在 window 时间内的资源曲线：CPU、内存为使用率（百分比），
+// 上传、下载为速率（字节/秒），流量为每日总字节数。labels 为空时统计所有实例
+func (c *Client) ResourceHistory(resource string, labels model.Metric, window time.Duration, now time.Time) (model.Matrix, error) {
+	labelMatchers := BuildLabelMatchers(labels)
+	networkMatchers := networkDeviceMatcher
+	if labelMatchers != "" {
+		networkMatchers = labelMatchers + "," + networkDeviceMatcher
+	}
+	step := rangeStep(window)
+	var query string
+	switch resource {
+	case HistoryCPU:
+		if labelMatchers != "" {
+			labelMatchers += ","
+		}
+		query = fmt.Sprintf(`avg(rate(node_cpu_seconds_total{%smode!="idle"}[%s])) * 100`, labelMatchers, rateWindow(window))
+	case HistoryMemory:
+		query = fmt.Sprintf(`(1 - avg(node_memory_MemAvailable_bytes{%s}) / avg(node_memory_MemTotal_bytes{%s})) * 100`, labelMatchers, labelMatchers)
+	case HistoryUpload:
+		query = fmt.Sprintf(`sum(rate(node_network_transmit_bytes_total{%s}[%s]))`, networkMatchers, rateWindow(window))
+	case HistoryDownload:
+		query = fmt.Sprintf(`sum(rate(node_network_receive_bytes_total{%s}[%s]))`, networkMatchers, rateWindow(window))
+	case HistoryTraffic:
+		step = 24 * time.Hour
+		query = fmt.Sprintf(`sum(increase(node_network_transmit_bytes_total{%s}[1d])) + sum(increase(node_network_receive_bytes_total{%s}[1d]))`, networkMatchers, networkMatchers)
+	default:
+		return nil, fmt.Errorf("unknown history resource %q", resource)
+	}
+
+	r := promv1.Range{Start: now.Add(-window), End: now, Step: step}
+	matrix, err := c.queryMatrix(query, r)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query %s history: %v", resource, err)
+	}
+	return DownsampleMatrix(matrix, chartPoints), nil
+}
+
+// QueryHistory 执行任意 PromQL 在 window 时间内的范围查询，步长与资源曲线相同，结果已降采样
+func (c *Client) QueryHistory(query string, window time.Duration, now time.Time) (model.Matrix, error) {
+	r := promv1.Range{Start: now.Add(-window), End: now, Step: rangeStep(window)}
+	matrix, err := c.queryMatrix(query, r)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query history: %v", err)
+	}
+	return DownsampleMatrix(matrix, chartPoints), nil
+}
+
+// Trend 返回资源在 window 时间内的迷你趋势图，例如 "▁▂▃▅▇"，没有数据时返回空字符串
+func (c *Client) Trend(resource string, labels model.Metric, window time.Duration, now time.Time) (string, error) {
+	matrix, err := c.ResourceHistory(resource, labels, window, now)
+	if err != nil {
+		return "", err
+	}
+	if len(matrix) == 0 {
+		return "", nil
+	}
+	values := make([]float64, len(matrix[0].Values))
+	for i, v := range matrix[0].Values {
+		values[i] = float64(v.Value)
+	}
+	return utils.Sparkline(values, trendWidth), nil
+}
+
+// queryMatrix 执行范围查询并要求结果为矩阵
+func (c *Client) queryMatrix(query string, r promv1.Range) (model.Matrix, error) {
+	result, err := c.QueryRange(query, r)
+	if err != nil {
+		return nil, err
+	}
+	matrix, ok := result.(model.Matrix)
+	if !ok {
+		return nil, fmt.Errorf("unexpected result type %s", result.Type())
+	}
+	return matrix, nil
+}
+
+// rateWindow 返回与步长匹配的 rate 窗口，保证相邻点之间不会漏掉样本
+func rateWindow(window time.Duration) string {
+	w := 2 * rangeStep(window)
+	if w < 5*time.Minute {
+		w = 5 * time.Minute
+	}
+	return model.Duration(w).String()
+}
+
+// Trends 是详情页各项数值旁显示的迷你趋势图，查询失败或没有数据时为空
+type Trends struct {
+	// CPU、Memory、Upload、Download 为最近 1 小时的趋势
+	CPU      string
+	Memory   string
+	Upload   string
+	Download string
+	// Traffic 为最近 7 天每日总流量的趋势
+	Traffic string
+}
+
+// QueryTrends 查询实例各项指标的趋势，单项失败只记录日志
+func (c *Client) QueryTrends(labels model.Metric, now time.Time) Trends {
+	var trends Trends
+	for _, item := range []struct {
+		resource string
+		window   time.Duration
+		trend    *string
+	}{
+		{HistoryCPU, time.Hour, &trends.CPU},
+		{HistoryMemory, time.Hour, &trends.Memory},
+		{HistoryUpload, time.Hour, &trends.Upload},
+		{HistoryDownload, time.Hour, &trends.Download},
+		{HistoryTraffic, 7 * 24 * time.Hour, &trends.Traffic},
+	} {
+		trend, err := c.Trend(item.resource, labels, item.window, now)
+		if err != nil {
+			log.Printf("Failed to query %s trend: %v", item.resource, err)
+			continue
+		}
+		*item.trend = trend
+	}
+	return trends
+}
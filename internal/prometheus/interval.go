@@ -0,0 +1,143 @@
+package prometheus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+const (
+	// TrafficRateWindow 是网络速率默认的计算窗口，默认的 15s 抓取间隔下包含 4 个样本
+	TrafficRateWindow = time.Minute
+	// defaultRateWindow 是网卡、磁盘 IO、PSI 等速率默认的计算窗口
+	defaultRateWindow = 5 * time.Minute
+	// scrapeIntervalTTL 是抓取间隔缓存的有效期，目标的抓取间隔很少变化
+	scrapeIntervalTTL = 10 * time.Minute
+)
+
+// scrapeIntervals 缓存从 targets API 获取的各实例抓取间隔，由同一个 Client 派生的所有客户端共享
+type scrapeIntervals struct {
+	mu         sync.Mutex
+	byInstance map[string]time.Duration
+	// longest 是所有目标中最长的抓取间隔，用于统计所有实例的查询
+	longest   time.Duration
+	fetchedAt time.Time
+}
+
+// targetsResponse 是 /api/v1/targets 响应中需要的部分。promv1 的 ActiveTarget 没有 scrapeInterval 字段，
+// 因此直接调用接口
+type targetsResponse struct {
+	Status string `json:"status"`
+	Data   struct {
+		ActiveTargets []struct {
+			Labels         map[string]string `json:"labels"`
+			ScrapeInterval string            `json:"scrapeInterval"`
+		} `json:"activeTargets"`
+	} `json:"data"`
+}
+
+// fetchScrapeIntervals 通过 targets API 获取各实例的抓取间隔。Prometheus 2.42 之前的版本不返回抓取间隔，此时结果为空
+func (c *Client) fetchScrapeIntervals(ctx context.Context) (map[string]time.Duration, error) {
+	u := c.raw.URL("/api/v1/targets", nil)
+	u.RawQuery = "state=active"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create targets request: %v", err)
+	}
+	resp, body, err := c.raw.Do(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query targets: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("targets API returned %s", resp.Status)
+	}
+	var targets targetsResponse
+	if err := json.Unmarshal(body, &targets); err != nil {
+		return nil, fmt.Errorf("Failed to parse targets: %v", err)
+	}
+	intervals := make(map[string]time.Duration)
+	for _, t := range targets.Data.ActiveTargets {
+		interval, err := model.ParseDuration(t.ScrapeInterval)
+		if err != nil || t.Labels["instance"] == "" {
+			continue
+		}
+		// 同一实例被多个 job 抓取时按最长的间隔
+		if d := time.Duration(interval); d > intervals[t.Labels["instance"]] {
+			intervals[t.Labels["instance"]] = d
+		}
+	}
+	return intervals, nil
+}
+
+// scrapeInterval 返回实例的抓取间隔，instance 为空时返回所有目标中最长的间隔，未知时为 0。
+// 缓存过期后重新获取，获取在锁外进行，同一时间只有一个调用方获取，其他调用方继续使用旧的结果；
+// 获取失败时在有效期内不再重试
+func (c *Client) scrapeInterval(instance string) time.Duration {
+	if c.intervals == nil || c.raw == nil {
+		return 0
+	}
+	c.intervals.mu.Lock()
+	refresh := time.Since(c.intervals.fetchedAt) > scrapeIntervalTTL
+	if refresh {
+		// 先更新获取时间，避免并发的调用方重复获取
+		c.intervals.fetchedAt = time.Now()
+	}
+	c.intervals.mu.Unlock()
+
+	if refresh {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		intervals, err := c.fetchScrapeIntervals(ctx)
+		cancel()
+		if err != nil {
+			log.Printf("Failed to detect scrape intervals: %v", err)
+		} else {
+			var longest time.Duration
+			var slow int
+			for _, d := range intervals {
+				longest = max(longest, d)
+				if 2*d > TrafficRateWindow {
+					slow++
+				}
+			}
+			if slow > 0 {
+				log.Printf("%d targets are scraped less often than every %s, rate windows are widened to twice their scrape interval (longest %s)",
+					slow, TrafficRateWindow/2, longest)
+			}
+			c.intervals.mu.Lock()
+			c.intervals.byInstance, c.intervals.longest = intervals, longest
+			c.intervals.mu.Unlock()
+		}
+	}
+
+	c.intervals.mu.Lock()
+	defer c.intervals.mu.Unlock()
+	if instance == "" {
+		return c.intervals.longest
+	}
+	return c.intervals.byInstance[instance]
+}
+
+// ScrapeInterval 返回实例的抓取间隔，未知时为 0
+func (c *Client) ScrapeInterval(instance string) time.Duration {
+	if instance == "" {
+		return 0
+	}
+	return c.scrapeInterval(instance)
+}
+
+// RateWindow 返回计算速率时使用的窗口：max(2×抓取间隔, window)。窗口内少于两个样本时 rate 和 increase 没有结果，
+// 抓取间隔为 1m 时 rate(...[1m]) 总是为空。labels 中有 instance 时按该实例的抓取间隔，否则按所有目标中最长的间隔
+func (c *Client) RateWindow(window time.Duration, labels model.Metric) time.Duration {
+	return max(window, 2*c.scrapeInterval(string(labels["instance"])))
+}
+
+// RateRange 返回 RateWindow 对应的 PromQL 范围，例如 "1m"
+func (c *Client) RateRange(window time.Duration, labels model.Metric) string {
+	return model.Duration(c.RateWindow(window, labels)).String()
+}
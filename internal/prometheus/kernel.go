@@ -0,0 +1,81 @@
+package prometheus
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+// lowEntropyBits 是可用熵偏低的判断标准，低于该值时读取 /dev/random 可能阻塞
+const lowEntropyBits = 200
+
+// KernelHealth 是 OOM Kill、内存 ECC 错误和可用熵等内核状态，Has* 为 false 表示没有对应的指标
+type KernelHealth struct {
+	// OOMKills 是最近 24 小时内核因内存不足终止进程的次数（node_vmstat_oom_kill）
+	OOMKills float64
+	HasOOM   bool
+	// EDACCorrectable 和 EDACUncorrectable 是最近 24 小时可纠正和不可纠正的内存 ECC 错误数（edac 收集器）
+	EDACCorrectable   float64
+	EDACUncorrectable float64
+	HasEDAC           bool
+	// Entropy 和 EntropyPool 是内核可用熵和熵池大小（bits）
+	Entropy     float64
+	EntropyPool float64
+	HasEntropy  bool
+}
+
+// LowEntropy 判断可用熵是否偏低
+func (k KernelHealth) LowEntropy() bool {
+	return k.HasEntropy && k.Entropy < lowEntropyBits
+}
+
+// QueryKernelHealth 查询实例的内核状态指标，没有任何相关指标时返回 nil
+func (c *Client) QueryKernelHealth(labels model.Metric, now time.Time) (*KernelHealth, error) {
+	labelMatchers := BuildLabelMatchers(labels)
+	var health KernelHealth
+	found := false
+	for _, item := range []struct {
+		name  string
+		query string
+		value *float64
+		found *bool
+	}{
+		{"OOM kills", fmt.Sprintf(`round(sum(increase(node_vmstat_oom_kill{%s}[24h])))`, labelMatchers), &health.OOMKills, &health.HasOOM},
+		{"correctable memory errors", fmt.Sprintf(`round(sum(increase(node_edac_correctable_errors_total{%s}[24h])))`, labelMatchers), &health.EDACCorrectable, &health.HasEDAC},
+		{"uncorrectable memory errors", fmt.Sprintf(`round(sum(increase(node_edac_uncorrectable_errors_total{%s}[24h])))`, labelMatchers), &health.EDACUncorrectable, &health.HasEDAC},
+		{"available entropy", fmt.Sprintf(`min(node_entropy_available_bits{%s})`, labelMatchers), &health.Entropy, &health.HasEntropy},
+		{"entropy pool size", fmt.Sprintf(`min(node_entropy_pool_size_bits{%s})`, labelMatchers), &health.EntropyPool, nil},
+	} {
+		result, err := c.QueryPrometheus(item.query, now)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to query %s: %v", item.name, err)
+		}
+		if vector, ok := result.(model.Vector); ok && vector.Len() > 0 {
+			found = true
+			*item.value = float64(vector[0].Value)
+			if item.found != nil {
+				*item.found = true
+			}
+		}
+	}
+	if !found {
+		return nil, nil
+	}
+	return &health, nil
+}
+
+// OOMKillCounters 返回每个实例 OOM Kill 的累计次数，用于检测新发生的 OOM Kill
+func (c *Client) OOMKillCounters(now time.Time) (map[string]float64, error) {
+	result, err := c.QueryPrometheus(`sum by (instance) (node_vmstat_oom_kill)`, now)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query OOM kill counters: %v", err)
+	}
+	counters := make(map[string]float64)
+	if vector, ok := result.(model.Vector); ok {
+		for _, sample := range vector {
+			counters[string(sample.Metric["instance"])] = float64(sample.Value)
+		}
+	}
+	return counters, nil
+}
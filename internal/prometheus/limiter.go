@@ -0,0 +1,59 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// queryLimiter 限制同时发往 Prometheus 的查询数量。
+// global 限制总并发数，perKey 限制单个来源（聊天）的并发数，避免一个聊天的批量查询占满所有名额
+type queryLimiter struct {
+	global chan struct{}
+	perKey int
+
+	mu   sync.Mutex
+	keys map[string]chan struct{}
+}
+
+func newQueryLimiter(global, perKey int) *queryLimiter {
+	if perKey <= 0 || perKey > global {
+		perKey = global
+	}
+	return &queryLimiter{
+		global: make(chan struct{}, global),
+		perKey: perKey,
+		keys:   make(map[string]chan struct{}),
+	}
+}
+
+func (l *queryLimiter) keySemaphore(key string) chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	sem, ok := l.keys[key]
+	if !ok {
+		sem = make(chan struct{}, l.perKey)
+		l.keys[key] = sem
+	}
+	return sem
+}
+
+// acquire 先占用来源的名额再占用全局名额，返回释放函数
+func (l *queryLimiter) acquire(ctx context.Context, key string) (func(), error) {
+	keySem := l.keySemaphore(key)
+	select {
+	case keySem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("waiting for query slot: %v", ctx.Err())
+	}
+	select {
+	case l.global <- struct{}{}:
+	case <-ctx.Done():
+		<-keySem
+		return nil, fmt.Errorf("waiting for query slot: %v", ctx.Err())
+	}
+	return func() {
+		<-l.global
+		<-keySem
+	}, nil
+}
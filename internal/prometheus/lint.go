@@ -0,0 +1,134 @@
+package prometheus
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/utils"
+	"github.com/prometheus/common/model"
+)
+
+// LabelProblem 是实例的一个缺失或无法解析的续费、计费标签
+type LabelProblem struct {
+	Label string
+	// Value 是标签的原始值，缺少标签时为空
+	Value   string
+	Problem string
+	// Blocking 表示该问题会导致实例详情无法显示，其他问题只影响费用统计等部分功能
+	Blocking bool
+}
+
+// InstanceLabelProblems 是一个实例的所有标签问题
+type InstanceLabelProblems struct {
+	Instance string
+	Problems []LabelProblem
+}
+
+// Blocking 判断实例是否有导致详情无法显示的问题
+func (p InstanceLabelProblems) Blocking() bool {
+	for _, problem := range p.Problems {
+		if problem.Blocking {
+			return true
+		}
+	}
+	return false
+}
+
+// LintInstanceLabels 按 GetInstanceDetail、费用统计和流量配额的解析规则检查实例的续费和计费标签，
+// 返回发现的问题，标签都正确时为空
+func LintInstanceLabels(labels model.Metric) []LabelProblem {
+	var problems []LabelProblem
+	add := func(label, problem string, blocking bool) {
+		problems = append(problems, LabelProblem{Label: label, Value: string(labels[model.LabelName(label)]), Problem: problem, Blocking: blocking})
+	}
+
+	if expiry := string(labels["expiry"]); strings.TrimSpace(expiry) == "" {
+		add("expiry", "缺少到期日期", true)
+	} else if _, err := time.Parse("2006-01-02", expiry); err != nil {
+		add("expiry", "无法解析的日期，格式应为 2006-01-02", true)
+	}
+
+	cycle := string(labels["cycle"])
+	if cycle == "" {
+		add("cycle", "缺少付费周期，到期日期不会按周期顺延", false)
+	} else if CycleMonths(cycle) == 0 {
+		add("cycle", "无法识别的付费周期，可选 1month、3month、6month、1year、3year", false)
+	}
+
+	if price := string(labels["price"]); price == "" {
+		add("price", "缺少价格，不计入费用统计", false)
+	} else if _, _, ok := utils.ParsePrice(price); !ok {
+		add("price", "无法解析的价格，例如 5USD 或 ¥30/月", false)
+	}
+
+	if strings.TrimSpace(string(labels["info"])) == "" {
+		add("info", "缺少说明", false)
+	}
+
+	// 与 ResetPolicyFor 的解析规则相同，没有 reset_policy 时按 cycle 推导
+	policy := ResetPeriod(labels["reset_policy"])
+	if policy == "" {
+		policy = defaultResetPeriod(cycle)
+	}
+	switch policy {
+	case ResetMonthly, ResetQuarterly, ResetYearly:
+	case ResetNone:
+		if CycleMonths(cycle) == 0 {
+			add("reset_policy", "续费时重置需要可识别的 cycle 标签", true)
+		}
+	default:
+		add("reset_policy", "无法识别的重置规则，可选 monthly、quarterly、yearly、none", true)
+	}
+	if v := string(labels["reset_day"]); v != "" && policy != ResetNone {
+		if _, err := time.Parse("2006-01-02", v); err != nil {
+			add("reset_day", "无法解析的日期，格式应为 2006-01-02", true)
+		}
+	}
+
+	switch billing := TrafficBilling(labels["billing"]); billing {
+	case "", BillingSum, BillingMax, BillingOut, BillingIn:
+	case BillingP95:
+		if v := string(labels["commit_rate"]); v != "" {
+			if _, ok := utils.ParseBitRate(v); !ok {
+				add("commit_rate", "无法解析的承诺速率，例如 100Mbps", false)
+			}
+		}
+		if v := string(labels["overage_price"]); v != "" {
+			if _, _, ok := utils.ParsePrice(v); !ok {
+				add("overage_price", "无法解析的超额价格", false)
+			}
+		}
+	default:
+		add("billing", "无法识别的计费方式，可选 sum、max、out、in、p95，当前按上传和下载之和计费", false)
+	}
+
+	if v := string(labels["traffic_quota"]); v != "" {
+		if quota, ok := utils.ParseBytes(v); !ok || quota <= 0 {
+			add("traffic_quota", "无法解析的流量配额，例如 1TB，配额用量不会显示", false)
+		}
+		if v := string(labels["overage_per_gb"]); v != "" {
+			if _, _, ok := utils.ParsePrice(v); !ok {
+				add("overage_per_gb", "无法解析的超额单价，配额用量不会显示", false)
+			}
+		}
+	}
+	return problems
+}
+
+// LintInstances 检查所有实例的标签，只返回有问题的实例，会导致详情无法显示的排在前面，其余按实例名排序
+func LintInstances(instances []model.Metric) []InstanceLabelProblems {
+	var result []InstanceLabelProblems
+	for _, instance := range instances {
+		if problems := LintInstanceLabels(instance); len(problems) > 0 {
+			result = append(result, InstanceLabelProblems{Instance: string(instance["instance"]), Problems: problems})
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if bi, bj := result[i].Blocking(), result[j].Blocking(); bi != bj {
+			return bi
+		}
+		return result[i].Instance < result[j].Instance
+	})
+	return result
+}
@@ -0,0 +1,81 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// MetricMetadata 是指标的类型和说明，来自 Prometheus 的 /api/v1/metadata
+type MetricMetadata struct {
+	Metric string
+	Type   string
+	Help   string
+	Unit   string
+}
+
+// Metadata 查询指标的元数据。不同 target 上报的说明可能不同，返回所有不重复的版本，
+// 指标不存在或是 recording rule 生成的指标时返回空列表
+func (c *Client) Metadata(metric string) ([]MetricMetadata, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	release, err := c.acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query metric metadata: %v", err)
+	}
+	defer release()
+
+	result, err := c.api.Metadata(ctx, metric, "")
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query metric metadata: %v", err)
+	}
+	var metadata []MetricMetadata
+	for _, m := range result[metric] {
+		metadata = append(metadata, MetricMetadata{Metric: metric, Type: string(m.Type), Help: m.Help, Unit: m.Unit})
+	}
+	return metadata, nil
+}
+
+var (
+	// promqlIgnored 匹配 PromQL 中不含指标名的部分：字符串、标签匹配器和范围选择器
+	promqlIgnored = regexp.MustCompile(`"(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*'|\{[^}]*\}|\[[^\]]*\]`)
+	// promqlGrouping 匹配 by/without/on/ignoring/group_left/group_right 后的标签列表
+	promqlGrouping = regexp.MustCompile(`(?i)\b(?:by|without|on|ignoring|group_left|group_right)\s*\([^)]*\)`)
+	promqlIdent    = regexp.MustCompile(`[a-zA-Z_:][a-zA-Z0-9_:]*(\s*\()?`)
+)
+
+// promqlKeywords 是 PromQL 中的关键字和运算符，不是指标名
+var promqlKeywords = map[string]bool{
+	"and": true, "or": true, "unless": true, "bool": true, "offset": true,
+	"by": true, "without": true, "on": true, "ignoring": true, "group_left": true, "group_right": true,
+	"inf": true, "nan": true,
+}
+
+// MetricNames 返回 PromQL 表达式中引用的指标名称，按出现顺序去重。
+// 这里只做简单的词法分析，不处理 {__name__="..."} 形式的选择器
+func MetricNames(query string) []string {
+	query = promqlIgnored.ReplaceAllString(query, " ")
+	query = promqlGrouping.ReplaceAllString(query, " ")
+
+	seen := make(map[string]bool)
+	var names []string
+	for _, match := range promqlIdent.FindAllStringSubmatchIndex(query, -1) {
+		// 后面紧跟括号的是函数或聚合操作
+		if match[2] >= 0 {
+			continue
+		}
+		// 数字中的指数部分（例如 1e3 中的 e3）
+		if start := match[0]; start > 0 && (query[start-1] >= '0' && query[start-1] <= '9' || query[start-1] == '.') {
+			continue
+		}
+		name := query[match[0]:match[1]]
+		if promqlKeywords[name] || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,116 @@
+package prometheus
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/utils"
+	"github.com/prometheus/common/model"
+)
+
+// TrafficQuota 是按流量计费的套餐：traffic_quota 标签指定每个重置周期包含的流量（例如 "1TB"），
+// overage_per_gb 标签指定超出部分每 GB 的价格（例如 "$0.01"）
+type TrafficQuota struct {
+	// Label 是 traffic_quota 标签的原始值，按服务商的写法显示配额
+	Label string
+	// Included 是每个重置周期包含的流量字节数
+	Included float64
+	// PricePerGB 是超出部分每 GB（10^9 字节）的价格，Priced 为 false 表示未设置
+	PricePerGB float64
+	Currency   string
+	Priced     bool
+}
+
+// TrafficQuotaFor 解析实例的流量配额标签，没有 traffic_quota 标签时返回 nil
+func TrafficQuotaFor(labels model.Metric) (*TrafficQuota, error) {
+	v := string(labels["traffic_quota"])
+	if v == "" {
+		return nil, nil
+	}
+	included, ok := utils.ParseBytes(v)
+	if !ok || included <= 0 {
+		return nil, fmt.Errorf("invalid traffic_quota %q", v)
+	}
+	q := &TrafficQuota{Label: v, Included: included}
+	if v := string(labels["overage_per_gb"]); v != "" {
+		q.PricePerGB, q.Currency, q.Priced = utils.ParsePrice(v)
+		if !q.Priced {
+			return nil, fmt.Errorf("invalid overage_per_gb %q", v)
+		}
+	}
+	return q, nil
+}
+
+// Overage 返回用量超出配额的字节数
+func (q TrafficQuota) Overage(usage float64) float64 {
+	return max(usage-q.Included, 0)
+}
+
+// OverageCost 返回用量超出配额部分的费用，未设置价格时为 0
+func (q TrafficQuota) OverageCost(usage float64) float64 {
+	return q.Overage(usage) / 1e9 * q.PricePerGB
+}
+
+// MeteredUsage 是按流量计费的实例在当前重置周期内的用量和预计的超额费用
+type MeteredUsage struct {
+	TrafficQuota
+	// Used 是本周期已用的计费流量，Projected 是按本周期至今的平均速度推算到周期结束时的用量
+	Used      float64
+	Projected float64
+}
+
+// NewMeteredUsage 根据本周期（lastReset 到 nextReset）至今的用量推算周期结束时的用量
+func NewMeteredUsage(quota TrafficQuota, used float64, lastReset, nextReset, now time.Time) *MeteredUsage {
+	m := &MeteredUsage{TrafficQuota: quota, Used: used, Projected: used}
+	if elapsed := now.Sub(lastReset); elapsed > 0 && nextReset.After(now) {
+		m.Projected = used / elapsed.Seconds() * nextReset.Sub(lastReset).Seconds()
+	}
+	return m
+}
+
+// UsedPercent 返回已用流量占配额的百分比
+func (m MeteredUsage) UsedPercent() float64 {
+	return m.Used / m.Included * 100
+}
+
+// CurrentOverage 返回已经超出配额的流量
+func (m MeteredUsage) CurrentOverage() float64 {
+	return m.Overage(m.Used)
+}
+
+// CurrentOverageCost 返回已经超出配额部分的费用
+func (m MeteredUsage) CurrentOverageCost() float64 {
+	return m.OverageCost(m.Used)
+}
+
+// ProjectedOverage 返回按推算用量预计在周期结束时超出配额的流量
+func (m MeteredUsage) ProjectedOverage() float64 {
+	return m.Overage(m.Projected)
+}
+
+// ProjectedOverageCost 返回按推算用量预计在周期结束时的超额费用
+func (m MeteredUsage) ProjectedOverageCost() float64 {
+	return m.OverageCost(m.Projected)
+}
+
+// QueryMeteredUsage 查询实例在当前重置周期内的计费流量，没有 traffic_quota 标签的实例返回 nil
+func (c *Client) QueryMeteredUsage(labels model.Metric, now time.Time) (*MeteredUsage, error) {
+	quota, err := TrafficQuotaFor(labels)
+	if err != nil || quota == nil {
+		return nil, err
+	}
+	policy, err := ResetPolicyFor(labels, now)
+	if err != nil {
+		return nil, err
+	}
+	lastReset, nextReset := policy.LastReset(now), policy.NextReset(now)
+	duration := getDurationString(now, lastReset)
+	if duration == "" {
+		return NewMeteredUsage(*quota, 0, lastReset, nextReset, now), nil
+	}
+	transmit, receive, err := c.queryTrafficForDuration(labels, duration, now)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query metered traffic: %v", err)
+	}
+	return NewMeteredUsage(*quota, TrafficBillingFor(labels).Usage(transmit, receive), lastReset, nextReset, now), nil
+}
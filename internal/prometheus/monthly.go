@@ -0,0 +1,169 @@
+package prometheus
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/utils"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+)
+
+// MonthlyInstance 是单个实例在一个自然月内的流量、在线率和费用
+type MonthlyInstance struct {
+	Instance string
+	Transmit float64
+	Receive  float64
+	// Billing 是实例的流量计费方式
+	Billing TrafficBilling
+	// Uptime 是 0 到 1 之间的在线比例，HasUptime 为 false 表示该月没有数据
+	Uptime    float64
+	HasUptime bool
+	// MonthlyCost 是按周期折算到每月的费用，Priced 为 false 表示价格标签无法解析
+	MonthlyCost float64
+	Currency    string
+	Priced      bool
+	// Quota 是 traffic_quota 标签指定的流量配额，未设置时为 nil。报告按自然月统计，
+	// 重置日不在月初的实例的超额是近似值
+	Quota *TrafficQuota
+}
+
+// TotalTraffic 返回上传和下载流量之和
+func (m MonthlyInstance) TotalTraffic() float64 {
+	return m.Transmit + m.Receive
+}
+
+// BilledTraffic 返回按实例计费方式统计的流量用量
+func (m MonthlyInstance) BilledTraffic() float64 {
+	return m.Billing.Usage(m.Transmit, m.Receive)
+}
+
+// TrafficOverage 返回计费流量超出配额的部分，未设置配额时为 0
+func (m MonthlyInstance) TrafficOverage() float64 {
+	if m.Quota == nil {
+		return 0
+	}
+	return m.Quota.Overage(m.BilledTraffic())
+}
+
+// OverageCost 返回超出配额部分的流量费用，未设置配额或价格时为 0
+func (m MonthlyInstance) OverageCost() float64 {
+	if m.Quota == nil {
+		return 0
+	}
+	return m.Quota.OverageCost(m.BilledTraffic())
+}
+
+// DailyTraffic 是所有实例一天的总流量
+type DailyTraffic struct {
+	Day   time.Time
+	Bytes float64
+}
+
+// MonthlyReport 是所有实例一个自然月的汇总
+type MonthlyReport struct {
+	// Start 是月初，End 是月末或（当月报告的）生成时间
+	Start     time.Time
+	End       time.Time
+	Instances []MonthlyInstance
+	Daily     []DailyTraffic
+	// Costs 是按货币汇总的月费用
+	Costs map[string]float64
+	// OverageCosts 是按货币汇总的流量超额费用，没有实例超出配额时为空
+	OverageCosts map[string]float64
+}
+
+// MonthlyReport 汇总 month 所在自然月的数据，month 为当月时统计到 now 为止
+func (c *Client) MonthlyReport(month, now time.Time) (*MonthlyReport, error) {
+	start := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, now.Location())
+	end := start.AddDate(0, 1, 0)
+	if end.After(now) {
+		end = now
+	}
+	duration := getDurationString(end, start)
+	if duration == "" {
+		return nil, fmt.Errorf("month %s has not started", start.Format("2006-01"))
+	}
+
+	instances, err := c.FetchInstances(`up{job="node-exporter"}`)
+	if err != nil {
+		return nil, err
+	}
+	report := &MonthlyReport{Start: start, End: end, Costs: make(map[string]float64), OverageCosts: make(map[string]float64)}
+	byInstance := make(map[string]*MonthlyInstance)
+	for _, labels := range instances {
+		name := string(labels["instance"])
+		m := &MonthlyInstance{Instance: name, Billing: TrafficBillingFor(labels)}
+		amount, currency, ok := utils.ParsePrice(string(labels["price"]))
+		if months := CycleMonths(string(labels["cycle"])); ok && months > 0 {
+			m.MonthlyCost, m.Currency, m.Priced = amount/float64(months), currency, true
+			report.Costs[currency] += m.MonthlyCost
+		}
+		if quota, err := TrafficQuotaFor(labels); err != nil {
+			log.Printf("Failed to parse traffic quota of %s: %v", name, err)
+		} else {
+			m.Quota = quota
+		}
+		byInstance[name] = m
+	}
+
+	queries := []struct {
+		name  string
+		query string
+		set   func(m *MonthlyInstance, v float64)
+	}{
+		{"upload traffic", fmt.Sprintf(`sum by (instance) (increase(node_network_transmit_bytes_total{%s}[%s]))`, networkDeviceMatcher, duration),
+			func(m *MonthlyInstance, v float64) { m.Transmit = v }},
+		{"download traffic", fmt.Sprintf(`sum by (instance) (increase(node_network_receive_bytes_total{%s}[%s]))`, networkDeviceMatcher, duration),
+			func(m *MonthlyInstance, v float64) { m.Receive = v }},
+		{"uptime", fmt.Sprintf(`avg_over_time(up{job="node-exporter"}[%s])`, duration),
+			func(m *MonthlyInstance, v float64) { m.Uptime, m.HasUptime = v, true }},
+	}
+	for _, q := range queries {
+		result, err := c.QueryPrometheusOver(q.query, end, end.Sub(start))
+		if err != nil {
+			return nil, fmt.Errorf("Failed to query monthly %s: %v", q.name, err)
+		}
+		vector, _ := result.(model.Vector)
+		for _, sample := range vector {
+			if m, ok := byInstance[string(sample.Metric["instance"])]; ok {
+				q.set(m, float64(sample.Value))
+			}
+		}
+	}
+	for _, m := range byInstance {
+		if cost := m.OverageCost(); cost > 0 {
+			report.OverageCosts[m.Quota.Currency] += cost
+		}
+		report.Instances = append(report.Instances, *m)
+	}
+	sort.Slice(report.Instances, func(i, j int) bool {
+		return report.Instances[i].BilledTraffic() > report.Instances[j].BilledTraffic()
+	})
+
+	// 每个点是前一天的流量，因此从第二天零点开始取点
+	if end.Sub(start) >= 24*time.Hour {
+		query := fmt.Sprintf(`sum(increase(node_network_transmit_bytes_total{%[1]s}[1d])) + sum(increase(node_network_receive_bytes_total{%[1]s}[1d]))`, networkDeviceMatcher)
+		matrix, err := c.queryMatrix(query, promv1.Range{Start: start.AddDate(0, 0, 1), End: end, Step: 24 * time.Hour})
+		if err != nil {
+			return nil, fmt.Errorf("Failed to query daily traffic: %v", err)
+		}
+		for _, series := range matrix {
+			for _, p := range series.Values {
+				report.Daily = append(report.Daily, DailyTraffic{Day: p.Timestamp.Time().In(now.Location()).AddDate(0, 0, -1), Bytes: float64(p.Value)})
+			}
+		}
+	}
+	return report, nil
+}
+
+// TotalTraffic 返回所有实例的流量之和
+func (r MonthlyReport) TotalTraffic() float64 {
+	var total float64
+	for _, m := range r.Instances {
+		total += m.TotalTraffic()
+	}
+	return total
+}
@@ -0,0 +1,82 @@
+package prometheus
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+// NetworkInterface 是单个网卡过去 5 分钟的平均速率、错误和丢包情况，单位均为每秒
+type NetworkInterface struct {
+	Device   string
+	Upload   float64
+	Download float64
+	// RxErrors、TxErrors 是收发错误包的速率，持续不为 0 通常说明网卡、网线故障或 MTU 不匹配
+	RxErrors float64
+	TxErrors float64
+	RxDrops  float64
+	TxDrops  float64
+}
+
+// Errors 返回收发错误包速率之和
+func (n NetworkInterface) Errors() float64 {
+	return n.RxErrors + n.TxErrors
+}
+
+// Drops 返回收发丢包速率之和
+func (n NetworkInterface) Drops() float64 {
+	return n.RxDrops + n.TxDrops
+}
+
+// QueryNetworkInterfaces 返回实例每个网卡的收发速率、错误和丢包，按流量从高到低排序
+func (c *Client) QueryNetworkInterfaces(labels model.Metric, now time.Time) ([]NetworkInterface, error) {
+	matchers := networkDeviceMatcher
+	if labelMatchers := BuildLabelMatchers(labels); labelMatchers != "" {
+		matchers = labelMatchers + "," + networkDeviceMatcher
+	}
+	devices := make(map[string]*NetworkInterface)
+	for _, item := range []struct {
+		name   string
+		metric string
+		field  func(*NetworkInterface) *float64
+	}{
+		{"transmit bytes", "node_network_transmit_bytes_total", func(n *NetworkInterface) *float64 { return &n.Upload }},
+		{"receive bytes", "node_network_receive_bytes_total", func(n *NetworkInterface) *float64 { return &n.Download }},
+		{"receive errors", "node_network_receive_errs_total", func(n *NetworkInterface) *float64 { return &n.RxErrors }},
+		{"transmit errors", "node_network_transmit_errs_total", func(n *NetworkInterface) *float64 { return &n.TxErrors }},
+		{"receive drops", "node_network_receive_drop_total", func(n *NetworkInterface) *float64 { return &n.RxDrops }},
+		{"transmit drops", "node_network_transmit_drop_total", func(n *NetworkInterface) *float64 { return &n.TxDrops }},
+	} {
+		query := fmt.Sprintf(`sum by (device) (rate(%s{%s}[%s]))`, item.metric, matchers, c.RateRange(defaultRateWindow, labels))
+		result, err := c.QueryPrometheus(query, now)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to query network %s: %v", item.name, err)
+		}
+		vector, ok := result.(model.Vector)
+		if !ok {
+			continue
+		}
+		for _, sample := range vector {
+			device := string(sample.Metric["device"])
+			if devices[device] == nil {
+				devices[device] = &NetworkInterface{Device: device}
+			}
+			*item.field(devices[device]) = float64(sample.Value)
+		}
+	}
+
+	interfaces := make([]NetworkInterface, 0, len(devices))
+	for _, n := range devices {
+		interfaces = append(interfaces, *n)
+	}
+	sort.Slice(interfaces, func(i, j int) bool {
+		ti, tj := interfaces[i].Upload+interfaces[i].Download, interfaces[j].Upload+interfaces[j].Download
+		if ti != tj {
+			return ti > tj
+		}
+		return interfaces[i].Device < interfaces[j].Device
+	})
+	return interfaces, nil
+}
@@ -0,0 +1,91 @@
+package prometheus
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/utils"
+	"github.com/prometheus/common/model"
+)
+
+// BillingP95 是 billing 标签表示按 95 计费（burstable billing）的值，流量用量仍按上传和下载之和统计
+const BillingP95 TrafficBilling = "p95"
+
+// percentileStep 是计算 95 值时的采样间隔，与常见的 5 分钟计费采样一致
+const percentileStep = "5m"
+
+// Percentile95 是按 95 计费的实例在当前计费周期内的 95 百分位速率，速率为每秒字节数
+type Percentile95 struct {
+	// Since 是计费周期的开始时间，即上一次流量重置
+	Since    time.Time
+	Upload   float64
+	Download float64
+	// Commit 是 commit_rate 标签指定的承诺速率，为 0 表示未设置
+	Commit float64
+	// OveragePrice 是 overage_price 标签指定的超出部分每 Mbps 的价格，Priced 为 false 表示未设置
+	OveragePrice float64
+	Currency     string
+	Priced       bool
+}
+
+// Billed 返回计费速率：上传和下载的 95 值中较大的一个
+func (p Percentile95) Billed() float64 {
+	return max(p.Upload, p.Download)
+}
+
+// Overage 返回计费速率超出承诺速率的部分，未设置承诺速率时为 0
+func (p Percentile95) Overage() float64 {
+	if p.Commit <= 0 {
+		return 0
+	}
+	return max(p.Billed()-p.Commit, 0)
+}
+
+// OverageCost 返回按当前 95 值预计的超额费用
+func (p Percentile95) OverageCost() float64 {
+	return p.Overage() * 8 / 1e6 * p.OveragePrice
+}
+
+// QueryPercentile95 计算 since 到 now 之间每 5 分钟速率的 95 百分位，
+// 只对 billing 标签为 p95 的实例查询，其他实例返回 nil
+func (c *Client) QueryPercentile95(labels model.Metric, since, now time.Time) (*Percentile95, error) {
+	if TrafficBilling(labels["billing"]) != BillingP95 {
+		return nil, nil
+	}
+	p := &Percentile95{Since: since}
+	if v := string(labels["commit_rate"]); v != "" {
+		bits, ok := utils.ParseBitRate(v)
+		if !ok {
+			return nil, fmt.Errorf("invalid commit_rate %q", v)
+		}
+		p.Commit = bits / 8
+	}
+	if v := string(labels["overage_price"]); v != "" {
+		p.OveragePrice, p.Currency, p.Priced = utils.ParsePrice(v)
+	}
+
+	duration := getDurationString(now, since)
+	if duration == "" {
+		return p, nil
+	}
+	labelMatchers := BuildLabelMatchers(labels)
+	if labelMatchers != "" {
+		labelMatchers += ", "
+	}
+	for _, q := range []struct {
+		metric string
+		value  *float64
+	}{
+		{"node_network_transmit_bytes_total", &p.Upload},
+		{"node_network_receive_bytes_total", &p.Download},
+	} {
+		query := fmt.Sprintf(`quantile_over_time(0.95, sum(rate(%s{%s%s}[%s]))[%s:%s])`,
+			q.metric, labelMatchers, networkDeviceMatcher, percentileStep, duration, percentileStep)
+		result, err := c.QueryPrometheus(query, now)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to query 95th percentile: %v", err)
+		}
+		*q.value = c.GetFloatFromPromResult(result)
+	}
+	return p, nil
+}
@@ -0,0 +1,71 @@
+package prometheus
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+// PSI（Pressure Stall Information）资源名称，对应 node_pressure_<resource>_waiting_seconds_total
+const (
+	PressureCPU    = "cpu"
+	PressureMemory = "memory"
+	PressureIO     = "io"
+)
+
+// Pressure 是过去 5 分钟内有任务因资源不足而等待的时间占比（百分比，即 PSI 的 some 指标）
+type Pressure struct {
+	CPU    float64
+	Memory float64
+	IO     float64
+}
+
+// pressureQuery 返回资源等待时间占比的查询，labelMatchers 为空时统计所有实例的平均值
+func pressureQuery(resource, labelMatchers, rateRange string) string {
+	return fmt.Sprintf(`avg(rate(node_pressure_%s_waiting_seconds_total{%s}[%s])) * 100`, resource, labelMatchers, rateRange)
+}
+
+// QueryPressure 查询 PSI 指标，内核或 node_exporter 不支持 PSI 时返回 nil
+func (c *Client) QueryPressure(labels model.Metric, now time.Time) (*Pressure, error) {
+	labelMatchers := BuildLabelMatchers(labels)
+	rateRange := c.RateRange(defaultRateWindow, labels)
+	var pressure Pressure
+	found := false
+	for _, item := range []struct {
+		resource string
+		value    *float64
+	}{
+		{PressureCPU, &pressure.CPU},
+		{PressureMemory, &pressure.Memory},
+		{PressureIO, &pressure.IO},
+	} {
+		result, err := c.QueryPrometheus(pressureQuery(item.resource, labelMatchers, rateRange), now)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to query %s pressure: %v", item.resource, err)
+		}
+		if vector, ok := result.(model.Vector); ok && vector.Len() > 0 {
+			found = true
+			*item.value = float64(vector[0].Value)
+		}
+	}
+	if !found {
+		return nil, nil
+	}
+	return &pressure, nil
+}
+
+// GetHighestPressureInstance 返回指定资源 PSI 最高的实例名称和等待时间占比
+func (c *Client) GetHighestPressureInstance(resource string, now time.Time) (string, float64, error) {
+	query := fmt.Sprintf(`topk(1, rate(node_pressure_%s_waiting_seconds_total[%s]) * 100)`, resource, c.RateRange(defaultRateWindow, nil))
+
+	result, err := c.QueryPrometheus(query, now)
+	if err != nil {
+		return "", 0, fmt.Errorf("Failed to query highest %s pressure instance: %v", resource, err)
+	}
+
+	if vector, ok := result.(model.Vector); ok && vector.Len() > 0 {
+		return string(vector[0].Metric["instance"]), float64(vector[0].Value), nil
+	}
+	return "", 0, nil
+}
@@ -0,0 +1,68 @@
+package prometheus
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+// TrafficProjection 是实例在当前重置周期内的计费流量，用于推算周期结束时的用量
+type TrafficProjection struct {
+	// Since 和 Until 是当前重置周期的开始和结束，即上一次和下一次流量重置
+	Since time.Time
+	Until time.Time
+	Now   time.Time
+	// Used 是本周期至今的计费流量
+	Used float64
+	// Recent 是最近 24 小时的计费流量
+	Recent float64
+	// Quota 是 traffic_quota 标签指定的流量配额，未设置时为 nil
+	Quota *TrafficQuota
+}
+
+// AverageRate 返回本周期至今的平均计费速率（字节/秒）
+func (p TrafficProjection) AverageRate() float64 {
+	elapsed := p.Now.Sub(p.Since).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return p.Used / elapsed
+}
+
+// Remaining 返回本周期剩余的时间
+func (p TrafficProjection) Remaining() time.Duration {
+	return max(p.Until.Sub(p.Now), 0)
+}
+
+// Project 返回剩余时间内的速率为本周期平均速率的 factor 倍时，周期结束时的用量
+func (p TrafficProjection) Project(factor float64) float64 {
+	return p.Used + p.AverageRate()*factor*p.Remaining().Seconds()
+}
+
+// QueryTrafficProjection 查询实例本周期至今和最近 24 小时的计费流量
+func (c *Client) QueryTrafficProjection(labels model.Metric, now time.Time) (*TrafficProjection, error) {
+	policy, err := ResetPolicyFor(labels, now)
+	if err != nil {
+		return nil, err
+	}
+	p := &TrafficProjection{Since: policy.LastReset(now), Until: policy.NextReset(now), Now: now}
+	p.Quota, err = TrafficQuotaFor(labels)
+	if err != nil {
+		return nil, err
+	}
+	billing := TrafficBillingFor(labels)
+	if duration := getDurationString(now, p.Since); duration != "" {
+		transmit, receive, err := c.queryTrafficForDuration(labels, duration, now)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to query reset period traffic: %v", err)
+		}
+		p.Used = billing.Usage(transmit, receive)
+	}
+	transmit, receive, err := c.queryTrafficForDuration(labels, "1d", now)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query recent traffic: %v", err)
+	}
+	p.Recent = billing.Usage(transmit, receive)
+	return p, nil
+}
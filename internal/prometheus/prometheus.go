@@ -4,10 +4,10 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"math"
 	"strings"
 	"time"
 
+	"github.com/bestmjj/prometheus-telegram-bot/internal/utils"
 	"github.com/prometheus/client_golang/api"
 	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
 	"github.com/prometheus/common/model"
@@ -15,9 +15,71 @@ import (
 
 type Client struct {
 	api promv1.API
+	// raw 用于调用 promv1 不支持的接口字段，例如目标的抓取间隔
+	raw api.Client
+	// fallback 是可选的长期存储查询端点（如 Thanos Query），查询超出本地保留期时用来补充本地缺少的数据
+	fallback promv1.API
+	// retention 缓存本地 Prometheus 的保留期，只在配置了 fallback 时使用
+	retention *localRetention
+
+	limiter *queryLimiter
+	// key 标识查询来源，用于按来源限制并发
+	key string
+
+	staleThreshold time.Duration
+
+	filesystemFilter FilesystemFilter
+
+	// dirSizeMetric 和 dirSizeLabel 是 textfile 收集器上报目录大小使用的指标和标签
+	dirSizeMetric string
+	dirSizeLabel  string
+
+	// slowLog 记录耗时超过阈值的查询，为 nil 时不记录
+	slowLog *slowQueryLog
+
+	// resourceWindows 是各视图计算 CPU 使用率的窗口，见 ResourceWindow
+	resourceWindows map[string]time.Duration
+	// rateWindows 是实例详情中显示的网络速率窗口，见 NetworkRateWindows
+	rateWindows []time.Duration
+
+	// maxSeries 是分组和自定义查询允许涉及的最大序列数，为 0 时不检查
+	maxSeries int
+
+	// dedupe 按主机名标签合并同一主机的多个实例，为 nil 时不合并。ForKey 返回的客户端共享同一个映射
+	dedupe *instanceDedupe
+
+	// trace 按来源记录最近完成的查询，用于解释模式
+	trace *queryTrace
+
+	// intervals 缓存各实例的抓取间隔，用于放宽速率窗口
+	intervals *scrapeIntervals
+}
+
+// SetConcurrencyLimit 设置同时进行的查询总数上限和单个来源的查询数上限
+func (c *Client) SetConcurrencyLimit(global, perKey int) {
+	if global <= 0 {
+		c.limiter = nil
+		return
+	}
+	c.limiter = newQueryLimiter(global, perKey)
 }
 
-func NewClient(prometheusURL string) (*Client, error) {
+// ForKey 返回一个共享连接和并发限制、但以 key 作为查询来源的客户端
+func (c *Client) ForKey(key string) *Client {
+	cp := *c
+	cp.key = key
+	return &cp
+}
+
+// acquire 在发出查询前等待并发名额
+func (c *Client) acquire(ctx context.Context) (func(), error) {
+	if c.limiter == nil {
+		return func() {}, nil
+	}
+	return c.limiter.acquire(ctx, c.key)
+}
+
+func NewClient(prometheusURL string, fallbackURL string) (*Client, error) {
 	client, err := api.NewClient(api.Config{
 		Address: prometheusURL,
 	})
@@ -25,14 +87,34 @@ func NewClient(prometheusURL string) (*Client, error) {
 		return nil, fmt.Errorf("Failed to create Prometheus client: %v", err)
 	}
 	v1api := promv1.NewAPI(client)
-	return &Client{api: v1api}, nil
+	c := &Client{api: v1api, raw: client, filesystemFilter: DefaultFilesystemFilter, trace: &queryTrace{}, intervals: &scrapeIntervals{}}
+
+	if fallbackURL != "" {
+		fallbackClient, err := api.NewClient(api.Config{
+			Address: fallbackURL,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Failed to create fallback Prometheus client: %v", err)
+		}
+		c.fallback = promv1.NewAPI(fallbackClient)
+		c.retention = &localRetention{}
+	}
+	return c, nil
 }
 
 func (c *Client) FetchInstances(query string) ([]model.Metric, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	result, warnings, err := c.api.Query(ctx, query, time.Now())
+	release, err := c.acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query Prometheus: %v", err)
+	}
+	defer release()
+
+	started := time.Now()
+	result, warnings, err := c.api.Query(ctx, query, started)
+	c.observeQuery(query, false, started)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to query Prometheus: %v", err)
 	}
@@ -40,184 +122,161 @@ func (c *Client) FetchInstances(query string) ([]model.Metric, error) {
 		log.Printf("Warnings: %v", warnings)
 	}
 
+	vector, _ := result.(model.Vector)
+	if c.dedupe != nil {
+		hostnames, err := c.dedupe.lookupHostnames(ctx, c.api, vector)
+		if err != nil {
+			return nil, err
+		}
+		return c.dedupe.merge(vector, hostnames), nil
+	}
+
 	var metrics []model.Metric
-	for _, res := range result.(model.Vector) {
+	for _, res := range vector {
 		metrics = append(metrics, res.Metric)
 	}
 	return metrics, nil
 }
 
-func (c *Client) GetInstanceInfo(labels model.Metric) (string, error) {
+// Traffic 表示一段时间内的上传和下载字节数
+type Traffic struct {
+	Transmit float64
+	Receive  float64
+	// Billing 是实例的流量计费方式，零值按上传和下载之和计费
+	Billing TrafficBilling
+}
+
+func (t Traffic) Total() float64 {
+	return t.Transmit + t.Receive
+}
+
+// Usage 返回按实例计费方式统计的用量，与服务商账单一致
+func (t Traffic) Usage() float64 {
+	return t.Billing.Usage(t.Transmit, t.Receive)
+}
+
+// CustomBilling 判断实例是否使用上传加下载之外的计费方式，此时需要单独显示计费用量
+func (t Traffic) CustomBilling() bool {
+	return t.Billing != "" && t.Billing != BillingSum
+}
+
+// InstanceDetail 汇总实例详情页需要展示的所有数据，由渲染模板负责格式化
+type InstanceDetail struct {
+	Instance string
+	// Merged 是按主机名合并到该实例的其他实例，未合并时为空
+	Merged     []string
+	Info       string
+	BootTime   string
+	Expiry     string
+	Price      string
+	Cycle      string
+	Expired    bool
+	YearsLeft  int
+	MonthsLeft int
+	DaysLeft   int
+	// TotalDaysLeft 是到续费日期的精确剩余天数
+	TotalDaysLeft int
+	ResetDate     string
+	// ResetPolicy 是流量重置规则的描述，例如 "每月 5 日"
+	ResetPolicy string
+
+	ResetTraffic     Traffic
+	MonthlyTraffic   Traffic
+	YesterdayTraffic Traffic
+	DailyTraffic     Traffic
+
+	// UploadRate 和 DownloadRate 是第一个网络速率窗口（默认 1m）内的速率
+	UploadRate   float64
+	DownloadRate float64
+	// Rates 是 NetworkRateWindows 中每个窗口的速率，只设置了一个窗口时为空
+	Rates []NetworkRate
+	// RateNote 在实例的抓取间隔较长、网络速率的计算窗口被放宽时说明原因，例如 "抓取间隔 1m，按 2m 平均"，否则为空
+	RateNote string
+
+	// ResourceWindow 是计算 CPU 使用率的窗口，例如 "5m"
+	ResourceWindow string
+	CPUUsage       float64
+	MemoryUsage    float64
+	MemTotal       float64
+	MemAvailable   float64
+	DiskUsage      float64
+	DiskTotal      float64
+	DiskAvailable  float64
+
+	Trends Trends
+
+	// Pressure 是 PSI 指标，内核不支持时为 nil
+	Pressure *Pressure
+	// CPU 是 steal 时间和频率信息，没有相关指标时为 nil
+	CPU *CPUInfo
+	// Kernel 是 OOM Kill、内存 ECC 错误和可用熵，没有相关指标时为 nil
+	Kernel *KernelHealth
+
+	// DiskIO 是各块设备的 IO 情况，按繁忙度从高到低排序
+	DiskIO []DiskIO
+	// Interfaces 是各网卡的速率、错误和丢包情况，按流量从高到低排序
+	Interfaces []NetworkInterface
+
+	FDAllocated float64
+	FDMaximum   float64
+	// Inodes 是各文件系统的 inode 使用情况，按使用率从高到低排序
+	Inodes []InodeUsage
+
+	// System 是内核、发行版和待处理更新信息，没有 node_uname_info 时为 nil
+	System *SystemInfo
+	// TimeSync 是时钟同步状态，没有 timex 指标时为 nil
+	TimeSync *TimeSync
+	// Percentile95 是按 95 计费的实例在本计费周期的 95 值，其他实例为 nil
+	Percentile95 *Percentile95
+	// Metered 是设置了 traffic_quota 标签的实例在本重置周期的配额用量，其他实例为 nil
+	Metered *MeteredUsage
+
+	// Stale 在指标数据过期时为过期标记（例如 "数据过期(5m前)"），否则为空
+	Stale string
+}
+
+func (c *Client) GetInstanceDetail(labels model.Metric) (*InstanceDetail, error) {
 	now := time.Now()
 	expiryStr := string(labels["expiry"])
-	resetDayStr := string(labels["reset_day"])
 	priceStr := string(labels["price"])
 	infoStr := string(labels["info"])
 	cycleStr := string(labels["cycle"])
 
 	expiryTime, err := time.Parse("2006-01-02", expiryStr)
 	if err != nil {
-		return "", fmt.Errorf("Failed to parse expiry date: %v", err)
+		return nil, fmt.Errorf("Failed to parse expiry date: %v", err)
 	}
 
 	// Calculate actual expiry date based on cycle
 	actualExpiryTime := calculateActualExpiryDate(expiryTime, cycleStr, now)
 	actualExpiryStr := actualExpiryTime.Format("2006-01-02")
 
-	var lastResetDate time.Time
-	var nextResetDate time.Time
-	var resetDateStr string
-	var duration string
-
-	if resetDayStr != "" {
-		// 如果有固定的重置日，则使用该重置日
-		resetDay, err := time.Parse("2006-01-02", resetDayStr)
-		if err != nil {
-			return "", fmt.Errorf("Failed to parse reset day: %v", err)
-		}
-
-		// 从重置日中提取日期
-		resetDayOfMonth := resetDay.Day()
-
-		// 计算上一个重置日
-		year := now.Year()
-		month := now.Month()
-
-		// 首先尝试当前月份的重置日
-		currentMonthResetDate := time.Date(year, month, resetDayOfMonth, 0, 0, 0, 0, time.Local)
-
-		if currentMonthResetDate.Before(now) || currentMonthResetDate.Equal(now.Truncate(24*time.Hour)) {
-			// 如果当前月的重置日已经过了，上一个重置日就是当前月的重置日
-			lastResetDate = currentMonthResetDate
-		} else {
-			// 如果当前月的重置日还没到，上一个重置日是上个月的重置日
-			var lastMonthYear int
-			var lastMonthMonth time.Month
-			if month-1 < 1 {
-				lastMonthYear = year - 1
-				lastMonthMonth = 12
-			} else {
-				lastMonthYear = year
-				lastMonthMonth = month - 1
-			}
-
-			// 获取上个月的总天数
-			daysInLastMonth := time.Date(lastMonthYear, lastMonthMonth+1, 0, 0, 0, 0, 0, time.Local).Day()
-			dayToUse := resetDayOfMonth
-			if dayToUse > daysInLastMonth {
-				dayToUse = daysInLastMonth
-			}
-			lastResetDate = time.Date(lastMonthYear, lastMonthMonth, dayToUse, 0, 0, 0, 0, time.Local)
-		}
-
-		// 计算下一个重置日
-		if currentMonthResetDate.After(now) {
-			// 如果当前月的重置日还没到，那就是下一个重置日
-			nextResetDate = currentMonthResetDate
-		} else {
-			// 如果当前月的重置日已经过了，那么下个重置日是下个月的重置日
-			var nextMonthYear int
-			var nextMonthMonth time.Month
-			if month+1 > 12 {
-				nextMonthYear = year + 1
-				nextMonthMonth = 1
-			} else {
-				nextMonthYear = year
-				nextMonthMonth = month + 1
-			}
-
-			// 获取下个月的总天数
-			daysInNextMonth := time.Date(nextMonthYear, nextMonthMonth+1, 0, 0, 0, 0, 0, time.Local).Day()
-			dayToUse := resetDayOfMonth
-			if dayToUse > daysInNextMonth {
-				dayToUse = daysInNextMonth
-			}
-			nextResetDate = time.Date(nextMonthYear, nextMonthMonth, dayToUse, 0, 0, 0, 0, time.Local)
-		}
-
-		// 计算从上一个重置日到现在的时间差
-		duration = getDurationString(now, lastResetDate)
-		if duration == "" {
-			duration = "1s"
-		}
-		resetDateStr = fmt.Sprintf("%d-%02d-%02d", nextResetDate.Year(), nextResetDate.Month(), nextResetDate.Day())
-	} else {
-		// 如果没有固定的重置日，使用到期日作为参考
-		expiryDay := actualExpiryTime.Day()
-		year := now.Year()
-		month := now.Month()
-
-		// 计算上一个重置日（使用到期日的日期作为周期日）
-		currentMonthExpiryDate := time.Date(year, month, expiryDay, 0, 0, 0, 0, time.Local)
-
-		if currentMonthExpiryDate.Before(now) || currentMonthExpiryDate.Equal(now.Truncate(24*time.Hour)) {
-			// 如果当前月的到期日已经过了，上一个重置日就是当前月的到期日
-			lastResetDate = currentMonthExpiryDate
-		} else {
-			// 如果当前月的到期日还没到，上一个重置日是上个月的对应日期
-			var lastMonthYear int
-			var lastMonthMonth time.Month
-			if month-1 < 1 {
-				lastMonthYear = year - 1
-				lastMonthMonth = 12
-			} else {
-				lastMonthYear = year
-				lastMonthMonth = month - 1
-			}
-
-			// 获取上个月的总天数
-			daysInLastMonth := time.Date(lastMonthYear, lastMonthMonth+1, 0, 0, 0, 0, 0, time.Local).Day()
-			dayToUse := expiryDay
-			if dayToUse > daysInLastMonth {
-				dayToUse = daysInLastMonth
-			}
-			lastResetDate = time.Date(lastMonthYear, lastMonthMonth, dayToUse, 0, 0, 0, 0, time.Local)
-		}
-
-		// 计算下一个重置日
-		if currentMonthExpiryDate.After(now) {
-			// 如果当前月的到期日还没到，那就是下一个重置日
-			nextResetDate = currentMonthExpiryDate
-		} else {
-			// 如果当前月的到期日已经过了，那么下个重置日是下个月的到期日
-			var nextMonthYear int
-			var nextMonthMonth time.Month
-			if month+1 > 12 {
-				nextMonthYear = year + 1
-				nextMonthMonth = 1
-			} else {
-				nextMonthYear = year
-				nextMonthMonth = month + 1
-			}
-
-			// 获取下个月的总天数
-			daysInNextMonth := time.Date(nextMonthYear, nextMonthMonth+1, 0, 0, 0, 0, 0, time.Local).Day()
-			dayToUse := expiryDay
-			if dayToUse > daysInNextMonth {
-				dayToUse = daysInNextMonth
-			}
-			nextResetDate = time.Date(nextMonthYear, nextMonthMonth, dayToUse, 0, 0, 0, 0, time.Local)
-		}
+	// 按实例的重置规则计算上一次和下一次流量重置日期
+	policy, err := ResetPolicyFor(labels, now)
+	if err != nil {
+		return nil, err
+	}
+	lastResetDate := policy.LastReset(now)
+	nextResetDate := policy.NextReset(now)
 
-		duration = getDurationString(now, lastResetDate)
-		if duration == "" {
-			duration = "1s"
-		}
-		resetDateStr = fmt.Sprintf("%d-%02d-%02d", nextResetDate.Year(), nextResetDate.Month(), nextResetDate.Day())
+	// 计算从上一个重置日到现在的时间差
+	duration := getDurationString(now, lastResetDate)
+	if duration == "" {
+		duration = "1s"
 	}
+	resetDateStr := nextResetDate.Format("2006-01-02")
 
 	// 获取重置日流量
 	transmitBytes, receiveBytes, err := c.queryTrafficForDuration(labels, duration, now)
 	if err != nil {
-		return "", fmt.Errorf("Failed to query reset day traffic: %v", err)
+		return nil, fmt.Errorf("Failed to query reset day traffic: %v", err)
 	}
 
 	timeLeft := actualExpiryTime.Sub(now)
-	yearsLeft, monthsLeft, daysLeft := calculateTimeDifference(now, actualExpiryTime)
-
-	// If the time difference is negative, set all values to 0
-	if timeLeft < 0 {
-		yearsLeft, monthsLeft, daysLeft = 0, 0, 0
+	yearsLeft, monthsLeft, daysLeft := utils.CalculateTimeLeft(now, actualExpiryTime)
+	totalDaysLeft := utils.DaysBetween(now, actualExpiryTime)
+	if totalDaysLeft < 0 {
+		totalDaysLeft = 0
 	}
 
 	// 获取启动时长
@@ -226,103 +285,234 @@ func (c *Client) GetInstanceInfo(labels model.Metric) (string, error) {
 		log.Printf("Failed to query boot time: %v", err)
 	}
 
-	info := fmt.Sprintf("<b>实例:</b> %s-->%s\n", string(labels["instance"]), infoStr)
-	if bootTime != "" {
-		info += fmt.Sprintf("<b>在线时长:</b> %s\n", bootTime)
+	var merged []string
+	if v := string(labels[MergedInstancesLabel]); v != "" {
+		merged = strings.Split(v, ",")
 	}
 
-	info += fmt.Sprintf("<b>续费日期:</b> %s\n", actualExpiryStr)
-	info += fmt.Sprintf("<b>续费价格:</b> %s(%s)\n", priceStr, convertCycleToFriendlyText(cycleStr))
-	if timeLeft >= 0 {
-		info += fmt.Sprintf("<b>剩余时间:</b> %d 年 %d 月 %d 天\n", yearsLeft, monthsLeft, daysLeft)
-	} else {
-		info += fmt.Sprintf("<b>剩余时间:</b> 已过期\n")
+	detail := &InstanceDetail{
+		Instance:      string(labels["instance"]),
+		Merged:        merged,
+		Info:          infoStr,
+		BootTime:      bootTime,
+		Expiry:        actualExpiryStr,
+		Price:         priceStr,
+		Cycle:         convertCycleToFriendlyText(cycleStr),
+		Expired:       timeLeft < 0,
+		YearsLeft:     yearsLeft,
+		MonthsLeft:    monthsLeft,
+		DaysLeft:      daysLeft,
+		TotalDaysLeft: totalDaysLeft,
+		ResetDate:     resetDateStr,
+		ResetPolicy:   policy.String(),
+		ResetTraffic:  Traffic{Transmit: transmitBytes, Receive: receiveBytes},
 	}
-	info += fmt.Sprintf("<b>重置日期:</b> %s\n", resetDateStr)
 
-	info += fmt.Sprintf("\n<b>重置日流量:</b>\n")
-	info += fmt.Sprintf("  上传: %s\n", FormatBytes(transmitBytes))
-	info += fmt.Sprintf("  下载: %s\n", FormatBytes(receiveBytes))
-	info += fmt.Sprintf("  总共: %s\n", FormatBytes(receiveBytes+transmitBytes))
 	// 获取自然月流量
-	naturalMonthTransmitBytes, naturalMonthReceiveBytes, err := c.GetNaturalMonthTraffic(labels, now)
+	detail.MonthlyTraffic.Transmit, detail.MonthlyTraffic.Receive, err = c.GetNaturalMonthTraffic(labels, now)
 	if err != nil {
-		return "", fmt.Errorf("Failed to query natural month traffic: %v", err)
+		return nil, fmt.Errorf("Failed to query natural month traffic: %v", err)
 	}
-	naturalMonthTotalBytes := naturalMonthTransmitBytes + naturalMonthReceiveBytes
-	info += fmt.Sprintf("\n<b>月流量:</b>\n")
-	info += fmt.Sprintf("  上传: %s\n", FormatBytes(naturalMonthTransmitBytes))
-	info += fmt.Sprintf("  下载: %s\n", FormatBytes(naturalMonthReceiveBytes))
-	info += fmt.Sprintf("  总共: %s\n", FormatBytes(naturalMonthTotalBytes))
 
 	// 获取昨日流量
-	yesterdayTransmitBytes, yesterdayReceiveBytes, err := c.GetYesterdayTraffic(labels, now)
+	detail.YesterdayTraffic.Transmit, detail.YesterdayTraffic.Receive, err = c.GetYesterdayTraffic(labels, now)
 	if err != nil {
-		return "", fmt.Errorf("Failed to query yesterday traffic: %v", err)
+		return nil, fmt.Errorf("Failed to query yesterday traffic: %v", err)
 	}
-	yesterdayTotalBytes := yesterdayTransmitBytes + yesterdayReceiveBytes
-
-	info += "\n<b>昨日流量:</b>\n"
-	info += fmt.Sprintf("  上传: %s\n", FormatBytes(yesterdayTransmitBytes))
-	info += fmt.Sprintf("  下载: %s\n", FormatBytes(yesterdayReceiveBytes))
-	info += fmt.Sprintf("  总共: %s\n", FormatBytes(yesterdayTotalBytes))
 
 	// 获取每日流量
-	naturalDailyTransmitBytes, naturalDailyReceiveBytes, err := c.GetDailyTraffic(labels, now)
+	detail.DailyTraffic.Transmit, detail.DailyTraffic.Receive, err = c.GetDailyTraffic(labels, now)
 	if err != nil {
-		return "", fmt.Errorf("Failed to query natural daily traffic: %v", err)
+		return nil, fmt.Errorf("Failed to query natural daily traffic: %v", err)
+	}
+
+	billing := TrafficBillingFor(labels)
+	for _, t := range []*Traffic{&detail.ResetTraffic, &detail.MonthlyTraffic, &detail.YesterdayTraffic, &detail.DailyTraffic} {
+		t.Billing = billing
 	}
-	naturalDailyTotalBytes := naturalDailyTransmitBytes + naturalDailyReceiveBytes
-	info += "\n<b>日流量:</b>\n"
-	info += fmt.Sprintf("  上传: %s\n", FormatBytes(naturalDailyTransmitBytes))
-	info += fmt.Sprintf("  下载: %s\n", FormatBytes(naturalDailyReceiveBytes))
-	info += fmt.Sprintf("  总共: %s\n", FormatBytes(naturalDailyTotalBytes))
 
 	// 获取网络速率
-	uploadRate, downloadRate, err := c.QueryNetworkRate(labels, now)
+	windows := c.NetworkRateWindows()
+	for _, w := range windows {
+		rate := NetworkRate{Window: c.RateRange(w, labels)}
+		rate.Upload, rate.Download, err = c.QueryNetworkRateOver(labels, w, now)
+		if err != nil {
+			log.Printf("Failed to query network rate over %s: %v", rate.Window, err)
+		}
+		detail.Rates = append(detail.Rates, rate)
+	}
+	detail.UploadRate, detail.DownloadRate = detail.Rates[0].Upload, detail.Rates[0].Download
+	if len(detail.Rates) == 1 {
+		detail.Rates = nil
+	}
+	if window := c.RateWindow(windows[0], labels); window > windows[0] {
+		detail.RateNote = fmt.Sprintf("抓取间隔 %s，按 %s 平均", model.Duration(c.ScrapeInterval(string(labels["instance"]))), model.Duration(window))
+	}
+	detail.Percentile95, err = c.QueryPercentile95(labels, lastResetDate, now)
 	if err != nil {
-		log.Printf("Failed to query network rate: %v", err)
+		log.Printf("Failed to query 95th percentile: %v", err)
+	}
+	if quota, err := TrafficQuotaFor(labels); err != nil {
+		log.Printf("Failed to parse traffic quota: %v", err)
+	} else if quota != nil {
+		detail.Metered = NewMeteredUsage(*quota, detail.ResetTraffic.Usage(), lastResetDate, nextResetDate, now)
 	}
-	info += "\n<b>网络速率:</b>\n"
-	info += fmt.Sprintf("  上传: %s\n", FormatBytesPerSecond(uploadRate))
-	info += fmt.Sprintf("  下载: %s\n", FormatBytesPerSecond(downloadRate))
 
-	// 获取每日流量
-	naturalDailyTransmitBytes, naturalDailyReceiveBytes, err = c.GetDailyTraffic(labels, now)
+	detail.ResourceWindow = c.ResourceRange(ResourceViewDetail)
+	detail.CPUUsage, detail.MemoryUsage, detail.DiskUsage, detail.DiskTotal, detail.DiskAvailable, detail.MemTotal, detail.MemAvailable, err = c.FetchResourceMetrics(labels, detail.ResourceWindow, now)
 	if err != nil {
-		return "", fmt.Errorf("Failed to query natural daily traffic: %v", err)
+		log.Printf("Failed to fetch resource metrics: %v", err)
 	}
-	naturalDailyTotalBytes = naturalDailyTransmitBytes + naturalDailyReceiveBytes
-	info += "\n<b>日流量:</b>\n"
-	info += fmt.Sprintf("  上传: %s\n", FormatBytes(naturalDailyTransmitBytes))
-	info += fmt.Sprintf("  下载: %s\n", FormatBytes(naturalDailyReceiveBytes))
-	info += fmt.Sprintf("  总共: %s\n", FormatBytes(naturalDailyTotalBytes))
 
-	cpuUsage, memoryUsage, diskUsage, diskTotal, diskAvaileble, memTotal, memAvaileble, err := c.FetchResourceMetrics(labels, duration, now)
+	detail.Trends = c.QueryTrends(labels, now)
+
+	detail.Pressure, err = c.QueryPressure(labels, now)
 	if err != nil {
-		log.Printf("Failed to fetch resource metrics: %v", err)
+		log.Printf("Failed to query pressure: %v", err)
+	}
+	detail.CPU, err = c.QueryCPUInfo(labels, detail.ResourceWindow, now)
+	if err != nil {
+		log.Printf("Failed to query CPU info: %v", err)
+	}
+	detail.Kernel, err = c.QueryKernelHealth(labels, now)
+	if err != nil {
+		log.Printf("Failed to query kernel health: %v", err)
+	}
+	detail.DiskIO, err = c.QueryDiskIO(labels, now)
+	if err != nil {
+		log.Printf("Failed to query disk IO: %v", err)
+	}
+	detail.Interfaces, err = c.QueryNetworkInterfaces(labels, now)
+	if err != nil {
+		log.Printf("Failed to query network interfaces: %v", err)
+	}
+	detail.FDAllocated, detail.FDMaximum, err = c.QueryFileDescriptors(labels, now)
+	if err != nil {
+		log.Printf("Failed to query file descriptors: %v", err)
+	}
+	detail.Inodes, err = c.QueryInodeUsage(labels, now)
+	if err != nil {
+		log.Printf("Failed to query inode usage: %v", err)
+	}
+	detail.System, err = c.QuerySystemInfo(labels, now)
+	if err != nil {
+		log.Printf("Failed to query system info: %v", err)
+	}
+	detail.TimeSync, err = c.QueryTimeSync(labels, now)
+	if err != nil {
+		log.Printf("Failed to query time sync: %v", err)
 	}
 
-	info += "\n<b>资源使用情况:</b>\n"
-	info += fmt.Sprintf("  CPU 使用率: %.2f%%\n", cpuUsage)
-	info += fmt.Sprintf("  内存使用率: %.2f%%(共: %s,可用: %s)\n", memoryUsage, FormatBytes(memTotal), FormatBytes(memAvaileble))
-	info += fmt.Sprintf("  磁盘使用率: %.2f%%(共: %s,可用: %s)\n", diskUsage, FormatBytes(diskTotal), FormatBytes(diskAvaileble))
+	// 节点在线但数据过期时，速率和资源使用率会显示为 0，需要标记出来
+	if c.staleThreshold > 0 {
+		freshness, err := c.GetFreshness(labels, now)
+		if err != nil {
+			log.Printf("Failed to query metric freshness: %v", err)
+		} else if f := FreshnessOf(freshness, string(labels["instance"])); f.Stale(c.staleThreshold) {
+			detail.Stale = f.Label()
+		}
+	}
 
-	return info, nil
+	return detail, nil
 }
 
 func (c *Client) QueryPrometheus(query string, queryTime time.Time) (model.Value, error) {
+	result, err := c.query(query, queryTime, 0)
+	if err != nil {
+		return nil, err
+	}
+	return c.dedupe.apply(result), nil
+}
+
+// QueryPrometheusOver 执行回看 lookback 的即时查询，例如 increase(...[30d])。窗口超出本地保留期且配置了长期存储端点时，
+// 长期存储中有的序列使用长期存储的结果
+func (c *Client) QueryPrometheusOver(query string, queryTime time.Time, lookback time.Duration) (model.Value, error) {
+	result, err := c.query(query, queryTime, lookback)
+	if err != nil {
+		return nil, err
+	}
+	return c.dedupe.apply(result), nil
+}
+
+func (c *Client) query(query string, queryTime time.Time, lookback time.Duration) (model.Value, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	release, err := c.acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query Prometheus: %v", err)
+	}
+	defer release()
+
+	started := time.Now()
 	result, warnings, err := c.api.Query(ctx, query, queryTime)
+	c.observeQuery(query, false, started)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to query Prometheus: %v", err)
 	}
 	if len(warnings) > 0 {
 		log.Printf("Warning from Prometheus: %v", warnings)
 	}
-	return result, nil
+
+	vector, ok := result.(model.Vector)
+	if lookback <= 0 || !ok || !c.needsFallback(ctx, queryTime.Add(-lookback)) {
+		return result, nil
+	}
+	fallbackResult, warnings, err := c.fallback.Query(ctx, query, queryTime)
+	if err != nil {
+		log.Printf("Failed to query fallback for %s: %v", query, err)
+		return result, nil
+	}
+	if len(warnings) > 0 {
+		log.Printf("Warning from fallback Prometheus: %v", warnings)
+	}
+	fallbackVector, _ := fallbackResult.(model.Vector)
+	return mergeFallbackVector(vector, fallbackVector), nil
+}
+
+// QueryRange 执行范围查询。时间范围超出本地保留期且配置了长期存储端点时，用长期存储的数据按序列补齐本地缺少的部分
+func (c *Client) QueryRange(query string, r promv1.Range) (model.Value, error) {
+	result, err := c.queryRange(query, r)
+	if err != nil {
+		return nil, err
+	}
+	return c.dedupe.apply(result), nil
+}
+
+func (c *Client) queryRange(query string, r promv1.Range) (model.Value, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	release, err := c.acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query Prometheus range: %v", err)
+	}
+	defer release()
+
+	started := time.Now()
+	result, warnings, err := c.api.QueryRange(ctx, query, r)
+	c.observeQuery(query, true, started)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query Prometheus range: %v", err)
+	}
+	if len(warnings) > 0 {
+		log.Printf("Warning from Prometheus: %v", warnings)
+	}
+
+	matrix, ok := result.(model.Matrix)
+	if !ok || !c.needsFallback(ctx, r.Start) {
+		return result, nil
+	}
+	fallbackResult, warnings, err := c.fallback.QueryRange(ctx, query, r)
+	if err != nil {
+		log.Printf("Failed to query fallback range for %s: %v", query, err)
+		return result, nil
+	}
+	if len(warnings) > 0 {
+		log.Printf("Warning from fallback Prometheus: %v", warnings)
+	}
+	fallbackMatrix, _ := fallbackResult.(model.Matrix)
+	return mergeFallbackMatrix(matrix, fallbackMatrix), nil
 }
 
 func (c *Client) GetFloatFromPromResult(result model.Value) float64 {
@@ -348,7 +538,7 @@ func (c *Client) queryNodeBootTime(labels model.Metric, queryTime time.Time) (st
 		bootTime := now.Add(-time.Duration(uptimeSeconds) * time.Second)
 
 		// Calculate years, months, days from the boot time
-		years, months, days := calculateTimeDifference(bootTime, now)
+		years, months, days := utils.CalendarDiff(bootTime, now)
 
 		if years > 0 {
 			return fmt.Sprintf("%d 年 %d 月 %d 天", years, months, days), nil
@@ -413,22 +603,29 @@ func (c *Client) GetNaturalMonthTraffic(labels model.Metric, now time.Time) (tra
 	return c.queryTrafficForDuration(labels, durationCurrentMonth, now)
 }
 
+// GetTrafficBetween 返回 start 到 end 之间的流量，用于时间范围选择器指定的任意范围
+func (c *Client) GetTrafficBetween(labels model.Metric, start, end time.Time) (transmitBytes float64, receiveBytes float64, err error) {
+	duration := getDurationString(end, start)
+	if duration == "" {
+		return 0, 0, nil
+	}
+	return c.queryTrafficForDuration(labels, duration, end)
+}
+
 func (c *Client) FetchResourceMetrics(labels model.Metric, duration string, now time.Time) (cpuUsage, memoryUsage, diskUsage, diskTotal, diskAvaileble, memTotal, memAvaileble float64, err error) {
 	labelMatchers := BuildLabelMatchers(labels)
 	cpuQuery := fmt.Sprintf(`avg(rate(node_cpu_seconds_total{mode!="idle"}[%s])) * 100`, duration)
 	memoryQuery := fmt.Sprintf(`(1 - avg(node_memory_MemAvailable_bytes{}) / avg(node_memory_MemTotal_bytes{}))*100`)
-	diskQuery := fmt.Sprintf(`(1 - avg(node_filesystem_avail_bytes{fstype!="rootfs"}) / avg(node_filesystem_size_bytes{fstype!="rootfs"}))*100`)
-	diskTotalQuery := fmt.Sprintf(`node_filesystem_size_bytes{fstype!="rootfs",fstype=~"ext4|xfs"}`)
-	diskAvailebleQuery := fmt.Sprintf(`node_filesystem_avail_bytes{fstype!="rootfs",fstype=~"ext4|xfs"}`)
+	fsMatchers := c.filesystemMatchers(labels)
+	diskQuery := fmt.Sprintf(`(1 - sum(node_filesystem_avail_bytes{%s}) / sum(node_filesystem_size_bytes{%s}))*100`, fsMatchers, fsMatchers)
+	diskTotalQuery := fmt.Sprintf(`sum(node_filesystem_size_bytes{%s})`, fsMatchers)
+	diskAvailebleQuery := fmt.Sprintf(`sum(node_filesystem_avail_bytes{%s})`, fsMatchers)
 	memTotalQuery := fmt.Sprintf(`node_memory_MemTotal_bytes`)
 	memAvailebleQuery := fmt.Sprintf(`node_memory_MemAvailable_bytes`)
 
 	if len(labelMatchers) > 0 {
 		cpuQuery = fmt.Sprintf(`avg(rate(node_cpu_seconds_total{%s, mode!="idle"}[%s])) * 100`, labelMatchers, duration)
 		memoryQuery = fmt.Sprintf(`(1 - avg(node_memory_MemAvailable_bytes{%s}) / avg(node_memory_MemTotal_bytes{%s}))*100`, labelMatchers, labelMatchers)
-		diskQuery = fmt.Sprintf(`(1 - avg(node_filesystem_avail_bytes{%s, fstype!="rootfs"}) / avg(node_filesystem_size_bytes{%s, fstype!="rootfs"}))*100`, labelMatchers, labelMatchers)
-		diskTotalQuery = fmt.Sprintf(`node_filesystem_size_bytes{%s, fstype!="rootfs",fstype=~"ext4|xfs"}`, labelMatchers)
-		diskAvailebleQuery = fmt.Sprintf(`node_filesystem_avail_bytes{%s, fstype!="rootfs",fstype=~"ext4|xfs"}`, labelMatchers)
 		memTotalQuery = fmt.Sprintf(`node_memory_MemTotal_bytes{%s}`, labelMatchers)
 		memAvailebleQuery = fmt.Sprintf(`node_memory_MemAvailable_bytes{%s}`, labelMatchers)
 	}
@@ -476,15 +673,21 @@ func (c *Client) FetchResourceMetrics(labels model.Metric, duration string, now
 }
 
 func (c *Client) QueryNetworkRate(labels model.Metric, now time.Time) (uploadRate float64, downloadRate float64, err error) {
+	return c.QueryNetworkRateOver(labels, TrafficRateWindow, now)
+}
+
+// QueryNetworkRateOver 返回 window 内的平均网络速率，抓取间隔较长时窗口按 RateWindow 放宽
+func (c *Client) QueryNetworkRateOver(labels model.Metric, rateWindow time.Duration, now time.Time) (uploadRate float64, downloadRate float64, err error) {
 	labelMatchers := BuildLabelMatchers(labels)
+	window := c.RateRange(rateWindow, labels)
 	uploadQuery := ""
 	downloadQuery := ""
 	if len(labelMatchers) > 0 {
-		uploadQuery = fmt.Sprintf(`sum(rate(node_network_transmit_bytes_total{%s, device=~"eth.*|ens.*|eno.*|enp.*|enx.*|enX.*|wlan.*|venet.*"}[1m]))`, labelMatchers)
-		downloadQuery = fmt.Sprintf(`sum(rate(node_network_receive_bytes_total{%s, device=~"eth.*|ens.*|eno.*|enp.*|enx.*|enX.*|wlan.*|venet.*"}[1m]))`, labelMatchers)
+		uploadQuery = fmt.Sprintf(`sum(rate(node_network_transmit_bytes_total{%s, device=~"eth.*|ens.*|eno.*|enp.*|enx.*|enX.*|wlan.*|venet.*"}[%s]))`, labelMatchers, window)
+		downloadQuery = fmt.Sprintf(`sum(rate(node_network_receive_bytes_total{%s, device=~"eth.*|ens.*|eno.*|enp.*|enx.*|enX.*|wlan.*|venet.*"}[%s]))`, labelMatchers, window)
 	} else {
-		uploadQuery = fmt.Sprintf(`sum(rate(node_network_transmit_bytes_total{device=~"eth.*|ens.*|eno.*|enp.*|enx.*|enX.*|wlan.*|venet.*"}[1m]))`)
-		downloadQuery = fmt.Sprintf(`sum(rate(node_network_receive_bytes_total{device=~"eth.*|ens.*|eno.*|enp.*|enx.*|enX.*|wlan.*|venet.*"}[1m]))`)
+		uploadQuery = fmt.Sprintf(`sum(rate(node_network_transmit_bytes_total{device=~"eth.*|ens.*|eno.*|enp.*|enx.*|enX.*|wlan.*|venet.*"}[%s]))`, window)
+		downloadQuery = fmt.Sprintf(`sum(rate(node_network_receive_bytes_total{device=~"eth.*|ens.*|eno.*|enp.*|enx.*|enX.*|wlan.*|venet.*"}[%s]))`, window)
 	}
 
 	uploadResult, err := c.QueryPrometheus(uploadQuery, now)
@@ -502,7 +705,7 @@ func (c *Client) QueryNetworkRate(labels model.Metric, now time.Time) (uploadRat
 
 // GetHighestCpuUsageInstance 返回CPU使用率最高的实例名称和使用率值
 func (c *Client) GetHighestCpuUsageInstance(now time.Time) (string, float64, error) {
-	query := `topk(1, (1 - avg by (instance) (rate(node_cpu_seconds_total{mode="idle"}[5m]))) * 100)`
+	query := fmt.Sprintf(`topk(1, (1 - avg by (instance) (rate(node_cpu_seconds_total{mode="idle"}[%s]))) * 100)`, c.ResourceRange(ResourceViewOverview))
 
 	result, err := c.QueryPrometheus(query, now)
 	if err != nil {
@@ -548,7 +751,8 @@ func (c *Client) GetHighestMemoryUsageInstance(now time.Time) (string, float64,
 
 // GetHighestDiskUsageInstance 返回磁盘使用率最高的实例名称和使用率值
 func (c *Client) GetHighestDiskUsageInstance(now time.Time) (string, float64, error) {
-	query := `topk(1, (1 - (node_filesystem_avail_bytes / node_filesystem_size_bytes)) * 100)`
+	fsMatchers := c.filesystemFilter.Matchers()
+	query := fmt.Sprintf(`topk(1, (1 - sum by (instance) (node_filesystem_avail_bytes{%s}) / sum by (instance) (node_filesystem_size_bytes{%s})) * 100)`, fsMatchers, fsMatchers)
 
 	result, err := c.QueryPrometheus(query, now)
 	if err != nil {
@@ -571,7 +775,7 @@ func (c *Client) GetHighestDiskUsageInstance(now time.Time) (string, float64, er
 
 // GetHighestUploadRateInstance 返回上传速率最高的实例名称和速率值
 func (c *Client) GetHighestUploadRateInstance(now time.Time) (string, float64, error) {
-	query := `topk(1, sum by (instance) (rate(node_network_transmit_bytes_total{device=~"eth.*|ens.*|eno.*|enp.*|enx.*|enX.*|wlan.*|venet.*"}[1m])))`
+	query := fmt.Sprintf(`topk(1, sum by (instance) (rate(node_network_transmit_bytes_total{device=~"eth.*|ens.*|eno.*|enp.*|enx.*|enX.*|wlan.*|venet.*"}[%s])))`, c.RateRange(TrafficRateWindow, nil))
 
 	result, err := c.QueryPrometheus(query, now)
 	if err != nil {
@@ -594,7 +798,7 @@ func (c *Client) GetHighestUploadRateInstance(now time.Time) (string, float64, e
 
 // GetHighestDownloadRateInstance 返回下载速率最高的实例名称和速率值
 func (c *Client) GetHighestDownloadRateInstance(now time.Time) (string, float64, error) {
-	query := `topk(1, sum by (instance) (rate(node_network_receive_bytes_total{device=~"eth.*|ens.*|eno.*|enp.*|enx.*|enX.*|wlan.*|venet.*"}[1m])))`
+	query := fmt.Sprintf(`topk(1, sum by (instance) (rate(node_network_receive_bytes_total{device=~"eth.*|ens.*|eno.*|enp.*|enx.*|enX.*|wlan.*|venet.*"}[%s])))`, c.RateRange(TrafficRateWindow, nil))
 
 	result, err := c.QueryPrometheus(query, now)
 	if err != nil {
@@ -939,74 +1143,15 @@ func (c *Client) GetHighestMonthlyTotalTrafficInstance(now time.Time) (string, f
 	return highestInstance, highestValue, nil
 }
 
-func calculateLastMonthExpiry(expiryTime time.Time, now time.Time) time.Time {
-	expiryDay := expiryTime.Day()
-	currentYear := now.Year()
-	currentMonth := now.Month()
-	currentDay := now.Day()
-
-	// 首先尝试在当前月份查找到期日
-	var lastMonthExpiry time.Time
-
-	// 获取当前月有多少天，以防止到期日在当前月中不存在（例如2月30日）
-	daysInCurrentMonth := time.Date(currentYear, currentMonth+1, 0, 0, 0, 0, 0, time.Local).Day()
-
-	if currentDay >= expiryDay {
-		// 如果当前日期已经过了或等于到期日，那么本次周期的到期日就是本月的到期日
-		// 上一个周期的到期日就是本月的到期日
-		if expiryDay <= daysInCurrentMonth {
-			lastMonthExpiry = time.Date(currentYear, currentMonth, expiryDay, 0, 0, 0, 0, time.Local)
-		} else {
-			// 如果到期日大于当前月的天数，使用当月最后一天
-			lastMonthExpiry = time.Date(currentYear, currentMonth, daysInCurrentMonth, 0, 0, 0, 0, time.Local)
-		}
-
-		// 如果这个日期是今天或未来，我们需要找上一个周期的日期
-		if lastMonthExpiry.After(now) {
-			// 移动到上一个月
-			lastMonthExpiry = shiftToPreviousMonth(currentYear, currentMonth, expiryDay)
-		}
-	} else {
-		// 如果当前日期还没到期，那么上一个周期的到期日是在上个月
-		lastMonthExpiry = shiftToPreviousMonth(currentYear, currentMonth, expiryDay)
-	}
-
-	return lastMonthExpiry
-}
-
-// 辅助函数：获取上一个月的对应到期日
-func shiftToPreviousMonth(year int, month time.Month, day int) time.Time {
-	// 将日期设置为上个月
-	prevMonth := month - 1
-	prevYear := year
-	if prevMonth < 1 {
-		prevYear = year - 1
-		prevMonth = 12
-	}
-
-	// 获取上个月的最大天数
-	daysInPrevMonth := time.Date(prevYear, prevMonth+1, 0, 0, 0, 0, 0, time.Local).Day()
-
-	// 如果原日期在上个月不存在（如1月31日），则使用上个月的最后一天
-	if day > daysInPrevMonth {
-		return time.Date(prevYear, prevMonth, daysInPrevMonth, 0, 0, 0, 0, time.Local)
-	}
-
-	return time.Date(prevYear, prevMonth, day, 0, 0, 0, 0, time.Local)
-}
-
-func calculateDaysDifference(now, lastMonthExpiry time.Time) int {
-	daysDiff := int(math.Floor(now.Sub(lastMonthExpiry).Hours() / 24))
-	if daysDiff < 0 {
-		daysDiff = -daysDiff
-	}
-	return daysDiff
-}
-
 func BuildLabelMatchers(labels model.Metric) string {
 	var matcherStrings []string
 	for k, v := range labels {
-		if k == "__name__" || k == "expiry" || k == "price" || k == "info" || k == "cycle" || k == "job" || k == "cpu" {
+		if k == excludedInstancesLabel {
+			matcherStrings = append(matcherStrings, fmt.Sprintf("instance!~%q", string(v)))
+			continue
+		}
+		if k == "__name__" || k == "job" || k == "cpu" || k == MergedInstancesLabel || IsMetadataLabel(k) ||
+			k == fsTypesIncludeLabel || k == fsTypesExcludeLabel || k == mountpointsExcludeLabel {
 			continue
 		}
 		matcherStrings = append(matcherStrings, fmt.Sprintf("%s=\"%s\"", k, string(v)))
@@ -1018,6 +1163,15 @@ func BuildLabelMatchers(labels model.Metric) string {
 	return result
 }
 
+// IsMetadataLabel 判断标签是否为实例的续费、计费等信息，而不是用于选择序列的标签
+func IsMetadataLabel(k model.LabelName) bool {
+	switch k {
+	case "expiry", "price", "info", "cycle", "billing", "commit_rate", "overage_price", "traffic_quota", "overage_per_gb":
+		return true
+	}
+	return false
+}
+
 func CalculateTraffic(transmitBytes, receiveBytes float64) (float64, float64, float64) {
 	totalBytes := transmitBytes + receiveBytes
 	receiveGiB := receiveBytes / (1024 * 1024 * 1024)
@@ -1025,29 +1179,6 @@ func CalculateTraffic(transmitBytes, receiveBytes float64) (float64, float64, fl
 	return totalBytes, receiveGiB, transmitGiB
 }
 
-func calculateTimeLeft(timeLeft time.Duration) (int, int, int) {
-	yearsLeft := int(timeLeft.Hours() / (24 * 365))
-	monthsLeft := int((timeLeft.Hours() / (24 * 30)) - (float64(yearsLeft) * 12))
-	daysLeft := int(timeLeft.Hours()/24) - (yearsLeft * 365) - (monthsLeft * 30)
-	return yearsLeft, monthsLeft, daysLeft
-}
-
-func formatDuration(d time.Duration) string {
-	hours := int(d.Hours())
-	minutes := int(d.Minutes()) % 60
-	seconds := int(d.Seconds()) % 60
-
-	if hours >= 24 {
-		return fmt.Sprintf("%dd", hours/24)
-	} else if minutes > 0 {
-		return fmt.Sprintf("%dh%dm", hours, minutes)
-	} else if seconds > 0 {
-		return fmt.Sprintf("%dh%dm%ds", hours, minutes, seconds)
-	} else {
-		return fmt.Sprintf("%dh", hours)
-	}
-}
-
 func FormatBytesPerSecond(bytesPerSecond float64) string {
 	const (
 		KB float64 = 1024
@@ -1107,41 +1238,27 @@ func calculateActualExpiryDate(originalExpiry time.Time, cycleStr string, now ti
 		return originalExpiry
 	}
 
-	// Parse the cycle string to determine the renewal period
-	switch cycleStr {
-	case "1year":
-		// Add 1 year to the original expiry date until we reach a date in the future
-		// Start from the original expiry date
-		adjustedExpiry := originalExpiry
-		for adjustedExpiry.Before(now) || adjustedExpiry.Equal(now.Truncate(24*time.Hour)) {
-			adjustedExpiry = adjustedExpiry.AddDate(1, 0, 0)
-		}
-		return adjustedExpiry
-	case "1month":
-		// Add 1 month to the original expiry date until we reach a date in the future
-		adjustedExpiry := originalExpiry
-		for adjustedExpiry.Before(now) || adjustedExpiry.Equal(now.Truncate(24*time.Hour)) {
-			adjustedExpiry = adjustedExpiry.AddDate(0, 1, 0)
-		}
-		return adjustedExpiry
-	case "3month":
-		// Add 3 months to the original expiry date until we reach a date in the future
-		adjustedExpiry := originalExpiry
-		for adjustedExpiry.Before(now) || adjustedExpiry.Equal(now.Truncate(24*time.Hour)) {
-			adjustedExpiry = adjustedExpiry.AddDate(0, 3, 0)
-		}
-		return adjustedExpiry
-	case "6month":
-		// Add 6 months to the original expiry date until we reach a date in the future
-		adjustedExpiry := originalExpiry
-		for adjustedExpiry.Before(now) || adjustedExpiry.Equal(now.Truncate(24*time.Hour)) {
-			adjustedExpiry = adjustedExpiry.AddDate(0, 6, 0)
-		}
-		return adjustedExpiry
-	default:
-		// If the cycle is not recognized, return the original expiry date
+	// If the cycle is not recognized, return the original expiry date
+	months := CycleMonths(cycleStr)
+	if months == 0 {
 		return originalExpiry
 	}
+
+	// 每次都从原始到期日推算，避免 1 月 31 日这类日期在逐月累加时漂移到 3 日
+	adjustedExpiry := originalExpiry
+	for k := 1; adjustedExpiry.Before(now) || adjustedExpiry.Equal(now.Truncate(24*time.Hour)); k++ {
+		adjustedExpiry = utils.AddMonthsClamped(originalExpiry, k*months)
+	}
+	return adjustedExpiry
+}
+
+// ActualExpiryDate 解析实例的 expiry 标签，并按 cycle 标签推算出当前周期的续费日期
+func ActualExpiryDate(labels model.Metric, now time.Time) (time.Time, error) {
+	expiryTime, err := time.Parse("2006-01-02", string(labels["expiry"]))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Failed to parse expiry date: %v", err)
+	}
+	return calculateActualExpiryDate(expiryTime, string(labels["cycle"]), now), nil
 }
 
 // convertCycleToFriendlyText converts cycle values to friendly Chinese descriptions
@@ -1162,26 +1279,3 @@ func convertCycleToFriendlyText(cycleStr string) string {
 		return cycleStr
 	}
 }
-
-// calculateTimeDifference computes the difference between two times in years, months, and days
-func calculateTimeDifference(start, end time.Time) (int, int, int) {
-	years := end.Year() - start.Year()
-	months := int(end.Month() - start.Month())
-	days := end.Day() - start.Day()
-
-	// Adjust for negative days
-	if days < 0 {
-		months--
-		// Get the last day of the previous month
-		previousMonth := end.AddDate(0, 0, -end.Day())
-		days = previousMonth.Day() + days
-	}
-
-	// Adjust for negative months
-	if months < 0 {
-		years--
-		months += 12
-	}
-
-	return years, months, days
-}
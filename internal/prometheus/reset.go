@@ -0,0 +1,167 @@
+package prometheus
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/utils"
+	"github.com/prometheus/common/model"
+)
+
+// ResetPeriod 是流量重置的周期
+type ResetPeriod string
+
+const (
+	ResetMonthly   ResetPeriod = "monthly"
+	ResetQuarterly ResetPeriod = "quarterly"
+	ResetYearly    ResetPeriod = "yearly"
+	// ResetNone 表示账单周期内不重置，流量从本次续费开始累计到下次续费
+	ResetNone ResetPeriod = "none"
+)
+
+// ResetPolicy 描述实例的流量重置规则
+type ResetPolicy struct {
+	Period ResetPeriod
+	// Anchor 是任意一次重置发生的日期，后续重置按周期从该日期推算，日期取其日
+	Anchor time.Time
+	// months 是相邻两次重置之间的月数
+	months int
+}
+
+// ResetPolicyFor 根据实例标签确定流量重置规则：
+//   - 默认按 cycle 标签的账单周期重置：月付每月、季付每季度、年付每年，半年付和三年付在续费时重置，
+//     缺少或无法识别 cycle 时按月重置
+//   - reset_policy 标签可覆盖默认周期，显式指定 monthly、quarterly、yearly 或 none，
+//     例如每月重置流量的年付套餐设置 reset_policy=monthly
+//   - reset_day 标签指定重置锚点日期，未指定时使用当前周期的续费日期
+//   - none 表示只在续费时重置，周期长度由 cycle 标签决定
+//
+// 锚点日期按 now 所在时区的零点计算，与续费提醒和月度报告一致
+func ResetPolicyFor(labels model.Metric, now time.Time) (ResetPolicy, error) {
+	expiry, err := ActualExpiryDate(labels, now)
+	if err != nil {
+		return ResetPolicy{}, err
+	}
+
+	cycle := string(labels["cycle"])
+	policy := ResetPolicy{Period: defaultResetPeriod(cycle), Anchor: dateIn(expiry, now.Location())}
+	if v := string(labels["reset_policy"]); v != "" {
+		policy.Period = ResetPeriod(v)
+	}
+	if v := string(labels["reset_day"]); v != "" && policy.Period != ResetNone {
+		anchor, err := time.ParseInLocation("2006-01-02", v, now.Location())
+		if err != nil {
+			return ResetPolicy{}, fmt.Errorf("Failed to parse reset day: %v", err)
+		}
+		policy.Anchor = anchor
+	}
+
+	switch policy.Period {
+	case ResetMonthly:
+		policy.months = 1
+	case ResetQuarterly:
+		policy.months = 3
+	case ResetYearly:
+		policy.months = 12
+	case ResetNone:
+		policy.months = CycleMonths(cycle)
+		if policy.months == 0 {
+			return ResetPolicy{}, fmt.Errorf("reset policy none requires a known cycle, got %q", cycle)
+		}
+	default:
+		return ResetPolicy{}, fmt.Errorf("unknown reset policy %q", policy.Period)
+	}
+	return policy, nil
+}
+
+// defaultResetPeriod 返回没有 reset_policy 标签时按 cycle 标签推导的重置周期
+func defaultResetPeriod(cycle string) ResetPeriod {
+	switch CycleMonths(cycle) {
+	case 0, 1:
+		return ResetMonthly
+	case 3:
+		return ResetQuarterly
+	case 12:
+		return ResetYearly
+	default:
+		return ResetNone
+	}
+}
+
+// dateIn 返回 t 的日期在 loc 时区的零点
+func dateIn(t time.Time, loc *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+// LastReset 返回不晚于 now 的最近一次重置时间
+func (p ResetPolicy) LastReset(now time.Time) time.Time {
+	last, _ := p.resetBounds(now)
+	return last
+}
+
+// NextReset 返回晚于 now 的下一次重置时间
+func (p ResetPolicy) NextReset(now time.Time) time.Time {
+	_, next := p.resetBounds(now)
+	return next
+}
+
+// String 返回重置周期的中文描述
+func (p ResetPolicy) String() string {
+	switch p.Period {
+	case ResetMonthly:
+		return fmt.Sprintf("每月 %d 日", p.Anchor.Day())
+	case ResetQuarterly:
+		return fmt.Sprintf("每季度 %d 日", p.Anchor.Day())
+	case ResetYearly:
+		return fmt.Sprintf("每年 %d 月 %d 日", p.Anchor.Month(), p.Anchor.Day())
+	case ResetNone:
+		return "续费时重置"
+	default:
+		return string(p.Period)
+	}
+}
+
+// resetBounds 计算包含 now 的重置区间 [last, next)
+func (p ResetPolicy) resetBounds(now time.Time) (time.Time, time.Time) {
+	months := p.months
+	if months <= 0 {
+		months = 1
+	}
+
+	// 先按月份差估算 now 所在的区间，再向前或向后修正
+	diff := (now.Year()-p.Anchor.Year())*12 + int(now.Month()-p.Anchor.Month())
+	k := diff / months
+	if diff < 0 && diff%months != 0 {
+		k--
+	}
+	last := utils.AddMonthsClamped(p.Anchor, k*months)
+	for last.After(now) {
+		k--
+		last = utils.AddMonthsClamped(p.Anchor, k*months)
+	}
+	next := utils.AddMonthsClamped(p.Anchor, (k+1)*months)
+	for !next.After(now) {
+		k++
+		last = next
+		next = utils.AddMonthsClamped(p.Anchor, (k+1)*months)
+	}
+	return last, next
+}
+
+// CycleMonths 返回 cycle 标签对应的账单周期月数，无法识别时返回 0
+func CycleMonths(cycleStr string) int {
+	switch cycleStr {
+	case "1month":
+		return 1
+	case "3month":
+		return 3
+	case "6month":
+		return 6
+	case "1year":
+		return 12
+	case "3year":
+		return 36
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,118 @@
+package prometheus
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+// AlertRule 是一条与机器人检测逻辑等价的 Prometheus 告警规则
+type AlertRule struct {
+	Alert   string
+	Expr    string
+	For     time.Duration
+	Summary string
+}
+
+// RuleOptions 是机器人当前的检测配置，为零值的项不生成对应规则
+type RuleOptions struct {
+	// PollInterval 是机器人的轮询间隔，用作规则的 for 持续时间，与机器人最快的通知时机一致
+	PollInterval   time.Duration
+	Thresholds     map[string]float64
+	StaleThreshold time.Duration
+	UPSMinRuntime  time.Duration
+}
+
+// usageAlertNames 是使用率阈值对应的告警名称
+var usageAlertNames = map[string]string{
+	UsageFileDescriptors: "NodeFileDescriptorUsageHigh",
+	UsageInodes:          "NodeInodeUsageHigh",
+	UsageFailedUnits:     "NodeSystemdUnitsFailed",
+	UsageClockDrift:      "NodeClockDrift",
+}
+
+// AlertRules 根据机器人的检测配置生成告警规则，顺序与机器人的检查顺序一致
+func (c *Client) AlertRules(opts RuleOptions) ([]AlertRule, error) {
+	rules := []AlertRule{{
+		Alert:   "NodeExporterDown",
+		Expr:    `up{job="node-exporter"} == 0`,
+		For:     opts.PollInterval,
+		Summary: "实例 {{ $labels.instance }} 离线",
+	}}
+
+	if opts.StaleThreshold > 0 {
+		// 与 GetFreshness 相同，取窗口内最近一次抓取的时间戳；离线实例由 NodeExporterDown 覆盖
+		rules = append(rules, AlertRule{
+			Alert: "NodeMetricsStale",
+			Expr: fmt.Sprintf(`time() - max by (instance) (max_over_time(timestamp(node_time_seconds{job="node-exporter"})[%s:1m])) > %g and on (instance) up{job="node-exporter"} == 1`,
+				freshnessWindow, opts.StaleThreshold.Seconds()),
+			For:     opts.PollInterval,
+			Summary: "实例 {{ $labels.instance }} 在线但指标已 {{ $value | humanizeDuration }} 未更新",
+		})
+	}
+
+	metrics := make([]string, 0, len(opts.Thresholds))
+	for metric := range opts.Thresholds {
+		metrics = append(metrics, metric)
+	}
+	sort.Strings(metrics)
+	for _, metric := range metrics {
+		query, err := c.UsageQuery(metric)
+		if err != nil {
+			return nil, err
+		}
+		limit := opts.Thresholds[metric]
+		label, _ := UsageLabel(metric)
+		rules = append(rules, AlertRule{
+			Alert:   usageAlertNames[metric],
+			Expr:    fmt.Sprintf(`%s >= %g`, query, limit),
+			For:     opts.PollInterval,
+			Summary: fmt.Sprintf(`实例 {{ $labels.instance }} %s {{ printf "%%.1f" $value }}，超过阈值 %s`, label, FormatUsage(metric, limit)),
+		})
+	}
+
+	if opts.UPSMinRuntime > 0 {
+		for _, source := range upsSources {
+			onBattery := fmt.Sprintf(`(%s) == 1`, source.onBattery)
+			rules = append(rules,
+				AlertRule{
+					Alert:   "UPSOnBattery",
+					Expr:    onBattery,
+					For:     opts.PollInterval,
+					Summary: "UPS {{ $labels.instance }} {{ $labels.ups }} 切换到电池供电",
+				},
+				AlertRule{
+					Alert:   "UPSLowRuntime",
+					Expr:    fmt.Sprintf(`%s < %g and on (instance, ups) %s`, source.runtime, opts.UPSMinRuntime.Seconds(), onBattery),
+					For:     opts.PollInterval,
+					Summary: fmt.Sprintf("UPS {{ $labels.instance }} {{ $labels.ups }} 电池剩余 {{ $value | humanizeDuration }}，低于 %s", model.Duration(opts.UPSMinRuntime)),
+				},
+			)
+		}
+	}
+	return rules, nil
+}
+
+// RulesYAML 将告警规则编码为 Prometheus 规则文件，所有字符串使用双引号，避免 PromQL 中的特殊字符破坏格式
+func RulesYAML(group string, rules []AlertRule) []byte {
+	var sb strings.Builder
+	sb.WriteString("groups:\n")
+	fmt.Fprintf(&sb, "  - name: %s\n", strconv.Quote(group))
+	sb.WriteString("    rules:\n")
+	for _, rule := range rules {
+		fmt.Fprintf(&sb, "      - alert: %s\n", rule.Alert)
+		fmt.Fprintf(&sb, "        expr: %s\n", strconv.Quote(rule.Expr))
+		if rule.For > 0 {
+			fmt.Fprintf(&sb, "        for: %s\n", model.Duration(rule.For))
+		}
+		sb.WriteString("        labels:\n")
+		sb.WriteString("          source: \"prometheus-telegram-bot\"\n")
+		sb.WriteString("        annotations:\n")
+		fmt.Fprintf(&sb, "          summary: %s\n", strconv.Quote(rule.Summary))
+	}
+	return []byte(sb.String())
+}
@@ -0,0 +1,87 @@
+package prometheus
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// maxSlowQueries 是慢查询记录保留的最大条数，超出后丢弃最旧的记录
+const maxSlowQueries = 100
+
+// SlowQuery 是一次耗时超过阈值的查询
+type SlowQuery struct {
+	Query string
+	// Key 是查询来源，通常为聊天ID，后台任务发出的查询为空
+	Key      string
+	Range    bool
+	Duration time.Duration
+	At       time.Time
+}
+
+// slowQueryLog 记录耗时超过阈值的查询，由同一个 Client 派生的所有客户端共享
+type slowQueryLog struct {
+	threshold time.Duration
+
+	mu      sync.Mutex
+	entries []SlowQuery
+}
+
+func (l *slowQueryLog) add(q SlowQuery) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, q)
+	if len(l.entries) > maxSlowQueries {
+		l.entries = l.entries[len(l.entries)-maxSlowQueries:]
+	}
+}
+
+// SetSlowQueryThreshold 设置慢查询阈值，耗时超过 threshold 的查询会被记录，为 0 时不记录
+func (c *Client) SetSlowQueryThreshold(threshold time.Duration) {
+	if threshold <= 0 {
+		c.slowLog = nil
+		return
+	}
+	c.slowLog = &slowQueryLog{threshold: threshold}
+}
+
+// observeQuery 在查询完成后调用，记录到查询来源的最近查询中，耗时超过阈值时写日志并记录为慢查询。
+// 不包括等待并发名额的时间
+func (c *Client) observeQuery(query string, isRange bool, started time.Time) {
+	d := time.Since(started)
+	c.trace.add(c.key, TracedQuery{Query: query, Range: isRange, Duration: d, At: started})
+	if c.slowLog == nil || d < c.slowLog.threshold {
+		return
+	}
+	log.Printf("Slow Prometheus query (%s, key %q): %s", d.Round(time.Millisecond), c.key, query)
+	c.slowLog.add(SlowQuery{Query: query, Key: c.key, Range: isRange, Duration: d, At: time.Now()})
+}
+
+// SlowQueries 返回记录的慢查询，最新的在前
+func (c *Client) SlowQueries() []SlowQuery {
+	if c.slowLog == nil {
+		return nil
+	}
+	c.slowLog.mu.Lock()
+	defer c.slowLog.mu.Unlock()
+	queries := make([]SlowQuery, len(c.slowLog.entries))
+	for i, q := range c.slowLog.entries {
+		queries[len(queries)-1-i] = q
+	}
+	return queries
+}
+
+// SlowestSince 返回当前来源在 since 之后完成的最慢的一次慢查询
+func (c *Client) SlowestSince(since time.Time) (SlowQuery, bool) {
+	var slowest SlowQuery
+	found := false
+	for _, q := range c.SlowQueries() {
+		if q.At.Before(since) {
+			break
+		}
+		if q.Key == c.key && q.Duration > slowest.Duration {
+			slowest, found = q, true
+		}
+	}
+	return slowest, found
+}
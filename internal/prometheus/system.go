@@ -0,0 +1,144 @@
+package prometheus
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/utils"
+	"github.com/prometheus/common/model"
+)
+
+// SystemInfo 是实例的操作系统、内核和待处理更新信息
+type SystemInfo struct {
+	Instance string
+	// OS 来自 node_os_info 的 pretty_name，旧版本 node_exporter 没有该指标时为空
+	OS      string
+	Kernel  string
+	Machine string
+	// RebootRequired 来自 textfile 收集器的 node_reboot_required 或 needrestart 的内核状态
+	RebootRequired bool
+	// PendingUpdates 和 SecurityUpdates 来自 apt_info / yum 脚本，HasUpdates 为 false 表示没有上报
+	HasUpdates      bool
+	PendingUpdates  float64
+	SecurityUpdates float64
+	// OutdatedKernel 表示同一系统的其他实例运行着更新的内核，只在汇总视图中计算
+	OutdatedKernel bool
+}
+
+// systemQueries 是查询系统信息使用的表达式，%s 为标签匹配器
+var systemQueries = struct {
+	uname, os, reboot, updates, security string
+}{
+	uname: `node_uname_info{%s}`,
+	os:    `node_os_info{%s}`,
+	// apt_info.py 生成 node_reboot_required；needrestart 的内核状态大于 0 表示有新内核等待重启
+	reboot:   `max by (instance) (node_reboot_required{%[1]s} or clamp_max(needrestart_kernel_status{%[1]s}, 1))`,
+	updates:  `sum by (instance) (apt_upgrades_pending{%[1]s} or yum_upgrades_pending{%[1]s})`,
+	security: `sum by (instance) (apt_upgrades_pending{%s})`,
+}
+
+// QuerySystemInfo 返回单个实例的系统信息，没有 node_uname_info 时返回 nil
+func (c *Client) QuerySystemInfo(labels model.Metric, now time.Time) (*SystemInfo, error) {
+	infos, err := c.querySystemInfos(BuildLabelMatchers(labels), now)
+	if err != nil {
+		return nil, err
+	}
+	if len(infos) == 0 {
+		return nil, nil
+	}
+	return &infos[0], nil
+}
+
+// FleetSystemInfo 返回所有实例的系统信息，并标记内核版本落后于同一系统其他实例的主机
+func (c *Client) FleetSystemInfo(now time.Time) ([]SystemInfo, error) {
+	infos, err := c.querySystemInfos("", now)
+	if err != nil {
+		return nil, err
+	}
+	newest := make(map[string]string)
+	for _, info := range infos {
+		if utils.CompareVersions(info.Kernel, newest[info.OS]) > 0 {
+			newest[info.OS] = info.Kernel
+		}
+	}
+	for i := range infos {
+		infos[i].OutdatedKernel = utils.CompareVersions(infos[i].Kernel, newest[infos[i].OS]) < 0
+	}
+	return infos, nil
+}
+
+func (c *Client) querySystemInfos(labelMatchers string, now time.Time) ([]SystemInfo, error) {
+	securityMatchers := `origin=~".*[Ss]ecurity.*"`
+	if labelMatchers != "" {
+		securityMatchers = labelMatchers + "," + securityMatchers
+	}
+	vector := func(name, query string) (model.Vector, error) {
+		matchers := labelMatchers
+		if query == systemQueries.security {
+			matchers = securityMatchers
+		}
+		result, err := c.QueryPrometheus(fmt.Sprintf(query, matchers), now)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to query %s: %v", name, err)
+		}
+		v, _ := result.(model.Vector)
+		return v, nil
+	}
+
+	unames, err := vector("uname info", systemQueries.uname)
+	if err != nil {
+		return nil, err
+	}
+	byInstance := make(map[string]*SystemInfo)
+	for _, sample := range unames {
+		instance := string(sample.Metric["instance"])
+		byInstance[instance] = &SystemInfo{
+			Instance: instance,
+			Kernel:   string(sample.Metric["release"]),
+			Machine:  string(sample.Metric["machine"]),
+		}
+	}
+
+	// 以下指标都是可选的，查询失败不影响基本信息
+	apply := func(name, query string, set func(*SystemInfo, model.Sample)) error {
+		samples, err := vector(name, query)
+		if err != nil {
+			return err
+		}
+		for _, sample := range samples {
+			if info, ok := byInstance[string(sample.Metric["instance"])]; ok {
+				set(info, *sample)
+			}
+		}
+		return nil
+	}
+	if err := apply("os info", systemQueries.os, func(info *SystemInfo, s model.Sample) {
+		info.OS = string(s.Metric["pretty_name"])
+	}); err != nil {
+		return nil, err
+	}
+	if err := apply("reboot required", systemQueries.reboot, func(info *SystemInfo, s model.Sample) {
+		info.RebootRequired = s.Value > 0
+	}); err != nil {
+		return nil, err
+	}
+	if err := apply("pending updates", systemQueries.updates, func(info *SystemInfo, s model.Sample) {
+		info.HasUpdates = true
+		info.PendingUpdates = float64(s.Value)
+	}); err != nil {
+		return nil, err
+	}
+	if err := apply("security updates", systemQueries.security, func(info *SystemInfo, s model.Sample) {
+		info.SecurityUpdates = float64(s.Value)
+	}); err != nil {
+		return nil, err
+	}
+
+	infos := make([]SystemInfo, 0, len(byInstance))
+	for _, info := range byInstance {
+		infos = append(infos, *info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Instance < infos[j].Instance })
+	return infos, nil
+}
@@ -0,0 +1,106 @@
+package prometheus
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+// SystemdUnit 是一个 systemd 单元的状态
+type SystemdUnit struct {
+	Name  string
+	State string
+	// Restarts 是服务的重启次数，只有 node_exporter 启用了 --collector.systemd.enable-restarts-metrics 时才有
+	Restarts    float64
+	HasRestarts bool
+}
+
+// SystemdStatus 是实例的 systemd 单元状态
+type SystemdStatus struct {
+	// Failed 是所有处于 failed 状态的单元
+	Failed []SystemdUnit
+	// Services 是配置中关注的服务，未找到的服务状态为空
+	Services []SystemdUnit
+}
+
+// HasSystemd 判断实例的 node_exporter 是否启用了 systemd 收集器
+func (c *Client) HasSystemd(labels model.Metric, now time.Time) (bool, error) {
+	result, err := c.QueryPrometheus(fmt.Sprintf(`count(node_systemd_units{%s})`, BuildLabelMatchers(labels)), now)
+	if err != nil {
+		return false, fmt.Errorf("Failed to query systemd units: %v", err)
+	}
+	vector, ok := result.(model.Vector)
+	return ok && len(vector) > 0, nil
+}
+
+// QuerySystemd 查询实例失败的 systemd 单元和 services 中服务的状态。
+// node_exporter 未启用 systemd 收集器时返回 nil
+func (c *Client) QuerySystemd(labels model.Metric, services []string, now time.Time) (*SystemdStatus, error) {
+	labelMatchers := BuildLabelMatchers(labels)
+	withLabels := func(matchers string) string {
+		if labelMatchers == "" {
+			return matchers
+		}
+		return labelMatchers + "," + matchers
+	}
+
+	enabled, err := c.HasSystemd(labels, now)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return nil, nil
+	}
+
+	status := &SystemdStatus{}
+	failedResult, err := c.QueryPrometheus(fmt.Sprintf(`node_systemd_unit_state{%s} == 1`, withLabels(`state="failed"`)), now)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query failed systemd units: %v", err)
+	}
+	if vector, ok := failedResult.(model.Vector); ok {
+		for _, sample := range vector {
+			status.Failed = append(status.Failed, SystemdUnit{Name: string(sample.Metric["name"]), State: "failed"})
+		}
+	}
+	sort.Slice(status.Failed, func(i, j int) bool { return status.Failed[i].Name < status.Failed[j].Name })
+
+	if len(services) == 0 {
+		return status, nil
+	}
+	quoted := make([]string, len(services))
+	for i, s := range services {
+		quoted[i] = regexp.QuoteMeta(s)
+	}
+	nameMatcher := fmt.Sprintf(`name=~"%s"`, strings.Join(quoted, "|"))
+	stateResult, err := c.QueryPrometheus(fmt.Sprintf(`node_systemd_unit_state{%s} == 1`, withLabels(nameMatcher)), now)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query systemd service states: %v", err)
+	}
+	restartsResult, err := c.QueryPrometheus(fmt.Sprintf(`node_systemd_service_restart_total{%s}`, withLabels(nameMatcher)), now)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query systemd service restarts: %v", err)
+	}
+
+	states := make(map[string]string)
+	if vector, ok := stateResult.(model.Vector); ok {
+		for _, sample := range vector {
+			states[string(sample.Metric["name"])] = string(sample.Metric["state"])
+		}
+	}
+	restarts := make(map[string]float64)
+	if vector, ok := restartsResult.(model.Vector); ok {
+		for _, sample := range vector {
+			restarts[string(sample.Metric["name"])] = float64(sample.Value)
+		}
+	}
+	for _, name := range services {
+		unit := SystemdUnit{Name: name, State: states[name]}
+		unit.Restarts, unit.HasRestarts = restarts[name]
+		status.Services = append(status.Services, unit)
+	}
+	return status, nil
+}
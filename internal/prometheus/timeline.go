@@ -0,0 +1,77 @@
+package prometheus
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/utils"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+)
+
+// UptimeDay 是实例一天内每小时的在线比例
+type UptimeDay struct {
+	Date time.Time
+	// Slots 是 24 个小时的在线比例（0 到 1），没有数据（未来时间或未被抓取）为 NaN
+	Slots [24]float64
+}
+
+// Availability 返回当天有数据的小时的平均在线比例，整天没有数据时返回 false
+func (d UptimeDay) Availability() (float64, bool) {
+	var sum float64
+	n := 0
+	for _, v := range d.Slots {
+		if !math.IsNaN(v) {
+			sum += v
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, false
+	}
+	return sum / float64(n), true
+}
+
+// UptimeTimeline 返回实例最近 days 天（含今天）按小时统计的在线比例，按日期从早到晚排列
+func (c *Client) UptimeTimeline(labels model.Metric, days int, now time.Time) ([]UptimeDay, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	return c.UptimeTimelineBetween(labels, today.AddDate(0, 0, -(days-1)), now)
+}
+
+// UptimeTimelineBetween 返回 start 所在日期到 end 所在日期之间每天按小时统计的在线比例
+func (c *Client) UptimeTimelineBetween(labels model.Metric, start, end time.Time) ([]UptimeDay, error) {
+	start = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, end.Location())
+	// end 恰好是零点时不包含当天
+	days := utils.DaysBetween(start, end.Add(-time.Nanosecond)) + 1
+	if days <= 0 {
+		return nil, nil
+	}
+
+	timeline := make([]UptimeDay, days)
+	for i := range timeline {
+		timeline[i].Date = start.AddDate(0, 0, i)
+		for h := range timeline[i].Slots {
+			timeline[i].Slots[h] = math.NaN()
+		}
+	}
+
+	// 每个点是截至该时刻前一小时的平均值，因此第一个点在起始时间后一小时
+	query := fmt.Sprintf(`avg_over_time(up{%s}[1h])`, BuildLabelMatchers(labels))
+	r := promv1.Range{Start: start.Add(time.Hour), End: end, Step: time.Hour}
+	matrix, err := c.queryMatrix(query, r)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query uptime timeline: %v", err)
+	}
+	for _, series := range matrix {
+		for _, p := range series.Values {
+			slotStart := p.Timestamp.Time().In(end.Location()).Add(-time.Hour)
+			day := utils.DaysBetween(start, slotStart)
+			if day < 0 || day >= days {
+				continue
+			}
+			timeline[day].Slots[slotStart.Hour()] = float64(p.Value)
+		}
+	}
+	return timeline, nil
+}
@@ -0,0 +1,48 @@
+package prometheus
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+// TimeSync 是实例的时钟同步状态，来自 node_exporter 的 timex 收集器
+type TimeSync struct {
+	// Offset 是本地时钟与参考时钟的偏差（秒），可能为负
+	Offset float64
+	// MaxError 是内核估算的最大误差（秒）
+	MaxError float64
+	Synced   bool
+}
+
+// OffsetMillis 返回以毫秒为单位的时钟偏差
+func (t TimeSync) OffsetMillis() float64 {
+	return t.Offset * 1000
+}
+
+// QueryTimeSync 查询实例的时钟偏差和同步状态，没有 timex 指标时返回 nil
+func (c *Client) QueryTimeSync(labels model.Metric, now time.Time) (*TimeSync, error) {
+	labelMatchers := BuildLabelMatchers(labels)
+	syncResult, err := c.QueryPrometheus(fmt.Sprintf(`max(node_timex_sync_status{%s})`, labelMatchers), now)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query clock sync status: %v", err)
+	}
+	vector, ok := syncResult.(model.Vector)
+	if !ok || len(vector) == 0 {
+		return nil, nil
+	}
+	offsetResult, err := c.QueryPrometheus(fmt.Sprintf(`max(node_timex_offset_seconds{%s})`, labelMatchers), now)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query clock offset: %v", err)
+	}
+	maxErrorResult, err := c.QueryPrometheus(fmt.Sprintf(`max(node_timex_maxerror_seconds{%s})`, labelMatchers), now)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query clock max error: %v", err)
+	}
+	return &TimeSync{
+		Offset:   c.GetFloatFromPromResult(offsetResult),
+		MaxError: c.GetFloatFromPromResult(maxErrorResult),
+		Synced:   vector[0].Value == 1,
+	}, nil
+}
@@ -0,0 +1,57 @@
+package prometheus
+
+import (
+	"sync"
+	"time"
+)
+
+// maxTracedQueries 是每个来源保留的最近查询条数，超出后丢弃最旧的记录
+const maxTracedQueries = 50
+
+// TracedQuery 是一次已完成的查询，用于在页面上显示生成页面时执行的 PromQL
+type TracedQuery struct {
+	Query    string
+	Range    bool
+	Duration time.Duration
+	At       time.Time
+}
+
+// queryTrace 按来源记录最近完成的查询，由同一个 Client 派生的所有客户端共享。
+// 后台任务发出的查询（来源为空）不记录
+type queryTrace struct {
+	mu      sync.Mutex
+	entries map[string][]TracedQuery
+}
+
+func (t *queryTrace) add(key string, q TracedQuery) {
+	if t == nil || key == "" {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.entries == nil {
+		t.entries = make(map[string][]TracedQuery)
+	}
+	entries := append(t.entries[key], q)
+	if len(entries) > maxTracedQueries {
+		entries = entries[len(entries)-maxTracedQueries:]
+	}
+	t.entries[key] = entries
+}
+
+// QueriesSince 返回当前来源在 since 之后开始的查询，按开始时间排列。
+// 同一来源的后台刷新也会出现在结果中
+func (c *Client) QueriesSince(since time.Time) []TracedQuery {
+	if c.trace == nil {
+		return nil
+	}
+	c.trace.mu.Lock()
+	defer c.trace.mu.Unlock()
+	var queries []TracedQuery
+	for _, q := range c.trace.entries[c.key] {
+		if !q.At.Before(since) {
+			queries = append(queries, q)
+		}
+	}
+	return queries
+}
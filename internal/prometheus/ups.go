@@ -0,0 +1,99 @@
+package prometheus
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+// UPS 是一台不间断电源的状态，来自 apcupsd_exporter 或 nut_exporter
+type UPS struct {
+	// Name 是 exporter 的 instance，带有 ups 标签时附加 UPS 名称，例如 nas:9162/ups1
+	Name string
+	// Charge 和 Load 为百分比，Runtime 是电池剩余可用时间
+	Charge    float64
+	Load      float64
+	Runtime   time.Duration
+	OnBattery bool
+}
+
+// upsSource 是一种 UPS exporter 的查询表达式
+type upsSource struct {
+	charge, load, runtime, onBattery string
+}
+
+// upsSources 列出支持的 UPS exporter，同一台 UPS 只会被其中一种上报
+var upsSources = []upsSource{
+	{
+		charge:    `apcupsd_battery_charge_percent`,
+		load:      `apcupsd_ups_load_percent`,
+		runtime:   `apcupsd_battery_time_left_seconds`,
+		onBattery: `apcupsd_battery_time_on_seconds > bool 0`,
+	},
+	{
+		charge:    `network_ups_tools_battery_charge`,
+		load:      `network_ups_tools_ups_load`,
+		runtime:   `network_ups_tools_battery_runtime`,
+		onBattery: `network_ups_tools_ups_status{flag="OB"}`,
+	},
+}
+
+// upsName 返回样本对应的 UPS 名称
+func upsName(metric model.Metric) string {
+	name := string(metric["instance"])
+	if ups := metric["ups"]; ups != "" {
+		name += "/" + string(ups)
+	}
+	return name
+}
+
+// QueryUPS 返回所有 UPS 的状态，按名称排序，没有 UPS exporter 时返回空列表
+func (c *Client) QueryUPS(now time.Time) ([]UPS, error) {
+	byName := make(map[string]*UPS)
+	for _, source := range upsSources {
+		apply := func(query string, set func(*UPS, float64)) error {
+			result, err := c.QueryPrometheus(query, now)
+			if err != nil {
+				return fmt.Errorf("Failed to query %s: %v", query, err)
+			}
+			vector, _ := result.(model.Vector)
+			for _, sample := range vector {
+				name := upsName(sample.Metric)
+				ups, ok := byName[name]
+				if !ok {
+					ups = &UPS{Name: name}
+					byName[name] = ups
+				}
+				set(ups, float64(sample.Value))
+			}
+			return nil
+		}
+		// nut_exporter 的电量和负载为百分比数值，与 apcupsd 相同
+		if err := apply(source.charge, func(u *UPS, v float64) { u.Charge = v }); err != nil {
+			return nil, err
+		}
+		if err := apply(source.load, func(u *UPS, v float64) { u.Load = v }); err != nil {
+			return nil, err
+		}
+		if err := apply(source.runtime, func(u *UPS, v float64) { u.Runtime = time.Duration(v) * time.Second }); err != nil {
+			return nil, err
+		}
+		if err := apply(source.onBattery, func(u *UPS, v float64) { u.OnBattery = v == 1 }); err != nil {
+			return nil, err
+		}
+	}
+
+	upses := make([]UPS, 0, len(byName))
+	for _, ups := range byName {
+		upses = append(upses, *ups)
+	}
+	sort.Slice(upses, func(i, j int) bool { return upses[i].Name < upses[j].Name })
+	return upses, nil
+}
+
+// ChargeFraction 返回 0 到 1 之间的电量比例，用于条形图
+func (u UPS) ChargeFraction() float64 {
+	return u.Charge / 100
+}
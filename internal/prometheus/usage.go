@@ -0,0 +1,170 @@
+package prometheus
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+// 可设置告警阈值的使用率指标名称
+const (
+	UsageFileDescriptors = "fd"
+	UsageInodes          = "inode"
+	// UsageFailedUnits 是失败的 systemd 单元数量，不是百分比
+	UsageFailedUnits = "systemd"
+	// UsageClockDrift 是时钟偏差的绝对值，单位为毫秒
+	UsageClockDrift = "clock"
+	// UsageCPUSteal 是 CPU steal 时间占比，用于发现超售或被邻居抢占 CPU 的 VPS
+	UsageCPUSteal = "steal"
+	// UsageNetworkErrors 是最近 5 分钟网卡收发错误包的数量，不是百分比
+	UsageNetworkErrors = "neterr"
+)
+
+// usageLabels 是使用率指标的中文名称，同时用于校验阈值配置中的指标名
+var usageLabels = map[string]string{
+	UsageFileDescriptors: "文件描述符使用率",
+	UsageInodes:          "inode 使用率",
+	UsageFailedUnits:     "失败的 systemd 单元数",
+	UsageClockDrift:      "时钟偏差",
+	UsageCPUSteal:        "CPU steal 时间占比",
+	UsageNetworkErrors:   "网卡错误包数（5 分钟）",
+}
+
+// UsageLabel 返回使用率指标的中文名称，未知指标返回 false
+func UsageLabel(metric string) (string, bool) {
+	label, ok := usageLabels[metric]
+	return label, ok
+}
+
+// UsageIsPercent 判断指标的值是否为百分比
+func UsageIsPercent(metric string) bool {
+	return metric != UsageFailedUnits && metric != UsageClockDrift && metric != UsageNetworkErrors
+}
+
+// FormatUsage 按指标的单位格式化数值，百分比保留一位小数
+func FormatUsage(metric string, value float64) string {
+	if UsageIsPercent(metric) {
+		return fmt.Sprintf("%.1f%%", value)
+	}
+	if metric == UsageClockDrift {
+		return fmt.Sprintf("%.0fms", value)
+	}
+	return fmt.Sprintf("%.0f", value)
+}
+
+// InodeUsage 是单个文件系统的 inode 使用情况
+type InodeUsage struct {
+	Mountpoint string
+	Used       float64
+	Total      float64
+}
+
+// Usage 返回 inode 使用率百分比
+func (u InodeUsage) Usage() float64 {
+	if u.Total == 0 {
+		return 0
+	}
+	return u.Used / u.Total * 100
+}
+
+// QueryFileDescriptors 返回实例已分配的文件描述符数量和系统上限
+func (c *Client) QueryFileDescriptors(labels model.Metric, now time.Time) (allocated, maximum float64, err error) {
+	labelMatchers := BuildLabelMatchers(labels)
+	allocatedResult, err := c.QueryPrometheus(fmt.Sprintf(`sum(node_filefd_allocated{%s})`, labelMatchers), now)
+	if err != nil {
+		return 0, 0, fmt.Errorf("Failed to query allocated file descriptors: %v", err)
+	}
+	maximumResult, err := c.QueryPrometheus(fmt.Sprintf(`sum(node_filefd_maximum{%s})`, labelMatchers), now)
+	if err != nil {
+		return 0, 0, fmt.Errorf("Failed to query maximum file descriptors: %v", err)
+	}
+	return c.GetFloatFromPromResult(allocatedResult), c.GetFloatFromPromResult(maximumResult), nil
+}
+
+// QueryInodeUsage 返回实例每个文件系统的 inode 使用情况，按使用率从高到低排序
+func (c *Client) QueryInodeUsage(labels model.Metric, now time.Time) ([]InodeUsage, error) {
+	fsMatchers := c.filesystemMatchers(labels)
+	filesResult, err := c.QueryPrometheus(fmt.Sprintf(`max by (mountpoint) (node_filesystem_files{%s})`, fsMatchers), now)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query inode total: %v", err)
+	}
+	freeResult, err := c.QueryPrometheus(fmt.Sprintf(`max by (mountpoint) (node_filesystem_files_free{%s})`, fsMatchers), now)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query free inodes: %v", err)
+	}
+
+	free := make(map[string]float64)
+	if vector, ok := freeResult.(model.Vector); ok {
+		for _, sample := range vector {
+			free[string(sample.Metric["mountpoint"])] = float64(sample.Value)
+		}
+	}
+	var usages []InodeUsage
+	if vector, ok := filesResult.(model.Vector); ok {
+		for _, sample := range vector {
+			total := float64(sample.Value)
+			// btrfs 等文件系统不限制 inode 数量，node_filesystem_files 为 0
+			if total == 0 {
+				continue
+			}
+			mountpoint := string(sample.Metric["mountpoint"])
+			usages = append(usages, InodeUsage{Mountpoint: mountpoint, Used: total - free[mountpoint], Total: total})
+		}
+	}
+	sort.Slice(usages, func(i, j int) bool { return usages[i].Usage() > usages[j].Usage() })
+	return usages, nil
+}
+
+// UsageQuery 返回按实例计算使用率指标的 PromQL，阈值检查和导出的告警规则共用
+func (c *Client) UsageQuery(metric string) (string, error) {
+	switch metric {
+	case UsageFileDescriptors:
+		return `100 * sum by (instance) (node_filefd_allocated) / sum by (instance) (node_filefd_maximum)`, nil
+	case UsageInodes:
+		fsMatchers := c.filesystemFilter.Matchers()
+		// 取每个实例 inode 使用率最高的文件系统
+		return fmt.Sprintf(`max by (instance) (100 * (1 - node_filesystem_files_free{%s} / (node_filesystem_files{%s} > 0)))`, fsMatchers, fsMatchers), nil
+	case UsageFailedUnits:
+		return `sum by (instance) (node_systemd_units{state="failed"})`, nil
+	case UsageClockDrift:
+		return `1000 * max by (instance) (abs(node_timex_offset_seconds))`, nil
+	case UsageNetworkErrors:
+		window := c.RateRange(defaultRateWindow, nil)
+		return fmt.Sprintf(`sum by (instance) (increase(node_network_receive_errs_total{%s}[%s]) + increase(node_network_transmit_errs_total{%s}[%s]))`,
+			networkDeviceMatcher, window, networkDeviceMatcher, window), nil
+	case UsageCPUSteal:
+		return fmt.Sprintf(`100 * avg by (instance) (rate(node_cpu_seconds_total{mode="steal"}[%s]))`, c.RateRange(defaultRateWindow, nil)), nil
+	default:
+		return "", fmt.Errorf("unknown usage metric %q", metric)
+	}
+}
+
+// UsageByInstance 返回每个实例指定使用率指标的百分比，用于阈值检查
+func (c *Client) UsageByInstance(metric string, now time.Time) (map[string]float64, error) {
+	query, err := c.UsageQuery(metric)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := c.QueryPrometheus(query, now)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query %s usage: %v", metric, err)
+	}
+	usage := make(map[string]float64)
+	if vector, ok := result.(model.Vector); ok {
+		for _, sample := range vector {
+			usage[string(sample.Metric["instance"])] = float64(sample.Value)
+		}
+	}
+	return usage, nil
+}
+
+// FDUsage 返回文件描述符使用率百分比
+func (d InstanceDetail) FDUsage() float64 {
+	if d.FDMaximum == 0 {
+		return 0
+	}
+	return d.FDAllocated / d.FDMaximum * 100
+}
@@ -0,0 +1,70 @@
+package prometheus
+
+import (
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+// 可以单独设置资源使用率统计窗口的视图
+const (
+	ResourceViewOverview = "overview"
+	ResourceViewDetail   = "detail"
+	ResourceViewGroup    = "group"
+	ResourceViewExport   = "export"
+)
+
+// DefaultResourceWindow 是未单独设置的视图计算 CPU 使用率等速率时使用的窗口
+const DefaultResourceWindow = 5 * time.Minute
+
+// ResourceViews 列出所有可设置资源窗口的视图
+var ResourceViews = []string{ResourceViewOverview, ResourceViewDetail, ResourceViewGroup, ResourceViewExport}
+
+// IsResourceView 判断 view 是否为可设置资源窗口的视图
+func IsResourceView(view string) bool {
+	for _, v := range ResourceViews {
+		if v == view {
+			return true
+		}
+	}
+	return false
+}
+
+// SetResourceWindows 设置各视图的资源使用率统计窗口，未设置的视图使用 DefaultResourceWindow
+func (c *Client) SetResourceWindows(windows map[string]time.Duration) {
+	c.resourceWindows = windows
+}
+
+// ResourceWindow 返回视图的资源使用率统计窗口
+func (c *Client) ResourceWindow(view string) time.Duration {
+	if w, ok := c.resourceWindows[view]; ok && w > 0 {
+		return w
+	}
+	return DefaultResourceWindow
+}
+
+// NetworkRate 是一个窗口内的平均网络速率（字节/秒）
+type NetworkRate struct {
+	// Window 是实际使用的 PromQL 范围，抓取间隔较长时可能比设置的窗口长
+	Window   string
+	Upload   float64
+	Download float64
+}
+
+// SetNetworkRateWindows 设置实例详情中同时显示的网络速率窗口，短窗口反映瞬时突发，长窗口反映持续流量
+func (c *Client) SetNetworkRateWindows(windows []time.Duration) {
+	c.rateWindows = windows
+}
+
+// NetworkRateWindows 返回实例详情显示的网络速率窗口，未设置时只有 TrafficRateWindow
+func (c *Client) NetworkRateWindows() []time.Duration {
+	if len(c.rateWindows) == 0 {
+		return []time.Duration{TrafficRateWindow}
+	}
+	return c.rateWindows
+}
+
+// ResourceRange 返回视图的资源统计窗口对应的 PromQL 范围，例如 "5m"
+func (c *Client) ResourceRange(view string) string {
+	return model.Duration(c.ResourceWindow(view)).String()
+}
@@ -0,0 +1,429 @@
+package render
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/geo"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/probe"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/prometheus"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/scrape"
+)
+
+// OverviewData 是实例总览模板的数据
+type OverviewData struct {
+	Total     int
+	Online    int
+	Offline   int
+	Yesterday []OverviewLine
+	Daily     []OverviewLine
+	Monthly   []OverviewLine
+	Rates     []OverviewLine
+	Resources []OverviewLine
+	// Pressure 是 PSI 资源压力，所有实例都不支持 PSI 时为空
+	Pressure []OverviewLine
+}
+
+// OverviewLine 表示总览中的一行数值，Top 不为空时附带数值最高的实例
+type OverviewLine struct {
+	Label    string
+	Value    string
+	Top      string
+	TopValue string
+	// Trend 是该数值的迷你趋势图，为空时不显示
+	Trend string
+}
+
+// InstanceTableEntry 是实例详情表中单个实例的数据，Detail 为 nil 表示获取失败
+type InstanceTableEntry struct {
+	Index  int
+	Name   string
+	Detail *prometheus.InstanceDetail
+}
+
+// AlertData 是事件通知模板的数据
+type AlertData struct {
+	Icon     string
+	Instance string
+	Message  string
+	// Severity 是严重程度的名称，例如 "严重"
+	Severity string
+	Time     time.Time
+	Duration string
+}
+
+// NewInstanceData 是发现新实例通知模板的数据
+type NewInstanceData struct {
+	Icon     string
+	Instance string
+	// Labels 是按名称排序的 name=value 标签
+	Labels []string
+	Time   time.Time
+	// Thresholds 是新实例将使用的全局告警阈值说明，为空表示未开启阈值告警
+	Thresholds []string
+	// MissingLabels 是统计费用所需但实例没有设置的标签
+	MissingLabels []string
+}
+
+// BriefingData 是每日早报模板的数据，Show* 为 false 的部分在聊天设置中被隐藏
+type BriefingData struct {
+	Time time.Time
+
+	ShowExpiring bool
+	// Expiring 是 7 天内到期的实例，按到期日期排序
+	Expiring []BriefingExpiry
+
+	ShowQuota bool
+	// Quota 是 95 计费值达到承诺速率 80% 的实例，按占比从高到低排序
+	Quota []BriefingQuota
+
+	ShowAnomalies bool
+	// Anomalies 是昨天发生的异常事件，AnomalyCount 是总数，列表最多显示前几条
+	Anomalies    []AlertData
+	AnomalyCount int
+
+	ShowAlerts bool
+	// Alerts 是尚未恢复的告警，AlertCount 是总数，列表最多显示前几条
+	Alerts     []AlertData
+	AlertCount int
+
+	// Errors 是查询失败的部分及错误编号
+	Errors []string
+}
+
+// BriefingExpiry 是早报中即将到期的实例
+type BriefingExpiry struct {
+	Instance string
+	Expiry   time.Time
+	DaysLeft int
+	Price    string
+}
+
+// BriefingQuota 是早报中接近承诺速率的实例，速率为每秒字节数
+type BriefingQuota struct {
+	Instance string
+	Billed   float64
+	Commit   float64
+	Percent  float64
+}
+
+// DigestData 是告警汇总模板的数据，Expanded 为 true 时列出每个事件的详情
+type DigestData struct {
+	Count    int
+	Time     time.Time
+	Groups   []DigestGroup
+	Alerts   []AlertData
+	Expanded bool
+}
+
+// DigestGroup 是汇总中同一类型的事件
+type DigestGroup struct {
+	Icon      string
+	Label     string
+	Count     int
+	Instances []string
+}
+
+// GroupData 是分组汇总模板的数据
+type GroupData struct {
+	Label       string
+	GeneratedAt time.Time
+	Groups      []prometheus.GroupSummary
+}
+
+// UsageData 是使用统计模板的数据
+type UsageData struct {
+	Days        int
+	GeneratedAt time.Time
+	// Chats 是统计期内使用过机器人的聊天数量
+	Chats     int
+	TopChats  []UsageItem
+	Commands  []UsageItem
+	Menus     []UsageItem
+	Instances []UsageItem
+	// TelemetryEnabled 表示是否开启了本地匿名功能计数，Features 是计数最多的功能
+	TelemetryEnabled bool
+	Features         []UsageItem
+}
+
+// UsageItem 是使用统计中的一项及其次数
+type UsageItem struct {
+	Name  string
+	Count int
+}
+
+// DirectoryData 是目录占用报告模板的数据
+type DirectoryData struct {
+	Instance    string
+	GeneratedAt time.Time
+	// Total 是列出的所有目录大小之和，Largest 是最大的目录大小，用于计算条形图长度
+	Total       float64
+	Largest     float64
+	Directories []prometheus.DirectorySize
+}
+
+// Share 返回目录大小占列出目录总和的比例
+func (d DirectoryData) Share(bytes float64) float64 {
+	if d.Total == 0 {
+		return 0
+	}
+	return bytes / d.Total * 100
+}
+
+// Fraction 返回目录大小相对最大目录的比例，用于条形图
+func (d DirectoryData) Fraction(bytes float64) float64 {
+	if d.Largest == 0 {
+		return 0
+	}
+	return bytes / d.Largest
+}
+
+// SystemdData 是服务状态模板的数据，Status 为 nil 表示实例没有 systemd 指标
+type SystemdData struct {
+	Instance    string
+	GeneratedAt time.Time
+	Status      *prometheus.SystemdStatus
+}
+
+// FleetSystemData 是系统更新汇总模板的数据
+type FleetSystemData struct {
+	GeneratedAt time.Time
+	Total       int
+	// Reboot 是需要重启的主机，Outdated 是内核版本落后于同一系统其他主机的主机
+	Reboot   []prometheus.SystemInfo
+	Outdated []prometheus.SystemInfo
+	// Updates 是有待更新软件包的主机，按待更新数量从多到少排序
+	Updates []prometheus.SystemInfo
+}
+
+// UPSData 是 UPS 状态模板的数据
+type UPSData struct {
+	GeneratedAt time.Time
+	UPS         []prometheus.UPS
+}
+
+// SlowQueryData 是慢查询页面模板的数据，Queries 按时间从新到旧排列
+type SlowQueryData struct {
+	Threshold   time.Duration
+	GeneratedAt time.Time
+	Queries     []prometheus.SlowQuery
+}
+
+// InstanceDetailData 是实例详情模板的数据，Delta 为与上一次查看时相比的变化，首次查看时为 nil
+type InstanceDetailData struct {
+	*prometheus.InstanceDetail
+	Delta *DetailDelta
+	// Labels 是按配置顺序显示的自定义标签，未配置 DISPLAY_LABELS 时为空，只显示 info 标签
+	Labels []LabelValue
+	// Geo 是实例 IP 所在的国家和运营商，未配置 GeoIP 时为 nil
+	Geo *geo.Info
+}
+
+// RateWindows 返回同时显示的网络速率窗口，只有一个窗口时为空
+func (d InstanceDetailData) RateWindows() []string {
+	var windows []string
+	for _, r := range d.Rates {
+		windows = append(windows, r.Window)
+	}
+	return windows
+}
+
+// LongerRates 返回第一个窗口之后各窗口的发送（out 为 true）或接收速率
+func (d InstanceDetailData) LongerRates(out bool) []float64 {
+	var rates []float64
+	for i, r := range d.Rates {
+		if i == 0 {
+			continue
+		}
+		if out {
+			rates = append(rates, r.Upload)
+		} else {
+			rates = append(rates, r.Download)
+		}
+	}
+	return rates
+}
+
+// LabelValue 是一个实例标签的显示名称和值
+type LabelValue struct {
+	Title string
+	Value string
+}
+
+// DetailDelta 是两次查看实例详情之间的变化，流量为字节数，使用率为百分点
+type DetailDelta struct {
+	Since          time.Time
+	DailyTraffic   float64
+	MonthlyTraffic float64
+	CPU            float64
+	Memory         float64
+	Disk           float64
+}
+
+// ProbeData 是连通性测试模板的数据
+type ProbeData struct {
+	Instance string
+	Host     string
+	// Online 是 Prometheus 中实例的 up 状态
+	Online      bool
+	GeneratedAt time.Time
+	Results     []probe.Result
+}
+
+// Reachable 判断是否有任何端口表明主机可达
+func (d ProbeData) Reachable() bool {
+	for _, r := range d.Results {
+		if r.Reachable() {
+			return true
+		}
+	}
+	return false
+}
+
+// Latency 将连接耗时格式化为保留 10µs 精度的文字
+func (d ProbeData) Latency(v time.Duration) string {
+	return v.Round(10 * time.Microsecond).String()
+}
+
+// UptimeData 是在线时间线模板的数据，每天显示为 24 格的条形图
+type UptimeData struct {
+	Instance    string
+	GeneratedAt time.Time
+	// Range 是时间范围选择器选中的范围名称，为空时表示最近几天
+	Range string
+	Days  []prometheus.UptimeDay
+}
+
+// 在线时间线中每小时的字符：全部在线、部分时间离线、全部离线、没有数据
+const (
+	uptimeUp      = "▇"
+	uptimePartial = "▄"
+	uptimeDown    = "░"
+	uptimeNoData  = " "
+)
+
+// Bar 返回一天 24 小时的在线状态条
+func (d UptimeData) Bar(day prometheus.UptimeDay) string {
+	var b strings.Builder
+	for _, v := range day.Slots {
+		switch {
+		case math.IsNaN(v):
+			b.WriteString(uptimeNoData)
+		case v >= 0.999:
+			b.WriteString(uptimeUp)
+		case v > 0:
+			b.WriteString(uptimePartial)
+		default:
+			b.WriteString(uptimeDown)
+		}
+	}
+	return b.String()
+}
+
+// HasData 判断一天内是否有任何数据
+func (d UptimeData) HasData(day prometheus.UptimeDay) bool {
+	_, ok := day.Availability()
+	return ok
+}
+
+// Availability 返回一天的在线率百分比
+func (d UptimeData) Availability(day prometheus.UptimeDay) float64 {
+	a, _ := day.Availability()
+	return a * 100
+}
+
+// Legend 返回时间线的图例
+func (d UptimeData) Legend() string {
+	return uptimeUp + " 在线 " + uptimePartial + " 部分离线 " + uptimeDown + " 离线"
+}
+
+// HeatmapData 是流量热力图模板的数据，每天一行，每小时一格，颜色表示相对于最大值的流量
+type HeatmapData struct {
+	// Instance 为空时表示所有实例
+	Instance    string
+	GeneratedAt time.Time
+	Days        []prometheus.TrafficDay
+}
+
+// 热力图中每小时方块的图标名称，按流量从低到高排列，实际显示的字符由主题决定
+var heatmapLevels = []string{GlyphHeat0, GlyphHeat1, GlyphHeat2, GlyphHeat3, GlyphHeat4}
+
+// Max 返回单个小时的最大流量
+func (d HeatmapData) Max() float64 {
+	var peak float64
+	for _, day := range d.Days {
+		for _, v := range day.Slots {
+			if !math.IsNaN(v) && v > peak {
+				peak = v
+			}
+		}
+	}
+	return peak
+}
+
+// Row 返回一天 24 小时的热力方块的图标名称，模板中通过 glyph 按主题显示
+func (d HeatmapData) Row(day prometheus.TrafficDay) []string {
+	peak := d.Max()
+	glyphs := make([]string, 0, len(day.Slots))
+	for _, v := range day.Slots {
+		switch {
+		case math.IsNaN(v):
+			glyphs = append(glyphs, GlyphHeatNoData)
+		case v <= 0 || peak <= 0:
+			glyphs = append(glyphs, heatmapLevels[0])
+		default:
+			// 流量大于零时至少显示为最低一级颜色，其余按四等分划分
+			level := int(math.Ceil(v / peak * float64(len(heatmapLevels)-1)))
+			glyphs = append(glyphs, heatmapLevels[min(max(level, 1), len(heatmapLevels)-1)])
+		}
+	}
+	return glyphs
+}
+
+// PeakHourText 返回平均流量最大的小时，例如 "20:00-21:00"，没有任何数据时返回空字符串
+func (d HeatmapData) PeakHourText() string {
+	peakHour, peak, found := 0, 0.0, false
+	for h := range 24 {
+		var sum float64
+		n := 0
+		for _, day := range d.Days {
+			if v := day.Slots[h]; !math.IsNaN(v) {
+				sum += v
+				n++
+			}
+		}
+		if n > 0 && (!found || sum/float64(n) > peak) {
+			peakHour, peak, found = h, sum/float64(n), true
+		}
+	}
+	if !found {
+		return ""
+	}
+	return fmt.Sprintf("%02d:00-%02d:00", peakHour, peakHour+1)
+}
+
+// Levels 返回图例中从低到高的各级图标名称
+func (d HeatmapData) Levels() []string {
+	return heatmapLevels
+}
+
+// ScrapeData 是直接抓取状态模板的数据
+type ScrapeData struct {
+	GeneratedAt time.Time
+	Statuses    []scrape.Status
+}
+
+// LabelLintData 是标签检查模板的数据，Total 是检查的实例数，Instances 只包含有问题的实例
+type LabelLintData struct {
+	GeneratedAt time.Time
+	Total       int
+	Instances   []prometheus.InstanceLabelProblems
+}
+
+// ReportData 是文字报告模板的数据
+type ReportData struct {
+	GeneratedAt time.Time
+	Items       interface{}
+}
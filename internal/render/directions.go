@@ -0,0 +1,88 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/prometheus"
+)
+
+// Directions 是流量方向的显示名称和顺序。服务器通常按出站流量计费，
+// 可以用 TRAFFIC_DIRECTIONS 改为 "出站/入站" 等更明确的名称
+type Directions struct {
+	// Out 是发送（transmit）方向的名称，In 是接收（receive）方向的名称
+	Out string
+	In  string
+	// InFirst 为 true 时先显示接收方向
+	InFirst bool
+}
+
+// DefaultDirections 是未配置时使用的上传/下载
+var DefaultDirections = Directions{Out: "上传", In: "下载"}
+
+// ParseDirections 解析 "out=出站,in=入站" 形式的配置，按书写顺序显示，只写一个方向时另一个使用默认名称
+func ParseDirections(s string) (Directions, error) {
+	d := DefaultDirections
+	if strings.TrimSpace(s) == "" {
+		return d, nil
+	}
+	var order []string
+	for _, item := range strings.Split(s, ",") {
+		key, value, found := strings.Cut(item, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		// 名称直接插入 HTML 消息，不允许包含 HTML 特殊字符
+		if !found || value == "" || strings.ContainsAny(value, "<>&") {
+			return d, fmt.Errorf("invalid traffic direction %q", item)
+		}
+		switch key {
+		case "out":
+			d.Out = value
+		case "in":
+			d.In = value
+		default:
+			return d, fmt.Errorf("unknown traffic direction %q, expected out or in", key)
+		}
+		order = append(order, key)
+	}
+	d.InFirst = order[0] == "in"
+	return d, nil
+}
+
+// DirectionValue 是一个方向的名称和数值，Out 表示发送方向
+type DirectionValue struct {
+	Label string
+	Value float64
+	Out   bool
+}
+
+// Pair 按配置的顺序返回发送和接收两个方向的数值
+func (d Directions) Pair(out, in float64) []DirectionValue {
+	values := []DirectionValue{{Label: d.Out, Value: out, Out: true}, {Label: d.In, Value: in}}
+	if d.InFirst {
+		values[0], values[1] = values[1], values[0]
+	}
+	return values
+}
+
+// Join 按配置的顺序格式化两个方向的数值，例如 "上传 1.00 GiB / 下载 2.00 GiB"
+func (d Directions) Join(out, in float64, format func(float64) string) string {
+	var parts []string
+	for _, v := range d.Pair(out, in) {
+		parts = append(parts, v.Label+" "+format(v.Value))
+	}
+	return strings.Join(parts, " / ")
+}
+
+// BillingLabel 返回使用方向名称的计费方式说明
+func (d Directions) BillingLabel(m prometheus.TrafficBilling) string {
+	switch m {
+	case prometheus.BillingMax:
+		return d.Out + "/" + d.In + "取大"
+	case prometheus.BillingOut:
+		return "仅" + d.Out
+	case prometheus.BillingIn:
+		return "仅" + d.In
+	default:
+		return d.Out + "+" + d.In
+	}
+}
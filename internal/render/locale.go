@@ -0,0 +1,220 @@
+package render
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+)
+
+// localeFormats 是某种语言下日期和相对时间的格式
+type localeFormats struct {
+	date     string
+	dateTime string
+	short    string
+	justNow  string
+	ago      func(n int, unit string) string
+	later    func(n int, unit string) string
+	units    map[string]string
+}
+
+var zhFormats = localeFormats{
+	date:     "2006-01-02",
+	dateTime: "2006-01-02 15:04",
+	short:    "01-02 15:04",
+	justNow:  "刚刚",
+	ago:      func(n int, unit string) string { return fmt.Sprintf("%d %s前", n, unit) },
+	later:    func(n int, unit string) string { return fmt.Sprintf("%d %s后", n, unit) },
+	units:    map[string]string{"minute": "分钟", "hour": "小时", "day": "天", "month": "个月", "year": "年"},
+}
+
+var enFormats = localeFormats{
+	date:     "Jan 2, 2006",
+	dateTime: "Jan 2, 2006 15:04",
+	short:    "Jan 2 15:04",
+	justNow:  "just now",
+	ago:      func(n int, unit string) string { return fmt.Sprintf("%d %s ago", n, pluralize(n, unit)) },
+	later:    func(n int, unit string) string { return fmt.Sprintf("in %d %s", n, pluralize(n, unit)) },
+	units:    map[string]string{"minute": "minute", "hour": "hour", "day": "day", "month": "month", "year": "year"},
+}
+
+// supportedLocales 的第一个元素为无法匹配时使用的默认语言
+var supportedLocales = []language.Tag{language.SimplifiedChinese, language.English}
+
+var localeMatcher = language.NewMatcher(supportedLocales)
+
+// Locale 根据语言格式化数字、日期和相对时间
+type Locale struct {
+	tag     language.Tag
+	printer *message.Printer
+	formats localeFormats
+	// bitRates 为 true 时网络速率以 bit/s 显示
+	bitRates bool
+	// directions 是流量方向的名称和顺序
+	directions Directions
+}
+
+// NewLocale 根据 Telegram 的 language_code（如 "zh-hans"、"en"）选择最接近的受支持语言
+func NewLocale(code string) Locale {
+	tag, _ := language.MatchStrings(localeMatcher, code)
+	base, _ := tag.Base()
+	formats := zhFormats
+	if base.String() == "en" {
+		formats = enFormats
+	}
+	return Locale{tag: tag, printer: message.NewPrinter(tag), formats: formats, directions: DefaultDirections}
+}
+
+// Tag 返回语言标签
+func (l Locale) Tag() language.Tag {
+	return l.tag
+}
+
+// Number 按语言的千位分隔符和小数点格式化数字，保留 decimals 位小数
+func (l Locale) Number(v float64, decimals int) string {
+	return l.printer.Sprint(number.Decimal(v, number.MinFractionDigits(decimals), number.MaxFractionDigits(decimals)))
+}
+
+// Percent 格式化百分比数值，v 为 0-100
+func (l Locale) Percent(v float64) string {
+	return l.Number(v, 2) + "%"
+}
+
+// Bytes 以二进制单位格式化字节数
+func (l Locale) Bytes(v float64) string {
+	value, unit := scaleBytes(v)
+	return l.Number(value, 2) + " " + unit
+}
+
+// Rate 以二进制单位格式化每秒字节数
+func (l Locale) Rate(v float64) string {
+	return l.Bytes(v) + "/s"
+}
+
+// WithBitRates 返回网络速率以 bit/s（Kbps、Mbps、Gbps）显示的副本
+func (l Locale) WithBitRates(on bool) Locale {
+	l.bitRates = on
+	return l
+}
+
+// BitRates 判断网络速率是否以 bit/s 显示
+func (l Locale) BitRates() bool {
+	return l.bitRates
+}
+
+// WithDirections 返回使用指定流量方向名称和顺序的副本
+func (l Locale) WithDirections(d Directions) Locale {
+	l.directions = d
+	return l
+}
+
+// Directions 返回流量方向的名称和顺序
+func (l Locale) Directions() Directions {
+	return l.directions
+}
+
+// sameFormat 判断两个 Locale 的格式化结果是否完全相同
+func (l Locale) sameFormat(o Locale) bool {
+	return l.tag == o.tag && l.bitRates == o.bitRates && l.directions == o.directions
+}
+
+// NetworkRate 格式化网络速率 v（每秒字节数），开启 bit 单位时以十进制的 Kbps、Mbps、Gbps 显示，否则与 Rate 相同
+func (l Locale) NetworkRate(v float64) string {
+	if !l.bitRates {
+		return l.Rate(v)
+	}
+	value, unit := scaleBits(v * 8)
+	return l.Number(value, 2) + " " + unit
+}
+
+// Date 格式化日期
+func (l Locale) Date(t time.Time) string {
+	return t.Local().Format(l.formats.date)
+}
+
+// DateTime 格式化日期和时间
+func (l Locale) DateTime(t time.Time) string {
+	return t.Local().Format(l.formats.dateTime)
+}
+
+// ShortDateTime 格式化不含年份的日期和时间，用于列表
+func (l Locale) ShortDateTime(t time.Time) string {
+	return t.Local().Format(l.formats.short)
+}
+
+// Relative 返回 t 相对于 now 的描述，例如 "3 天前"、"2 小时后"
+func (l Locale) Relative(t, now time.Time) string {
+	d := now.Sub(t)
+	past := d >= 0
+	if !past {
+		d = -d
+	}
+
+	var n int
+	var unit string
+	switch {
+	case d < time.Minute:
+		return l.formats.justNow
+	case d < time.Hour:
+		n, unit = int(d.Minutes()), "minute"
+	case d < 24*time.Hour:
+		n, unit = int(d.Hours()), "hour"
+	case d < 30*24*time.Hour:
+		n, unit = int(d.Hours()/24), "day"
+	case d < 365*24*time.Hour:
+		n, unit = int(d.Hours()/(24*30)), "month"
+	default:
+		n, unit = int(d.Hours()/(24*365)), "year"
+	}
+	if past {
+		return l.formats.ago(n, l.formats.units[unit])
+	}
+	return l.formats.later(n, l.formats.units[unit])
+}
+
+func scaleBytes(v float64) (float64, string) {
+	const (
+		KB float64 = 1024
+		MB float64 = KB * 1024
+		GB float64 = MB * 1024
+		TB float64 = GB * 1024
+	)
+
+	switch {
+	case v >= TB:
+		return v / TB, "TiB"
+	case v >= GB:
+		return v / GB, "GiB"
+	case v >= MB:
+		return v / MB, "MiB"
+	case v >= KB:
+		return v / KB, "KiB"
+	default:
+		return v, "B"
+	}
+}
+
+// scaleBits 按十进制单位缩放每秒比特数，与运营商和网卡标称速率的习惯一致
+func scaleBits(v float64) (float64, string) {
+	switch {
+	case v >= 1e12:
+		return v / 1e12, "Tbps"
+	case v >= 1e9:
+		return v / 1e9, "Gbps"
+	case v >= 1e6:
+		return v / 1e6, "Mbps"
+	case v >= 1e3:
+		return v / 1e3, "Kbps"
+	default:
+		return v, "bps"
+	}
+}
+
+func pluralize(n int, unit string) string {
+	if n == 1 {
+		return unit
+	}
+	return unit + "s"
+}
@@ -0,0 +1,162 @@
+package render
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/utils"
+)
+
+//go:embed templates/*.tmpl
+var defaultTemplates embed.FS
+
+// 可由模板目录覆盖的消息模板名称，文件名为 <名称>.tmpl
+const (
+	InstanceDetail = "instance_detail"
+	InstanceTable  = "instance_table"
+	Overview       = "overview"
+	Alert          = "alert"
+	Report         = "report"
+	Digest         = "digest"
+	Group          = "group"
+	Usage          = "usage"
+	Directories    = "directories"
+	Systemd        = "systemd"
+	FleetSystem    = "fleet_system"
+	UPS            = "ups"
+	SlowQueries    = "slow_queries"
+	Uptime         = "uptime"
+	NewInstance    = "new_instance"
+	Probe          = "probe"
+	Heatmap        = "heatmap"
+	Briefing       = "briefing"
+	Scrape         = "scrape"
+	LabelLint      = "label_lint"
+)
+
+// common 中定义各模板共用的子模板
+const common = "common"
+
+var templateNames = []string{common, InstanceDetail, InstanceTable, Overview, Alert, Report, Digest, Group, Usage, Directories, Systemd, FleetSystem, UPS, SlowQueries, Uptime, NewInstance, Probe, Heatmap, Briefing, Scrape, LabelLint}
+
+// Renderer 负责将数据渲染为 Telegram HTML 消息
+type Renderer struct {
+	templates *template.Template
+	theme     Theme
+	// locale 是未指定语言时使用的默认语言
+	locale Locale
+}
+
+// New 加载内置的默认模板，如果 dir 中存在同名的 .tmpl 文件则使用其覆盖默认模板
+func New(dir string, theme Theme, locale Locale) (*Renderer, error) {
+	root := template.New("").Funcs(funcMap(theme, locale))
+	for _, name := range templateNames {
+		content, err := defaultTemplates.ReadFile("templates/" + name + ".tmpl")
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read default template %s: %v", name, err)
+		}
+		if _, err := root.New(name).Parse(string(content)); err != nil {
+			return nil, fmt.Errorf("Failed to parse default template %s: %v", name, err)
+		}
+	}
+
+	if dir != "" {
+		for _, name := range templateNames {
+			path := filepath.Join(dir, name+".tmpl")
+			content, err := os.ReadFile(path)
+			if err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+				return nil, fmt.Errorf("Failed to read template %s: %v", path, err)
+			}
+			if _, err := root.New(name).Parse(string(content)); err != nil {
+				return nil, fmt.Errorf("Failed to parse template %s: %v", path, err)
+			}
+		}
+	}
+
+	return &Renderer{templates: root, theme: theme, locale: locale}, nil
+}
+
+// Render 使用默认语言渲染模板
+func (r *Renderer) Render(name string, data interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := r.templates.ExecuteTemplate(&buf, name, data); err != nil {
+		return "", fmt.Errorf("Failed to render template %s: %v", name, err)
+	}
+	return buf.String(), nil
+}
+
+// RenderLocale 使用指定语言格式化数字和日期并渲染模板
+func (r *Renderer) RenderLocale(locale Locale, name string, data interface{}) (string, error) {
+	if locale.sameFormat(r.locale) {
+		return r.Render(name, data)
+	}
+	templates, err := r.templates.Clone()
+	if err != nil {
+		return "", fmt.Errorf("Failed to clone templates: %v", err)
+	}
+	templates.Funcs(funcMap(r.theme, locale))
+
+	var buf bytes.Buffer
+	if err := templates.ExecuteTemplate(&buf, name, data); err != nil {
+		return "", fmt.Errorf("Failed to render template %s: %v", name, err)
+	}
+	return buf.String(), nil
+}
+
+// Locale 返回默认语言
+func (r *Renderer) Locale() Locale {
+	return r.locale
+}
+
+// Glyph 返回当前主题下的状态图标
+func (r *Renderer) Glyph(name string) string {
+	return r.theme.Glyph(name)
+}
+
+func funcMap(theme Theme, locale Locale) template.FuncMap {
+	return template.FuncMap{
+		"glyph":    theme.Glyph,
+		"bytes":    locale.Bytes,
+		"rate":     locale.Rate,
+		"netrate":  locale.NetworkRate,
+		"bitrate":  locale.WithBitRates(true).NetworkRate,
+		"dirs":     locale.Directions().Pair,
+		"billing":  locale.Directions().BillingLabel,
+		"pct":      locale.Percent,
+		"num":      locale.Number,
+		"date":     locale.Date,
+		"datetime": locale.DateTime,
+		"ago":      func(t time.Time) string { return locale.Relative(t, time.Now()) },
+		"escape":   html.EscapeString,
+		"truncate": truncate,
+		"join":     strings.Join,
+		"bar":      utils.ProgressBar,
+		"duration": utils.ShortDuration,
+		"sign":     sign,
+		"abs":      math.Abs,
+	}
+}
+
+// sign 返回数值的正负号，用于显示变化量，例如 {{sign .CPU}}{{num (abs .CPU) 1}}
+func sign(v float64) string {
+	if v < 0 {
+		return "-"
+	}
+	return "+"
+}
+
+// truncate 的参数顺序便于在模板管道中使用，例如 {{.Name | truncate 30}}
+func truncate(maxLength int, s string) string {
+	return utils.TruncateString(s, maxLength)
+}
@@ -0,0 +1,106 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 消息中使用的状态图标名称
+const (
+	GlyphDown     = "down"
+	GlyphUp       = "up"
+	GlyphInfo     = "info"
+	GlyphWarning  = "warning"
+	GlyphCritical = "critical"
+	GlyphQuota    = "quota"
+	GlyphBullet   = "bullet"
+	// GlyphSelected 和 GlyphUnselected 标记设置页面中按钮的选中状态
+	GlyphSelected   = "selected"
+	GlyphUnselected = "unselected"
+	// 流量热力图中从低到高的各级流量，GlyphHeatNoData 表示没有数据
+	GlyphHeat0      = "heat0"
+	GlyphHeat1      = "heat1"
+	GlyphHeat2      = "heat2"
+	GlyphHeat3      = "heat3"
+	GlyphHeat4      = "heat4"
+	GlyphHeatNoData = "heat_nodata"
+)
+
+// Theme 保存各状态图标对应的文本
+type Theme map[string]string
+
+var themes = map[string]Theme{
+	"default": {
+		GlyphDown:     "🔴",
+		GlyphUp:       "🟢",
+		GlyphInfo:     "ℹ️",
+		GlyphWarning:  "⚠️",
+		GlyphCritical: "🚨",
+		GlyphQuota:    "📶",
+		GlyphBullet:   "•",
+
+		GlyphSelected:   "✅",
+		GlyphUnselected: "⬜",
+		GlyphHeat0:      "⬜",
+		GlyphHeat1:      "🟩",
+		GlyphHeat2:      "🟨",
+		GlyphHeat3:      "🟧",
+		GlyphHeat4:      "🟥",
+		GlyphHeatNoData: "⬛",
+	},
+	// plain 模式不使用任何 emoji，适合不希望出现表情符号的聊天
+	"plain": {
+		GlyphDown:     "[DOWN]",
+		GlyphUp:       "[UP]",
+		GlyphInfo:     "[INFO]",
+		GlyphWarning:  "[WARN]",
+		GlyphCritical: "[CRIT]",
+		GlyphQuota:    "[QUOTA]",
+		GlyphBullet:   "-",
+
+		GlyphSelected:   "[x]",
+		GlyphUnselected: "[ ]",
+		GlyphHeat0:      ".",
+		GlyphHeat1:      "░",
+		GlyphHeat2:      "▒",
+		GlyphHeat3:      "▓",
+		GlyphHeat4:      "█",
+		GlyphHeatNoData: "x",
+	},
+}
+
+// NewTheme 返回指定名称的主题，overrides 为 "down=❌,up=✅" 形式的单项覆盖
+func NewTheme(name, overrides string) (Theme, error) {
+	if name == "" {
+		name = "default"
+	}
+	base, ok := themes[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown theme %q", name)
+	}
+
+	theme := make(Theme, len(base))
+	for k, v := range base {
+		theme[k] = v
+	}
+	for _, item := range strings.Split(overrides, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		key, value, found := strings.Cut(item, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid theme override %q", item)
+		}
+		key = strings.TrimSpace(key)
+		if _, ok := base[key]; !ok {
+			return nil, fmt.Errorf("unknown theme glyph %q", key)
+		}
+		theme[key] = strings.TrimSpace(value)
+	}
+	return theme, nil
+}
+
+func (t Theme) Glyph(name string) string {
+	return t[name]
+}
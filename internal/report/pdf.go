@@ -0,0 +1,217 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bestmjj/prometheus-telegram-bot/internal/chart"
+	"github.com/bestmjj/prometheus-telegram-bot/internal/prometheus"
+	"github.com/go-pdf/fpdf"
+)
+
+// Options 控制 PDF 报告的字体和数值格式
+type Options struct {
+	// FontPath 是 UTF-8 TrueType 字体文件路径，用于显示中文实例名等非 ASCII 字符；
+	// 为空时使用内置的 Helvetica，非 ASCII 字符显示为 ?。报告的标题和表头与图表一样只使用英文
+	FontPath string
+	// FormatBytes 格式化流量，为空时以 GiB 显示
+	FormatBytes func(float64) string
+	// GeneratedAt 是报告的生成时间
+	GeneratedAt time.Time
+	// Chart 是图表样式偏好。报告用于打印，只使用其中的图表类型和图例设置，主题和尺寸固定
+	Chart chart.Preferences
+}
+
+const (
+	fontFamily = "report"
+	pageWidth  = 190.0
+)
+
+// tableColumns 是实例表格的列名和宽度（毫米）
+var tableColumns = []struct {
+	name  string
+	width float64
+}{
+	{"Instance", 42}, {"Upload", 25}, {"Download", 25}, {"Billed", 25}, {"Uptime", 20}, {"Cost/mo", 33}, {"Overage", 20},
+}
+
+// MonthlyPDF 将月度汇总渲染为 A4 PDF：概要、每日流量图和实例明细表
+func MonthlyPDF(r *prometheus.MonthlyReport, opts Options) ([]byte, error) {
+	formatBytes := opts.FormatBytes
+	if formatBytes == nil {
+		formatBytes = func(v float64) string { return fmt.Sprintf("%.2f GiB", v/(1<<30)) }
+	}
+
+	pdf := fpdf.New("P", "mm", "A4", "")
+	family, text := "Helvetica", asciiOnly
+	if opts.FontPath != "" {
+		pdf.AddUTF8Font(fontFamily, "", opts.FontPath)
+		pdf.AddUTF8Font(fontFamily, "B", opts.FontPath)
+		family, text = fontFamily, func(s string) string { return s }
+	}
+	if err := pdf.Error(); err != nil {
+		return nil, fmt.Errorf("Failed to load PDF font: %v", err)
+	}
+	pdf.SetTitle("Monthly Report "+r.Start.Format("2006-01"), true)
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-15)
+		pdf.SetFont(family, "", 8)
+		pdf.CellFormat(0, 10, fmt.Sprintf("Page %d", pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+	pdf.AddPage()
+
+	pdf.SetFont(family, "B", 18)
+	pdf.CellFormat(0, 10, "Monthly Report "+r.Start.Format("2006-01"), "", 1, "L", false, 0, "")
+	pdf.SetFont(family, "", 10)
+	pdf.CellFormat(0, 6, fmt.Sprintf("Period: %s - %s    Generated: %s",
+		r.Start.Format("2006-01-02"), r.End.Add(-time.Second).Format("2006-01-02"), opts.GeneratedAt.Format("2006-01-02 15:04")), "", 1, "L", false, 0, "")
+	pdf.Ln(4)
+
+	// 概要
+	pdf.SetFont(family, "B", 12)
+	pdf.CellFormat(0, 8, "Summary", "", 1, "L", false, 0, "")
+	pdf.SetFont(family, "", 10)
+	summary := [][2]string{
+		{"Instances", fmt.Sprintf("%d", len(r.Instances))},
+		{"Total traffic", formatBytes(r.TotalTraffic())},
+		{"Average uptime", averageUptime(r.Instances)},
+		{"Monthly cost", formatCosts(r.Costs)},
+	}
+	if len(r.OverageCosts) > 0 {
+		summary = append(summary, [2]string{"Traffic overage", formatCosts(r.OverageCosts)})
+	}
+	for _, row := range summary {
+		pdf.CellFormat(45, 6, row[0], "", 0, "L", false, 0, "")
+		pdf.CellFormat(0, 6, text(row[1]), "", 1, "L", false, 0, "")
+	}
+	pdf.Ln(4)
+
+	// 每日流量图
+	if len(r.Daily) > 0 {
+		series := chart.Series{Name: "Daily traffic"}
+		for _, d := range r.Daily {
+			series.Points = append(series.Points, chart.Point{Time: d.Day, Value: d.Bytes})
+		}
+		png, err := chart.RenderPNG([]chart.Series{series}, chart.Options{
+			Preferences: chart.Preferences{Bars: opts.Chart.Bars, HideLegend: opts.Chart.HideLegend},
+			Title:       "Daily traffic (all instances)",
+			FormatValue: formatBytes,
+		})
+		if err != nil {
+			return nil, err
+		}
+		pdf.RegisterImageOptionsReader("daily", fpdf.ImageOptions{ImageType: "PNG"}, bytes.NewReader(png))
+		pdf.ImageOptions("daily", pdf.GetX(), pdf.GetY(), pageWidth, 0, true, fpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+		pdf.Ln(4)
+	}
+
+	// 实例明细表，换页时重复表头
+	header := func() {
+		pdf.SetFont(family, "B", 9)
+		pdf.SetFillColor(230, 230, 230)
+		for _, col := range tableColumns {
+			pdf.CellFormat(col.width, 7, col.name, "1", 0, "C", true, 0, "")
+		}
+		pdf.Ln(-1)
+		pdf.SetFont(family, "", 9)
+	}
+	pdf.SetFont(family, "B", 12)
+	pdf.CellFormat(0, 8, "Instances", "", 1, "L", false, 0, "")
+	header()
+	_, pageHeight := pdf.GetPageSize()
+	_, _, _, bottom := pdf.GetMargins()
+	for _, m := range r.Instances {
+		if pdf.GetY()+6 > pageHeight-bottom-15 {
+			pdf.AddPage()
+			header()
+		}
+		uptime := "-"
+		if m.HasUptime {
+			uptime = fmt.Sprintf("%.2f%%", m.Uptime*100)
+		}
+		cost := "-"
+		if m.Priced {
+			cost = strings.TrimSpace(fmt.Sprintf("%.2f %s", m.MonthlyCost, m.Currency))
+		}
+		// 超出配额但没有设置价格时只显示超出的流量
+		overage := "-"
+		if m.OverageCost() > 0 {
+			overage = strings.TrimSpace(fmt.Sprintf("%.2f %s", m.OverageCost(), m.Quota.Currency))
+		} else if m.TrafficOverage() > 0 {
+			overage = "+" + formatBytes(m.TrafficOverage())
+		}
+		cells := []string{text(m.Instance), formatBytes(m.Transmit), formatBytes(m.Receive), formatBytes(m.BilledTraffic()), uptime, text(cost), text(overage)}
+		for i, col := range tableColumns {
+			align := "R"
+			if i == 0 {
+				align = "L"
+			}
+			pdf.CellFormat(col.width, 6, fitWidth(pdf, cells[i], col.width-2), "1", 0, align, false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("Failed to render PDF: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// averageUptime 返回有数据的实例的平均在线率
+func averageUptime(instances []prometheus.MonthlyInstance) string {
+	var sum float64
+	n := 0
+	for _, m := range instances {
+		if m.HasUptime {
+			sum += m.Uptime
+			n++
+		}
+	}
+	if n == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f%%", sum/float64(n)*100)
+}
+
+// formatCosts 按货币名称排序列出费用，例如 "12.00 EUR, 30.00 USD"
+func formatCosts(costs map[string]float64) string {
+	if len(costs) == 0 {
+		return "-"
+	}
+	currencies := make([]string, 0, len(costs))
+	for c := range costs {
+		currencies = append(currencies, c)
+	}
+	sort.Strings(currencies)
+	parts := make([]string, len(currencies))
+	for i, c := range currencies {
+		parts[i] = strings.TrimSpace(fmt.Sprintf("%.2f %s", costs[c], c))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// fitWidth 截断超出单元格宽度的文字
+func fitWidth(pdf *fpdf.Fpdf, s string, width float64) string {
+	if pdf.GetStringWidth(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && pdf.GetStringWidth(string(runes)+"...") > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "..."
+}
+
+// asciiOnly 将非 ASCII 字符替换为 ?，内置字体无法显示这些字符
+func asciiOnly(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r > 126 {
+			return '?'
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,155 @@
+package scrape
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/expfmt"
+)
+
+// maxBodySize 限制读取的 /metrics 响应大小，node_exporter 的输出通常在 1MB 以内
+const maxBodySize = 16 << 20
+
+// Status 是直接从 node_exporter 的 /metrics 读取的基本状态，在 Prometheus 不可用时代替查询结果
+type Status struct {
+	Target string
+	// Up 表示成功抓取并解析了指标，失败时 Err 是原因
+	Up  bool
+	Err string
+	// Duration 是抓取耗时
+	Duration time.Duration
+
+	Load1, Load5, Load15 float64
+	// CPUs 是逻辑 CPU 数，用于判断负载高低
+	CPUs int
+	// MemTotal 和 MemAvailable 以字节为单位
+	MemTotal     float64
+	MemAvailable float64
+	// DiskTotal 和 DiskAvail 是根文件系统的大小和可用空间（字节），没有挂载在 / 的文件系统时为 0
+	DiskTotal float64
+	DiskAvail float64
+	// BootTime 是系统启动时间，未知时为零值
+	BootTime time.Time
+}
+
+// MemUsedPercent 返回内存使用率，总量未知时为 0
+func (s Status) MemUsedPercent() float64 {
+	if s.MemTotal <= 0 {
+		return 0
+	}
+	return (s.MemTotal - s.MemAvailable) / s.MemTotal * 100
+}
+
+// DiskUsedPercent 返回根文件系统的使用率，大小未知时为 0
+func (s Status) DiskUsedPercent() float64 {
+	if s.DiskTotal <= 0 {
+		return 0
+	}
+	return (s.DiskTotal - s.DiskAvail) / s.DiskTotal * 100
+}
+
+// URL 返回抓取目标的地址。目标可以是完整的 URL，也可以是 host:port（补全为 http://host:port/metrics）
+func URL(target string) string {
+	if strings.Contains(target, "://") {
+		return target
+	}
+	return "http://" + target + "/metrics"
+}
+
+// Fetch 抓取 target 的 node_exporter 指标并解析关键的指标，失败时返回 Up 为 false 的 Status
+func Fetch(ctx context.Context, client *http.Client, target string) Status {
+	s := Status{Target: target}
+	started := time.Now()
+	err := s.fetch(ctx, client)
+	s.Duration = time.Since(started)
+	if err != nil {
+		s.Err = err.Error()
+		return s
+	}
+	s.Up = true
+	return s
+}
+
+func (s *Status) fetch(ctx context.Context, client *http.Client) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, URL(s.Target), nil)
+	if err != nil {
+		return fmt.Errorf("Failed to create scrape request: %v", err)
+	}
+	// 只接受文本格式，不需要解析 protobuf 或 OpenMetrics
+	req.Header.Set("Accept", "text/plain;version=0.0.4")
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Failed to scrape %s: %v", s.Target, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("scrape %s returned %s", s.Target, resp.Status)
+	}
+
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(http.MaxBytesReader(nil, resp.Body, maxBodySize))
+	if err != nil {
+		return fmt.Errorf("Failed to parse metrics from %s: %v", s.Target, err)
+	}
+	gauge := func(name string, labels map[string]string) (float64, bool) {
+		family, ok := families[name]
+		if !ok {
+			return 0, false
+		}
+	metrics:
+		for _, m := range family.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if want, ok := labels[l.GetName()]; ok && want != l.GetValue() {
+					continue metrics
+				}
+			}
+			if m.GetGauge() != nil {
+				return m.GetGauge().GetValue(), true
+			}
+			return m.GetUntyped().GetValue(), true
+		}
+		return 0, false
+	}
+
+	s.Load1, _ = gauge("node_load1", nil)
+	s.Load5, _ = gauge("node_load5", nil)
+	s.Load15, _ = gauge("node_load15", nil)
+	s.MemTotal, _ = gauge("node_memory_MemTotal_bytes", nil)
+	s.MemAvailable, _ = gauge("node_memory_MemAvailable_bytes", nil)
+	root := map[string]string{"mountpoint": "/"}
+	s.DiskTotal, _ = gauge("node_filesystem_size_bytes", root)
+	s.DiskAvail, _ = gauge("node_filesystem_avail_bytes", root)
+	if boot, ok := gauge("node_boot_time_seconds", nil); ok && boot > 0 {
+		s.BootTime = time.Unix(int64(boot), 0)
+	}
+	// 每个 CPU 的 idle 计数器各有一条序列
+	if family, ok := families["node_cpu_seconds_total"]; ok {
+		for _, m := range family.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "mode" && l.GetValue() == "idle" {
+					s.CPUs++
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// FetchAll 并发抓取多个目标，结果与 targets 的顺序一致
+func FetchAll(ctx context.Context, client *http.Client, targets []string) []Status {
+	statuses := make([]Status, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func(i int, target string) {
+			defer wg.Done()
+			statuses[i] = Fetch(ctx, client, target)
+		}(i, target)
+	}
+	wg.Wait()
+	return statuses
+}
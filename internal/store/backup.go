@@ -0,0 +1,72 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// BackupSummary 概括备份中的内容，恢复前展示给管理员确认
+type BackupSummary struct {
+	Events             int
+	ScheduledQueries   int
+	RuleSubscriptions  int
+	ThresholdOverrides int
+	Chats              int
+	Grants             int
+}
+
+// Backup 返回全部存储数据的 JSON，用于加密后发送备份
+func (s *Store) Backup() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	content, err := json.Marshal(&s.data)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to encode store: %v", err)
+	}
+	return content, nil
+}
+
+// ParseBackup 检查备份内容是否有效，并返回其中的内容概要
+func ParseBackup(content []byte) (BackupSummary, error) {
+	var data storeData
+	if err := json.Unmarshal(content, &data); err != nil {
+		return BackupSummary{}, fmt.Errorf("Failed to parse backup: %v", err)
+	}
+	return BackupSummary{
+		Events:             len(data.Events),
+		ScheduledQueries:   len(data.ScheduledQueries),
+		RuleSubscriptions:  len(data.RuleSubscriptions),
+		ThresholdOverrides: len(data.ThresholdOverrides),
+		Chats:              len(data.Chats),
+		Grants:             len(data.Grants),
+	}, nil
+}
+
+// Restore 用备份内容替换全部存储数据。自动备份的时间保持不变，避免恢复后立即重新发送备份
+func (s *Store) Restore(content []byte) error {
+	var data storeData
+	if err := json.Unmarshal(content, &data); err != nil {
+		return fmt.Errorf("Failed to parse backup: %v", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data.LastBackupAt = s.data.LastBackupAt
+	s.data = data
+	return s.save()
+}
+
+// LastBackup 返回最近一次自动发送备份的时间，从未发送过时为零值
+func (s *Store) LastBackup() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.data.LastBackupAt
+}
+
+// MarkBackup 记录自动备份的发送时间
+func (s *Store) MarkBackup(at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.LastBackupAt = at
+	return s.save()
+}
@@ -0,0 +1,49 @@
+package store
+
+// Budget 是每月预算，只统计与预算货币相同的支出
+type Budget struct {
+	Amount   float64 `json:"amount"`
+	Currency string  `json:"currency,omitempty"`
+}
+
+// Budget 返回每月预算，未设置时返回 false
+func (s *Store) Budget() (Budget, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data.Budget == nil {
+		return Budget{}, false
+	}
+	return *s.data.Budget, true
+}
+
+// SetBudget 设置每月预算，并清除本月的提醒记录，以便按新预算重新检查
+func (s *Store) SetBudget(b Budget) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Budget = &b
+	s.data.BudgetWarned = ""
+	return s.save()
+}
+
+// ClearBudget 删除每月预算
+func (s *Store) ClearBudget() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Budget = nil
+	return s.save()
+}
+
+// BudgetWarned 判断 month（格式 2006-01）是否已经发送过超预算提醒
+func (s *Store) BudgetWarned(month string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.data.BudgetWarned >= month
+}
+
+// MarkBudgetWarned 记录 month 已发送超预算提醒，每月只提醒一次
+func (s *Store) MarkBudgetWarned(month string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.BudgetWarned = month
+	return s.save()
+}
@@ -0,0 +1,38 @@
+package store
+
+// RememberChat 记录与机器人交互过的聊天，用于广播。已记录的聊天不会重复写入
+func (s *Store) RememberChat(chatID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range s.data.Chats {
+		if id == chatID {
+			return nil
+		}
+	}
+	s.data.Chats = append(s.data.Chats, chatID)
+	return s.save()
+}
+
+// Chats 返回所有记录过的聊天ID
+func (s *Store) Chats() []int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]int64(nil), s.data.Chats...)
+}
+
+// ForgetChat 删除聊天记录，例如机器人被用户屏蔽或移出群组后
+func (s *Store) ForgetChat(chatID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	chats := s.data.Chats[:0]
+	for _, id := range s.data.Chats {
+		if id != chatID {
+			chats = append(chats, id)
+		}
+	}
+	s.data.Chats = chats
+	return s.save()
+}
@@ -0,0 +1,252 @@
+package store
+
+import (
+	"time"
+)
+
+type EventKind string
+
+const (
+	EventInstanceDown    EventKind = "instance_down"
+	EventInstanceUp      EventKind = "instance_up"
+	EventThresholdBreach EventKind = "threshold_breach"
+	EventQuotaCrossing   EventKind = "quota_crossing"
+	// EventStaleMetrics 表示实例在线但指标数据过期（抓取失败、时钟偏差等）
+	EventStaleMetrics EventKind = "stale_metrics"
+	// EventUPSOnBattery 和 EventUPSLowRuntime 表示 UPS 切换到电池供电或电池剩余时间低于阈值
+	EventUPSOnBattery  EventKind = "ups_on_battery"
+	EventUPSLowRuntime EventKind = "ups_low_runtime"
+	// EventNewInstance 表示 Prometheus 中出现了之前从未见过的实例
+	EventNewInstance EventKind = "new_instance"
+	// EventPrometheusAlert 表示订阅的 Prometheus 告警规则正在触发，Metric 为规则名称
+	EventPrometheusAlert EventKind = "prometheus_alert"
+	// EventOOMKill 表示内核因内存不足终止了进程，是一次性事件，记录时即已恢复
+	EventOOMKill EventKind = "oom_kill"
+)
+
+// Label 返回事件类型的中文名称
+func (k EventKind) Label() string {
+	switch k {
+	case EventInstanceDown:
+		return "实例离线"
+	case EventInstanceUp:
+		return "恢复"
+	case EventThresholdBreach:
+		return "超过阈值"
+	case EventQuotaCrossing:
+		return "流量配额"
+	case EventStaleMetrics:
+		return "指标过期"
+	case EventUPSOnBattery:
+		return "UPS 电池供电"
+	case EventUPSLowRuntime:
+		return "UPS 续航不足"
+	case EventNewInstance:
+		return "发现新实例"
+	case EventPrometheusAlert:
+		return "Prometheus 告警"
+	case EventOOMKill:
+		return "OOM Kill"
+	default:
+		return string(k)
+	}
+}
+
+// Severity 是事件的严重程度，用于筛选告警历史
+type Severity string
+
+const (
+	SeverityInfo     Severity = "info"
+	SeverityWarning  Severity = "warning"
+	SeverityCritical Severity = "critical"
+)
+
+// Severities 是所有严重程度，从低到高排列
+var Severities = []Severity{SeverityInfo, SeverityWarning, SeverityCritical}
+
+// Label 返回严重程度的中文名称
+func (s Severity) Label() string {
+	switch s {
+	case SeverityInfo:
+		return "信息"
+	case SeverityWarning:
+		return "警告"
+	case SeverityCritical:
+		return "严重"
+	default:
+		return string(s)
+	}
+}
+
+// Severity 返回该类事件的严重程度：离线和 UPS 事件为严重，恢复和发现新实例为信息，其余为警告
+func (k EventKind) Severity() Severity {
+	switch k {
+	case EventInstanceDown, EventUPSOnBattery, EventUPSLowRuntime:
+		return SeverityCritical
+	case EventInstanceUp, EventNewInstance:
+		return SeverityInfo
+	default:
+		return SeverityWarning
+	}
+}
+
+// Urgent 判断该类事件是否需要立即通知，不参与合并汇总
+func (k EventKind) Urgent() bool {
+	return k == EventUPSOnBattery || k == EventUPSLowRuntime
+}
+
+// maxEvents 限制事件日志的最大条数，超出后丢弃最旧的已恢复事件，未恢复的事件始终保留
+const maxEvents = 1000
+
+type Event struct {
+	ID       int64     `json:"id"`
+	Instance string    `json:"instance"`
+	Kind     EventKind `json:"kind"`
+	Message  string    `json:"message"`
+	// Metric 区分同一实例上的多个阈值事件，例如 "fd"、"inode"
+	Metric string `json:"metric,omitempty"`
+	// Labels 是发现新实例时记录的实例标签
+	Labels     map[string]string `json:"labels,omitempty"`
+	StartedAt  time.Time         `json:"started_at"`
+	ResolvedAt time.Time         `json:"resolved_at,omitempty"`
+	// AckedAt 和 AckedBy 记录事件被确认的时间和确认人
+	AckedAt time.Time `json:"acked_at,omitempty"`
+	AckedBy string    `json:"acked_by,omitempty"`
+	// ResolvesID 是本事件恢复的另一个事件的 ID，例如实例上线事件对应的离线事件，用于取消该事件告警的置顶
+	ResolvesID int64 `json:"resolves_id,omitempty"`
+}
+
+func (e Event) Resolved() bool {
+	return !e.ResolvedAt.IsZero()
+}
+
+// Duration 返回事件的持续时间，未恢复的事件计算到 now 为止
+func (e Event) Duration(now time.Time) time.Duration {
+	if e.Resolved() {
+		return e.ResolvedAt.Sub(e.StartedAt)
+	}
+	return now.Sub(e.StartedAt)
+}
+
+// AddEvent 记录一个新事件并返回分配的事件ID
+func (s *Store) AddEvent(e Event) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.NextEventID++
+	e.ID = s.data.NextEventID
+	s.data.Events = append(s.data.Events, e)
+	s.data.Events = trimEvents(s.data.Events, maxEvents)
+	return e.ID, s.save()
+}
+
+// trimEvents 从最旧的开始丢弃已恢复的事件，直到不超过 limit 条。未恢复的事件不丢弃，
+// 否则长时间的故障在繁忙时被挤出日志后，恢复时找不到对应的事件，也就无法记录持续时间
+func trimEvents(events []Event, limit int) []Event {
+	excess := len(events) - limit
+	if excess <= 0 {
+		return events
+	}
+	kept := events[:0]
+	for _, e := range events {
+		if excess > 0 && e.Resolved() {
+			excess--
+			continue
+		}
+		kept = append(kept, e)
+	}
+	return kept
+}
+
+// ResolveEvent 将指定实例最近一个未恢复的同类事件标记为已恢复，返回被恢复的事件
+func (s *Store) ResolveEvent(instance string, kind EventKind, at time.Time) (Event, bool, error) {
+	return s.resolveEvent(instance, kind, nil, at)
+}
+
+// ResolveMetricEvent 与 ResolveEvent 相同，但只恢复 Metric 匹配的事件
+func (s *Store) ResolveMetricEvent(instance string, kind EventKind, metric string, at time.Time) (Event, bool, error) {
+	return s.resolveEvent(instance, kind, &metric, at)
+}
+
+func (s *Store) resolveEvent(instance string, kind EventKind, metric *string, at time.Time) (Event, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := len(s.data.Events) - 1; i >= 0; i-- {
+		e := &s.data.Events[i]
+		if metric != nil && e.Metric != *metric {
+			continue
+		}
+		if e.Instance == instance && e.Kind == kind && !e.Resolved() {
+			e.ResolvedAt = at
+			return *e, true, s.save()
+		}
+	}
+	return Event{}, false, nil
+}
+
+// RecentEvents 按时间倒序返回最近的事件，instance 为空时返回所有实例的事件
+func (s *Store) RecentEvents(instance string, limit int) []Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var events []Event
+	for i := len(s.data.Events) - 1; i >= 0; i-- {
+		e := s.data.Events[i]
+		if instance != "" && e.Instance != instance {
+			continue
+		}
+		events = append(events, e)
+		if limit > 0 && len(events) >= limit {
+			break
+		}
+	}
+	return events
+}
+
+// OpenEvents 返回所有尚未恢复的指定类型事件
+func (s *Store) OpenEvents(kind EventKind) []Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var events []Event
+	for _, e := range s.data.Events {
+		if e.Kind == kind && !e.Resolved() {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// AcknowledgeEvent 将事件标记为已确认，已确认过的事件保留第一次确认的记录
+func (s *Store) AcknowledgeEvent(id int64, by string, at time.Time) (Event, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.data.Events {
+		e := &s.data.Events[i]
+		if e.ID != id {
+			continue
+		}
+		if !e.AckedAt.IsZero() {
+			return *e, true, nil
+		}
+		e.AckedAt = at
+		e.AckedBy = by
+		return *e, true, s.save()
+	}
+	return Event{}, false, nil
+}
+
+// Event 按ID返回事件，事件已被清理时返回 false
+func (s *Store) Event(id int64) (Event, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, e := range s.data.Events {
+		if e.ID == id {
+			return e, true
+		}
+	}
+	return Event{}, false
+}
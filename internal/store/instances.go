@@ -0,0 +1,33 @@
+package store
+
+import "time"
+
+// HasKnownInstances 判断是否已经记录过实例，首次运行时为 false
+func (s *Store) HasKnownInstances() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.data.KnownInstances) > 0
+}
+
+// AddKnownInstances 记录见到的实例，返回其中之前从未见过的实例
+func (s *Store) AddKnownInstances(instances []string, at time.Time) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.data.KnownInstances == nil {
+		s.data.KnownInstances = make(map[string]time.Time)
+	}
+	var added []string
+	for _, instance := range instances {
+		if _, ok := s.data.KnownInstances[instance]; ok {
+			continue
+		}
+		s.data.KnownInstances[instance] = at
+		added = append(added, instance)
+	}
+	if len(added) == 0 {
+		return nil, nil
+	}
+	return added, s.save()
+}
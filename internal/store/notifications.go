@@ -0,0 +1,84 @@
+package store
+
+import (
+	"time"
+)
+
+// maxNotifications 限制通知记录的最大条数，超出后丢弃最旧的记录
+const maxNotifications = 5000
+
+// Notification 是一条已发送的告警通知，用于事后检索告警历史。
+// 与事件不同，事件恢复时发送的通知会单独记录一条
+type Notification struct {
+	EventID  int64     `json:"event_id,omitempty"`
+	Kind     EventKind `json:"kind"`
+	Severity Severity  `json:"severity"`
+	Instance string    `json:"instance"`
+	Message  string    `json:"message"`
+	SentAt   time.Time `json:"sent_at"`
+	// ChatIDs 是成功发送到的聊天
+	ChatIDs []int64 `json:"chat_ids,omitempty"`
+	// MessageIDs 是各聊天中的消息ID，与 ChatIDs 一一对应，旧记录中没有
+	MessageIDs []int `json:"message_ids,omitempty"`
+	// Digest 表示该通知包含在汇总消息中发送
+	Digest bool `json:"digest,omitempty"`
+}
+
+// NotificationFilter 是告警历史的筛选条件，零值字段表示不筛选
+type NotificationFilter struct {
+	Instance string
+	Severity Severity
+	// Since 和 Until 是发送时间的范围，包含 Since，不包含 Until
+	Since time.Time
+	Until time.Time
+}
+
+func (f NotificationFilter) match(n Notification) bool {
+	return (f.Instance == "" || n.Instance == f.Instance) &&
+		(f.Severity == "" || n.Severity == f.Severity) &&
+		(f.Since.IsZero() || !n.SentAt.Before(f.Since)) &&
+		(f.Until.IsZero() || n.SentAt.Before(f.Until))
+}
+
+// RecordNotification 记录一条已发送的通知
+func (s *Store) RecordNotification(n Notification) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.Notifications = append(s.data.Notifications, n)
+	if len(s.data.Notifications) > maxNotifications {
+		s.data.Notifications = s.data.Notifications[len(s.data.Notifications)-maxNotifications:]
+	}
+	return s.save()
+}
+
+// Notifications 按发送时间倒序返回符合筛选条件的通知
+func (s *Store) Notifications(f NotificationFilter) []Notification {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var notifications []Notification
+	for i := len(s.data.Notifications) - 1; i >= 0; i-- {
+		if n := s.data.Notifications[i]; f.match(n) {
+			notifications = append(notifications, n)
+		}
+	}
+	return notifications
+}
+
+// NotificationsForMessage 返回通过某条消息发送的通知，汇总消息对应多条通知
+func (s *Store) NotificationsForMessage(chatID int64, messageID int) []Notification {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var notifications []Notification
+	for _, n := range s.data.Notifications {
+		for i, id := range n.MessageIDs {
+			if id == messageID && i < len(n.ChatIDs) && n.ChatIDs[i] == chatID {
+				notifications = append(notifications, n)
+				break
+			}
+		}
+	}
+	return notifications
+}
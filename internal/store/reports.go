@@ -0,0 +1,16 @@
+package store
+
+// MonthlyReportSent 判断 month（格式 2006-01）的月度报告是否已经自动发送过
+func (s *Store) MonthlyReportSent(month string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.data.MonthlyReportSent >= month
+}
+
+// MarkMonthlyReportSent 记录 month 的月度报告已发送，重启后不会重复发送
+func (s *Store) MarkMonthlyReportSent(month string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.MonthlyReportSent = month
+	return s.save()
+}
@@ -0,0 +1,53 @@
+package store
+
+import (
+	"slices"
+	"sort"
+)
+
+// RuleSubscriptions 返回已订阅的 Prometheus 告警规则名称，按名称排序
+func (s *Store) RuleSubscriptions() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.data.RuleSubscriptions)
+}
+
+// SubscribeRules 订阅 Prometheus 告警规则，规则触发时通过机器人发送通知，返回其中新订阅的规则
+func (s *Store) SubscribeRules(names []string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var added []string
+	for _, name := range names {
+		if slices.Contains(s.data.RuleSubscriptions, name) {
+			continue
+		}
+		s.data.RuleSubscriptions = append(s.data.RuleSubscriptions, name)
+		added = append(added, name)
+	}
+	if len(added) == 0 {
+		return nil, nil
+	}
+	sort.Strings(s.data.RuleSubscriptions)
+	return added, s.save()
+}
+
+// UnsubscribeRules 取消订阅 Prometheus 告警规则，返回其中确实被取消的规则
+func (s *Store) UnsubscribeRules(names []string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var removed []string
+	s.data.RuleSubscriptions = slices.DeleteFunc(s.data.RuleSubscriptions, func(name string) bool {
+		if slices.Contains(names, name) {
+			removed = append(removed, name)
+			return true
+		}
+		return false
+	})
+	if len(removed) == 0 {
+		return nil, nil
+	}
+	return removed, s.save()
+}
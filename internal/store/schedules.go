@@ -0,0 +1,87 @@
+package store
+
+import (
+	"slices"
+	"time"
+)
+
+// ScheduledQuery 是聊天设置的定时任务：按 Cron 表达式执行 PromQL 并把结果发送到聊天
+type ScheduledQuery struct {
+	ID     int64  `json:"id"`
+	ChatID int64  `json:"chat_id"`
+	Name   string `json:"name"`
+	Query  string `json:"query"`
+	// Cron 是五段 cron 表达式，按机器人所在服务器的时区计算
+	Cron string `json:"cron"`
+	// ChartWindow 不为空时发送该时间范围的图表（例如 "24h"），否则发送当前的查询结果
+	ChartWindow string    `json:"chart_window,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	LastRun     time.Time `json:"last_run,omitempty"`
+}
+
+// ScheduledQueries 返回聊天的定时任务，chatID 为 0 时返回所有聊天的定时任务，按创建顺序排列
+func (s *Store) ScheduledQueries(chatID int64) []ScheduledQuery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var queries []ScheduledQuery
+	for _, q := range s.data.ScheduledQueries {
+		if chatID == 0 || q.ChatID == chatID {
+			queries = append(queries, q)
+		}
+	}
+	return queries
+}
+
+// ScheduledQuery 按ID查找聊天的定时任务
+func (s *Store) ScheduledQuery(chatID, id int64) (ScheduledQuery, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, q := range s.data.ScheduledQueries {
+		if q.ID == id && q.ChatID == chatID {
+			return q, true
+		}
+	}
+	return ScheduledQuery{}, false
+}
+
+// AddScheduledQuery 保存定时任务并分配ID
+func (s *Store) AddScheduledQuery(q ScheduledQuery) (ScheduledQuery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.NextScheduleID++
+	q.ID = s.data.NextScheduleID
+	s.data.ScheduledQueries = append(s.data.ScheduledQueries, q)
+	return q, s.save()
+}
+
+// DeleteScheduledQuery 删除聊天的定时任务，返回是否存在该任务
+func (s *Store) DeleteScheduledQuery(chatID, id int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := len(s.data.ScheduledQueries)
+	s.data.ScheduledQueries = slices.DeleteFunc(s.data.ScheduledQueries, func(q ScheduledQuery) bool {
+		return q.ID == id && q.ChatID == chatID
+	})
+	if len(s.data.ScheduledQueries) == n {
+		return false, nil
+	}
+	return true, s.save()
+}
+
+// MarkScheduledQueryRun 记录定时任务最近一次执行的时间
+func (s *Store) MarkScheduledQueryRun(id int64, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.data.ScheduledQueries {
+		if s.data.ScheduledQueries[i].ID == id {
+			s.data.ScheduledQueries[i].LastRun = at
+			return s.save()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package store
+
+import (
+	"time"
+)
+
+// MenuSession 记录一条菜单消息最后一次被使用的时间，用于让长时间不用的菜单过期
+type MenuSession struct {
+	ChatID     int64     `json:"chat_id"`
+	MessageID  int       `json:"message_id"`
+	LastActive time.Time `json:"last_active"`
+}
+
+// TouchMenuSession 更新菜单消息的最后使用时间，不存在时新建
+func (s *Store) TouchMenuSession(chatID int64, messageID int, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.data.MenuSessions {
+		ms := &s.data.MenuSessions[i]
+		if ms.ChatID == chatID && ms.MessageID == messageID {
+			ms.LastActive = at
+			return s.save()
+		}
+	}
+	s.data.MenuSessions = append(s.data.MenuSessions, MenuSession{ChatID: chatID, MessageID: messageID, LastActive: at})
+	return s.save()
+}
+
+// MenuSession 返回菜单消息的会话记录，未记录的消息（例如告警通知）返回 false
+func (s *Store) MenuSession(chatID int64, messageID int) (MenuSession, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, ms := range s.data.MenuSessions {
+		if ms.ChatID == chatID && ms.MessageID == messageID {
+			return ms, true
+		}
+	}
+	return MenuSession{}, false
+}
+
+// StaleMenuSessions 返回最后使用时间早于 before 的菜单会话
+func (s *Store) StaleMenuSessions(before time.Time) []MenuSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var stale []MenuSession
+	for _, ms := range s.data.MenuSessions {
+		if ms.LastActive.Before(before) {
+			stale = append(stale, ms)
+		}
+	}
+	return stale
+}
+
+// ChatMenuSessions 返回聊天中仍然有效的菜单会话
+func (s *Store) ChatMenuSessions(chatID int64) []MenuSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var sessions []MenuSession
+	for _, ms := range s.data.MenuSessions {
+		if ms.ChatID == chatID {
+			sessions = append(sessions, ms)
+		}
+	}
+	return sessions
+}
+
+// DropMenuSession 删除菜单消息的会话记录
+func (s *Store) DropMenuSession(chatID int64, messageID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sessions := s.data.MenuSessions[:0]
+	for _, ms := range s.data.MenuSessions {
+		if ms.ChatID == chatID && ms.MessageID == messageID {
+			continue
+		}
+		sessions = append(sessions, ms)
+	}
+	s.data.MenuSessions = sessions
+	return s.save()
+}
@@ -0,0 +1,78 @@
+package store
+
+// ChatSettings 是每个聊天单独的偏好设置，零值表示使用全局配置
+type ChatSettings struct {
+	// Privacy 是隐私模式：off、mask 或 alias
+	Privacy string `json:"privacy,omitempty"`
+	// BitRates 为 true 时网络速率以 bit/s 显示
+	BitRates bool `json:"bit_rates,omitempty"`
+	// TelemetryOptOut 为 true 时该聊天的操作不计入匿名功能计数
+	TelemetryOptOut bool `json:"telemetry_opt_out,omitempty"`
+	// Briefing 是每日早报的设置
+	Briefing BriefingSettings `json:"briefing"`
+	// Chart 是图表的样式偏好
+	Chart ChartSettings `json:"chart"`
+	// Subscriptions 是聊天单独订阅告警的实例，不在告警聊天中的聊天也会收到这些实例的告警
+	Subscriptions AlertSubscriptions `json:"subscriptions"`
+	// Explain 为 true 时在菜单页面末尾显示生成页面时执行的查询，只对管理员聊天生效
+	Explain bool `json:"explain,omitempty"`
+}
+
+// AlertSubscriptions 是聊天按告警类型订阅的实例
+type AlertSubscriptions struct {
+	// Offline 是订阅离线和恢复通知的实例
+	Offline []string `json:"offline,omitempty"`
+	// Threshold 是订阅阈值和流量配额通知的实例
+	Threshold []string `json:"threshold,omitempty"`
+}
+
+// ChartSettings 是聊天的图表样式偏好，零值为浅色、中等尺寸、折线图并显示图例
+type ChartSettings struct {
+	Dark bool `json:"dark,omitempty"`
+	// Size 是 small、medium 或 large，为空时为中等
+	Size       string `json:"size,omitempty"`
+	Bars       bool   `json:"bars,omitempty"`
+	HideLegend bool   `json:"hide_legend,omitempty"`
+}
+
+// BriefingSettings 是聊天的每日早报设置
+type BriefingSettings struct {
+	// Time 是每天发送早报的时间（HH:MM），为空表示未开启
+	Time string `json:"time,omitempty"`
+	// Hidden 是早报中不显示的部分
+	Hidden []string `json:"hidden,omitempty"`
+}
+
+// ChatSettings 返回聊天的偏好设置
+func (s *Store) ChatSettings(chatID int64) ChatSettings {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.data.ChatSettings[chatID]
+}
+
+// UpdateChatSettings 修改聊天的偏好设置并保存
+func (s *Store) UpdateChatSettings(chatID int64, update func(*ChatSettings)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.data.ChatSettings == nil {
+		s.data.ChatSettings = make(map[int64]ChatSettings)
+	}
+	settings := s.data.ChatSettings[chatID]
+	update(&settings)
+	s.data.ChatSettings[chatID] = settings
+	return s.save()
+}
+
+// AllChatSettings 返回所有设置过偏好的聊天及其设置
+func (s *Store) AllChatSettings() map[int64]ChatSettings {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	settings := make(map[int64]ChatSettings, len(s.data.ChatSettings))
+	for chatID, cs := range s.data.ChatSettings {
+		settings[chatID] = cs
+	}
+	return settings
+}
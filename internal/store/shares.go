@@ -0,0 +1,89 @@
+package store
+
+import (
+	"time"
+)
+
+// ShareTarget 是只读分享的对象，Instance 和 GroupLabel 二选一
+type ShareTarget struct {
+	Instance string `json:"instance,omitempty"`
+	// GroupLabel 和 GroupValue 表示分组汇总中的一个分组，例如 provider=aws
+	GroupLabel string `json:"group_label,omitempty"`
+	GroupValue string `json:"group_value,omitempty"`
+}
+
+// ShareToken 是尚未使用的一次性分享链接
+type ShareToken struct {
+	Token     string      `json:"token"`
+	Target    ShareTarget `json:"target"`
+	CreatedBy int64       `json:"created_by"`
+	ExpiresAt time.Time   `json:"expires_at"`
+}
+
+// Grant 是聊天通过分享链接获得的只读访问权限
+type Grant struct {
+	ChatID int64       `json:"chat_id"`
+	Target ShareTarget `json:"target"`
+	Until  time.Time   `json:"until"`
+}
+
+// AddShareToken 保存分享链接，同时清理已过期的链接和授权
+func (s *Store) AddShareToken(t ShareToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pruneSharesLocked(time.Now())
+	s.data.ShareTokens = append(s.data.ShareTokens, t)
+	return s.save()
+}
+
+// RedeemShareToken 使用分享链接为 chatID 授予只读访问，链接使用后立即失效。
+// 链接不存在或已过期时返回 false
+func (s *Store) RedeemShareToken(token string, chatID int64, now time.Time) (Grant, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pruneSharesLocked(now)
+	for i, t := range s.data.ShareTokens {
+		if t.Token != token {
+			continue
+		}
+		s.data.ShareTokens = append(s.data.ShareTokens[:i], s.data.ShareTokens[i+1:]...)
+		g := Grant{ChatID: chatID, Target: t.Target, Until: t.ExpiresAt}
+		s.data.Grants = append(s.data.Grants, g)
+		return g, true, s.save()
+	}
+	return Grant{}, false, nil
+}
+
+// ActiveGrant 返回聊天在 now 时仍然有效的最近一个授权
+func (s *Store) ActiveGrant(chatID int64, now time.Time) (Grant, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := len(s.data.Grants) - 1; i >= 0; i-- {
+		g := s.data.Grants[i]
+		if g.ChatID == chatID && g.Until.After(now) {
+			return g, true
+		}
+	}
+	return Grant{}, false
+}
+
+// pruneSharesLocked 删除已过期的分享链接和授权。调用方需持有锁
+func (s *Store) pruneSharesLocked(now time.Time) {
+	tokens := s.data.ShareTokens[:0]
+	for _, t := range s.data.ShareTokens {
+		if t.ExpiresAt.After(now) {
+			tokens = append(tokens, t)
+		}
+	}
+	s.data.ShareTokens = tokens
+	grants := s.data.Grants[:0]
+	for _, g := range s.data.Grants {
+		if g.Until.After(now) {
+			grants = append(grants, g)
+		}
+	}
+	s.data.Grants = grants
+}
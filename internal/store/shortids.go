@@ -0,0 +1,45 @@
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// shortIDLength 是实例短ID的初始长度，与已有短ID冲突时逐步加长
+const shortIDLength = 8
+
+// InstanceShortID 返回实例的短ID，没有时根据实例名生成并保存。
+// 实例名中的冒号和点不能出现在 /start 参数中，深层链接中改用短ID表示实例
+func (s *Store) InstanceShortID(instance string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, name := range s.data.ShortIDs {
+		if name == instance {
+			return id, nil
+		}
+	}
+	if s.data.ShortIDs == nil {
+		s.data.ShortIDs = make(map[string]string)
+	}
+	sum := sha256.Sum256([]byte(instance))
+	digest := hex.EncodeToString(sum[:])
+	id := digest[:shortIDLength]
+	for n := shortIDLength + 1; n <= len(digest); n++ {
+		if _, ok := s.data.ShortIDs[id]; !ok {
+			break
+		}
+		id = digest[:n]
+	}
+	s.data.ShortIDs[id] = instance
+	return id, s.save()
+}
+
+// LookupShortID 返回短ID对应的实例名，短ID不存在时返回 false
+func (s *Store) LookupShortID(id string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	instance, ok := s.data.ShortIDs[id]
+	return instance, ok
+}
@@ -0,0 +1,42 @@
+package store
+
+import (
+	"time"
+)
+
+// Snooze 表示在 Until 之前不再发送实例某项阈值告警的通知，事件仍会被记录
+type Snooze struct {
+	Instance string    `json:"instance"`
+	Metric   string    `json:"metric"`
+	Until    time.Time `json:"until"`
+}
+
+// SnoozeAlerts 暂停实例某项阈值的通知直到 until，已有的暂停会被覆盖
+func (s *Store) SnoozeAlerts(instance, metric string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snoozes := s.data.Snoozes[:0]
+	for _, sn := range s.data.Snoozes {
+		// 顺便清理已过期的暂停
+		if (sn.Instance == instance && sn.Metric == metric) || !sn.Until.After(time.Now()) {
+			continue
+		}
+		snoozes = append(snoozes, sn)
+	}
+	s.data.Snoozes = append(snoozes, Snooze{Instance: instance, Metric: metric, Until: until})
+	return s.save()
+}
+
+// Snoozed 判断实例某项阈值的通知在 now 时是否处于暂停中
+func (s *Store) Snoozed(instance, metric string, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, sn := range s.data.Snoozes {
+		if sn.Instance == instance && sn.Metric == metric && sn.Until.After(now) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Store 是一个基于 JSON 文件的简单持久化存储
+type Store struct {
+	mu   sync.Mutex
+	path string
+	data storeData
+}
+
+type storeData struct {
+	NextEventID int64   `json:"next_event_id"`
+	Events      []Event `json:"events"`
+	// Snoozes 是暂停中的阈值通知
+	Snoozes []Snooze `json:"snoozes,omitempty"`
+	// MenuSessions 是仍然有效的菜单消息
+	MenuSessions []MenuSession `json:"menu_sessions,omitempty"`
+	// ShareTokens 和 Grants 是只读分享的链接和已授予的访问权限
+	ShareTokens []ShareToken `json:"share_tokens,omitempty"`
+	Grants      []Grant      `json:"grants,omitempty"`
+	// Chats 是与机器人交互过的聊天，用于广播
+	Chats []int64 `json:"chats,omitempty"`
+	// ChatSettings 是每个聊天的偏好设置
+	ChatSettings map[int64]ChatSettings `json:"chat_settings,omitempty"`
+	// Usage 是按天汇总的功能使用次数
+	Usage []UsageCount `json:"usage,omitempty"`
+	// Telemetry 是按天汇总的匿名功能计数，只在开启 TELEMETRY_ENABLED 时记录
+	Telemetry []TelemetryCount `json:"telemetry,omitempty"`
+	// MonthlyReportSent 是最近一次已自动发送月度报告的月份，例如 "2026-09"
+	MonthlyReportSent string `json:"monthly_report_sent,omitempty"`
+	// KnownInstances 是见过的实例及第一次见到的时间，用于发现新实例
+	KnownInstances map[string]time.Time `json:"known_instances,omitempty"`
+	// RuleSubscriptions 是导入的 Prometheus 告警规则名称，这些规则触发时由机器人发送通知
+	RuleSubscriptions []string `json:"rule_subscriptions,omitempty"`
+	// ScheduledQueries 是聊天设置的定时任务，NextScheduleID 是下一个任务的ID
+	NextScheduleID   int64            `json:"next_schedule_id,omitempty"`
+	ScheduledQueries []ScheduledQuery `json:"scheduled_queries,omitempty"`
+	// Notifications 是已发送的告警通知，用于检索告警历史
+	Notifications []Notification `json:"notifications,omitempty"`
+	// ThresholdOverrides 是在机器人中修改的告警阈值，优先于配置文件
+	ThresholdOverrides map[string]float64 `json:"threshold_overrides,omitempty"`
+	// Budget 是每月预算，为 nil 表示未设置；BudgetWarned 是最近一次发送超预算提醒的月份，例如 "2026-10"
+	Budget       *Budget `json:"budget,omitempty"`
+	BudgetWarned string  `json:"budget_warned,omitempty"`
+	// ShortIDs 是深层链接中使用的实例短ID到实例名的映射
+	ShortIDs map[string]string `json:"short_ids,omitempty"`
+	// LastBackupAt 是最近一次自动发送加密备份的时间
+	LastBackupAt time.Time `json:"last_backup_at"`
+}
+
+func Open(path string) (*Store, error) {
+	s := &Store{path: path}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, fmt.Errorf("Failed to read store %s: %v", path, err)
+	}
+	if len(content) > 0 {
+		if err := json.Unmarshal(content, &s.data); err != nil {
+			return nil, fmt.Errorf("Failed to parse store %s: %v", path, err)
+		}
+	}
+	return s, nil
+}
+
+// save 将数据写入临时文件后再重命名，避免写入中断导致文件损坏。调用方需持有锁
+func (s *Store) save() error {
+	content, err := json.MarshalIndent(&s.data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Failed to encode store: %v", err)
+	}
+	if dir := filepath.Dir(s.path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("Failed to create store directory: %v", err)
+		}
+	}
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, content, 0o600); err != nil {
+		return fmt.Errorf("Failed to write store: %v", err)
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		return fmt.Errorf("Failed to replace store: %v", err)
+	}
+	return nil
+}
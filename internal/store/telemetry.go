@@ -0,0 +1,53 @@
+package store
+
+import (
+	"time"
+)
+
+// TelemetryCount 是某一天某项功能的匿名使用次数，不记录是哪个聊天使用的
+type TelemetryCount struct {
+	Day     string `json:"day"`
+	Feature string `json:"feature"`
+	Count   int    `json:"count"`
+}
+
+// RecordTelemetry 将功能计数加一，同时清理超过保留期的计数
+func (s *Store) RecordTelemetry(feature string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	day := at.Local().Format(usageDayLayout)
+	oldest := at.Local().AddDate(0, 0, -usageRetentionDays).Format(usageDayLayout)
+	counts := s.data.Telemetry[:0]
+	found := false
+	for _, c := range s.data.Telemetry {
+		if c.Day < oldest {
+			continue
+		}
+		if c.Day == day && c.Feature == feature {
+			c.Count++
+			found = true
+		}
+		counts = append(counts, c)
+	}
+	if !found {
+		counts = append(counts, TelemetryCount{Day: day, Feature: feature, Count: 1})
+	}
+	s.data.Telemetry = counts
+	return s.save()
+}
+
+// TelemetrySince 返回 since 当天及之后各功能的使用次数之和
+func (s *Store) TelemetrySince(since time.Time) map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	day := since.Local().Format(usageDayLayout)
+	totals := make(map[string]int)
+	for _, c := range s.data.Telemetry {
+		if c.Day >= day {
+			totals[c.Feature] += c.Count
+		}
+	}
+	return totals
+}
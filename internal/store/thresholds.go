@@ -0,0 +1,51 @@
+package store
+
+import (
+	"maps"
+)
+
+// Thresholds 返回生效的告警阈值：defaults 是配置文件中的阈值，在机器人中修改过的指标使用修改后的值
+func (s *Store) Thresholds(defaults map[string]float64) map[string]float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	thresholds := maps.Clone(defaults)
+	if thresholds == nil {
+		thresholds = make(map[string]float64)
+	}
+	maps.Copy(thresholds, s.data.ThresholdOverrides)
+	return thresholds
+}
+
+// ThresholdOverridden 判断指标的阈值是否在机器人中修改过
+func (s *Store) ThresholdOverridden(metric string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.data.ThresholdOverrides[metric]
+	return ok
+}
+
+// SetThreshold 保存在机器人中修改的阈值，优先于配置文件
+func (s *Store) SetThreshold(metric string, limit float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.data.ThresholdOverrides == nil {
+		s.data.ThresholdOverrides = make(map[string]float64)
+	}
+	s.data.ThresholdOverrides[metric] = limit
+	return s.save()
+}
+
+// ResetThreshold 删除在机器人中修改的阈值，恢复使用配置文件中的值，返回之前是否修改过
+func (s *Store) ResetThreshold(metric string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.data.ThresholdOverrides[metric]; !ok {
+		return false, nil
+	}
+	delete(s.data.ThresholdOverrides, metric)
+	return true, s.save()
+}
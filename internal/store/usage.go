@@ -0,0 +1,68 @@
+package store
+
+import (
+	"time"
+)
+
+type UsageKind string
+
+const (
+	UsageCommand  UsageKind = "command"
+	UsageMenu     UsageKind = "menu"
+	UsageInstance UsageKind = "instance"
+)
+
+// usageRetentionDays 是使用统计保留的天数
+const usageRetentionDays = 31
+
+const usageDayLayout = "2006-01-02"
+
+// UsageCount 是某个聊天某一天使用某项功能的次数
+type UsageCount struct {
+	Day    string    `json:"day"`
+	ChatID int64     `json:"chat_id"`
+	Kind   UsageKind `json:"kind"`
+	Name   string    `json:"name"`
+	Count  int       `json:"count"`
+}
+
+// RecordUsage 记录一次功能使用，同时清理超过保留期的统计
+func (s *Store) RecordUsage(chatID int64, kind UsageKind, name string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	day := at.Local().Format(usageDayLayout)
+	oldest := at.Local().AddDate(0, 0, -usageRetentionDays).Format(usageDayLayout)
+	usage := s.data.Usage[:0]
+	found := false
+	for _, u := range s.data.Usage {
+		if u.Day < oldest {
+			continue
+		}
+		if u.Day == day && u.ChatID == chatID && u.Kind == kind && u.Name == name {
+			u.Count++
+			found = true
+		}
+		usage = append(usage, u)
+	}
+	if !found {
+		usage = append(usage, UsageCount{Day: day, ChatID: chatID, Kind: kind, Name: name, Count: 1})
+	}
+	s.data.Usage = usage
+	return s.save()
+}
+
+// UsageSince 返回 since 当天及之后的使用统计
+func (s *Store) UsageSince(since time.Time) []UsageCount {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	day := since.Local().Format(usageDayLayout)
+	var usage []UsageCount
+	for _, u := range s.data.Usage {
+		if u.Day >= day {
+			usage = append(usage, u)
+		}
+	}
+	return usage
+}
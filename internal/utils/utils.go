@@ -3,35 +3,42 @@ package utils
 import (
 	"fmt"
 	"math"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
+	"unicode"
 )
 
+// AddMonthsClamped 在 t 上增加 months 个月（可为负数）。与 time.AddDate 不同，
+// 日期超出目标月份天数时取该月最后一天，例如 1 月 31 日加一个月为 2 月 28 日（闰年 29 日），而不是 3 月 3 日
+func AddMonthsClamped(t time.Time, months int) time.Time {
+	return addMonthsClampedIn(t, months, time.Local)
+}
+
+func addMonthsClampedIn(t time.Time, months int, loc *time.Location) time.Time {
+	first := time.Date(t.Year(), t.Month()+time.Month(months), 1, 0, 0, 0, 0, loc)
+	day := t.Day()
+	if daysInMonth := first.AddDate(0, 1, -1).Day(); day > daysInMonth {
+		day = daysInMonth
+	}
+	return time.Date(first.Year(), first.Month(), day, 0, 0, 0, 0, loc)
+}
+
+// CalculateLastMonthExpiry 以 expiryTime 的日期作为每月的周期日，返回不晚于 now 的最近一个周期日。
+// 周期日在某月不存在时（例如 31 日）使用该月最后一天
 func CalculateLastMonthExpiry(expiryTime time.Time, now time.Time) time.Time {
-	dayOfMonth := expiryTime.Day()
-	year, month, _ := expiryTime.Date()
-	for {
-		// 推到上一个月
-		if month == 1 {
-			year--
-			month = 12
-		} else {
-			month--
-		}
-		// 获取上一个月的最后一天
-		lastDayOfPrevMonth := time.Date(year, month+1, 0, 0, 0, 0, 0, time.Local).Day()
-		// 计算上一个月的到期时间
-		monthExpiry := time.Date(year, month, dayOfMonth, 0, 0, 0, 0, time.Local)
-		// 如果上一个月的到期时间的日子大于上一个月的最后一天，使用上一个月的最后一天
-		if dayOfMonth > lastDayOfPrevMonth {
-			monthExpiry = time.Date(year, month, lastDayOfPrevMonth, 0, 0, 0, 0, time.Local)
-		}
-		// 如果上一个月的到期时间不在 now 后，返回
-		if !monthExpiry.After(now) {
-			return monthExpiry
-		}
+	months := (now.Year()-expiryTime.Year())*12 + int(now.Month()-expiryTime.Month())
+	last := AddMonthsClamped(expiryTime, months)
+	for last.After(now) {
+		months--
+		last = AddMonthsClamped(expiryTime, months)
 	}
+	return last
 }
 
+// CalculateDaysDifference 返回两个时间之间相差的整天数（绝对值）
 func CalculateDaysDifference(now, lastMonthExpiry time.Time) int {
 	daysDiff := int(math.Floor(now.Sub(lastMonthExpiry).Hours() / 24))
 	if daysDiff < 0 {
@@ -40,13 +47,43 @@ func CalculateDaysDifference(now, lastMonthExpiry time.Time) int {
 	return daysDiff
 }
 
-func CalculateTimeLeft(timeLeft time.Duration) (int, int, int) {
-	yearsLeft := int(timeLeft.Hours() / (24 * 365))
-	monthsLeft := int((timeLeft.Hours() / (24 * 30)) - (float64(yearsLeft) * 12))
-	daysLeft := int(timeLeft.Hours()/24) - (yearsLeft * 365) - (monthsLeft * 30)
-	return yearsLeft, monthsLeft, daysLeft
+// CalculateTimeLeft 返回从 now 到 expiry 剩余的年、月、日，已过期时返回 0
+func CalculateTimeLeft(now, expiry time.Time) (int, int, int) {
+	if expiry.Before(now) {
+		return 0, 0, 0
+	}
+	return CalendarDiff(now, expiry)
+}
+
+// CalendarDiff 按日历计算 start 到 end 之间相差的年、月、日，忽略时刻。
+// 先找出不超过 end 的最大整月数，剩余部分按天计算，因此不受每月天数和闰年影响。
+// 月末日期按 AddMonthsClamped 的规则处理，例如 1 月 31 日到 2 月 29 日为整一个月
+func CalendarDiff(start, end time.Time) (int, int, int) {
+	start, end = calendarDate(start), calendarDate(end)
+	if end.Before(start) {
+		return 0, 0, 0
+	}
+
+	months := (end.Year()-start.Year())*12 + int(end.Month()-start.Month())
+	for months > 0 && addMonthsClampedIn(start, months, time.UTC).After(end) {
+		months--
+	}
+	days := int(end.Sub(addMonthsClampedIn(start, months, time.UTC)).Hours() / 24)
+	return months / 12, months % 12, days
+}
+
+// DaysBetween 返回 start 到 end 之间相差的日历天数，end 早于 start 时为负数
+func DaysBetween(start, end time.Time) int {
+	return int(calendarDate(end).Sub(calendarDate(start)).Hours() / 24)
+}
+
+// calendarDate 取 t 在其时区下的日期，并转换为 UTC 零点，避免夏令时导致一天不是 24 小时
+func calendarDate(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
 }
 
+// FormatDuration 将时长格式化为 PromQL 范围选择器可用的形式，超过一天时只保留天数
 func FormatDuration(d time.Duration) string {
 	hours := int(d.Hours())
 	minutes := int(d.Minutes()) % 60
@@ -63,9 +100,349 @@ func FormatDuration(d time.Duration) string {
 	}
 }
 
+// ShortDuration 将时长格式化为 "1d2h"、"3h5m"、"45s" 这样的紧凑形式
+func ShortDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	days := int(d.Hours()) / 24
+	hours := int(d.Hours()) % 24
+	minutes := int(d.Minutes()) % 60
+
+	var parts []string
+	if days > 0 {
+		parts = append(parts, fmt.Sprintf("%dd", days))
+	}
+	if hours > 0 {
+		parts = append(parts, fmt.Sprintf("%dh", hours))
+	}
+	if minutes > 0 && days == 0 {
+		parts = append(parts, fmt.Sprintf("%dm", minutes))
+	}
+	if len(parts) == 0 {
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	}
+	return strings.Join(parts, "")
+}
+
+// TruncateString 按字符截断字符串，超出 maxLength 时追加省略号，不会截断多字节字符
 func TruncateString(s string, maxLength int) string {
-	if len(s) <= maxLength {
+	runes := []rune(s)
+	if len(runes) <= maxLength {
 		return s
 	}
-	return s[:maxLength] + "..."
+	return string(runes[:maxLength]) + "..."
+}
+
+// sparkBars 是从低到高的八级柱状字符
+var sparkBars = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline 将数值序列转换为 ▁▂▃▅▇ 风格的迷你趋势图。width 大于 0 且数据点更多时，
+// 按相邻分组取平均值压缩到 width 个字符。NaN 和无穷值被忽略，数据全部相同时显示为最低一级
+func Sparkline(values []float64, width int) string {
+	var points []float64
+	for _, v := range values {
+		if !math.IsNaN(v) && !math.IsInf(v, 0) {
+			points = append(points, v)
+		}
+	}
+	if len(points) == 0 {
+		return ""
+	}
+	if width > 0 && len(points) > width {
+		points = downsampleAverage(points, width)
+	}
+
+	low, high := points[0], points[0]
+	for _, v := range points {
+		low = math.Min(low, v)
+		high = math.Max(high, v)
+	}
+	line := make([]rune, len(points))
+	for i, v := range points {
+		level := 0
+		if high > low {
+			level = int((v - low) / (high - low) * float64(len(sparkBars)-1))
+		}
+		line[i] = sparkBars[level]
+	}
+	return string(line)
+}
+
+// downsampleAverage 将 values 平均分为 width 组，每组取平均值
+func downsampleAverage(values []float64, width int) []float64 {
+	result := make([]float64, width)
+	for i := range result {
+		start := i * len(values) / width
+		end := (i + 1) * len(values) / width
+		sum := 0.0
+		for _, v := range values[start:end] {
+			sum += v
+		}
+		result[i] = sum / float64(end-start)
+	}
+	return result
+}
+
+// ParsePrice 解析价格标签，例如 "€23"、"$5.99"、"10 USD"、"¥30/月"、"23EUR"。
+// 返回金额和货币（符号或代码，原样保留），没有货币时 currency 为空，无法解析金额时 ok 为 false
+func ParsePrice(s string) (amount float64, currency string, ok bool) {
+	s = strings.TrimSpace(s)
+	// 去掉 "/月"、"/year" 之类的周期后缀，周期由 cycle 标签决定
+	if i := strings.Index(s, "/"); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+
+	start := strings.IndexFunc(s, func(r rune) bool { return unicode.IsDigit(r) })
+	if start < 0 {
+		return 0, "", false
+	}
+	end := start
+	for end < len(s) && (s[end] == '.' || s[end] == ',' || unicode.IsDigit(rune(s[end]))) {
+		end++
+	}
+	amount, err := strconv.ParseFloat(strings.ReplaceAll(s[start:end], ",", ""), 64)
+	if err != nil {
+		return 0, "", false
+	}
+
+	currency = strings.TrimSpace(s[:start])
+	if currency == "" {
+		currency = strings.TrimSpace(s[end:])
+	}
+	return amount, strings.ToUpper(currency), true
+}
+
+// ipv4Pattern 匹配 IPv4 地址及可选的端口
+var ipv4Pattern = regexp.MustCompile(`\b(\d{1,3})\.(\d{1,3})\.\d{1,3}\.\d{1,3}(:\d{1,5})?\b`)
+
+// MaskIPs 隐藏文本中 IPv4 地址的后两段和端口，例如 203.0.113.5:9100 变为 203.0.*.*
+func MaskIPs(text string) string {
+	return ipv4Pattern.ReplaceAllString(text, "$1.$2.*.*")
+}
+
+// ProgressBar 返回 width 个字符的横向条形图，fraction 为 0 到 1 之间的比例，超出范围时截断
+func ProgressBar(fraction float64, width int) string {
+	if math.IsNaN(fraction) || fraction < 0 {
+		fraction = 0
+	}
+	if fraction > 1 {
+		fraction = 1
+	}
+	filled := int(math.Round(fraction * float64(width)))
+	return strings.Repeat("█", filled) + strings.Repeat("░", width-filled)
+}
+
+// CompareVersions 比较两个版本号，例如内核版本 5.15.0-91-generic。
+// 连续的数字按数值比较，其他字符按字典序比较；a 较旧时返回 -1，相同返回 0，较新返回 1
+func CompareVersions(a, b string) int {
+	for a != "" && b != "" {
+		pa, ra := versionPart(a)
+		pb, rb := versionPart(b)
+		na, errA := strconv.Atoi(pa)
+		nb, errB := strconv.Atoi(pb)
+		switch {
+		case errA == nil && errB == nil:
+			if na != nb {
+				if na < nb {
+					return -1
+				}
+				return 1
+			}
+		case pa != pb:
+			return strings.Compare(pa, pb)
+		}
+		a, b = ra, rb
+	}
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return -1
+	default:
+		return 1
+	}
+}
+
+// versionPart 返回版本号开头连续的数字或非数字部分以及剩余部分
+func versionPart(s string) (string, string) {
+	digit := unicode.IsDigit(rune(s[0]))
+	i := 1
+	for i < len(s) && unicode.IsDigit(rune(s[i])) == digit {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+// SplitInstance 将 instance 标签拆分为主机和端口，例如 [2001:db8::1]:9100 拆分为 2001:db8::1 和 9100，
+// 没有端口时 port 为空
+func SplitInstance(instance string) (host, port string) {
+	if host, port, err := net.SplitHostPort(instance); err == nil {
+		return host, port
+	}
+	return strings.Trim(instance, "[]"), ""
+}
+
+// bitRatePattern 匹配 "100Mbps"、"1.5 Gbit/s"、"500k" 之类的速率，大写 B 表示字节，不予接受
+var bitRatePattern = regexp.MustCompile(`^([\d.]+)\s*([kKmMgGtT]?)(bps|bit/s)?$`)
+
+// ParseBitRate 解析以十进制单位表示的比特速率，例如 "100Mbps"，返回每秒比特数
+func ParseBitRate(s string) (float64, bool) {
+	m := bitRatePattern.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, false
+	}
+	switch strings.ToLower(m[2]) {
+	case "k":
+		v *= 1e3
+	case "m":
+		v *= 1e6
+	case "g":
+		v *= 1e9
+	case "t":
+		v *= 1e12
+	}
+	return v, true
+}
+
+// bytesPattern 匹配 "1TB"、"500 GiB"、"1.5T" 之类的数据量
+var bytesPattern = regexp.MustCompile(`^([\d.]+)\s*([kKmMgGtTpP]?)(i?)[bB]?$`)
+
+// ParseBytes 解析数据量，返回字节数。KB、MB、GB、TB 按服务商的习惯使用十进制单位，
+// KiB、MiB、GiB、TiB 使用二进制单位，没有单位时为字节
+func ParseBytes(s string) (float64, bool) {
+	m := bytesPattern.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil || (m[3] != "" && m[2] == "") {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, false
+	}
+	base := 1000.0
+	if m[3] != "" {
+		base = 1024
+	}
+	exp := 0
+	if m[2] != "" {
+		exp = strings.Index("kmgtp", strings.ToLower(m[2])) + 1
+	}
+	return v * math.Pow(base, float64(exp)), true
+}
+
+// CronSchedule 是解析后的五段 cron 表达式（分 时 日 月 周），每段记录允许的取值
+type CronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// domAny、dowAny 表示日、周字段为 "*"。两者都受限时，按 cron 惯例满足其一即可
+	domAny, dowAny bool
+}
+
+// cronAliases 是常用的 cron 简写
+var cronAliases = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+// ParseCron 解析五段 cron 表达式，例如 "0 18 * * 5" 表示每周五 18:00。
+// 每段支持 *、数字、范围 a-b、列表 a,b 和步长 */n 或 a-b/n，周日可以写作 0 或 7
+func ParseCron(expr string) (CronSchedule, error) {
+	expr = strings.TrimSpace(expr)
+	if alias, ok := cronAliases[expr]; ok {
+		expr = alias
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return CronSchedule{}, fmt.Errorf("cron expression must have 5 fields, got %d", len(fields))
+	}
+	var s CronSchedule
+	var err error
+	if s.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return CronSchedule{}, fmt.Errorf("invalid minute %q: %v", fields[0], err)
+	}
+	if s.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return CronSchedule{}, fmt.Errorf("invalid hour %q: %v", fields[1], err)
+	}
+	if s.dom, err = parseCronField(fields[2], 1, 31); err != nil {
+		return CronSchedule{}, fmt.Errorf("invalid day of month %q: %v", fields[2], err)
+	}
+	if s.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return CronSchedule{}, fmt.Errorf("invalid month %q: %v", fields[3], err)
+	}
+	if s.dow, err = parseCronField(fields[4], 0, 7); err != nil {
+		return CronSchedule{}, fmt.Errorf("invalid day of week %q: %v", fields[4], err)
+	}
+	// 7 和 0 都表示周日
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	s.domAny, s.dowAny = fields[2] == "*", fields[4] == "*"
+	return s, nil
+}
+
+// parseCronField 将一段 cron 表达式转换为取值的位集合
+func parseCronField(field string, lo, hi int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangeText, stepText, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepText)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step %q", stepText)
+			}
+			step = n
+		}
+		start, end := lo, hi
+		if rangeText != "*" {
+			startText, endText, isRange := strings.Cut(rangeText, "-")
+			var err error
+			if start, err = strconv.Atoi(startText); err != nil {
+				return 0, fmt.Errorf("invalid value %q", startText)
+			}
+			end = start
+			if isRange {
+				if end, err = strconv.Atoi(endText); err != nil {
+					return 0, fmt.Errorf("invalid value %q", endText)
+				}
+			} else if hasStep {
+				// "5/15" 表示从 5 开始每隔 15
+				end = hi
+			}
+		}
+		if start < lo || end > hi || start > end {
+			return 0, fmt.Errorf("value out of range %d-%d", lo, hi)
+		}
+		for v := start; v <= end; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// Matches 判断 t 所在的分钟是否满足表达式
+func (s CronSchedule) Matches(t time.Time) bool {
+	if s.minute&(1<<uint(t.Minute())) == 0 || s.hour&(1<<uint(t.Hour())) == 0 || s.month&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domAny || s.dowAny {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// Next 返回晚于 after 的第一个满足表达式的时间（精确到分钟），一年内没有时返回零值
+func (s CronSchedule) Next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	for limit := t.AddDate(1, 0, 0); t.Before(limit); t = t.Add(time.Minute) {
+		if s.Matches(t) {
+			return t
+		}
+	}
+	return time.Time{}
 }
@@ -0,0 +1,345 @@
+package utils
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.Local)
+}
+
+func TestAddMonthsClamped(t *testing.T) {
+	tests := []struct {
+		name   string
+		start  time.Time
+		months int
+		want   time.Time
+	}{
+		{"same day", date(2024, 3, 15), 1, date(2024, 4, 15)},
+		{"31st into february", date(2023, 1, 31), 1, date(2023, 2, 28)},
+		{"31st into leap february", date(2024, 1, 31), 1, date(2024, 2, 29)},
+		{"31st into 30-day month", date(2024, 3, 31), 1, date(2024, 4, 30)},
+		{"two months keeps anchor day", date(2024, 1, 31), 2, date(2024, 3, 31)},
+		{"across year end", date(2024, 11, 30), 3, date(2025, 2, 28)},
+		{"negative months", date(2024, 3, 31), -1, date(2024, 2, 29)},
+		{"leap day next year", date(2024, 2, 29), 12, date(2025, 2, 28)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AddMonthsClamped(tt.start, tt.months); !got.Equal(tt.want) {
+				t.Errorf("AddMonthsClamped(%s, %d) = %s, want %s", tt.start.Format("2006-01-02"), tt.months, got.Format("2006-01-02"), tt.want.Format("2006-01-02"))
+			}
+		})
+	}
+}
+
+func TestCalculateLastMonthExpiry(t *testing.T) {
+	tests := []struct {
+		name   string
+		expiry time.Time
+		now    time.Time
+		want   time.Time
+	}{
+		{"before day in month", date(2024, 1, 15), date(2024, 6, 10).Add(12 * time.Hour), date(2024, 5, 15)},
+		{"after day in month", date(2024, 1, 15), date(2024, 6, 20), date(2024, 6, 15)},
+		{"on the day", date(2024, 1, 15), date(2024, 6, 15).Add(time.Hour), date(2024, 6, 15)},
+		{"31st in february", date(2023, 1, 31), date(2023, 3, 10), date(2023, 2, 28)},
+		{"31st in leap february", date(2024, 1, 31), date(2024, 2, 29).Add(time.Hour), date(2024, 2, 29)},
+		{"31st in 30-day month", date(2024, 1, 31), date(2024, 5, 1), date(2024, 4, 30)},
+		{"january from december anchor", date(2023, 12, 20), date(2024, 1, 5), date(2023, 12, 20)},
+		{"expiry in the future", date(2025, 3, 10), date(2024, 6, 20), date(2024, 6, 10)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CalculateLastMonthExpiry(tt.expiry, tt.now); !got.Equal(tt.want) {
+				t.Errorf("CalculateLastMonthExpiry(%s, %s) = %s, want %s", tt.expiry.Format("2006-01-02"), tt.now.Format("2006-01-02"), got.Format("2006-01-02"), tt.want.Format("2006-01-02"))
+			}
+		})
+	}
+}
+
+func TestCalendarDiff(t *testing.T) {
+	tests := []struct {
+		name                string
+		start, end          time.Time
+		years, months, days int
+	}{
+		{"same day", date(2024, 5, 5), date(2024, 5, 5), 0, 0, 0},
+		{"days only", date(2024, 5, 5), date(2024, 5, 20), 0, 0, 15},
+		{"across february", date(2023, 2, 1), date(2023, 3, 1), 0, 1, 0},
+		{"across leap february", date(2024, 2, 10), date(2024, 3, 9), 0, 0, 28},
+		{"31st to end of february", date(2024, 1, 31), date(2024, 2, 29), 0, 1, 0},
+		{"full year over leap day", date(2024, 2, 29), date(2025, 3, 1), 1, 0, 1},
+		{"years months days", date(2022, 10, 16), date(2024, 1, 20), 1, 3, 4},
+		{"time of day ignored", date(2024, 5, 5).Add(23 * time.Hour), date(2024, 5, 6), 0, 0, 1},
+		{"end before start", date(2024, 5, 6), date(2024, 5, 5), 0, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			years, months, days := CalendarDiff(tt.start, tt.end)
+			if years != tt.years || months != tt.months || days != tt.days {
+				t.Errorf("CalendarDiff = %d/%d/%d, want %d/%d/%d", years, months, days, tt.years, tt.months, tt.days)
+			}
+		})
+	}
+}
+
+func TestDaysBetween(t *testing.T) {
+	if got := DaysBetween(date(2024, 2, 28), date(2024, 3, 1)); got != 2 {
+		t.Errorf("DaysBetween across leap day = %d, want 2", got)
+	}
+	if got := DaysBetween(date(2023, 2, 28), date(2023, 3, 1)); got != 1 {
+		t.Errorf("DaysBetween across february = %d, want 1", got)
+	}
+	if got := DaysBetween(date(2024, 3, 1), date(2024, 2, 28)); got != -2 {
+		t.Errorf("DaysBetween backwards = %d, want -2", got)
+	}
+}
+
+func TestCalculateTimeLeft(t *testing.T) {
+	years, months, days := CalculateTimeLeft(date(2024, 6, 1), date(2024, 5, 1))
+	if years != 0 || months != 0 || days != 0 {
+		t.Errorf("CalculateTimeLeft for expired = %d/%d/%d, want 0/0/0", years, months, days)
+	}
+	years, months, days = CalculateTimeLeft(date(2024, 1, 31).Add(10*time.Hour), date(2025, 3, 2))
+	if years != 1 || months != 1 || days != 2 {
+		t.Errorf("CalculateTimeLeft = %d/%d/%d, want 1/1/2", years, months, days)
+	}
+}
+
+func TestTruncateString(t *testing.T) {
+	if got := TruncateString("实例名称很长", 4); got != "实例名称..." {
+		t.Errorf("TruncateString multibyte = %q", got)
+	}
+	if got := TruncateString("short", 10); got != "short" {
+		t.Errorf("TruncateString short = %q", got)
+	}
+}
+
+func TestSparkline(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		width  int
+		want   string
+	}{
+		{"empty", nil, 8, ""},
+		{"ascending", []float64{0, 1, 2, 3, 4, 5, 6, 7}, 0, "▁▂▃▄▅▆▇█"},
+		{"flat", []float64{3, 3, 3}, 0, "▁▁▁"},
+		{"downsampled", []float64{0, 0, 10, 10}, 2, "▁█"},
+		{"ignores NaN", []float64{0, math.NaN(), 1}, 0, "▁█"},
+		{"shorter than width", []float64{1, 0}, 10, "█▁"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sparkline(tt.values, tt.width); got != tt.want {
+				t.Errorf("Sparkline(%v, %d) = %q, want %q", tt.values, tt.width, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePrice(t *testing.T) {
+	tests := []struct {
+		in       string
+		amount   float64
+		currency string
+		ok       bool
+	}{
+		{"€23", 23, "€", true},
+		{"$5.99", 5.99, "$", true},
+		{"10 USD", 10, "USD", true},
+		{"23eur", 23, "EUR", true},
+		{"¥30/月", 30, "¥", true},
+		{"1,200 JPY", 1200, "JPY", true},
+		{"12", 12, "", true},
+		{"免费", 0, "", false},
+		{"", 0, "", false},
+	}
+	for _, tt := range tests {
+		amount, currency, ok := ParsePrice(tt.in)
+		if amount != tt.amount || currency != tt.currency || ok != tt.ok {
+			t.Errorf("ParsePrice(%q) = %v, %q, %v, want %v, %q, %v", tt.in, amount, currency, ok, tt.amount, tt.currency, tt.ok)
+		}
+	}
+}
+
+func TestMaskIPs(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"203.0.113.5", "203.0.*.*"},
+		{"203.0.113.5:9100", "203.0.*.*"},
+		{"实例 10.1.2.3:9100 离线", "实例 10.1.*.* 离线"},
+		{"a 1.2.3.4 b 5.6.7.8:80", "a 1.2.*.* b 5.6.*.*"},
+		{"web-1:9100", "web-1:9100"},
+		{"版本 1.2.3", "版本 1.2.3"},
+		{"203.0.*.*", "203.0.*.*"},
+	}
+	for _, tt := range tests {
+		if got := MaskIPs(tt.in); got != tt.want {
+			t.Errorf("MaskIPs(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestProgressBar(t *testing.T) {
+	tests := []struct {
+		fraction float64
+		width    int
+		want     string
+	}{
+		{0, 4, "░░░░"},
+		{0.5, 4, "██░░"},
+		{1, 4, "████"},
+		{1.5, 4, "████"},
+		{-1, 4, "░░░░"},
+		{math.NaN(), 2, "░░"},
+		{0.3, 10, "███░░░░░░░"},
+	}
+	for _, tt := range tests {
+		if got := ProgressBar(tt.fraction, tt.width); got != tt.want {
+			t.Errorf("ProgressBar(%v, %d) = %q, want %q", tt.fraction, tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"5.15.0-91-generic", "5.15.0-91-generic", 0},
+		{"5.15.0-91-generic", "5.15.0-105-generic", -1},
+		{"6.1.0-18-amd64", "5.10.0-28-amd64", 1},
+		{"5.4", "5.4.1", -1},
+		{"", "5.4", -1},
+		{"4.18.0-513.el8", "4.18.0-477.el8", 1},
+	}
+	for _, tt := range tests {
+		if got := CompareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestShortDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{45 * time.Second, "45s"},
+		{3*time.Hour + 5*time.Minute, "3h5m"},
+		{26*time.Hour + 30*time.Minute, "1d2h"},
+		{12 * time.Minute, "12m"},
+	}
+	for _, tt := range tests {
+		if got := ShortDuration(tt.d); got != tt.want {
+			t.Errorf("ShortDuration(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestSplitInstance(t *testing.T) {
+	tests := []struct {
+		instance, host, port string
+	}{
+		{"203.0.113.5:9100", "203.0.113.5", "9100"},
+		{"web-1.example.com:9100", "web-1.example.com", "9100"},
+		{"[2001:db8::1]:9100", "2001:db8::1", "9100"},
+		{"web-1", "web-1", ""},
+		{"[2001:db8::1]", "2001:db8::1", ""},
+	}
+	for _, tt := range tests {
+		if host, port := SplitInstance(tt.instance); host != tt.host || port != tt.port {
+			t.Errorf("SplitInstance(%q) = %q, %q, want %q, %q", tt.instance, host, port, tt.host, tt.port)
+		}
+	}
+}
+
+func TestParseBitRate(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+		ok   bool
+	}{
+		{"100Mbps", 100e6, true},
+		{"1.5 Gbit/s", 1.5e9, true},
+		{"500k", 500e3, true},
+		{"800", 800, true},
+		{"10MB/s", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseBitRate(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParseBitRate(%q) = %v, %v, want %v, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParseBytes(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+		ok   bool
+	}{
+		{"1TB", 1e12, true},
+		{"500 GB", 500e9, true},
+		{"1.5T", 1.5e12, true},
+		{"2GiB", 2 << 30, true},
+		{"1024", 1024, true},
+		{"5iB", 0, false},
+		{"1 PB/s", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseBytes(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParseBytes(%q) = %v, %v, want %v, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParseCron(t *testing.T) {
+	friday := time.Date(2026, 10, 16, 18, 0, 0, 0, time.Local)
+	tests := []struct {
+		expr  string
+		at    time.Time
+		match bool
+		ok    bool
+	}{
+		{"0 18 * * 5", friday, true, true},
+		{"0 18 * * 5", friday.Add(time.Minute), false, true},
+		{"0 18 * * 1-4", friday, false, true},
+		{"*/15 * * * *", friday.Add(45 * time.Minute), true, true},
+		{"*/15 * * * *", friday.Add(50 * time.Minute), false, true},
+		{"5/15 * * * *", friday.Add(20 * time.Minute), true, true},
+		{"0 9,18 16 * *", friday, true, true},
+		{"0 18 1 * 5", friday, true, true},
+		{"0 0 * * 7", time.Date(2026, 10, 18, 0, 0, 0, 0, time.Local), true, true},
+		{"@daily", time.Date(2026, 10, 18, 0, 0, 0, 0, time.Local), true, true},
+		{"0 18 * *", friday, false, false},
+		{"60 * * * *", friday, false, false},
+		{"0 18 * * mon", friday, false, false},
+		{"*/0 * * * *", friday, false, false},
+	}
+	for _, tt := range tests {
+		s, err := ParseCron(tt.expr)
+		if (err == nil) != tt.ok {
+			t.Errorf("ParseCron(%q) error = %v, want ok %v", tt.expr, err, tt.ok)
+			continue
+		}
+		if err == nil && s.Matches(tt.at) != tt.match {
+			t.Errorf("ParseCron(%q).Matches(%s) = %v, want %v", tt.expr, tt.at.Format(time.RFC3339), !tt.match, tt.match)
+		}
+	}
+
+	s, _ := ParseCron("0 18 * * 5")
+	if next := s.Next(friday); !next.Equal(friday.AddDate(0, 0, 7)) {
+		t.Errorf("Next(%s) = %s, want %s", friday, next, friday.AddDate(0, 0, 7))
+	}
+}